@@ -52,6 +52,18 @@ ALTER TABLE clients ADD COLUMN IF NOT EXISTS silent_sso BOOLEAN NOT NULL DEFAULT
 ALTER TABLE clients ADD COLUMN IF NOT EXISTS client_secret_hash TEXT;
 ALTER TABLE clients ALTER COLUMN client_secret DROP NOT NULL;
 
+-- client_credentials grant: client 별 명시적 허용 + 요청 가능한 scope 목록.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS client_credentials BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS client_credentials_scopes TEXT[] NOT NULL DEFAULT '{}';
+
+-- consent: first-party client 는 동의 화면 생략.
+-- 컬럼 추가 시점에 이미 있던 client 는 동의 없이 쓰던 앱이므로 true 로 채우고, 이후 INSERT 기본값은 false.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS first_party BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE clients ALTER COLUMN first_party SET DEFAULT false;
+
+-- scope: client 별 요청 가능 scope. 기존 client 는 지금까지처럼 표준 scope 전부 허용.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS allowed_scopes TEXT[] NOT NULL DEFAULT '{openid,profile,email}';
+
 -- Phase-R R-1: users 글로벌 테이블.
 CREATE TABLE IF NOT EXISTS users (
     id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -68,6 +80,255 @@ CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
 ALTER TABLE users ADD COLUMN IF NOT EXISTS email TEXT;
 ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT false;
 CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email IS NOT NULL;
+
+-- JWT 서명키 영속화. kid = RFC 7638 thumbprint.
+-- 재시작 / 다중 인스턴스에서도 같은 키로 서명해야 기존 토큰이 계속 검증된다.
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid              TEXT PRIMARY KEY,
+    private_key_pem  TEXT NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- 서명키 회전: next(미리 게시) → active(서명) → retired(검증용 게시 후 prune).
+-- 단일 키 시절 행은 active 로 간주.
+ALTER TABLE signing_keys ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('next', 'active', 'retired'));
+ALTER TABLE signing_keys ADD COLUMN IF NOT EXISTS activated_at TIMESTAMPTZ;
+ALTER TABLE signing_keys ADD COLUMN IF NOT EXISTS retired_at TIMESTAMPTZ;
+UPDATE signing_keys SET activated_at = created_at WHERE status = 'active' AND activated_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_signing_keys_status ON signing_keys(status);
+
+-- active / next 는 항상 1 개. 여러 replica 가 동시에 InitKeys / Reload 해도 한 인스턴스의 키만 들어간다
+-- (나머지는 unique 위반 → 다시 읽기). 이전 버전에서 생긴 중복은 먼저 정리:
+-- 가장 먼저 활성화된 active 만 남기고 retired, next 는 서명에 쓰인 적이 없으므로 가장 오래된 것만 남기고 삭제.
+UPDATE signing_keys SET status = 'retired', retired_at = now()
+WHERE status = 'active' AND kid <> (
+    SELECT kid FROM signing_keys WHERE status = 'active'
+    ORDER BY COALESCE(activated_at, created_at) ASC, kid ASC LIMIT 1
+);
+DELETE FROM signing_keys
+WHERE status = 'next' AND kid <> (
+    SELECT kid FROM signing_keys WHERE status = 'next'
+    ORDER BY created_at ASC, kid ASC LIMIT 1
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signing_keys_one_per_status ON signing_keys(status)
+    WHERE status IN ('active', 'next');
+
+-- authorization code / refresh token 영속화. 재시작 / 다중 replica 에서도 유지.
+-- 평문 대신 sha256 hex 를 PK 로 — DB 가 유출돼도 토큰 재사용 불가.
+CREATE TABLE IF NOT EXISTS auth_codes (
+    code_hash              TEXT PRIMARY KEY,
+    client_id              TEXT NOT NULL,
+    user_id                TEXT NOT NULL,
+    redirect_uri           TEXT NOT NULL,
+    scope                  TEXT NOT NULL DEFAULT '',
+    expires_at             TIMESTAMPTZ NOT NULL,
+    code_challenge         TEXT NOT NULL DEFAULT '',
+    code_challenge_method  TEXT NOT NULL DEFAULT '',
+    nonce                  TEXT NOT NULL DEFAULT '',
+    auth_time              TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_auth_codes_expires_at ON auth_codes(expires_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash   TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL,
+    client_id    TEXT NOT NULL,
+    scope        TEXT NOT NULL DEFAULT '',
+    expires_at   TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+-- refresh token family (rotation 계보) + 소비 tombstone — 재사용 탐지 시 family 일괄 폐기.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+
+-- IdP 글로벌 세션. 배포 후에도 silent SSO 유지 + 여러 인스턴스 공유.
+CREATE TABLE IF NOT EXISTS idp_sessions (
+    sid          TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL,
+    login_at     TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_idp_sessions_expires_at ON idp_sessions(expires_at);
+
+-- RFC 8628 device authorization. device_code 는 sha256 hex 만, user_code 는 정규화 형태.
+CREATE TABLE IF NOT EXISTS device_codes (
+    device_code_hash  TEXT PRIMARY KEY,
+    user_code         TEXT NOT NULL UNIQUE,
+    client_id         TEXT NOT NULL,
+    scope             TEXT NOT NULL DEFAULT '',
+    status            TEXT NOT NULL DEFAULT 'pending'
+                      CHECK (status IN ('pending', 'approved', 'denied')),
+    user_id           TEXT NOT NULL DEFAULT '',
+    auth_time         TIMESTAMPTZ,
+    interval_seconds  INTEGER NOT NULL,
+    last_polled_at    TIMESTAMPTZ,
+    expires_at        TIMESTAMPTZ NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_device_codes_expires_at ON device_codes(expires_at);
+
+-- 사용자 × client 동의 기록. scopes 는 지금까지 동의한 합집합.
+CREATE TABLE IF NOT EXISTS consents (
+    user_id     TEXT NOT NULL,
+    client_id   TEXT NOT NULL,
+    scopes      TEXT[] NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, client_id)
+);
+
+-- 2단계 인증 (TOTP). 컬럼 추가 이전 세션 / code 는 비밀번호 로그인이었으므로 amr 기본값 {pwd}.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS recovery_code_hashes TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE idp_sessions ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{pwd}';
+ALTER TABLE auth_codes ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{pwd}';
+ALTER TABLE device_codes ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{pwd}';
+
+-- 패스키 (WebAuthn). credential_id = base64url rawId, public_key = COSE_Key 원본.
+-- seed / 가입 사용자 모두 users.id 가 UUID 이므로 FK 로 묶고, 사용자 삭제 시 함께 삭제.
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    credential_id  TEXT PRIMARY KEY,
+    user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name           TEXT NOT NULL DEFAULT '',
+    public_key     BYTEA NOT NULL,
+    sign_count     BIGINT NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+-- 사용자 이름 단위 로그인 실패 누적 / 잠금. users 와 FK 없음 — 없는 이름도 같은 방식으로 기록.
+CREATE TABLE IF NOT EXISTS login_throttles (
+    username        TEXT PRIMARY KEY,
+    failures        INTEGER NOT NULL DEFAULT 0,
+    last_failed_at  TIMESTAMPTZ NOT NULL,
+    locked_until    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_login_throttles_last_failed_at ON login_throttles(last_failed_at);
+
+-- 비밀번호 재설정 링크. 평문 token 은 메일에만, 여기엔 sha256 hex.
+CREATE TABLE IF NOT EXISTS password_resets (
+    token_hash  TEXT PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets(user_id);
+CREATE INDEX IF NOT EXISTS idx_password_resets_expires_at ON password_resets(expires_at);
+
+-- 사용자 단위 일괄 폐기 (비밀번호 재설정) 용.
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_idp_sessions_user_id ON idp_sessions(user_id);
+
+-- 이메일 인증: 서명된 링크로 users.email_verified 를 켠다 (링크 자체는 저장하지 않음).
+-- client 별로 인증된 이메일을 요구할 수 있다.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS require_verified_email BOOLEAN NOT NULL DEFAULT false;
+
+-- 어드민 사용자 관리: 비활성화된 계정은 로그인 / silent SSO / refresh 거부.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
+
+-- client secret 회전: 유예 기간 동안 직전 hash 도 인정. client 삭제 시 refresh token / 동의 일괄 정리.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS previous_secret_hash TEXT;
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS previous_secret_expires_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_client_id ON refresh_tokens(client_id);
+CREATE INDEX IF NOT EXISTS idx_consents_client_id ON consents(client_id);
+
+-- 동적 client 등록 (RFC 7591 / 7592). 등록 토큰은 sha256 hex 만 — 관리 API 로 평문이 다시 나가지 않는다.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS logo_uri TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS grant_types TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS token_endpoint_auth_method TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS registration_access_token_hash TEXT NOT NULL DEFAULT '';
+
+-- public client (SPA / 모바일): secret 없이 client_id + S256 PKCE. 빈 값 = confidential.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS client_type TEXT NOT NULL DEFAULT '';
+
+-- JWT client 인증 (RFC 7523). private_key_jwt 공개키 (JWK Set 원문 또는 URI) +
+-- client_secret_jwt 용 암호화 secret (AES-GCM, 그 방식 client 만). jti 는 assertion 만료까지 보관해 재사용 차단.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS jwks TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS jwks_uri TEXT NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS client_secret_sealed TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS client_assertion_jtis (
+    client_id   TEXT NOT NULL,
+    jti         TEXT NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (client_id, jti)
+);
+CREATE INDEX IF NOT EXISTS idx_client_assertion_jtis_expires_at ON client_assertion_jtis(expires_at);
+
+-- OIDC Back-Channel Logout: IdP 세션마다 code 를 받아 간 client 목록. logout / 세션 폐기 때 이 client 들에 logout_token.
+-- auth_codes.sid 는 ID Token sid claim 으로 이어진다 (세션 쿠키 값이 아니라 그 파생값).
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS backchannel_logout_uri TEXT NOT NULL DEFAULT '';
+ALTER TABLE idp_sessions ADD COLUMN IF NOT EXISTS client_ids TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE auth_codes ADD COLUMN IF NOT EXISTS sid TEXT NOT NULL DEFAULT '';
+
+-- OIDC Front-Channel Logout: 로그아웃 페이지가 숨은 iframe 으로 띄울 client URI.
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS frontchannel_logout_uri TEXT NOT NULL DEFAULT '';
+
+-- /account: 사용자가 자기 세션 (기기 / IP / 로그인 · 마지막 사용 시각) 과 연결된 앱을 본다.
+-- 사용자별 조회는 idx_idp_sessions_user_id / idx_refresh_tokens_user_id 를 그대로 쓴다.
+ALTER TABLE idp_sessions ADD COLUMN IF NOT EXISTS ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE idp_sessions ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE idp_sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- 어드민: 공유 비밀번호 대신 IdP 사용자에게 역할 (viewer / client-manager / user-manager / superadmin) 부여.
+-- 빈 값 = 어드민 아님. 첫 superadmin 은 OAUTH_ADMIN_BOOTSTRAP 으로 부팅 시 지정.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS admin_role TEXT NOT NULL DEFAULT '';
+
+-- 감사 로그 영속 sink (OAUTH_AUDIT_STORE=postgres). stdout 로그와 같은 이벤트를 조회 열 + JSONB 로.
+-- 보존 기간 (OAUTH_AUDIT_RETENTION) 이 지난 행은 at 기준으로 주기 삭제.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id          BIGSERIAL PRIMARY KEY,
+    at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    level       TEXT NOT NULL,
+    event       TEXT NOT NULL,
+    actor       TEXT NOT NULL DEFAULT '',
+    subject     TEXT NOT NULL DEFAULT '',
+    client_id   TEXT NOT NULL DEFAULT '',
+    ip          TEXT NOT NULL DEFAULT '',
+    user_agent  TEXT NOT NULL DEFAULT '',
+    attrs       JSONB NOT NULL DEFAULT '{}'
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_at ON audit_events(at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor, at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_subject ON audit_events(subject, at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_client_id ON audit_events(client_id, at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_event ON audit_events(event text_pattern_ops, at);
+
+-- access token kill switch 기준 시각. 이전엔 인스턴스 메모리에만 있어 다른 replica 가 모르고 통과시켰다.
+-- 빈 user_id / client_id = 전체. expires_at (= 기준 + access token 최대 수명) 이 지나면 주기 삭제.
+CREATE TABLE IF NOT EXISTS access_token_cutoffs (
+    user_id     TEXT NOT NULL,
+    client_id   TEXT NOT NULL,
+    cutoff      TIMESTAMPTZ NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, client_id)
+);
+CREATE INDEX IF NOT EXISTS idx_access_token_cutoffs_expires_at ON access_token_cutoffs(expires_at);
+
+-- refresh token family 단위 access token 차단 (재사용 탐지 / 비활성 사용자). access token 의 fid claim 과 비교.
+CREATE TABLE IF NOT EXISTS access_token_family_cutoffs (
+    family_id   TEXT PRIMARY KEY,
+    cutoff      TIMESTAMPTZ NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_access_token_family_cutoffs_expires_at ON access_token_family_cutoffs(expires_at);
+
+-- 어드민 로그인 세션. 쿠키만으로는 로그아웃 / 비밀번호 재설정 후에도 복사된 쿠키가 살아 있어 서버에도 둔다.
+-- id_hash = sha256(쿠키의 세션 nonce).
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    id_hash     TEXT PRIMARY KEY,
+    user_id     TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_user_id ON admin_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_admin_sessions_expires_at ON admin_sessions(expires_at);
 `
 
 // RunMigrations: schema 를 멱등하게 적용. main 부팅 시 db.Connect 후 호출.
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// 사용자 계정 — GET·POST /oauth/account. IdP 세션이 있는 사용자 본인만 (passkeys / 2fa 와 같은 방식).
+//
+//	GET  /oauth/account                          로그인된 세션 목록 + refresh token 을 가진 앱 목록
+//	POST /oauth/account  action=signout_session  sid 의 세션 종료 (+ Back-Channel Logout). 지금 세션이면 /oauth/logout 으로
+//	                     action=revoke_app       client 의 동의 기록 + refresh token 폐기
+//
+// CSRF 쿠키가 Path=/oauth 라 페이지도 /oauth 아래에 둔다. 짧은 주소 /account 는 redirect 만.
+// 세션은 sid claim 값 (models.SessionSID) 으로 가리킨다 — 세션 쿠키 값은 페이지에 싣지 않는다.
+
+// accountPageData: account.html 템플릿 데이터.
+type accountPageData struct {
+	Username  string
+	Sessions  []accountSession
+	Apps      []accountApp
+	CSRFToken string
+	ErrorMsg  string
+	Notice    string
+}
+
+// accountSession: 세션 한 줄. Apps 는 그 세션으로 로그인한 앱 이름.
+type accountSession struct {
+	SID        string
+	Device     string
+	IP         string
+	LoginAt    time.Time
+	LastSeenAt time.Time
+	Current    bool
+	Apps       []string
+}
+
+// accountApp: 살아 있는 refresh token 을 가진 client 한 줄 (토큰 여러 개를 client 단위로 묶음).
+type accountApp struct {
+	ClientID     string
+	Name         string
+	URL          string
+	Scopes       []string
+	Tokens       int
+	LastIssuedAt time.Time
+}
+
+// AccountRedirectHandler: GET /account → /oauth/account.
+func AccountRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, "/oauth/account", http.StatusFound)
+}
+
+// AccountGetHandler: GET /oauth/account.
+func AccountGetHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := sessionUser(w, r, tmpl)
+		if !ok {
+			return
+		}
+		renderAccount(w, r, tmpl, user, "", "")
+	}
+}
+
+// AccountPostHandler: POST /oauth/account — action=signout_session / action=revoke_app. CSRF + IdP 세션 필수.
+func AccountPostHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		user, ok := sessionUser(w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		switch r.FormValue("action") {
+		case "signout_session":
+			sess := userSessionBySID(user.ID, r.FormValue("sid"))
+			if sess == nil {
+				renderAccount(w, r, tmpl, user, "세션을 찾을 수 없습니다. 이미 종료되었을 수 있습니다", "")
+				return
+			}
+			// 지금 쓰는 세션은 일반 로그아웃과 같다 — front-channel iframe 까지 /oauth/logout 이 처리
+			if current, _ := GetIdPSessionID(r); current == sess.SessionID {
+				http.Redirect(w, r, "/oauth/logout", http.StatusSeeOther)
+				return
+			}
+			store.IdPSessions.Delete(sess.SessionID)
+			backchannelLogout("account", sess)
+			AuditWarn(r, "account.session_signed_out", "sub", user.ID, "sid", sess.SID())
+			renderAccount(w, r, tmpl, user, "", "세션을 종료했습니다")
+
+		case "revoke_app":
+			clientID := r.FormValue("client_id")
+			if clientID == "" {
+				http.Error(w, "client_id 필요", http.StatusBadRequest)
+				return
+			}
+			ctx := r.Context()
+			err1 := revokeAccessTokensIssuedBefore(ctx, user.ID, clientID)
+			tokens, err2 := store.RefreshTokens.RevokeByUserClient(ctx, user.ID, clientID)
+			err3 := store.Consents.Revoke(ctx, user.ID, clientID)
+			if err := errors.Join(err1, err2, err3); err != nil {
+				log.Printf("[account] 앱 연결 해제 실패 sub=%s client_id=%s: %v", user.ID, clientID, err)
+				renderAccount(w, r, tmpl, user, "연결 해제에 실패했습니다. 다시 시도하세요", "")
+				return
+			}
+			AuditWarn(r, "account.app_revoked", "sub", user.ID, "client_id", clientID, "refresh_tokens", tokens)
+			renderAccount(w, r, tmpl, user, "", "앱 연결을 해제했습니다. 다음에 그 앱을 쓰면 다시 동의를 묻습니다")
+
+		default:
+			http.Error(w, "알 수 없는 action", http.StatusBadRequest)
+		}
+	}
+}
+
+// userSessionBySID: 사용자 본인의 세션 중 sid claim 값이 일치하는 것. 남의 세션은 목록에 없으므로 고를 수 없다.
+func userSessionBySID(userID, sid string) *models.IdPSession {
+	sessions, err := store.IdPSessions.ListByUser(userID)
+	if err != nil || sid == "" {
+		return nil
+	}
+	for _, s := range sessions {
+		if s.SID() == sid {
+			return s
+		}
+	}
+	return nil
+}
+
+// renderAccount: 세션 / 앱 목록 조회 + 새 CSRF 토큰 + clickjacking 차단 후 렌더.
+func renderAccount(w http.ResponseWriter, r *http.Request, tmpl *template.Template, user *models.User, errMsg, notice string) {
+	sessions, err := store.IdPSessions.ListByUser(user.ID)
+	if err != nil {
+		http.Error(w, "세션 조회 실패", http.StatusInternalServerError)
+		return
+	}
+	tokens, err := store.RefreshTokens.ListByUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "앱 목록 조회 실패", http.StatusInternalServerError)
+		return
+	}
+	csrfToken, err := NewCSRFToken(w)
+	if err != nil {
+		http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+
+	current, _ := GetIdPSessionID(r)
+	data := accountPageData{
+		Username:  user.Username,
+		CSRFToken: csrfToken,
+		ErrorMsg:  errMsg,
+		Notice:    notice,
+	}
+	for _, s := range sessions {
+		row := accountSession{
+			SID:        s.SID(),
+			Device:     describeUserAgent(s.UserAgent),
+			IP:         s.IP,
+			LoginAt:    s.LoginAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    s.SessionID == current,
+		}
+		for _, id := range s.ClientIDs {
+			row.Apps = append(row.Apps, clientDisplayName(id))
+		}
+		data.Sessions = append(data.Sessions, row)
+	}
+	data.Apps = accountApps(tokens)
+
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := tmpl.ExecuteTemplate(w, "account.html", data); err != nil {
+		http.Error(w, "템플릿 렌더링 실패", http.StatusInternalServerError)
+	}
+}
+
+// accountApps: refresh token 을 client 단위로 묶는다. scope 는 합집합, 최근 발급 순.
+func accountApps(tokens []*models.RefreshToken) []accountApp {
+	byClient := map[string]*accountApp{}
+	var order []string
+	for _, rt := range tokens {
+		app, ok := byClient[rt.ClientID]
+		if !ok {
+			app = &accountApp{ClientID: rt.ClientID, Name: rt.ClientID}
+			if c, ok := store.Clients.GetByClientID(rt.ClientID); ok {
+				app.Name, app.URL = c.Name, c.MainURL
+			}
+			byClient[rt.ClientID] = app
+			order = append(order, rt.ClientID)
+		}
+		app.Tokens++
+		if rt.CreatedAt.After(app.LastIssuedAt) {
+			app.LastIssuedAt = rt.CreatedAt
+		}
+		for _, s := range splitScope(rt.Scope) {
+			if !contains(app.Scopes, s) {
+				app.Scopes = append(app.Scopes, s)
+			}
+		}
+	}
+	out := make([]accountApp, 0, len(order))
+	for _, id := range order {
+		out = append(out, *byClient[id])
+	}
+	slices.SortFunc(out, func(a, b accountApp) int { return b.LastIssuedAt.Compare(a.LastIssuedAt) })
+	return out
+}
+
+func clientDisplayName(clientID string) string {
+	if c, ok := store.Clients.GetByClientID(clientID); ok && c.Name != "" {
+		return c.Name
+	}
+	return clientID
+}
+
+// describeUserAgent: User-Agent → "브라우저 · OS" 요약. 순서가 중요하다 (Chrome UA 에도 Safari, Android 에도 Linux 가 있다).
+func describeUserAgent(ua string) string {
+	if ua == "" {
+		return "알 수 없는 기기"
+	}
+	pick := func(pairs [][2]string) string {
+		for _, p := range pairs {
+			if strings.Contains(ua, p[0]) {
+				return p[1]
+			}
+		}
+		return ""
+	}
+	browser := pick([][2]string{{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"}})
+	os := pick([][2]string{{"Windows", "Windows"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Android", "Android"}, {"Mac OS X", "macOS"}, {"Linux", "Linux"}})
+	switch {
+	case browser != "" && os != "":
+		return browser + " · " + os
+	case browser != "" || os != "":
+		return browser + os
+	}
+	if len(ua) > 60 {
+		ua = strings.ToValidUTF8(ua[:60], "") + "…"
+	}
+	return ua
+}
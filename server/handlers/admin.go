@@ -2,17 +2,22 @@ package handlers
 
 import (
 	"html/template"
+	"log"
 	"net/http"
 
 	"github.com/ftery0/ouath/server/models"
 	"github.com/ftery0/ouath/server/store"
+	"github.com/ftery0/ouath/server/token"
 )
 
 // adminMainPageData: admin_main.html 에 넘어가는 데이터.
 // Phase-R 단순화: Groups 제거. SilentSSOCount 추가.
 type adminMainPageData struct {
+	adminChrome
 	Clients        []*models.Client
 	SilentSSOCount int
+	SigningKeys    []token.KeyInfo // JWKS 에 게시 중인 키 (active / next / retired)
+	TokenCounts    map[string]int  // client_id → 살아 있는 refresh token 수
 	FlashMsg       string
 	FlashErr       bool
 }
@@ -28,9 +33,16 @@ func AdminMainHandler(tmpl *template.Template) http.HandlerFunc {
 				silent++
 			}
 		}
+		counts, err := store.RefreshTokens.CountLiveByClient(r.Context())
+		if err != nil {
+			log.Printf("[admin] refresh token 집계 실패: %v", err)
+		}
 		data := adminMainPageData{
+			adminChrome:    adminChromeFor(r),
 			Clients:        clients,
+			TokenCounts:    counts,
 			SilentSSOCount: silent,
+			SigningKeys:    token.PublishedKeys(),
 			FlashMsg:       flash,
 			FlashErr:       isErr,
 		}
@@ -38,14 +50,82 @@ func AdminMainHandler(tmpl *template.Template) http.HandlerFunc {
 	}
 }
 
-// flashFromQuery: 쿼리스트링 ?error= 를 사용자 메시지로 변환.
-// Phase-R: 그룹 관련 메시지 제거.
+// flashFromQuery: 쿼리스트링 ?error= / ?notice= 를 사용자 메시지로 변환.
+// Phase-R: 그룹 관련 메시지 제거. 사용자 관리 (admin_users.go) 도 같은 코드 체계.
 func flashFromQuery(r *http.Request) (msg string, isErr bool) {
 	switch r.URL.Query().Get("error") {
 	case "register_failed":
 		return "등록에 실패했습니다", true
 	case "form_parse_failed":
 		return "폼 파싱 실패", true
+	case "key_rotate_failed":
+		return "서명키 회전에 실패했습니다 (다른 인스턴스가 먼저 회전했을 수 있음)", true
+	case "client_not_found":
+		return "서비스를 찾을 수 없습니다", true
+	case "client_update_failed":
+		return "서비스 정보 변경에 실패했습니다", true
+	case "invalid_grace":
+		return "유예 기간이 올바르지 않습니다 (0 ~ 168 시간)", true
+	case "grace_unsupported":
+		return "client_secret_jwt 앱은 이전 secret 을 유예할 수 없습니다 — 즉시 폐기로 재발급하세요", true
+	case "secret_rotate_failed":
+		return "client_secret 재발급에 실패했습니다", true
+	case "client_delete_failed":
+		return "서비스 삭제에 실패했습니다", true
+	case "client_no_secret":
+		return "이 서비스는 client_secret 을 쓰지 않습니다 (public / private_key_jwt)", true
+	case "user_not_found":
+		return "사용자를 찾을 수 없습니다", true
+	case "invalid_email":
+		return "이메일 형식이 올바르지 않습니다", true
+	case "email_in_use":
+		return "다른 사용자가 쓰고 있는 이메일입니다", true
+	case "user_update_failed":
+		return "사용자 정보 변경에 실패했습니다", true
+	case "no_email":
+		return "이메일이 없는 사용자는 재설정 메일을 받을 수 없습니다", true
+	case "password_reset_failed":
+		return "비밀번호 재설정에 실패했습니다", true
+	case "reset_mail_failed":
+		return "비밀번호는 무효화했지만 재설정 메일 발송에 실패했습니다", true
+	case "user_delete_failed":
+		return "사용자 삭제에 실패했습니다", true
+	case "revoke_failed":
+		return "세션 / 토큰 폐기에 실패했습니다", true
+	case "invalid_role":
+		return "알 수 없는 어드민 역할입니다", true
+	case "own_role":
+		return "자기 자신의 역할은 바꿀 수 없습니다", true
+	case "own_account":
+		return "자기 자신의 계정은 비활성화하거나 삭제할 수 없습니다", true
+	case "target_is_admin":
+		return "어드민 계정은 superadmin 만 변경할 수 있습니다", true
+	}
+	switch r.URL.Query().Get("notice") {
+	case "key_rotated":
+		return "서명키를 회전했습니다. 이전 키는 기존 토큰 만료까지 JWKS 에 남습니다", false
+	case "client_updated":
+		return "서비스 정보를 변경했습니다", false
+	case "client_updated_rotate":
+		return "서비스 정보를 변경했습니다. 새 인증 방식에 쓸 secret 이 없으니 \"secret 재발급\" 을 하세요", false
+	case "client_deleted":
+		return "서비스를 삭제했습니다. refresh token 과 동의 기록도 함께 지웠습니다", false
+	case "user_updated":
+		return "사용자 정보를 변경했습니다", false
+	case "user_disabled":
+		return "계정을 비활성화했습니다. 열려 있던 세션과 토큰은 폐기했습니다", false
+	case "user_enabled":
+		return "계정을 다시 활성화했습니다", false
+	case "password_reset_sent":
+		return "현재 비밀번호를 무효화하고 재설정 메일을 보냈습니다", false
+	case "user_deleted":
+		return "사용자를 삭제했습니다", false
+	case "sessions_revoked":
+		return "로그인 세션을 모두 종료했습니다", false
+	case "role_changed":
+		return "어드민 역할을 변경했습니다", false
+	case "tokens_revoked":
+		return "refresh token 을 폐기하고 이미 발급된 access token 을 차단했습니다", false
 	}
 	return "", false
 }
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// 어드민 감사 로그 — store.Audit (영속 sink) 조회 + CSV / JSON 내보내기.
+// sink 가 꺼져 있으면 (stdout 만) 화면은 안내만, 내보내기는 404.
+
+// auditTimeLayouts: 필터 시각 — datetime-local 입력 또는 날짜만. 서버 로컬 시간대로 해석.
+var auditTimeLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02"}
+
+// auditFilter: 폼에 되돌려 보여 줄 필터 원문.
+type auditFilter struct {
+	User     string // username 또는 sub
+	ClientID string
+	Event    string // 접두 일치
+	Since    string
+	Until    string
+}
+
+// auditRow: 목록 한 줄. Attrs 는 JSON 한 줄로.
+type auditRow struct {
+	*models.AuditEntry
+	AttrsJSON string
+}
+
+type adminAuditPageData struct {
+	adminChrome
+	Enabled    bool
+	Filter     auditFilter
+	Rows       []auditRow
+	Total      int
+	Page       int
+	PrevURL    string
+	NextURL    string
+	ExportCSV  string
+	ExportJSON string
+	ErrorMsg   string
+	Dropped    int64 // 이 인스턴스에서 큐가 넘쳐 sink 에 못 남긴 건수
+}
+
+// AdminAuditHandler: GET /admin/audit — 필터 (사용자 / client / 이벤트 / 기간) + 최신순 페이지.
+func AdminAuditHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := adminAuditPageData{adminChrome: adminChromeFor(r), Enabled: store.Audit != nil, Dropped: AuditDropped()}
+		f, q, err := parseAuditFilter(r)
+		data.Filter = f
+		if err != nil {
+			data.ErrorMsg = err.Error()
+		}
+		if !data.Enabled || err != nil {
+			tmpl.ExecuteTemplate(w, "admin_audit.html", data)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		page = max(page, 1)
+		q.Offset = (page - 1) * store.DefaultAuditPageSize
+		q.Limit = store.DefaultAuditPageSize
+		entries, total, err := store.Audit.List(r.Context(), q)
+		if err != nil {
+			log.Printf("[admin] 감사 로그 조회 실패: %v", err)
+			http.Error(w, "감사 로그 조회 실패", http.StatusInternalServerError)
+			return
+		}
+
+		data.Total = total
+		data.Page = page
+		for _, e := range entries {
+			data.Rows = append(data.Rows, auditRow{AuditEntry: e, AttrsJSON: auditAttrsJSON(e.Attrs)})
+		}
+		if page > 1 {
+			data.PrevURL = "/admin/audit?" + f.values("page", strconv.Itoa(page-1))
+		}
+		if page*store.DefaultAuditPageSize < total {
+			data.NextURL = "/admin/audit?" + f.values("page", strconv.Itoa(page+1))
+		}
+		data.ExportCSV = "/admin/audit/export?" + f.values("format", "csv")
+		data.ExportJSON = "/admin/audit/export?" + f.values("format", "json")
+		tmpl.ExecuteTemplate(w, "admin_audit.html", data)
+	}
+}
+
+// AdminAuditExportHandler: GET /admin/audit/export?format=csv|json — 현재 필터 그대로, 최신순 최대 AuditExportLimit 건.
+// 내보내기 자체도 감사 로그에 남긴다.
+func AdminAuditExportHandler(w http.ResponseWriter, r *http.Request) {
+	if store.Audit == nil {
+		http.Error(w, "감사 로그 저장소가 꺼져 있습니다 (OAUTH_AUDIT_STORE)", http.StatusNotFound)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "csv" && format != "json" {
+		http.Error(w, "format 은 csv 또는 json", http.StatusBadRequest)
+		return
+	}
+	f, q, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Limit = store.AuditExportLimit
+	entries, total, err := store.Audit.List(r.Context(), q)
+	if err != nil {
+		log.Printf("[admin] 감사 로그 내보내기 실패: %v", err)
+		http.Error(w, "감사 로그 조회 실패", http.StatusInternalServerError)
+		return
+	}
+	AuditEvent(r, "admin.audit_exported", "by", adminActor(r), "format", format, "count", len(entries), "total", total,
+		"filter_user", f.User, "filter_client_id", f.ClientID, "filter_event", f.Event, "since", f.Since, "until", f.Until)
+
+	filename := "audit-" + time.Now().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "json" {
+		out := make([]auditExportEntry, 0, len(entries))
+		for _, e := range entries {
+			out = append(out, newAuditExportEntry(e))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "level", "event", "actor", "subject", "client_id", "ip", "user_agent", "attrs"})
+	for _, e := range entries {
+		row := []string{
+			e.Time.Format(time.RFC3339), e.Level, e.Event, e.Actor, e.Subject, e.ClientID, e.IP, e.UserAgent,
+			auditAttrsJSON(e.Attrs),
+		}
+		for i := range row {
+			row[i] = csvSafeCell(row[i])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+}
+
+// auditExportEntry: JSON 내보내기 형식 (snake_case).
+type auditExportEntry struct {
+	Time      string         `json:"time"`
+	Level     string         `json:"level"`
+	Event     string         `json:"event"`
+	Actor     string         `json:"actor,omitempty"`
+	Subject   string         `json:"subject,omitempty"`
+	ClientID  string         `json:"client_id,omitempty"`
+	IP        string         `json:"ip,omitempty"`
+	UserAgent string         `json:"user_agent,omitempty"`
+	Attrs     map[string]any `json:"attrs,omitempty"`
+}
+
+func newAuditExportEntry(e *models.AuditEntry) auditExportEntry {
+	return auditExportEntry{
+		Time:      e.Time.Format(time.RFC3339),
+		Level:     e.Level,
+		Event:     e.Event,
+		Actor:     e.Actor,
+		Subject:   e.Subject,
+		ClientID:  e.ClientID,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		Attrs:     e.Attrs,
+	}
+}
+
+// parseAuditFilter: 쿼리스트링 → (폼 원문, store 조건).
+// 사용자 필터는 username 이면 sub 도, sub 면 username 도 같이 찾는다 (행위자 / 대상 어느 쪽이든).
+func parseAuditFilter(r *http.Request) (auditFilter, store.AuditQuery, error) {
+	v := r.URL.Query()
+	f := auditFilter{
+		User:     strings.TrimSpace(v.Get("user")),
+		ClientID: strings.TrimSpace(v.Get("client_id")),
+		Event:    strings.TrimSpace(v.Get("event")),
+		Since:    strings.TrimSpace(v.Get("since")),
+		Until:    strings.TrimSpace(v.Get("until")),
+	}
+	q := store.AuditQuery{ClientID: f.ClientID, Event: f.Event}
+	if f.User != "" {
+		q.Users = []string{f.User}
+		if u, err := store.Users.GetByUsername(r.Context(), f.User); err == nil {
+			q.Users = append(q.Users, u.ID)
+		} else if u, err := store.Users.GetByID(r.Context(), f.User); err == nil {
+			q.Users = append(q.Users, u.Username)
+		}
+	}
+	var err error
+	if q.Since, err = parseAuditTime(f.Since); err != nil {
+		return f, q, err
+	}
+	if q.Until, err = parseAuditTime(f.Until); err != nil {
+		return f, q, err
+	}
+	return f, q, nil
+}
+
+// parseAuditTime: 빈 값은 zero (조건 없음).
+func parseAuditTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range auditTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errInvalidAuditTime
+}
+
+var errInvalidAuditTime = errors.New("기간 형식이 올바르지 않습니다 (YYYY-MM-DD 또는 YYYY-MM-DDTHH:MM)")
+
+// values: 필터 + 추가 키 하나를 쿼리스트링으로 (페이지 / 내보내기 링크).
+func (f auditFilter) values(key, value string) string {
+	v := url.Values{}
+	for k, s := range map[string]string{"user": f.User, "client_id": f.ClientID, "event": f.Event, "since": f.Since, "until": f.Until} {
+		if s != "" {
+			v.Set(k, s)
+		}
+	}
+	v.Set(key, value)
+	return v.Encode()
+}
+
+// csvSafeCell: 스프레드시트가 수식으로 읽을 첫 글자 (= + - @ 탭 CR) 앞에 ' 를 붙인다.
+// username / user agent 같은 사용자 입력이 그대로 실리므로 내보낸 파일을 연 어드민 PC 에서 실행되지 않게.
+func csvSafeCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// auditAttrsJSON: Attrs 한 줄 JSON. 비어 있으면 "".
+func auditAttrsJSON(attrs map[string]any) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -7,9 +7,12 @@ import (
 	"errors"
 	"html/template"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/scope"
 	"github.com/ftery0/ouath/server/store"
 )
 
@@ -20,9 +23,12 @@ func writeJSON(w http.ResponseWriter, status int, data any) {
 	_ = json.NewEncoder(w).Encode(data)
 }
 
-// adminClientNewPageData: 신규 등록 폼에 넘기는 데이터.
+// adminClientNewPageData: 신규 등록 / 편집 폼에 넘기는 데이터.
 // Phase-R: 그룹/override 제거. silent_sso 토글 기본값 true.
+// ClientID 가 있으면 편집 모드 — 같은 템플릿이 POST /admin/clients/{id} 로 보낸다.
 type adminClientNewPageData struct {
+	adminChrome
+	ClientID     string
 	ErrorMsg     string
 	Name         string
 	Description  string
@@ -30,20 +36,62 @@ type adminClientNewPageData struct {
 	ServerURLs   []string
 	RedirectURIs []string
 	SilentSSO    bool
+	FirstParty   bool
+	Public       bool // client 유형 — true 면 public (secret 없음, PKCE 필수)
+
+	// confidential client 의 /token 인증 방식 + private_key_jwt 공개키 (JWK Set JSON 또는 URI 중 하나)
+	AuthMethod       string
+	JWKS             string
+	JWKSURI          string
+	SecretJWTEnabled bool // client_secret_jwt 선택지 노출 (OAUTH_CLIENT_SECRET_KEY 설정 시)
+
+	BackchannelLogoutURI  string // IdP 세션 종료 시 logout_token 을 받을 URI (비면 통지 안 함)
+	FrontchannelLogoutURI string // 로그아웃 페이지가 iframe 으로 띄울 URI (redirect_uri 와 같은 origin)
+
+	RequireVerifiedEmail bool
+
+	ClientCredentials       bool
+	ClientCredentialsScopes string // 공백 구분
+
+	Scopes []adminScopeOption // 허용 scope 체크박스 (레지스트리 전체)
+}
+
+// adminScopeOption: 등록 폼의 scope 체크박스 한 칸.
+type adminScopeOption struct {
+	scope.Scope
+	Checked bool
 }
 
-// adminClientCreatedPageData: 등록 성공 후 secret 1회 노출 페이지.
+// adminScopeOptions: 레지스트리 전체를 체크박스로. checked 에 있는 것만 체크.
+func adminScopeOptions(checked []string) []adminScopeOption {
+	out := make([]adminScopeOption, 0)
+	for _, s := range scope.All() {
+		out = append(out, adminScopeOption{Scope: s, Checked: contains(checked, s.Name)})
+	}
+	return out
+}
+
+// adminClientCreatedPageData: 등록 성공 / secret 재발급 후 secret 1회 노출 페이지.
+// Rotated 면 재발급 — GraceUntil 이 있으면 그때까지 옛 secret 도 통과.
 type adminClientCreatedPageData struct {
-	Client *models.Client
+	adminChrome
+	Client     *models.Client
+	Rotated    bool
+	GraceUntil time.Time
 }
 
 // AdminClientNewFormHandler: GET /admin/clients/new — 등록 폼.
 func AdminClientNewFormHandler(tmpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		data := adminClientNewPageData{
+			adminChrome:  adminChromeFor(r),
 			RedirectURIs: []string{""},
 			ServerURLs:   []string{""},
 			SilentSSO:    true,
+			AuthMethod:   models.AuthMethodClientSecretBasic,
+			Scopes:       adminScopeOptions(scope.DefaultNames()),
+
+			SecretJWTEnabled: clientSecretJWTEnabled(),
 		}
 		tmpl.ExecuteTemplate(w, "admin_client_new.html", data)
 	}
@@ -57,47 +105,26 @@ func AdminClientCreateHandler(tmpl *template.Template) http.HandlerFunc {
 			return
 		}
 
-		name := strings.TrimSpace(r.FormValue("name"))
-		description := strings.TrimSpace(r.FormValue("description"))
-		mainURL := strings.TrimSpace(r.FormValue("main_url"))
-		silentSSO := r.FormValue("silent_sso") == "true"
-
-		redirectURIs := cleanList(r.Form["redirect_uris"])
-		serverURLs := cleanList(r.Form["server_urls"])
-
-		if name == "" || len(redirectURIs) == 0 {
-			data := adminClientNewPageData{
-				Name:         name,
-				Description:  description,
-				MainURL:      mainURL,
-				ServerURLs:   r.Form["server_urls"],
-				RedirectURIs: r.Form["redirect_uris"],
-				SilentSSO:    silentSSO,
-				ErrorMsg:     "서비스명과 리다이렉트 URL 최소 1 개는 필수입니다",
-			}
-			if len(data.RedirectURIs) == 0 {
-				data.RedirectURIs = []string{""}
-			}
-			if len(data.ServerURLs) == 0 {
-				data.ServerURLs = []string{""}
-			}
-			tmpl.ExecuteTemplate(w, "admin_client_new.html", data)
+		form, ok := readClientForm(r)
+		if !ok {
+			tmpl.ExecuteTemplate(w, "admin_client_new.html", form)
 			return
 		}
 
-		clientID := "client-" + randomShort(8)
-		clientSecret := randomShort(32)
-
 		c := &models.Client{
-			ClientID:     clientID,
-			ClientSecret: clientSecret,
-			Name:         name,
-			Description:  description,
-			MainURL:      mainURL,
-			ServerURLs:   serverURLs,
-			RedirectURIs: redirectURIs,
-			OwnerID:      "",
-			SilentSSO:    silentSSO,
+			ClientID: "client-" + randomShort(8),
+			OwnerID:  "",
+		}
+		form.applyTo(c)
+		// public / private_key_jwt client 는 secret 을 발급하지 않는다
+		if c.UsesSecret() {
+			c.ClientSecret = randomShort(32)
+			sealed, err := sealClientSecret(c, c.ClientSecret)
+			if err != nil {
+				http.Error(w, "등록 실패: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			c.ClientSecretSealed = sealed
 		}
 
 		if err := store.Clients.Register(c); err != nil {
@@ -105,12 +132,324 @@ func AdminClientCreateHandler(tmpl *template.Template) http.HandlerFunc {
 			return
 		}
 
+		AuditEvent(r, "client.created", "by", adminActor(r), "client_id", c.ClientID, "name", c.Name)
 		tmpl.ExecuteTemplate(w, "admin_client_created.html", adminClientCreatedPageData{
-			Client: c,
+			adminChrome: adminChromeFor(r),
+			Client:      c,
 		})
 	}
 }
 
+// readClientForm: 등록 / 편집 폼 공통 파싱. 필수값이 빠지면 입력을 그대로 되돌린 데이터 + false.
+func readClientForm(r *http.Request) (adminClientNewPageData, bool) {
+	ccScopes := strings.Fields(r.FormValue("client_credentials_scopes"))
+	// 레지스트리에 없는 값 (폼 변조) 은 버린다
+	allowedScopes := make([]string, 0)
+	for _, s := range r.Form["allowed_scopes"] {
+		if _, ok := scope.Lookup(s); ok && !contains(allowedScopes, s) {
+			allowedScopes = append(allowedScopes, s)
+		}
+	}
+
+	data := adminClientNewPageData{
+		adminChrome:  adminChromeFor(r),
+		Name:         strings.TrimSpace(r.FormValue("name")),
+		Description:  strings.TrimSpace(r.FormValue("description")),
+		MainURL:      strings.TrimSpace(r.FormValue("main_url")),
+		ServerURLs:   cleanList(r.Form["server_urls"]),
+		RedirectURIs: cleanList(r.Form["redirect_uris"]),
+		SilentSSO:    r.FormValue("silent_sso") == "true",
+		FirstParty:   r.FormValue("first_party") == "true",
+		Public:       r.FormValue("client_type") == models.ClientTypePublic,
+
+		AuthMethod:       r.FormValue("token_endpoint_auth_method"),
+		JWKS:             strings.TrimSpace(r.FormValue("jwks")),
+		JWKSURI:          strings.TrimSpace(r.FormValue("jwks_uri")),
+		SecretJWTEnabled: clientSecretJWTEnabled(),
+
+		BackchannelLogoutURI:  strings.TrimSpace(r.FormValue("backchannel_logout_uri")),
+		FrontchannelLogoutURI: strings.TrimSpace(r.FormValue("frontchannel_logout_uri")),
+
+		RequireVerifiedEmail: r.FormValue("require_verified_email") == "true",
+
+		ClientCredentials:       r.FormValue("client_credentials") == "true",
+		ClientCredentialsScopes: strings.Join(ccScopes, " "),
+
+		Scopes: adminScopeOptions(allowedScopes),
+	}
+	if data.AuthMethod == "" {
+		data.AuthMethod = models.AuthMethodClientSecretBasic
+	}
+	if data.Name == "" || len(data.RedirectURIs) == 0 {
+		data.ErrorMsg = "서비스명과 리다이렉트 URL 최소 1 개는 필수입니다"
+	} else if data.BackchannelLogoutURI != "" && !validOutboundURI(data.BackchannelLogoutURI) {
+		data.ErrorMsg = "Back-Channel Logout URI 는 https (로컬 개발은 http://localhost) 절대 URL 이어야 합니다"
+	} else if data.FrontchannelLogoutURI != "" && !validFrontchannelLogoutURI(data.FrontchannelLogoutURI, data.RedirectURIs) {
+		data.ErrorMsg = "Front-Channel Logout URI 는 리다이렉트 URL 중 하나와 같은 origin 의 https (로컬 개발은 http://localhost) URL 이어야 합니다"
+	} else if !data.Public {
+		data.ErrorMsg = data.authMethodError()
+	}
+	if data.ErrorMsg == "" {
+		return data, true
+	}
+
+	data.ServerURLs = r.Form["server_urls"]
+	data.RedirectURIs = r.Form["redirect_uris"]
+	if len(data.RedirectURIs) == 0 {
+		data.RedirectURIs = []string{""}
+	}
+	if len(data.ServerURLs) == 0 {
+		data.ServerURLs = []string{""}
+	}
+	return data, false
+}
+
+// authMethodError: confidential client 의 인증 방식 검증. 문제 없으면 "".
+func (d adminClientNewPageData) authMethodError() string {
+	switch d.AuthMethod {
+	case models.AuthMethodClientSecretBasic, models.AuthMethodClientSecretPost:
+		return ""
+	case models.AuthMethodClientSecretJWT:
+		if !d.SecretJWTEnabled {
+			return "client_secret_jwt 는 OAUTH_CLIENT_SECRET_KEY 를 설정해야 쓸 수 있습니다"
+		}
+		return ""
+	case models.AuthMethodPrivateKeyJWT:
+		switch {
+		case (d.JWKS == "") == (d.JWKSURI == ""):
+			return "private_key_jwt 는 JWKS 또는 jwks_uri 중 하나만 입력하세요"
+		case d.JWKS != "" && !validClientJWKS(d.JWKS):
+			return "JWKS 에 쓸 수 있는 공개키 (RSA 2048+ / EC P-256·P-384) 가 없습니다"
+		case d.JWKSURI != "" && !validOutboundURI(d.JWKSURI):
+			return "jwks_uri 는 https (로컬 개발은 http://localhost) 절대 URL 이어야 합니다"
+		}
+		return ""
+	}
+	return "지원하지 않는 인증 방식입니다"
+}
+
+// applyTo: 폼 값 → client 필드. id / secret / owner 는 건드리지 않는다.
+func (d adminClientNewPageData) applyTo(c *models.Client) {
+	c.Name = d.Name
+	c.Description = d.Description
+	c.MainURL = d.MainURL
+	c.ServerURLs = d.ServerURLs
+	c.RedirectURIs = d.RedirectURIs
+	c.SilentSSO = d.SilentSSO
+	c.FirstParty = d.FirstParty
+	c.RequireVerifiedEmail = d.RequireVerifiedEmail
+	c.BackchannelLogoutURI = d.BackchannelLogoutURI
+	c.FrontchannelLogoutURI = d.FrontchannelLogoutURI
+	c.ClientCredentials = d.ClientCredentials
+	c.ClientCredentialsScopes = strings.Fields(d.ClientCredentialsScopes)
+	c.AllowedScopes = make([]string, 0)
+	for _, s := range d.Scopes {
+		if s.Checked {
+			c.AllowedScopes = append(c.AllowedScopes, s.Name)
+		}
+	}
+
+	c.ClientType = models.ClientTypeConfidential
+	c.TokenEndpointAuthMethod = d.AuthMethod
+	c.JWKS, c.JWKSURI = "", ""
+	if d.AuthMethod == models.AuthMethodPrivateKeyJWT {
+		c.JWKS, c.JWKSURI = d.JWKS, d.JWKSURI
+	}
+	// public client 는 사용자 없는 grant 를 쓸 수 없다 (secret 이 없으니 누구나 흉내 낸다)
+	if d.Public {
+		c.ClientType = models.ClientTypePublic
+		c.TokenEndpointAuthMethod = models.AuthMethodNone
+		c.ClientCredentials = false
+		c.ClientCredentialsScopes = nil
+	}
+}
+
+// maxSecretGrace: secret 회전 유예 상한. 그보다 길면 유출된 secret 을 막는 회전의 의미가 없다.
+const maxSecretGrace = 7 * 24 * time.Hour
+
+// AdminClientEditFormHandler: GET /admin/clients/{id}/edit — 편집 폼 (등록 폼 재사용).
+func AdminClientEditFormHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := store.Clients.GetByClientID(r.PathValue("id"))
+		if !ok {
+			http.Redirect(w, r, "/admin?error=client_not_found", http.StatusSeeOther)
+			return
+		}
+		data := adminClientNewPageData{
+			adminChrome:  adminChromeFor(r),
+			ClientID:     c.ClientID,
+			Name:         c.Name,
+			Description:  c.Description,
+			MainURL:      c.MainURL,
+			ServerURLs:   append([]string{}, c.ServerURLs...),
+			RedirectURIs: append([]string{}, c.RedirectURIs...),
+			SilentSSO:    c.SilentSSO,
+			FirstParty:   c.FirstParty,
+			Public:       c.IsPublic(),
+
+			AuthMethod:       c.AuthMethod(),
+			JWKS:             c.JWKS,
+			JWKSURI:          c.JWKSURI,
+			SecretJWTEnabled: clientSecretJWTEnabled(),
+
+			BackchannelLogoutURI:  c.BackchannelLogoutURI,
+			FrontchannelLogoutURI: c.FrontchannelLogoutURI,
+
+			RequireVerifiedEmail: c.RequireVerifiedEmail,
+
+			ClientCredentials:       c.ClientCredentials,
+			ClientCredentialsScopes: strings.Join(c.ClientCredentialsScopes, " "),
+
+			Scopes: adminScopeOptions(c.AllowedScopes),
+		}
+		if len(data.ServerURLs) == 0 {
+			data.ServerURLs = []string{""}
+		}
+		if c.IsPublic() {
+			data.AuthMethod = models.AuthMethodClientSecretBasic
+		}
+		tmpl.ExecuteTemplate(w, "admin_client_new.html", data)
+	}
+}
+
+// AdminClientUpdateHandler: POST /admin/clients/{id} — 편집 저장.
+// redirect_uris / allowed_scopes 를 줄이면 다음 authorize / refresh 부터 바로 적용된다.
+func AdminClientUpdateHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "form parse 실패", http.StatusBadRequest)
+			return
+		}
+		current, ok := store.Clients.GetByClientID(r.PathValue("id"))
+		if !ok {
+			http.Redirect(w, r, "/admin?error=client_not_found", http.StatusSeeOther)
+			return
+		}
+
+		form, ok := readClientForm(r)
+		form.ClientID = current.ClientID
+		if !ok {
+			tmpl.ExecuteTemplate(w, "admin_client_new.html", form)
+			return
+		}
+
+		// store 의 인스턴스를 직접 고치지 않고 사본으로 (인메모리 store 는 포인터를 공유)
+		updated := *current
+		form.applyTo(&updated)
+		if err := store.Clients.Update(&updated); err != nil {
+			AuditWarn(r, "client.update_failed", "by", adminActor(r), "client_id", current.ClientID, "err", err.Error())
+			http.Redirect(w, r, "/admin?error=client_update_failed", http.StatusSeeOther)
+			return
+		}
+		AuditEvent(r, "client.updated", "by", adminActor(r), "client_id", current.ClientID,
+			"client_type", updated.ClientType,
+			"auth_method", updated.AuthMethod(),
+			"redirect_uris", strings.Join(updated.RedirectURIs, " "),
+			"allowed_scopes", strings.Join(updated.AllowedScopes, " "),
+		)
+		// 새 방식에 맞는 secret 이 아직 없으면 (public / private_key_jwt → secret 방식, → client_secret_jwt) 재발급 안내
+		if updated.UsesSecret() && (updated.ClientSecretHash == "" ||
+			(updated.AuthMethod() == models.AuthMethodClientSecretJWT && updated.ClientSecretSealed == "")) {
+			http.Redirect(w, r, "/admin?notice=client_updated_rotate", http.StatusSeeOther)
+			return
+		}
+		http.Redirect(w, r, "/admin?notice=client_updated", http.StatusSeeOther)
+	}
+}
+
+// AdminClientRotateSecretHandler: POST /admin/clients/{id}/rotate-secret — secret 재발급.
+// form: grace_hours (0 = 옛 secret 즉시 폐기, 최대 maxSecretGrace). 새 secret 은 등록 때처럼 한 번만 노출.
+func AdminClientRotateSecretHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := store.Clients.GetByClientID(r.PathValue("id"))
+		if !ok {
+			http.Redirect(w, r, "/admin?error=client_not_found", http.StatusSeeOther)
+			return
+		}
+		if !c.UsesSecret() {
+			http.Redirect(w, r, "/admin?error=client_no_secret", http.StatusSeeOther)
+			return
+		}
+		// 범위 검사는 Duration 변환 전에 — 큰 값이 곱셈 overflow 로 0 ~ 7일 안에 들어오지 않게.
+		hours, err := strconv.Atoi(r.FormValue("grace_hours"))
+		if err != nil || hours < 0 || hours > int(maxSecretGrace/time.Hour) {
+			http.Redirect(w, r, "/admin?error=invalid_grace", http.StatusSeeOther)
+			return
+		}
+		grace := time.Duration(hours) * time.Hour
+		// client_secret_jwt 는 봉인된 secret 하나로 HMAC 을 검증한다 — 옛 secret 을 유예할 수 없으므로 즉시 폐기만.
+		if grace > 0 && c.AuthMethod() == models.AuthMethodClientSecretJWT {
+			http.Redirect(w, r, "/admin?error=grace_unsupported", http.StatusSeeOther)
+			return
+		}
+
+		rotated := &models.Client{ClientSecret: randomShort(32)}
+		if err := store.EnsureSecretHash(rotated); err != nil {
+			http.Redirect(w, r, "/admin?error=secret_rotate_failed", http.StatusSeeOther)
+			return
+		}
+		sealed, err := sealClientSecret(c, rotated.ClientSecret)
+		if err != nil {
+			http.Redirect(w, r, "/admin?error=secret_rotate_failed", http.StatusSeeOther)
+			return
+		}
+		if err := store.Clients.RotateSecret(c.ClientID, rotated.ClientSecretHash, sealed, grace); err != nil {
+			AuditWarn(r, "client.secret_rotate_failed", "by", adminActor(r), "client_id", c.ClientID, "err", err.Error())
+			http.Redirect(w, r, "/admin?error=secret_rotate_failed", http.StatusSeeOther)
+			return
+		}
+		AuditWarn(r, "client.secret_rotated", "by", adminActor(r), "client_id", c.ClientID, "grace", grace.String())
+
+		// 노출용 사본 — 평문은 이 응답에만
+		shown := *c
+		shown.ClientSecret = rotated.ClientSecret
+		data := adminClientCreatedPageData{adminChrome: adminChromeFor(r), Client: &shown, Rotated: true}
+		if grace > 0 {
+			data.GraceUntil = time.Now().Add(grace)
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		tmpl.ExecuteTemplate(w, "admin_client_created.html", data)
+	}
+}
+
+// AdminClientDeleteHandler: POST /admin/clients/{id}/delete — client 삭제.
+// refresh token 과 동의 기록을 먼저 지우고 이미 나간 access token 도 차단한다.
+// 이후 갱신 / 새 로그인은 client 인증 단계에서 막힌다.
+func AdminClientDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("id")
+	if _, ok := store.Clients.GetByClientID(clientID); !ok {
+		http.Redirect(w, r, "/admin?error=client_not_found", http.StatusSeeOther)
+		return
+	}
+	tokens, consents, err := deleteClient(r.Context(), clientID)
+	if err != nil {
+		AuditWarn(r, "client.delete_failed", "by", adminActor(r), "client_id", clientID, "err", err.Error())
+		http.Redirect(w, r, "/admin?error=client_delete_failed", http.StatusSeeOther)
+		return
+	}
+	AuditWarn(r, "client.deleted", "by", adminActor(r), "client_id", clientID, "refresh_tokens", tokens, "consents", consents)
+	http.Redirect(w, r, "/admin?notice=client_deleted", http.StatusSeeOther)
+}
+
+// AdminClientTokensRevokeHandler: POST /admin/clients/{id}/tokens/revoke — client 에 발급된 토큰 전부 폐기.
+// refresh token 삭제 + access token 차단. client 는 그대로라 사용자는 다시 로그인하면 된다.
+func AdminClientTokensRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("id")
+	if _, ok := store.Clients.GetByClientID(clientID); !ok {
+		http.Redirect(w, r, "/admin?error=client_not_found", http.StatusSeeOther)
+		return
+	}
+	err1 := revokeAccessTokensIssuedBefore(r.Context(), "", clientID)
+	tokens, err2 := store.RefreshTokens.RevokeByClient(r.Context(), clientID)
+	if err := errors.Join(err1, err2); err != nil {
+		AuditWarn(r, "admin.client_tokens_revoke_failed", "by", adminActor(r), "client_id", clientID, "err", err.Error())
+		http.Redirect(w, r, "/admin?error=revoke_failed", http.StatusSeeOther)
+		return
+	}
+	AuditWarn(r, "admin.client_tokens_revoked", "by", adminActor(r), "client_id", clientID, "refresh_tokens", tokens)
+	http.Redirect(w, r, "/admin?notice=tokens_revoked", http.StatusSeeOther)
+}
+
 // AdminClientSilentSSOHandler: POST /admin/clients/{id}/silent-sso
 // form: silent_sso=true|false
 // JSON 응답.
@@ -139,6 +478,7 @@ func AdminClientSilentSSOHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, status, map[string]any{"error": err.Error()})
 		return
 	}
+	AuditEvent(r, "client.silent_sso_changed", "by", adminActor(r), "client_id", clientID, "silent_sso", silentSSO)
 	writeJSON(w, http.StatusOK, map[string]any{
 		"ok":         true,
 		"client_id":  clientID,
@@ -2,59 +2,159 @@ package handlers
 
 import (
 	"html/template"
+	"log"
 	"net/http"
+	"strings"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
 )
 
+// 어드민 게이트 — IdP 사용자 중 AdminRole 이 있는 계정만 /admin 에 들어온다.
+// 로그인은 일반 로그인과 같은 잠금 (lockout.go) + 2단계 인증 (등록한 경우) 을 거친다.
+// 라우트마다 필요한 권한 (AdminPermission) 을 RequireAdmin 이 역할과 대조하고, POST 는 세션 CSRF 토큰까지 본다.
+
+// AdminPermission: 라우트가 요구하는 권한.
+type AdminPermission int
+
+const (
+	AdminView          AdminPermission = iota // 조회 (모든 역할)
+	AdminManageClients                        // client 등록 / 수정 / 삭제 / 토큰 폐기
+	AdminManageUsers                          // 사용자 수정 / 비활성화 / 삭제 / 세션 · 토큰 폐기
+	AdminSuper                                // 어드민 역할 부여, 서명키 회전
+)
+
+// adminAllows: 역할 → 권한 표.
+func adminAllows(role string, p AdminPermission) bool {
+	switch role {
+	case models.AdminRoleSuperadmin:
+		return true
+	case models.AdminRoleClientManager:
+		return p == AdminView || p == AdminManageClients
+	case models.AdminRoleUserManager:
+		return p == AdminView || p == AdminManageUsers
+	case models.AdminRoleViewer:
+		return p == AdminView
+	}
+	return false
+}
+
 type adminLoginPageData struct {
-	ErrorMsg string
+	Username  string
+	CSRFToken string
+	ErrorMsg  string
 }
 
 // AdminLoginGetHandler: GET /admin/login — 폼 표시.
 // 이미 인증되어 있으면 /admin 으로 보낸다.
 func AdminLoginGetHandler(tmpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if isAdminAuthed(r) {
+		if _, ok := authedAdmin(r); ok {
 			http.Redirect(w, r, "/admin", http.StatusFound)
 			return
 		}
-		tmpl.ExecuteTemplate(w, "admin_login.html", adminLoginPageData{})
+		renderAdminLogin(w, tmpl, "", "")
 	}
 }
 
-// AdminLoginPostHandler: POST /admin/login — bcrypt 검증 + 쿠키 set.
+// AdminLoginPostHandler: POST /admin/login — username / password (+ 2단계 코드) 검증 + 역할 확인 + 쿠키 set.
 func AdminLoginPostHandler(tmpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		password := r.FormValue("password")
-		err := bcrypt.CompareHashAndPassword([]byte(adminPasswordHash), []byte(password))
+		if err := verifyCSRFCookie(r, adminLoginCSRFCookie); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		username := strings.TrimSpace(r.FormValue("username"))
+		user, err := authenticateUser(r, username, r.FormValue("password"))
 		if err != nil {
-			tmpl.ExecuteTemplate(w, "admin_login.html", adminLoginPageData{
-				ErrorMsg: "비밀번호가 틀렸습니다",
-			})
+			renderAdminLogin(w, tmpl, username, loginFailureMessage(err, "아이디 또는 비밀번호가 틀렸습니다"))
+			return
+		}
+		if !user.IsAdmin() {
+			AuditWarn(r, "admin.login_failed", "sub", user.ID, "username", user.Username, "reason", "not_admin")
+			renderAdminLogin(w, tmpl, username, "어드민 권한이 없는 계정입니다")
 			return
 		}
-		if err := setAdminSessionCookie(w); err != nil {
+		method := "password"
+		if user.TOTPEnabled() {
+			m, ok := verifySecondFactor(r.Context(), user, r.FormValue("code"))
+			if !ok {
+				AuditWarn(r, "admin.login_failed", "sub", user.ID, "username", user.Username, "reason", "bad_otp")
+				renderAdminLogin(w, tmpl, username, loginFailureMessage(recordLoginFailure(r, user.Username), "2단계 인증 코드가 틀렸습니다"))
+				return
+			}
+			method = m
+		}
+		clearLoginFailures(r.Context(), user.Username)
+
+		if err := setAdminSessionCookie(r.Context(), w, user.ID); err != nil {
 			http.Error(w, "세션 쿠키 설정 실패", http.StatusInternalServerError)
 			return
 		}
+		AuditEvent(r, "admin.login", "by", user.Username, "sub", user.ID, "role", user.AdminRole, "method", method)
 		http.Redirect(w, r, "/admin", http.StatusFound)
 	}
 }
 
-// AdminLogoutHandler: POST /admin/logout — 쿠키 폐기.
+// renderAdminLogin: 로그인 폼마다 새 CSRF 쿠키 (Path=/admin/login).
+func renderAdminLogin(w http.ResponseWriter, tmpl *template.Template, username, errMsg string) {
+	csrfToken, err := newCSRFCookie(w, adminLoginCSRFCookie, "/admin/login")
+	if err != nil {
+		http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("X-Frame-Options", "DENY")
+	tmpl.ExecuteTemplate(w, "admin_login.html", adminLoginPageData{Username: username, CSRFToken: csrfToken, ErrorMsg: errMsg})
+}
+
+// AdminLogoutHandler: POST /admin/logout — 서버 세션 + 쿠키 폐기. RequireAdmin 뒤에 둬 CSRF 를 거친다.
 func AdminLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if ac, ok := r.Context().Value(adminCtxKey{}).(*adminContext); ok {
+		if err := store.AdminSessions.Delete(r.Context(), ac.sess.Nonce); err != nil {
+			log.Printf("[admin] 세션 폐기 실패 by=%s: %v", ac.user.Username, err)
+		}
+	}
 	clearAdminSessionCookie(w)
+	AuditEvent(r, "admin.logout", "by", adminActor(r))
 	http.Redirect(w, r, "/admin/login", http.StatusFound)
 }
 
-// RequireAdminAuth: 미들웨어. 비인증이면 /admin/login 으로 리다이렉트.
-func RequireAdminAuth(next http.Handler) http.Handler {
+// authedAdmin: 세션 쿠키 → 서버 세션 → 사용자. 로그아웃 / 폐기된 세션, 사용자가 없어졌거나 비활성 / 역할 회수면 false.
+func authedAdmin(r *http.Request) (*adminContext, bool) {
+	sess, ok := getAdminSession(r)
+	if !ok {
+		return nil, false
+	}
+	if userID, err := store.AdminSessions.Get(r.Context(), sess.Nonce); err != nil || userID != sess.UserID {
+		return nil, false
+	}
+	user, err := store.Users.GetByID(r.Context(), sess.UserID)
+	if err != nil || !user.IsAdmin() {
+		return nil, false
+	}
+	return &adminContext{user: user, sess: sess}, true
+}
+
+// RequireAdmin: 미들웨어. 비인증이면 /admin/login 으로, 역할이 perm 을 못 가지면 403.
+// GET / HEAD 외의 요청은 세션 CSRF 토큰 (form csrf_token 또는 X-CSRF-Token) 필수.
+func RequireAdmin(perm AdminPermission, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !isAdminAuthed(r) {
+		ac, ok := authedAdmin(r)
+		if !ok {
+			clearAdminSessionCookie(w)
 			http.Redirect(w, r, "/admin/login", http.StatusFound)
 			return
 		}
-		next.ServeHTTP(w, r)
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !verifyAdminCSRF(r, ac.sess) {
+			AuditWarn(r, "admin.csrf_failed", "by", ac.user.Username, "path", r.URL.Path)
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		if !adminAllows(ac.user.AdminRole, perm) {
+			AuditWarn(r, "admin.forbidden", "by", ac.user.Username, "role", ac.user.AdminRole, "path", r.URL.Path)
+			http.Error(w, "권한이 없습니다", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, withAdmin(r, ac.user, ac.sess))
 	})
 }
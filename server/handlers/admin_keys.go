@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ftery0/ouath/server/token"
+)
+
+// AdminSigningKeyRotateHandler: POST /admin/signing-keys/rotate — 서명키 수동 회전.
+// next → active, active → retired, 새 next 게시. 결과는 감사 로그 + /admin flash.
+func AdminSigningKeyRotateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	res, err := token.Rotate(ctx)
+	if err != nil {
+		reason := "rotate_failed"
+		if errors.Is(err, token.ErrRotationConflict) {
+			reason = "conflict"
+		}
+		AuditWarn(r, "signing_key.rotate_failed", "trigger", "admin", "by", adminActor(r), "reason", reason, "err", err.Error())
+		http.Redirect(w, r, "/admin?error=key_rotate_failed", http.StatusSeeOther)
+		return
+	}
+	AuditEvent(r, "signing_key.rotated",
+		"trigger", "admin",
+		"by", adminActor(r),
+		"retired_kid", res.RetiredKid,
+		"active_kid", res.ActiveKid,
+		"next_kid", res.NextKid,
+	)
+	http.Redirect(w, r, "/admin?notice=key_rotated", http.StatusSeeOther)
+}
@@ -1,40 +1,66 @@
 package handlers
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/securecookie"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
 )
 
 const (
 	adminSessionCookieName = "admin_session"
 	adminSessionTTL        = 12 * time.Hour
+	adminLoginCSRFCookie   = "admin_login_csrf"
 )
 
 // adminSecureCookie: AdminInit 에서 주입.
 var adminSecureCookie *securecookie.SecureCookie
 
-// adminPasswordHash: bcrypt hash. main 에서 cfg.AdminPasswordHash 로 주입.
-var adminPasswordHash string
+// adminCSRFKey: 어드민 세션 nonce → CSRF 토큰 HMAC 키. AdminInit 에서 도출.
+var adminCSRFKey []byte
 
-// AdminInit: main 에서 한 번 호출. 비밀번호 hash + SecureCookie 키 설정.
-func AdminInit(passwordHash, sessionSecret string) {
-	adminPasswordHash = passwordHash
+// AdminInit: main 에서 한 번 호출. SecureCookie 키 + CSRF 키 설정.
+func AdminInit(sessionSecret string) {
 	hash := sha256.Sum256([]byte(sessionSecret))
 	block := sha256.Sum256([]byte(sessionSecret + ".admin.block"))
+	csrf := sha256.Sum256([]byte(sessionSecret + ".admin.csrf"))
 	adminSecureCookie = securecookie.New(hash[:], block[:])
+	adminSecureCookie.MaxAge(int(adminSessionTTL.Seconds()))
+	adminCSRFKey = csrf[:]
+}
+
+// adminSession: 어드민 세션 쿠키 값. 로그인한 사용자 ID + 세션마다 새 nonce (CSRF 토큰의 재료).
+// 역할은 싣지 않는다 — 매 요청 store 에서 다시 읽어 회수 / 비활성화가 즉시 반영되게.
+// nonce 는 store.AdminSessions 의 세션 ID 이기도 하다 — 로그아웃하면 복사된 쿠키도 함께 무효.
+type adminSession struct {
+	UserID string
+	Nonce  string
 }
 
-// setAdminSessionCookie: 어드민 게이트 통과 시 호출.
-// 값은 단순 "ok" 마커 — 단일 비밀번호 모델이라 사용자 식별 정보 없음.
+// setAdminSessionCookie: 어드민 로그인 성공 시 호출. 세션을 store 에 만든 뒤 쿠키를 싣는다.
 // 쿠키 Path 가 /admin 으로 한정되어 OAuth 흐름과 격리.
-func setAdminSessionCookie(w http.ResponseWriter) error {
-	encoded, err := adminSecureCookie.Encode(adminSessionCookieName, "ok")
+func setAdminSessionCookie(ctx context.Context, w http.ResponseWriter, userID string) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sess := adminSession{UserID: userID, Nonce: hex.EncodeToString(nonce)}
+	encoded, err := adminSecureCookie.Encode(adminSessionCookieName, sess)
 	if err != nil {
 		return err
 	}
+	if err := store.AdminSessions.Create(ctx, sess.Nonce, userID, time.Now().Add(adminSessionTTL)); err != nil {
+		return err
+	}
 	http.SetCookie(w, &http.Cookie{
 		Name:     adminSessionCookieName,
 		Value:    encoded,
@@ -47,20 +73,20 @@ func setAdminSessionCookie(w http.ResponseWriter) error {
 	return nil
 }
 
-// isAdminAuthed: 쿠키가 유효한지 확인.
-func isAdminAuthed(r *http.Request) bool {
+// getAdminSession: 쿠키 복호화. 위조 / 만료면 false.
+func getAdminSession(r *http.Request) (adminSession, bool) {
+	var sess adminSession
 	c, err := r.Cookie(adminSessionCookieName)
 	if err != nil {
-		return false
+		return sess, false
 	}
-	var val string
-	if err := adminSecureCookie.Decode(adminSessionCookieName, c.Value, &val); err != nil {
-		return false
+	if err := adminSecureCookie.Decode(adminSessionCookieName, c.Value, &sess); err != nil || sess.UserID == "" {
+		return sess, false
 	}
-	return val == "ok"
+	return sess, true
 }
 
-// clearAdminSessionCookie: 로그아웃 시 호출.
+// clearAdminSessionCookie: 로그아웃 / 권한을 잃은 세션 정리.
 func clearAdminSessionCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:   adminSessionCookieName,
@@ -69,3 +95,75 @@ func clearAdminSessionCookie(w http.ResponseWriter) {
 		MaxAge: -1,
 	})
 }
+
+// adminCSRFToken: 세션 nonce 의 HMAC — 어드민 POST 폼의 csrf_token. 세션이 바뀌면 토큰도 바뀐다.
+func adminCSRFToken(sess adminSession) string {
+	mac := hmac.New(sha256.New, adminCSRFKey)
+	mac.Write([]byte(sess.Nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyAdminCSRF: form csrf_token 또는 X-CSRF-Token 헤더 (fetch 호출) 가 세션 토큰과 같은가.
+func verifyAdminCSRF(r *http.Request, sess adminSession) bool {
+	got := r.Header.Get("X-CSRF-Token")
+	if got == "" {
+		got = r.FormValue("csrf_token")
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(adminCSRFToken(sess))) == 1
+}
+
+type adminCtxKey struct{}
+
+// adminContext: RequireAdmin 이 요청 context 에 싣는 현재 어드민.
+type adminContext struct {
+	user *models.User
+	sess adminSession
+}
+
+func withAdmin(r *http.Request, user *models.User, sess adminSession) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), adminCtxKey{}, &adminContext{user: user, sess: sess}))
+}
+
+// currentAdmin: RequireAdmin 을 거친 요청의 어드민. 미들웨어 밖 (테스트의 직접 호출 등) 이면 nil.
+func currentAdmin(r *http.Request) *models.User {
+	if ac, ok := r.Context().Value(adminCtxKey{}).(*adminContext); ok {
+		return ac.user
+	}
+	return nil
+}
+
+// adminActor: 감사 로그의 by 값 — 어드민 username.
+func adminActor(r *http.Request) string {
+	if u := currentAdmin(r); u != nil {
+		return u.Username
+	}
+	return ""
+}
+
+// adminChrome: 어드민 페이지 공통 데이터 (헤더의 로그인 정보 + POST 폼 csrf_token). 각 page data 에 embed.
+type adminChrome struct {
+	Admin     *models.User
+	CSRFToken string
+}
+
+// adminChromeFor: 현재 요청의 어드민 / CSRF 토큰.
+func adminChromeFor(r *http.Request) adminChrome {
+	ac, ok := r.Context().Value(adminCtxKey{}).(*adminContext)
+	if !ok {
+		return adminChrome{}
+	}
+	return adminChrome{Admin: ac.user, CSRFToken: adminCSRFToken(ac.sess)}
+}
+
+// CanManageClients / CanManageUsers / IsSuperadmin: 템플릿이 버튼을 숨길 때 쓴다 (강제는 RequireAdmin).
+func (c adminChrome) CanManageClients() bool {
+	return c.Admin != nil && adminAllows(c.Admin.AdminRole, AdminManageClients)
+}
+
+func (c adminChrome) CanManageUsers() bool {
+	return c.Admin != nil && adminAllows(c.Admin.AdminRole, AdminManageUsers)
+}
+
+func (c adminChrome) IsSuperadmin() bool {
+	return c.Admin != nil && adminAllows(c.Admin.AdminRole, AdminSuper)
+}
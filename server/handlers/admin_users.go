@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// 어드민 사용자 관리 — 목록 (검색 / 페이지), 상세 수정, 비활성화, 비밀번호 강제 재설정, 삭제.
+//
+//	GET  /admin/users?q=&page=                 목록
+//	GET  /admin/users/{id}                     상세 + 수정 폼
+//	POST /admin/users/{id}                     display_name / email / email_verified 수정
+//	POST /admin/users/{id}/disable             disabled=true|false — 비활성화 시 세션 · refresh token 즉시 폐기
+//	POST /admin/users/{id}/password-reset      현재 비밀번호 무효화 + 재설정 메일
+//	POST /admin/users/{id}/delete              세션 · 토큰 · 동의 정리 후 삭제
+//	POST /admin/users/{id}/sessions/revoke     IdP 세션 전부 종료 (+ Back-Channel Logout)
+//	POST /admin/users/{id}/tokens/revoke       refresh / access token 폐기 — client_id 가 있으면 그 client 것만
+//	POST /admin/users/{id}/role                어드민 역할 부여 / 회수 (superadmin 만)
+//
+// 어드민 계정을 대상으로 한 조치는 superadmin 만 — user-manager 가 다른 어드민을 잠그거나 지우지 못하게.
+// 결과는 감사 로그 (by=어드민 username) + flash (?notice= / ?error=).
+
+// adminUsersPageData: admin_users.html 데이터.
+type adminUsersPageData struct {
+	adminChrome
+	Users    []*models.User
+	Query    string
+	Total    int
+	Page     int
+	PrevPage int // 0 이면 없음
+	NextPage int // 0 이면 없음
+	FlashMsg string
+	FlashErr bool
+}
+
+// adminUserPageData: admin_user.html 데이터.
+type adminUserPageData struct {
+	adminChrome
+	User         *models.User
+	PasskeyCount int
+	AdminRoles   []string     // 역할 선택지 (superadmin 화면)
+	CanEdit      bool         // 이 관리자가 대상 계정을 수정할 수 있는지
+	SessionCount int          // 살아 있는 IdP 세션 수
+	Apps         []accountApp // 살아 있는 refresh token 을 가진 client 별 묶음
+	FlashMsg     string
+	FlashErr     bool
+}
+
+// AdminUsersHandler: GET /admin/users — 사용자 목록.
+func AdminUsersHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := strings.TrimSpace(r.URL.Query().Get("q"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		page = max(page, 1)
+
+		users, total, err := store.Users.List(r.Context(), store.UserListOptions{
+			Query:  q,
+			Offset: (page - 1) * store.DefaultUserPageSize,
+			Limit:  store.DefaultUserPageSize,
+		})
+		if err != nil {
+			http.Error(w, "사용자 조회 실패", http.StatusInternalServerError)
+			return
+		}
+
+		flash, isErr := flashFromQuery(r)
+		data := adminUsersPageData{
+			adminChrome: adminChromeFor(r),
+			Users:       users,
+			Query:       q,
+			Total:       total,
+			Page:        page,
+			FlashMsg:    flash,
+			FlashErr:    isErr,
+		}
+		if page > 1 {
+			data.PrevPage = page - 1
+		}
+		if page*store.DefaultUserPageSize < total {
+			data.NextPage = page + 1
+		}
+		tmpl.ExecuteTemplate(w, "admin_users.html", data)
+	}
+}
+
+// AdminUserHandler: GET /admin/users/{id} — 상세.
+func AdminUserHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := store.Users.GetByID(r.Context(), r.PathValue("id"))
+		if err != nil {
+			http.Redirect(w, r, "/admin/users?error=user_not_found", http.StatusSeeOther)
+			return
+		}
+		passkeys, _ := store.Passkeys.ListByUser(r.Context(), user.ID)
+		sessions, _ := store.IdPSessions.ListByUser(user.ID)
+		tokens, _ := store.RefreshTokens.ListByUser(r.Context(), user.ID)
+
+		flash, isErr := flashFromQuery(r)
+		chrome := adminChromeFor(r)
+		tmpl.ExecuteTemplate(w, "admin_user.html", adminUserPageData{
+			adminChrome:  chrome,
+			AdminRoles:   models.AdminRoles,
+			CanEdit:      chrome.CanManageUsers() && (user.AdminRole == "" || chrome.IsSuperadmin()),
+			User:         user,
+			PasskeyCount: len(passkeys),
+			SessionCount: len(sessions),
+			Apps:         accountApps(tokens),
+			FlashMsg:     flash,
+			FlashErr:     isErr,
+		})
+	}
+}
+
+// AdminUserUpdateHandler: POST /admin/users/{id} — 프로필 수정.
+// 이메일을 바꾸면서 인증됨을 체크하지 않으면 미인증으로 돌아간다.
+func AdminUserUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	email := strings.TrimSpace(r.FormValue("email"))
+	if email != "" && !emailPattern.MatchString(email) {
+		adminUserRedirect(w, r, user.ID, "error", "invalid_email")
+		return
+	}
+
+	user.DisplayName = strings.TrimSpace(r.FormValue("display_name"))
+	user.Email = email
+	user.EmailVerified = email != "" && r.FormValue("email_verified") == "true"
+
+	if err := store.Users.Update(r.Context(), user); err != nil {
+		if errors.Is(err, store.ErrEmailAlreadyUsed) {
+			adminUserRedirect(w, r, user.ID, "error", "email_in_use")
+			return
+		}
+		log.Printf("[admin] 사용자 수정 실패 sub=%s: %v", user.ID, err)
+		adminUserRedirect(w, r, user.ID, "error", "user_update_failed")
+		return
+	}
+	AuditWarn(r, "user.updated", "by", adminActor(r), "sub", user.ID, "email_verified", user.EmailVerified)
+	adminUserRedirect(w, r, user.ID, "notice", "user_updated")
+}
+
+// AdminUserDisableHandler: POST /admin/users/{id}/disable — 비활성화 / 재활성화.
+// 비활성화하면 이미 열린 IdP 세션과 refresh token 도 즉시 폐기 — 기존 로그인으로 계속 쓰지 못하게.
+// 자기 계정은 못 건드린다 — 마지막 superadmin 이 스스로 /admin 에서 잠기는 일을 막는다.
+func AdminUserDisableHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if admin := currentAdmin(r); admin != nil && admin.ID == user.ID {
+		adminUserRedirect(w, r, user.ID, "error", "own_account")
+		return
+	}
+	val := r.FormValue("disabled")
+	if val != "true" && val != "false" {
+		adminUserRedirect(w, r, user.ID, "error", "form_parse_failed")
+		return
+	}
+	disabled := val == "true"
+
+	if err := store.Users.Disable(r.Context(), user.ID, disabled); err != nil {
+		log.Printf("[admin] 사용자 비활성화 실패 sub=%s: %v", user.ID, err)
+		adminUserRedirect(w, r, user.ID, "error", "user_update_failed")
+		return
+	}
+	if !disabled {
+		AuditWarn(r, "user.enabled", "by", adminActor(r), "sub", user.ID)
+		adminUserRedirect(w, r, user.ID, "notice", "user_enabled")
+		return
+	}
+	sessions, tokens := revokeUserLogins(r.Context(), user.ID)
+	AuditWarn(r, "user.disabled", "by", adminActor(r), "sub", user.ID, "sessions", sessions, "refresh_tokens", tokens)
+	adminUserRedirect(w, r, user.ID, "notice", "user_disabled")
+}
+
+// AdminUserPasswordResetHandler: POST /admin/users/{id}/password-reset — 비밀번호 강제 재설정.
+// 현재 비밀번호를 아무도 모르는 값으로 바꾸고 세션 · refresh token 을 폐기한 뒤 재설정 메일을 보낸다.
+// 메일을 받을 곳이 없으면 아무것도 바꾸지 않는다 (사용자가 영영 로그인할 수 없게 되므로).
+func AdminUserPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if user.Email == "" {
+		adminUserRedirect(w, r, user.ID, "error", "no_email")
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(randomShort(32)), bcrypt.DefaultCost)
+	if err != nil {
+		adminUserRedirect(w, r, user.ID, "error", "password_reset_failed")
+		return
+	}
+	if err := store.Users.UpdatePassword(r.Context(), user.ID, string(hash)); err != nil {
+		log.Printf("[admin] 비밀번호 무효화 실패 sub=%s: %v", user.ID, err)
+		adminUserRedirect(w, r, user.ID, "error", "password_reset_failed")
+		return
+	}
+	sessions, tokens := revokeUserLogins(r.Context(), user.ID)
+	AuditWarn(r, "user.password_reset_forced", "by", adminActor(r), "sub", user.ID, "sessions", sessions, "refresh_tokens", tokens)
+
+	ctx, cancel := context.WithTimeout(r.Context(), mailSendTimeout)
+	defer cancel()
+	if err := sendPasswordReset(ctx, user, clientIP(r)); err != nil {
+		// 비밀번호는 이미 무효 — 사용자는 "비밀번호를 잊으셨나요?" 로 다시 받을 수 있다
+		log.Printf("[admin] 재설정 메일 실패 sub=%s: %v", user.ID, err)
+		adminUserRedirect(w, r, user.ID, "error", "reset_mail_failed")
+		return
+	}
+	adminUserRedirect(w, r, user.ID, "notice", "password_reset_sent")
+}
+
+// AdminUserSessionsRevokeHandler: POST /admin/users/{id}/sessions/revoke — IdP 세션 전부 종료.
+// 이미 받아 간 토큰은 그대로 — 토큰까지 끊으려면 tokens/revoke 를 함께 쓴다.
+func AdminUserSessionsRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	removed, err := store.IdPSessions.DeleteByUser(user.ID)
+	if err != nil {
+		log.Printf("[admin] 세션 폐기 실패 sub=%s: %v", user.ID, err)
+		adminUserRedirect(w, r, user.ID, "error", "revoke_failed")
+		return
+	}
+	backchannelLogout("admin", removed...)
+	AuditWarn(r, "admin.user_sessions_revoked", "by", adminActor(r), "sub", user.ID, "sessions", len(removed))
+	adminUserRedirect(w, r, user.ID, "notice", "sessions_revoked")
+}
+
+// AdminUserTokensRevokeHandler: POST /admin/users/{id}/tokens/revoke — form client_id (선택).
+// refresh token 삭제 + 지금까지 발급된 access token 차단 (store.AccessTokenCutoffs).
+func AdminUserTokensRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	clientID := strings.TrimSpace(r.FormValue("client_id"))
+	if clientID != "" {
+		if _, ok := store.Clients.GetByClientID(clientID); !ok {
+			adminUserRedirect(w, r, user.ID, "error", "client_not_found")
+			return
+		}
+	}
+
+	if err := revokeAccessTokensIssuedBefore(r.Context(), user.ID, clientID); err != nil {
+		log.Printf("[admin] access token 차단 실패 sub=%s client_id=%s: %v", user.ID, clientID, err)
+		adminUserRedirect(w, r, user.ID, "error", "revoke_failed")
+		return
+	}
+	var tokens int
+	var err error
+	if clientID == "" {
+		tokens, err = store.RefreshTokens.RevokeByUser(r.Context(), user.ID)
+	} else {
+		tokens, err = store.RefreshTokens.RevokeByUserClient(r.Context(), user.ID, clientID)
+	}
+	if err != nil {
+		log.Printf("[admin] refresh token 폐기 실패 sub=%s client_id=%s: %v", user.ID, clientID, err)
+		adminUserRedirect(w, r, user.ID, "error", "revoke_failed")
+		return
+	}
+	AuditWarn(r, "admin.user_tokens_revoked", "by", adminActor(r), "sub", user.ID, "client_id", clientID, "refresh_tokens", tokens)
+	adminUserRedirect(w, r, user.ID, "notice", "tokens_revoked")
+}
+
+// AdminUserDeleteHandler: POST /admin/users/{id}/delete — 계정 삭제.
+// 세션 · refresh token · 동의 · 로그인 실패 누적을 먼저 정리하고 사용자 행을 지운다 (패스키 / 재설정 링크는 store 가).
+// 자기 계정은 지우지 못한다 (AdminUserDisableHandler 와 같은 이유).
+func AdminUserDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if admin := currentAdmin(r); admin != nil && admin.ID == user.ID {
+		adminUserRedirect(w, r, user.ID, "error", "own_account")
+		return
+	}
+	ctx := r.Context()
+	sessions, tokens := revokeUserLogins(ctx, user.ID)
+	if _, err := store.Consents.DeleteByUser(ctx, user.ID); err != nil {
+		log.Printf("[admin] 동의 삭제 실패 sub=%s: %v", user.ID, err)
+	}
+	clearLoginFailures(ctx, user.Username)
+
+	if err := store.Users.Delete(ctx, user.ID); err != nil {
+		log.Printf("[admin] 사용자 삭제 실패 sub=%s: %v", user.ID, err)
+		adminUserRedirect(w, r, user.ID, "error", "user_delete_failed")
+		return
+	}
+	AuditWarn(r, "user.deleted", "by", adminActor(r), "sub", user.ID, "username", user.Username, "sessions", sessions, "refresh_tokens", tokens)
+	http.Redirect(w, r, "/admin/users?notice=user_deleted", http.StatusSeeOther)
+}
+
+// AdminUserRoleHandler: POST /admin/users/{id}/role — form role (빈 값 = 회수).
+// 자기 역할은 바꾸지 못한다 — 마지막 superadmin 이 스스로 권한을 잃는 일을 막는다.
+func AdminUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	role := r.FormValue("role")
+	if role != "" && !contains(models.AdminRoles, role) {
+		adminUserRedirect(w, r, user.ID, "error", "invalid_role")
+		return
+	}
+	if admin := currentAdmin(r); admin != nil && admin.ID == user.ID {
+		adminUserRedirect(w, r, user.ID, "error", "own_role")
+		return
+	}
+	if err := store.Users.SetAdminRole(r.Context(), user.ID, role); err != nil {
+		log.Printf("[admin] 역할 변경 실패 sub=%s: %v", user.ID, err)
+		adminUserRedirect(w, r, user.ID, "error", "user_update_failed")
+		return
+	}
+	AuditWarn(r, "admin.role_changed", "by", adminActor(r), "sub", user.ID, "username", user.Username, "from", user.AdminRole, "to", role)
+	adminUserRedirect(w, r, user.ID, "notice", "role_changed")
+}
+
+// adminTargetUser: 경로의 {id} 사용자 + 폼 파싱. 실패하면 목록으로 redirect 하고 false.
+// 대상이 어드민이면 superadmin 만 통과.
+func adminTargetUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	if err := r.ParseForm(); err != nil {
+		http.Redirect(w, r, "/admin/users?error=form_parse_failed", http.StatusSeeOther)
+		return nil, false
+	}
+	user, err := store.Users.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Redirect(w, r, "/admin/users?error=user_not_found", http.StatusSeeOther)
+		return nil, false
+	}
+	if admin := currentAdmin(r); admin != nil && user.AdminRole != "" && !adminAllows(admin.AdminRole, AdminSuper) {
+		AuditWarn(r, "admin.forbidden", "by", admin.Username, "role", admin.AdminRole, "path", r.URL.Path, "reason", "target_is_admin")
+		adminUserRedirect(w, r, user.ID, "error", "target_is_admin")
+		return nil, false
+	}
+	return user, true
+}
+
+// adminUserRedirect: 상세 페이지로 flash 와 함께 돌아간다. kind 는 "notice" / "error".
+func adminUserRedirect(w http.ResponseWriter, r *http.Request, userID, kind, code string) {
+	http.Redirect(w, r, "/admin/users/"+url.PathEscape(userID)+"?"+kind+"="+code, http.StatusSeeOther)
+}
+
+// revokeUserLogins: 사용자의 IdP 세션 · 어드민 세션 + refresh token 전부 폐기, 발급된 access token 차단. 실패는 로그만 (0 으로 집계).
+// 폐기한 세션의 client 에는 Back-Channel Logout 을 보낸다.
+func revokeUserLogins(ctx context.Context, userID string) (sessions, tokens int) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	removed, err := store.IdPSessions.DeleteByUser(userID)
+	if err != nil {
+		log.Printf("[admin] 세션 폐기 실패 sub=%s: %v", userID, err)
+	}
+	sessions = len(removed)
+	backchannelLogout("admin", removed...)
+	if _, err := store.AdminSessions.DeleteByUser(ctx, userID); err != nil {
+		log.Printf("[admin] 어드민 세션 폐기 실패 sub=%s: %v", userID, err)
+	}
+	if err := revokeAccessTokensIssuedBefore(ctx, userID, ""); err != nil {
+		log.Printf("[admin] access token 차단 실패 sub=%s: %v", userID, err)
+	}
+	tokens, err = store.RefreshTokens.RevokeByUser(ctx, userID)
+	if err != nil {
+		log.Printf("[admin] refresh token 폐기 실패 sub=%s: %v", userID, err)
+	}
+	return sessions, tokens
+}
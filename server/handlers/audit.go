@@ -1,18 +1,27 @@
 package handlers
 
 import (
+	"context"
+	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
 )
 
 // auditLog: 토큰 발급 / 인증 성공·실패 / 폐기 등 보안 이벤트 단일 채널.
 // stdout 으로 흐르므로 docker logs / journalctl 로 수집 가능.
-var auditLog = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+// store.Audit 이 주입되어 있으면 같은 이벤트를 영속 sink 에도 남긴다 (어드민 감사 로그 화면).
+var auditLog = slog.New(&auditHandler{out: slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})})
 
 // AuditEvent: 정상 흐름 (200/302) — INFO.
 func AuditEvent(r *http.Request, event string, attrs ...any) {
-	auditLog.Info(event, append([]any{
+	auditLog.InfoContext(r.Context(), event, append([]any{
 		slog.String("ip", clientIP(r)),
 		slog.String("ua", r.UserAgent()),
 	}, attrs...)...)
@@ -20,8 +29,186 @@ func AuditEvent(r *http.Request, event string, attrs ...any) {
 
 // AuditWarn: 실패 / 거부 흐름 (4xx) — WARN.
 func AuditWarn(r *http.Request, event string, attrs ...any) {
-	auditLog.Warn(event, append([]any{
+	auditLog.WarnContext(r.Context(), event, append([]any{
 		slog.String("ip", clientIP(r)),
 		slog.String("ua", r.UserAgent()),
 	}, attrs...)...)
 }
+
+// AuditSystem: 요청 없이 서버가 스스로 일으킨 이벤트 (스케줄 키 회전 등) — INFO.
+func AuditSystem(event string, attrs ...any) {
+	auditLog.Info(event, append([]any{
+		slog.String("actor", "system"),
+	}, attrs...)...)
+}
+
+// AuditSystemWarn: 서버가 스스로 일으킨 작업의 실패 (Back-Channel Logout 전달 실패 등) — WARN.
+func AuditSystemWarn(event string, attrs ...any) {
+	auditLog.Warn(event, append([]any{
+		slog.String("actor", "system"),
+	}, attrs...)...)
+}
+
+// auditStoreTimeout: 영속 sink 기록 한 건의 상한. sink 가 느려도 writer 가 한 건에 오래 묶이지 않게.
+const auditStoreTimeout = 2 * time.Second
+
+// auditQueueSize: 요청 goroutine 과 sink writer 사이 버퍼. 가득 차면 새 항목은 버리고 센다.
+const auditQueueSize = 1024
+
+// auditJob: writer 가 처리할 한 건. flushed 가 있으면 앞선 항목을 다 쓴 뒤 닫는다 (flushAudit).
+type auditJob struct {
+	entry   *models.AuditEntry
+	flushed chan struct{}
+}
+
+var (
+	auditQueue      = make(chan auditJob, auditQueueSize)
+	auditWriterOnce sync.Once
+	auditDropped    atomic.Int64 // 큐가 넘쳐 sink 에 못 남긴 건수 (stdout 에는 남았다)
+)
+
+// auditHandler: stdout JSON handler 에 그대로 넘기고, store.Audit 이 있으면 AuditEntry 로 바꿔 큐에 넣는다.
+// sink 기록은 백그라운드 writer 몫 — 요청은 DB 왕복을 기다리지 않고, 큐가 넘치면 버린 수만 센다.
+type auditHandler struct {
+	out   slog.Handler
+	attrs []slog.Attr
+}
+
+func (h *auditHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.out.Enabled(ctx, level)
+}
+
+func (h *auditHandler) Handle(ctx context.Context, rec slog.Record) error {
+	err := h.out.Handle(ctx, rec)
+	if store.Audit != nil {
+		enqueueAudit(auditJob{entry: h.entry(rec)})
+	}
+	return err
+}
+
+// enqueueAudit: 막히지 않는 전송. 첫 건과 이후 1000 건마다 log 로 알린다.
+func enqueueAudit(job auditJob) {
+	auditWriterOnce.Do(func() { go runAuditWriter() })
+	select {
+	case auditQueue <- job:
+	default:
+		if n := auditDropped.Add(1); n == 1 || n%1000 == 0 {
+			log.Printf("[audit] sink queue full: dropped %d entries so far (event=%s)", n, job.entry.Event)
+		}
+	}
+}
+
+// runAuditWriter: 큐를 순서대로 sink 에 기록. sink 는 매 건 전역을 읽는다 (main / 테스트의 교체 반영).
+func runAuditWriter() {
+	for job := range auditQueue {
+		if job.flushed != nil {
+			close(job.flushed)
+			continue
+		}
+		sink := store.Audit
+		if sink == nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), auditStoreTimeout)
+		if err := sink.Append(ctx, job.entry); err != nil {
+			log.Printf("[audit] store append failed: event=%s err=%v", job.entry.Event, err)
+		}
+		cancel()
+	}
+}
+
+// flushAudit: 지금까지 큐에 들어간 항목이 sink 에 기록될 때까지 기다린다 (테스트).
+// 큐가 가득 차 있어도 flush 표시는 버리지 않는다.
+func flushAudit() {
+	auditWriterOnce.Do(func() { go runAuditWriter() })
+	done := make(chan struct{})
+	auditQueue <- auditJob{flushed: done}
+	<-done
+}
+
+// AuditDropped: 큐가 넘쳐 영속 sink 에 남기지 못한 감사 이벤트 수 (프로세스 시작 이후).
+func AuditDropped() int64 {
+	return auditDropped.Load()
+}
+
+func (h *auditHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &auditHandler{out: h.out.WithAttrs(attrs), attrs: append(append([]slog.Attr(nil), h.attrs...), attrs...)}
+}
+
+func (h *auditHandler) WithGroup(name string) slog.Handler {
+	return &auditHandler{out: h.out.WithGroup(name), attrs: h.attrs}
+}
+
+// entry: 알려진 키는 열로 — ip / ua / client_id / sub (Subject), by · actor (Actor, 없으면 username).
+// 나머지는 Attrs 에 JSON 으로 담을 수 있는 값으로.
+func (h *auditHandler) entry(rec slog.Record) *models.AuditEntry {
+	e := &models.AuditEntry{
+		Time:  rec.Time,
+		Level: rec.Level.String(),
+		Event: rec.Message,
+		Attrs: map[string]any{},
+	}
+	var by, actor, username string
+	add := func(a slog.Attr) bool {
+		v := a.Value.Resolve()
+		switch a.Key {
+		case "ip":
+			e.IP = v.String()
+		case "ua":
+			e.UserAgent = v.String()
+		case "client_id":
+			e.ClientID = v.String()
+		case "sub":
+			e.Subject = v.String()
+		case "by":
+			by = v.String()
+		case "actor":
+			actor = v.String()
+		default:
+			if a.Key == "username" {
+				username = v.String()
+			}
+			e.Attrs[a.Key] = auditAttrValue(v)
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	rec.Attrs(add)
+	switch {
+	case by != "":
+		e.Actor = by
+	case actor != "":
+		e.Actor = actor
+	default:
+		e.Actor = username
+	}
+	return e
+}
+
+// auditAttrValue: slog 값 → JSON 으로 옮길 수 있는 값. error 는 메시지, 시간 / 기간은 문자열.
+func auditAttrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		return v.Any()
+	}
+	return v.String()
+}
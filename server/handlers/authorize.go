@@ -19,6 +19,7 @@ type loginPageData struct {
 	ClientID            string
 	RedirectURI         string
 	Scope               string
+	Prompt              string
 	ErrorMsg            string
 	CSRFToken           string
 	CodeChallenge       string
@@ -26,12 +27,80 @@ type loginPageData struct {
 	Nonce               string
 }
 
+// authRequest: 검증을 통과한 /authorize 파라미터 묶음.
+// 로그인 / 가입 / 동의 폼의 hidden 필드로 왕복하다가 code 발급 시 AuthCode 에 실린다.
+type authRequest struct {
+	ClientID            string
+	RedirectURI         string
+	State               string
+	Scope               string
+	Prompt              string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+// authRequestFromForm: POST 폼 (login / register / consent) 의 hidden 필드에서 복원.
+func authRequestFromForm(r *http.Request) authRequest {
+	return authRequest{
+		ClientID:            r.FormValue("client_id"),
+		RedirectURI:         r.FormValue("redirect_uri"),
+		State:               r.FormValue("state"),
+		Scope:               r.FormValue("scope"),
+		Prompt:              r.FormValue("prompt"),
+		CodeChallenge:       r.FormValue("code_challenge"),
+		CodeChallengeMethod: r.FormValue("code_challenge_method"),
+		Nonce:               r.FormValue("nonce"),
+	}
+}
+
+// issueCodeRedirect: auth code 발급 (10분) → redirect_uri 로 안전 redirect.
+// 호출자가 client / redirect_uri 검증과 (필요 시) 동의 확인을 마친 뒤 호출한다.
+// code 를 내주는 유일한 경로라 client 의 이메일 인증 요구도 여기서 확인한다.
+// authTime / amr 은 ID Token 의 auth_time / amr claim 으로, sessionID 는 sid claim 으로 이어진다.
+// 세션에 이 client 를 기록해 두어 그 세션이 끝날 때 Back-Channel Logout 을 통지한다.
+func issueCodeRedirect(w http.ResponseWriter, r *http.Request, tmpl *template.Template, client *models.Client, req authRequest, userID, sessionID string, authTime time.Time, amr []string) bool {
+	if !requireVerifiedEmail(w, r, tmpl, client, req, userID) {
+		return false
+	}
+	code, err := generateCode()
+	if err != nil {
+		http.Error(w, "서버 오류", http.StatusInternalServerError)
+		return false
+	}
+	if err := store.AuthCodes.Save(r.Context(), &models.AuthCode{
+		Code:                code,
+		ClientID:            req.ClientID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		ExpiresAt:           time.Now().Add(10 * time.Minute),
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		AuthTime:            authTime,
+		AMR:                 amr,
+		SID:                 models.SessionSID(sessionID),
+	}); err != nil {
+		http.Error(w, "서버 오류", http.StatusInternalServerError)
+		return false
+	}
+	store.IdPSessions.AddClient(sessionID, client.ClientID)
+	safeOAuthRedirect(w, r, req.RedirectURI, map[string]string{
+		"code":  code,
+		"state": req.State,
+	})
+	return true
+}
+
 // AuthorizeHandler: GET /oauth/authorize 처리.
 //
 // 흐름:
 //  1. redirect_uri / client_id / response_type 검증 (Open Redirect 방어 — 기존 유지)
+//     + scope 검증 (레지스트리 + client 허용 목록 밖이면 invalid_scope)
 //  2. IdP 세션 + 그룹 조회
-//  3. policy.Resolve → SILENT / PROMPT / ERROR 분기
+//  3. 동의 기록 조회 (세션이 있을 때만)
+//  4. policy.Resolve → SILENT / PROMPT / CONSENT / ERROR 분기
 //
 // SILENT 분기에서 폼 없이 즉시 auth code 가 발급되는 것이 silent SSO 의 본질.
 func AuthorizeHandler(tmpl *template.Template) http.HandlerFunc {
@@ -64,6 +133,15 @@ func AuthorizeHandler(tmpl *template.Template) http.HandlerFunc {
 		}
 
 		// 2-b. PKCE method 검증 — challenge 가 있으면 S256 만 허용 (OAuth 2.1).
+		// public client 는 secret 이 없으니 challenge 자체가 필수.
+		if client.IsPublic() && codeChallenge == "" {
+			safeOAuthRedirect(w, r, redirectURI, map[string]string{
+				"error":             "invalid_request",
+				"error_description": "code_challenge required for public clients",
+				"state":             state,
+			})
+			return
+		}
 		if codeChallenge != "" {
 			if codeChallengeMethod == "" {
 				codeChallengeMethod = "plain"
@@ -78,54 +156,83 @@ func AuthorizeHandler(tmpl *template.Template) http.HandlerFunc {
 			}
 		}
 
+		// 2-c. scope 검증 — 레지스트리에 없거나 client 허용 목록 밖이면 invalid_scope.
+		// 통과한 scope 는 중복 제거된 형태로 이후 단계 (동의 / code) 에 실린다.
+		scope, ok = allowedScope(r, "authorize", client, scope)
+		if !ok {
+			safeOAuthRedirect(w, r, redirectURI, map[string]string{
+				"error":             "invalid_scope",
+				"error_description": "requested scope is not allowed for this client",
+				"state":             state,
+			})
+			return
+		}
+
+		req := authRequest{
+			ClientID:            clientID,
+			RedirectURI:         redirectURI,
+			State:               state,
+			Scope:               scope,
+			Prompt:              prompt,
+			CodeChallenge:       codeChallenge,
+			CodeChallengeMethod: codeChallengeMethod,
+			Nonce:               nonce,
+		}
+
 		// 3. IdP 세션 조회 — Phase-R 단순화: LastGroupID 더 이상 안 본다
 		var (
 			hasSession bool
 			userID     string
+			sessionID  string
+			amr        []string
 		)
-		if sid, ok := GetIdPSessionID(r); ok {
-			if sess, ok := store.IdPSessions.Get(sid); ok {
-				hasSession = true
-				userID = sess.UserID
+		if sess, ok := currentIdPSession(r); ok {
+			hasSession = true
+			userID = sess.UserID
+			sessionID = sess.SessionID
+			amr = sess.AMR
+		}
+		// 3-b. 세션 주인이 비활성화 (또는 삭제) 됐으면 세션을 버리고 로그인 폼으로 — silent 발급 차단
+		// 조회 자체가 실패하면 세션은 그대로 두고 500
+		if hasSession {
+			gone, err := userUnavailable(r.Context(), userID)
+			if err != nil {
+				http.Error(w, "서버 오류", http.StatusInternalServerError)
+				return
+			}
+			if gone {
+				if sid, ok := GetIdPSessionID(r); ok {
+					store.IdPSessions.Delete(sid)
+				}
+				ClearIdPSessionCookie(w)
+				AuditWarn(r, "authorize.session_rejected", "sub", userID, "client_id", clientID)
+				hasSession, userID, sessionID, amr = false, "", "", nil
 			}
 		}
 
-		// 4. 정책 결정 — 그룹 입력 제거 (HasSession, Client.SilentSSO, Prompt 3 입력)
-		decision := policy.Resolve(policy.Inputs{
+		// 4. 정책 결정 — HasSession, Client (silent_sso / first-party), Prompt, 동의 여부
+		in := policy.Inputs{
 			HasSession: hasSession,
 			Client:     client,
 			Prompt:     prompt,
-		})
+		}
+		if hasSession {
+			in.ConsentGranted = consentGranted(r.Context(), userID, clientID, scope)
+		}
 
-		switch decision {
+		switch policy.Resolve(in) {
 		case policy.DecisionSilent:
 			// 폼 없이 즉시 auth code 발급 → redirect_uri 로 반환
-			code, err := generateCode()
-			if err != nil {
-				http.Error(w, "서버 오류", http.StatusInternalServerError)
-				return
-			}
-			store.AuthCodes.Store(code, models.AuthCode{
-				Code:                code,
-				ClientID:            clientID,
-				UserID:              userID,
-				RedirectURI:         redirectURI,
-				Scope:               scope,
-				ExpiresAt:           time.Now().Add(10 * time.Minute),
-				CodeChallenge:       codeChallenge,
-				CodeChallengeMethod: codeChallengeMethod,
-				Nonce:               nonce,
-				AuthTime:            time.Now(),
-			})
-			safeOAuthRedirect(w, r, redirectURI, map[string]string{
-				"code":  code,
-				"state": state,
-			})
+			issueCodeRedirect(w, r, tmpl, client, req, userID, sessionID, time.Now(), amr)
+
+		case policy.DecisionConsent:
+			// 로그인은 유효 — 요청 scope 동의만 받는다
+			renderConsent(w, tmpl, client, req, "")
 
 		case policy.DecisionError:
-			// prompt=none 인데 silent 불가 → OIDC 표준 login_required
+			// prompt=none 인데 silent 불가 → OIDC 표준 login_required / consent_required
 			safeOAuthRedirect(w, r, redirectURI, map[string]string{
-				"error": "login_required",
+				"error": policy.ErrorCode(in),
 				"state": state,
 			})
 
@@ -142,6 +249,7 @@ func AuthorizeHandler(tmpl *template.Template) http.HandlerFunc {
 				ClientID:            clientID,
 				RedirectURI:         redirectURI,
 				Scope:               scope,
+				Prompt:              prompt,
 				CSRFToken:           csrfToken,
 				CodeChallenge:       codeChallenge,
 				CodeChallengeMethod: codeChallengeMethod,
@@ -154,6 +262,22 @@ func AuthorizeHandler(tmpl *template.Template) http.HandlerFunc {
 	}
 }
 
+// verifyAuthRequest: POST 폼 (login / register / consent) 의 hidden 필드 재검증.
+// client_id / redirect_uri / scope 중 하나라도 변조되면 에러 페이지를 렌더하고 false —
+// redirect_uri 를 믿을 수 없으므로 redirect 하지 않는다.
+func verifyAuthRequest(w http.ResponseWriter, r *http.Request, tmpl *template.Template, req authRequest) (*models.Client, bool) {
+	client, ok := store.Clients.GetByClientID(req.ClientID)
+	if !ok || !containsURI(client.RedirectURIs, req.RedirectURI) {
+		renderError(w, tmpl, "유효하지 않은 client_id 또는 redirect_uri입니다")
+		return nil, false
+	}
+	if _, ok := allowedScope(r, "authorize", client, req.Scope); !ok {
+		renderError(w, tmpl, "허용되지 않은 scope 입니다")
+		return nil, false
+	}
+	return client, true
+}
+
 // renderError: redirect_uri 자체가 잘못된 경우 전용 에러 페이지 표시.
 // 이때는 redirect 하면 안 되므로 에러 페이지를 직접 렌더링한다.
 func renderError(w http.ResponseWriter, tmpl *template.Template, msg string) {
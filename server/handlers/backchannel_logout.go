@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+	"github.com/ftery0/ouath/server/token"
+)
+
+// OIDC Back-Channel Logout 1.0 — IdP 세션이 끝나면 그 세션으로 code 를 받아 간 client 에
+// 서명된 logout_token 을 서버 간 POST 로 보낸다. 브라우저를 거치지 않으므로 RP 탭이 닫혀 있어도 전달된다.
+//
+// 전달은 요청과 분리된 goroutine — RP 가 느리거나 죽어 있어도 logout 응답을 붙잡지 않는다.
+// 5xx / 네트워크 오류만 재시도한다. 4xx 는 RP 가 토큰을 거부한 것이라 같은 토큰을 다시 보내도 소용없다.
+
+// backchannelRetryDelays: 각 시도 전 대기. 길이 = 최대 시도 횟수. 테스트는 짧게 바꾼다.
+var backchannelRetryDelays = []time.Duration{0, 2 * time.Second, 10 * time.Second}
+
+// backchannelHTTPClient: redirect 는 따라가지 않는다 — 등록된 URI 로만 보낸다.
+// 내부 주소 차단은 newOutboundHTTPClient 의 dial 검사.
+var backchannelHTTPClient = func() *http.Client {
+	c := newOutboundHTTPClient(5 * time.Second)
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return c
+}()
+
+// backchannelLogout: 끝난 세션들의 client 에 logout_token 통지.
+// trigger 는 감사 로그용 (logout / admin / password_reset). backchannel_logout_uri 가 없는 client 는 건너뛴다.
+func backchannelLogout(trigger string, sessions ...*models.IdPSession) {
+	for _, sess := range sessions {
+		for _, clientID := range sess.ClientIDs {
+			c, ok := store.Clients.GetByClientID(clientID)
+			if !ok || c.BackchannelLogoutURI == "" {
+				continue
+			}
+			attrs := []any{"client_id", clientID, "sub", sess.UserID, "sid", sess.SID(), "trigger", trigger}
+			logoutToken, err := token.CreateLogoutToken(sess.UserID, clientID, sess.SID(), randomShort(32))
+			if err != nil {
+				AuditSystemWarn("logout.backchannel_failed", append(attrs, "reason", err.Error())...)
+				continue
+			}
+			go deliverLogoutToken(c.BackchannelLogoutURI, logoutToken, attrs)
+		}
+	}
+}
+
+// deliverLogoutToken: application/x-www-form-urlencoded 로 logout_token POST (§2.5). 2xx 면 성공.
+func deliverLogoutToken(uri, logoutToken string, attrs []any) {
+	body := url.Values{"logout_token": {logoutToken}}.Encode()
+	var reason string
+	for i, delay := range backchannelRetryDelays {
+		time.Sleep(delay)
+		resp, err := backchannelHTTPClient.Post(uri, "application/x-www-form-urlencoded", strings.NewReader(body))
+		if err != nil {
+			reason = err.Error()
+			continue
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			AuditSystem("logout.backchannel_sent", append(attrs, "attempts", i+1)...)
+			return
+		case resp.StatusCode >= 500:
+			reason = resp.Status
+			continue
+		}
+		AuditSystemWarn("logout.backchannel_failed", append(attrs, "attempts", i+1, "reason", resp.Status)...)
+		return
+	}
+	AuditSystemWarn("logout.backchannel_failed", append(attrs, "attempts", len(backchannelRetryDelays), "reason", reason)...)
+}
@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ftery0/ouath/server/config"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// client 인증 — /oauth/token · /oauth/revoke · /oauth/introspect · /oauth/device_authorization 공통.
+//
+//	client_secret_basic  Authorization: Basic client_id:client_secret
+//	client_secret_post   form client_id + client_secret
+//	client_secret_jwt    form client_assertion (HS256, 키 = client_secret) — OAUTH_CLIENT_SECRET_KEY 설정 시만
+//	private_key_jwt      form client_assertion (RS256 / PS256 / ES256, 등록된 jwks 또는 jwks_uri 로 검증)
+//	none                 form client_id 만 (public client)
+//
+// 한 요청에 두 방식을 섞으면 거부 (RFC 6749 §2.3). basic / post 는 전달 위치만 다르므로 서로 바꿔 써도 되고,
+// JWT 방식과 none 은 등록한 방식 그대로여야 한다.
+// assertion 은 iss = sub = client_id, aud = issuer 또는 호출한 엔드포인트 URL, exp 필수 (최대 maxClientAssertionLifetime),
+// jti 는 만료까지 한 번만 (store.ClientAssertions).
+
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// maxClientAssertionLifetime: assertion exp 상한 (지금 기준). jti 보관 기간도 이만큼으로 묶인다.
+const maxClientAssertionLifetime = 10 * time.Minute
+
+// client_secret_jwt / private_key_jwt 에 허용하는 서명 알고리즘. none 과 알고리즘 혼동을 막으려 방식별로 고정.
+var (
+	clientSecretJWTAlgs = []string{"HS256"}
+	privateKeyJWTAlgs   = []string{"RS256", "PS256", "ES256"}
+)
+
+// clientSecretAEAD: ClientAuthInit 에서 만든 secret 암호화 (AES-256-GCM). nil 이면 client_secret_jwt 미지원.
+var clientSecretAEAD cipher.AEAD
+
+// ClientAuthInit: main 에서 한 번 호출 (config 의 OAUTH_CLIENT_SECRET_KEY). 비우면 client_secret_jwt 비활성.
+func ClientAuthInit(secretKey string) {
+	if secretKey == "" {
+		clientSecretAEAD = nil
+		return
+	}
+	key := sha256.Sum256([]byte(secretKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic("client secret key: " + err.Error())
+	}
+	clientSecretAEAD, err = cipher.NewGCM(block)
+	if err != nil {
+		panic("client secret key: " + err.Error())
+	}
+}
+
+// clientSecretJWTEnabled: client_secret_jwt 를 받을 수 있는가 (discovery / 등록 검증).
+func clientSecretJWTEnabled() bool {
+	return clientSecretAEAD != nil
+}
+
+// clientAuthMethods: confidential client 가 쓸 수 있는 방식. public client 의 none 은 엔드포인트마다 따로 (introspect 는 불가).
+func clientAuthMethods() []string {
+	methods := []string{models.AuthMethodClientSecretBasic, models.AuthMethodClientSecretPost}
+	if clientSecretJWTEnabled() {
+		methods = append(methods, models.AuthMethodClientSecretJWT)
+	}
+	return append(methods, models.AuthMethodPrivateKeyJWT)
+}
+
+// clientAuthSigningAlgs: discovery 의 token_endpoint_auth_signing_alg_values_supported.
+func clientAuthSigningAlgs() []string {
+	if clientSecretJWTEnabled() {
+		return append(append([]string{}, privateKeyJWTAlgs...), clientSecretJWTAlgs...)
+	}
+	return privateKeyJWTAlgs
+}
+
+// authenticateClient: 위 방식 중 하나로 client 를 확인한다.
+// 실패 시 invalid_client 응답까지 쓰고 false. endpoint 는 감사 이벤트 접두사 (token / device / revoke / introspect).
+func authenticateClient(w http.ResponseWriter, r *http.Request, endpoint string) (*models.Client, bool) {
+	client, clientID, reason := verifyClientCredentials(r)
+	if reason != "" {
+		if reason == "missing" {
+			AuditWarn(r, endpoint+".client_auth_missing")
+		} else {
+			AuditWarn(r, endpoint+".client_auth_failed", "client_id", clientID, "reason", reason)
+		}
+		tokenError(w, "invalid_client", "client 인증 실패", http.StatusUnauthorized)
+		return nil, false
+	}
+	return client, true
+}
+
+// verifyClientCredentials: 성공하면 client. 실패하면 감사 로그용 client_id (알 수 있으면) + 사유.
+func verifyClientCredentials(r *http.Request) (*models.Client, string, string) {
+	basicID, basicSecret, hasBasic := r.BasicAuth()
+	formID := r.PostFormValue("client_id")
+	formSecret := r.PostFormValue("client_secret")
+	assertion := r.PostFormValue("client_assertion")
+
+	presented := 0
+	for _, p := range []bool{hasBasic, formSecret != "", assertion != ""} {
+		if p {
+			presented++
+		}
+	}
+	if presented > 1 {
+		return nil, formID, "multiple_methods"
+	}
+
+	switch {
+	case hasBasic:
+		if formID != "" && formID != basicID {
+			return nil, basicID, "client_id_mismatch"
+		}
+		return verifyClientSecret(basicID, basicSecret)
+	case formSecret != "":
+		return verifyClientSecret(formID, formSecret)
+	case assertion != "":
+		if r.PostFormValue("client_assertion_type") != clientAssertionType {
+			return nil, formID, "unsupported_assertion_type"
+		}
+		return verifyClientAssertion(r, assertion, formID)
+	case formID != "":
+		// public client: client_id 만. 소유 증명은 grant 쪽 PKCE 몫
+		if client, ok := store.Clients.GetByClientID(formID); ok && client.IsPublic() {
+			return client, formID, ""
+		}
+		return nil, formID, "secret_required"
+	}
+	return nil, "", "missing"
+}
+
+// verifyClientSecret: basic / post 공통. 등록 방식이 둘 중 하나인 client 만.
+func verifyClientSecret(clientID, secret string) (*models.Client, string, string) {
+	client, ok := store.Clients.GetByClientID(clientID)
+	if !ok {
+		return nil, clientID, "unknown_client"
+	}
+	if m := client.AuthMethod(); m != models.AuthMethodClientSecretBasic && m != models.AuthMethodClientSecretPost {
+		return nil, clientID, "auth_method_mismatch"
+	}
+	if !store.VerifySecret(client, secret) {
+		return nil, clientID, "invalid_secret"
+	}
+	return client, clientID, ""
+}
+
+// verifyClientAssertion: RFC 7523 §3 검증 + jti 재사용 차단.
+func verifyClientAssertion(r *http.Request, assertion, formID string) (*models.Client, string, string) {
+	// 서명 검증 전에 누구의 키로 볼지 정해야 하므로 sub 만 먼저 읽는다 (신뢰는 검증 후)
+	var peek jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(assertion, &peek); err != nil {
+		return nil, formID, "malformed_assertion"
+	}
+	clientID := peek.Subject
+	if clientID == "" || (formID != "" && formID != clientID) {
+		return nil, formID, "client_id_mismatch"
+	}
+	client, ok := store.Clients.GetByClientID(clientID)
+	if !ok {
+		return nil, clientID, "unknown_client"
+	}
+
+	var algs []string
+	var keyfunc jwt.Keyfunc
+	switch client.AuthMethod() {
+	case models.AuthMethodClientSecretJWT:
+		algs = clientSecretJWTAlgs
+		keyfunc = func(*jwt.Token) (any, error) {
+			secret, err := openClientSecret(client)
+			return []byte(secret), err
+		}
+	case models.AuthMethodPrivateKeyJWT:
+		algs = privateKeyJWTAlgs
+		keyfunc = func(t *jwt.Token) (any, error) {
+			return clientVerificationKeys(r.Context(), client, t)
+		}
+	default:
+		return nil, clientID, "auth_method_mismatch"
+	}
+
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(assertion, &claims, keyfunc,
+		jwt.WithValidMethods(algs),
+		jwt.WithIssuer(clientID),
+		jwt.WithSubject(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, clientID, "invalid_assertion"
+	}
+	if !assertionAudienceOK(r, claims.Audience) {
+		return nil, clientID, "invalid_audience"
+	}
+	if claims.ID == "" {
+		return nil, clientID, "jti_required"
+	}
+	if claims.ExpiresAt.Time.After(time.Now().Add(maxClientAssertionLifetime)) {
+		return nil, clientID, "assertion_lifetime_too_long"
+	}
+	if err := store.ClientAssertions.Use(r.Context(), clientID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		if errors.Is(err, store.ErrClientAssertionReplayed) {
+			return nil, clientID, "jti_replayed"
+		}
+		return nil, clientID, "jti_store_failed"
+	}
+	return client, clientID, ""
+}
+
+// assertionAudienceOK: aud 에 issuer 또는 지금 호출된 엔드포인트 URL 이 있어야 한다.
+func assertionAudienceOK(r *http.Request, aud jwt.ClaimStrings) bool {
+	issuer := config.IssuerForDiscovery()
+	for _, a := range aud {
+		if a != "" && (a == issuer || a == issuer+r.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// ── private_key_jwt 공개키 ────────────────────────────────────────────────
+
+// clientJWKSCacheTTL: jwks_uri 응답 재사용 기간. 모르는 kid 가 오면 clientJWKSRefetchGap 이 지났을 때 한 번 더 받는다 (키 회전).
+const (
+	clientJWKSCacheTTL   = 10 * time.Minute
+	clientJWKSRefetchGap = time.Minute
+)
+
+type cachedJWKS struct {
+	uri       string
+	keys      []JWK
+	fetchedAt time.Time
+}
+
+var (
+	clientJWKSCache   = make(map[string]cachedJWKS) // key: client_id
+	clientJWKSCacheMu sync.Mutex
+	clientJWKSHTTP    = newOutboundHTTPClient(5 * time.Second)
+)
+
+// clientVerificationKeys: jwt.Keyfunc 본체. 헤더 kid (있으면) 와 알고리즘 계열이 맞는 키만 후보로.
+func clientVerificationKeys(ctx context.Context, c *models.Client, t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	pick := func(keys []JWK) jwt.VerificationKeySet {
+		var set jwt.VerificationKeySet
+		for _, k := range keys {
+			if kid != "" && k.Kid != kid {
+				continue
+			}
+			pub, err := k.PublicKey()
+			if err != nil || !keyMatchesMethod(pub, t.Method) {
+				continue
+			}
+			set.Keys = append(set.Keys, pub)
+		}
+		return set
+	}
+
+	if c.JWKS != "" {
+		var set JWKS
+		if err := json.Unmarshal([]byte(c.JWKS), &set); err != nil {
+			return nil, fmt.Errorf("등록된 jwks 파싱 실패: %w", err)
+		}
+		if ks := pick(set.Keys); len(ks.Keys) > 0 {
+			return ks, nil
+		}
+		return nil, errors.New("일치하는 공개키 없음")
+	}
+	if c.JWKSURI == "" {
+		return nil, errors.New("등록된 공개키 없음")
+	}
+
+	keys, err := fetchClientJWKS(ctx, c, false)
+	if err != nil {
+		return nil, err
+	}
+	if ks := pick(keys); len(ks.Keys) > 0 {
+		return ks, nil
+	}
+	// 모르는 kid — client 가 키를 회전했을 수 있으니 한 번 새로 받는다
+	if keys, err = fetchClientJWKS(ctx, c, true); err != nil {
+		return nil, err
+	}
+	if ks := pick(keys); len(ks.Keys) > 0 {
+		return ks, nil
+	}
+	return nil, errors.New("일치하는 공개키 없음")
+}
+
+// keyMatchesMethod: RSA 키는 RS / PS, EC 키는 ES 만 (곡선까지 확인).
+func keyMatchesMethod(pub any, m jwt.SigningMethod) bool {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		_, rs := m.(*jwt.SigningMethodRSA)
+		_, ps := m.(*jwt.SigningMethodRSAPSS)
+		return rs || ps
+	case *ecdsa.PublicKey:
+		es, ok := m.(*jwt.SigningMethodECDSA)
+		return ok && k.Curve.Params().BitSize == es.CurveBits
+	}
+	return false
+}
+
+// fetchClientJWKS: jwks_uri 캐시. refresh 면 TTL 과 무관하게 (단 clientJWKSRefetchGap 이 지났을 때만) 다시 받는다.
+// 받기에 실패해도 캐시가 있으면 그것을 쓴다.
+func fetchClientJWKS(ctx context.Context, c *models.Client, refresh bool) ([]JWK, error) {
+	clientJWKSCacheMu.Lock()
+	cached, ok := clientJWKSCache[c.ClientID]
+	clientJWKSCacheMu.Unlock()
+	ok = ok && cached.uri == c.JWKSURI
+	age := time.Since(cached.fetchedAt)
+	if ok && (age < clientJWKSRefetchGap || (!refresh && age < clientJWKSCacheTTL)) {
+		return cached.keys, nil
+	}
+
+	keys, err := getJWKS(ctx, c.JWKSURI)
+	if err != nil {
+		if ok {
+			return cached.keys, nil
+		}
+		return nil, err
+	}
+	clientJWKSCacheMu.Lock()
+	clientJWKSCache[c.ClientID] = cachedJWKS{uri: c.JWKSURI, keys: keys, fetchedAt: time.Now()}
+	clientJWKSCacheMu.Unlock()
+	return keys, nil
+}
+
+func getJWKS(ctx context.Context, uri string) ([]JWK, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := clientJWKSHTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks_uri 응답 %d", resp.StatusCode)
+	}
+	var set JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("jwks_uri 파싱 실패: %w", err)
+	}
+	return set.Keys, nil
+}
+
+// validClientJWKS: 등록 시 검증 — JWK Set JSON 이고 쓸 수 있는 공개키가 하나 이상.
+func validClientJWKS(raw string) bool {
+	var set JWKS
+	if err := json.Unmarshal([]byte(raw), &set); err != nil {
+		return false
+	}
+	for _, k := range set.Keys {
+		if _, err := k.PublicKey(); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// PublicKey: RSA (n, e) 또는 EC P-256 / P-384 (x, y) JWK → 공개키. 비밀키 성분 (d) 이 있으면 거부.
+func (k JWK) PublicKey() (any, error) {
+	if k.D != "" {
+		return nil, errors.New("jwk 에 비밀키 성분이 있음")
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("잘못된 RSA jwk")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA 키는 2048 비트 이상")
+		}
+		return pub, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("지원하지 않는 곡선: %s", k.Crv)
+		}
+		x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+		y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+		size := (curve.Params().BitSize + 7) / 8
+		if err1 != nil || err2 != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("잘못된 EC jwk")
+		}
+		return ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+	}
+	return nil, fmt.Errorf("지원하지 않는 kty: %s", k.Kty)
+}
+
+// ── client_secret_jwt secret 보관 ─────────────────────────────────────────
+
+// sealClientSecret: client_secret_jwt client 의 secret 평문 → AES-GCM 암호문 (base64url, nonce 앞에).
+// client_id 를 AAD 로 묶어 다른 행으로 옮겨 쓰지 못하게 한다. 다른 방식의 client 면 "".
+func sealClientSecret(c *models.Client, plain string) (string, error) {
+	if c.AuthMethod() != models.AuthMethodClientSecretJWT {
+		return "", nil
+	}
+	if clientSecretAEAD == nil {
+		return "", errors.New("OAUTH_CLIENT_SECRET_KEY 미설정")
+	}
+	nonce := make([]byte, clientSecretAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := clientSecretAEAD.Seal(nonce, nonce, []byte(plain), []byte(c.ClientID))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// openClientSecret: sealClientSecret 의 역. 키가 바뀌었거나 봉인된 secret 이 없으면 에러 (→ secret 재발급 필요).
+func openClientSecret(c *models.Client) (string, error) {
+	if clientSecretAEAD == nil || c.ClientSecretSealed == "" {
+		return "", errors.New("client_secret_jwt 용 secret 없음")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(c.ClientSecretSealed)
+	ns := clientSecretAEAD.NonceSize()
+	if err != nil || len(raw) < ns {
+		return "", errors.New("봉인된 secret 형식 오류")
+	}
+	plain, err := clientSecretAEAD.Open(nil, raw[:ns], raw[ns:], []byte(c.ClientID))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
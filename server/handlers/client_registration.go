@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ftery0/ouath/server/config"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/scope"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// 동적 client 등록 — RFC 7591 (등록) + RFC 7592 (관리).
+//
+//	POST   /oauth/clients/register              Authorization: Bearer <initial access token> → 201 + client_secret + registration_access_token
+//	GET    /oauth/clients/register/{client_id}  Authorization: Bearer <registration access token> → 현재 메타데이터
+//	PUT    /oauth/clients/register/{client_id}  메타데이터 전체 교체 (빠진 항목은 기본값으로)
+//	DELETE /oauth/clients/register/{client_id}  삭제 (refresh token / 동의 함께)
+//
+// initial access token 이 설정되지 않으면 등록 엔드포인트는 닫혀 있고 discovery 에도 나오지 않는다.
+// 동적 등록 client 는 항상 third-party — 첫 authorize 에서 동의 화면을 거친다.
+
+// dcrInitialAccessToken: DCRInit 에서 주입. 비어 있으면 등록 비활성.
+var dcrInitialAccessToken string
+
+// DCRInit: main 에서 한 번 호출 (config 의 OAUTH_DCR_INITIAL_ACCESS_TOKEN).
+func DCRInit(initialAccessToken string) {
+	dcrInitialAccessToken = initialAccessToken
+}
+
+// dcrEnabled: 등록 엔드포인트를 열었는가 (discovery 광고 여부).
+func dcrEnabled() bool {
+	return dcrInitialAccessToken != ""
+}
+
+// clientRegistrationPath: 등록 엔드포인트 경로. 관리 API 는 뒤에 /{client_id}.
+const clientRegistrationPath = "/oauth/clients/register"
+
+// dcrGrantTypes: 동적 등록으로 선언할 수 있는 grant.
+var dcrGrantTypes = []string{"authorization_code", "refresh_token", "client_credentials", deviceCodeGrantType}
+
+// clientMetadata: RFC 7591 §2 요청 / 응답 메타데이터 중 이 IdP 가 다루는 것.
+type clientMetadata struct {
+	RedirectURIs            []string `json:"redirect_uris"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	ClientName              string   `json:"client_name,omitempty"`
+	ClientURI               string   `json:"client_uri,omitempty"`
+	LogoURI                 string   `json:"logo_uri,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+
+	// private_key_jwt 공개키 — 둘 중 하나만 (RFC 7591 §2)
+	JWKS    json.RawMessage `json:"jwks,omitempty"`
+	JWKSURI string          `json:"jwks_uri,omitempty"`
+
+	// OIDC Back-Channel / Front-Channel Logout 1.0 §2.2. sid 는 항상 싣는다
+	BackchannelLogoutURI              string `json:"backchannel_logout_uri,omitempty"`
+	BackchannelLogoutSessionRequired  bool   `json:"backchannel_logout_session_required,omitempty"`
+	FrontchannelLogoutURI             string `json:"frontchannel_logout_uri,omitempty"`
+	FrontchannelLogoutSessionRequired bool   `json:"frontchannel_logout_session_required,omitempty"`
+
+	// PUT (RFC 7592 §2.2) 은 client_id 를 함께 보낸다. 경로와 다르면 거부.
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// clientInformation: RFC 7591 §3.2.1 / RFC 7592 §3 응답.
+type clientInformation struct {
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   *int64 `json:"client_secret_expires_at,omitempty"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
+	clientMetadata
+}
+
+// ClientRegisterHandler: POST /oauth/clients/register — RFC 7591 등록.
+func ClientRegisterHandler(w http.ResponseWriter, r *http.Request) {
+	if !dcrEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+	if !bearerMatches(r, dcrInitialAccessToken) {
+		AuditWarn(r, "client_registration.unauthorized")
+		bearerError(w, "invalid_token", "initial access token 이 필요합니다")
+		return
+	}
+
+	md, ok := decodeClientMetadata(w, r)
+	if !ok {
+		return
+	}
+	c := &models.Client{
+		ClientID:     "client-" + randomShort(8),
+		ClientSecret: randomShort(32),
+		OwnerID:      "dcr",
+		SilentSSO:    true,
+		FirstParty:   false,
+	}
+	if !applyClientMetadata(w, r, c, md) {
+		return
+	}
+	if !c.UsesSecret() {
+		c.ClientSecret = ""
+	}
+	sealed, err := sealClientSecret(c, c.ClientSecret)
+	if err != nil {
+		registrationError(w, http.StatusInternalServerError, "server_error", "secret 보관 실패")
+		return
+	}
+	c.ClientSecretSealed = sealed
+	regToken, err := generateCode()
+	if err != nil {
+		registrationError(w, http.StatusInternalServerError, "server_error", "토큰 생성 실패")
+		return
+	}
+	c.RegistrationAccessTokenHash = store.HashToken(regToken)
+	c.CreatedAt = time.Now()
+
+	if err := store.Clients.Register(c); err != nil {
+		registrationError(w, http.StatusInternalServerError, "server_error", "등록 실패")
+		return
+	}
+	AuditEvent(r, "client_registration.registered", "client_id", c.ClientID,
+		"redirect_uris", strings.Join(c.RedirectURIs, " "), "grant_types", strings.Join(c.GrantTypes, " "))
+
+	info := clientInfo(c)
+	info.ClientSecret = c.ClientSecret
+	info.RegistrationAccessToken = regToken
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, info)
+}
+
+// ClientConfigGetHandler: GET /oauth/clients/register/{client_id} — RFC 7592 §2.1 읽기.
+// secret 은 hash 만 있으므로 응답에 싣지 않는다.
+func ClientConfigGetHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := registeredClient(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, clientInfo(c))
+}
+
+// ClientConfigPutHandler: PUT /oauth/clients/register/{client_id} — RFC 7592 §2.2 갱신.
+// 요청 본문이 메타데이터 전체 — 빠진 값은 등록 때와 같은 기본값으로 돌아간다.
+func ClientConfigPutHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := registeredClient(w, r)
+	if !ok {
+		return
+	}
+	md, ok := decodeClientMetadata(w, r)
+	if !ok {
+		return
+	}
+	if md.ClientID != "" && md.ClientID != c.ClientID {
+		registrationError(w, http.StatusBadRequest, "invalid_client_metadata", "client_id 가 경로와 다릅니다")
+		return
+	}
+
+	updated := *c
+	if !applyClientMetadata(w, r, &updated, md) {
+		return
+	}
+	// 인증 방식 전환은 secret 발급 / 폐기 / 봉인이 따라와야 하므로 관리 API 로는 basic ↔ post 만 받는다
+	if from, to := c.AuthMethod(), updated.AuthMethod(); from != to && !(isSecretBasicOrPost(from) && isSecretBasicOrPost(to)) {
+		registrationError(w, http.StatusBadRequest, "invalid_client_metadata", "token_endpoint_auth_method 를 "+from+" 에서 "+to+" 로 바꿀 수 없습니다 — 새로 등록하세요")
+		return
+	}
+	if err := store.Clients.Update(&updated); err != nil {
+		registrationError(w, http.StatusInternalServerError, "server_error", "갱신 실패")
+		return
+	}
+	AuditEvent(r, "client_registration.updated", "client_id", c.ClientID,
+		"redirect_uris", strings.Join(updated.RedirectURIs, " "), "grant_types", strings.Join(updated.GrantTypes, " "))
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, clientInfo(&updated))
+}
+
+// ClientConfigDeleteHandler: DELETE /oauth/clients/register/{client_id} — RFC 7592 §2.3 삭제.
+func ClientConfigDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := registeredClient(w, r)
+	if !ok {
+		return
+	}
+	tokens, consents, err := deleteClient(r.Context(), c.ClientID)
+	if err != nil {
+		registrationError(w, http.StatusInternalServerError, "server_error", "삭제 실패")
+		return
+	}
+	AuditWarn(r, "client_registration.deleted", "client_id", c.ClientID, "refresh_tokens", tokens, "consents", consents)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// registeredClient: 경로의 client + registration access token 확인.
+// 없는 client / 토큰 불일치 / 관리 API 대상이 아닌 client 는 모두 같은 401 (존재 여부 비노출, RFC 7592 §3).
+func registeredClient(w http.ResponseWriter, r *http.Request) (*models.Client, bool) {
+	c, ok := store.Clients.GetByClientID(r.PathValue("client_id"))
+	tok := bearerToken(r)
+	if !ok || c.RegistrationAccessTokenHash == "" || tok == "" ||
+		subtle.ConstantTimeCompare([]byte(store.HashToken(tok)), []byte(c.RegistrationAccessTokenHash)) != 1 {
+		AuditWarn(r, "client_registration.unauthorized", "client_id", r.PathValue("client_id"))
+		bearerError(w, "invalid_token", "registration access token 이 올바르지 않습니다")
+		return nil, false
+	}
+	return c, true
+}
+
+// decodeClientMetadata: JSON 본문 → clientMetadata. 실패 시 400 invalid_client_metadata 까지 쓰고 false.
+func decodeClientMetadata(w http.ResponseWriter, r *http.Request) (clientMetadata, bool) {
+	var md clientMetadata
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&md); err != nil {
+		registrationError(w, http.StatusBadRequest, "invalid_client_metadata", "JSON 본문을 읽을 수 없습니다")
+		return md, false
+	}
+	return md, true
+}
+
+// applyClientMetadata: 검증 후 c 에 반영. 실패 시 RFC 7591 §3.2.2 에러까지 쓰고 false.
+func applyClientMetadata(w http.ResponseWriter, r *http.Request, c *models.Client, md clientMetadata) bool {
+	fail := func(code, desc string) bool {
+		AuditWarn(r, "client_registration.rejected", "client_id", c.ClientID, "error", code, "reason", desc)
+		registrationError(w, http.StatusBadRequest, code, desc)
+		return false
+	}
+
+	grantTypes := md.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = []string{"authorization_code"}
+	}
+	for _, g := range grantTypes {
+		if !contains(dcrGrantTypes, g) {
+			return fail("invalid_client_metadata", "지원하지 않는 grant_type: "+g)
+		}
+	}
+	for _, rt := range md.ResponseTypes {
+		if rt != "code" {
+			return fail("invalid_client_metadata", "지원하지 않는 response_type: "+rt)
+		}
+	}
+
+	authMethod := md.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = models.AuthMethodClientSecretBasic
+	}
+	if authMethod != models.AuthMethodNone && !contains(clientAuthMethods(), authMethod) {
+		return fail("invalid_client_metadata", "지원하지 않는 token_endpoint_auth_method: "+authMethod)
+	}
+	var jwks string
+	if len(md.JWKS) > 0 && string(md.JWKS) != "null" {
+		jwks = string(md.JWKS)
+	}
+	if authMethod == models.AuthMethodPrivateKeyJWT {
+		switch {
+		case (jwks == "") == (md.JWKSURI == ""):
+			return fail("invalid_client_metadata", "private_key_jwt 에는 jwks 또는 jwks_uri 중 하나가 필요합니다")
+		case jwks != "" && !validClientJWKS(jwks):
+			return fail("invalid_client_metadata", "jwks 에 쓸 수 있는 공개키 (RSA 2048+ / EC P-256·P-384) 가 없습니다")
+		case md.JWKSURI != "" && !validOutboundURI(md.JWKSURI):
+			return fail("invalid_client_metadata", "jwks_uri 는 https (로컬 개발은 http://localhost) 절대 URL: "+md.JWKSURI)
+		}
+	} else {
+		jwks, md.JWKSURI = "", ""
+	}
+	// none = public client. secret 없이 쓸 수 있는 건 PKCE 로 묶이는 grant 뿐
+	public := authMethod == models.AuthMethodNone
+	if public {
+		for _, g := range grantTypes {
+			if g != "authorization_code" && g != "refresh_token" {
+				return fail("invalid_client_metadata", "token_endpoint_auth_method=none 으로는 쓸 수 없는 grant_type: "+g)
+			}
+		}
+	}
+
+	redirectURIs := cleanList(md.RedirectURIs)
+	if contains(grantTypes, "authorization_code") && len(redirectURIs) == 0 {
+		return fail("invalid_redirect_uri", "authorization_code 에는 redirect_uris 가 필요합니다")
+	}
+	for _, u := range redirectURIs {
+		if !validRegisteredRedirectURI(u) {
+			return fail("invalid_redirect_uri", "redirect_uri 는 https (로컬 개발은 http://localhost) 절대 URL, fragment 없이: "+u)
+		}
+	}
+	for _, u := range []string{md.ClientURI, md.LogoURI} {
+		if u != "" && !validHTTPURL(u) {
+			return fail("invalid_client_metadata", "client_uri / logo_uri 는 http(s) 절대 URL: "+u)
+		}
+	}
+	if md.BackchannelLogoutURI != "" && !validOutboundURI(md.BackchannelLogoutURI) {
+		return fail("invalid_client_metadata", "backchannel_logout_uri 는 https (로컬 개발은 http://localhost) 절대 URL: "+md.BackchannelLogoutURI)
+	}
+	if md.FrontchannelLogoutURI != "" && !validFrontchannelLogoutURI(md.FrontchannelLogoutURI, redirectURIs) {
+		return fail("invalid_client_metadata", "frontchannel_logout_uri 는 redirect_uri 와 같은 origin 의 https (로컬 개발은 http://localhost) URL: "+md.FrontchannelLogoutURI)
+	}
+
+	allowed := scope.DefaultNames()
+	if strings.TrimSpace(md.Scope) != "" {
+		scopes, bad, ok := scope.Validate(md.Scope, scope.Supported())
+		if !ok {
+			return fail("invalid_client_metadata", "알 수 없는 scope: "+bad)
+		}
+		allowed = scopes
+	}
+
+	name := strings.TrimSpace(md.ClientName)
+	if name == "" {
+		name = c.ClientID
+	}
+
+	c.Name = name
+	c.MainURL = md.ClientURI
+	c.LogoURI = md.LogoURI
+	c.RedirectURIs = redirectURIs
+	c.GrantTypes = grantTypes
+	c.TokenEndpointAuthMethod = authMethod
+	c.JWKS = jwks
+	c.JWKSURI = md.JWKSURI
+	c.BackchannelLogoutURI = md.BackchannelLogoutURI
+	c.FrontchannelLogoutURI = md.FrontchannelLogoutURI
+	c.ClientType = models.ClientTypeConfidential
+	if public {
+		c.ClientType = models.ClientTypePublic
+	}
+	c.AllowedScopes = allowed
+	// client_credentials 는 선언만 받는다. 사용자 없는 토큰 발급은 관리자가 콘솔에서 켠다.
+	// 관리 API 로는 끌 수만 있고, 이미 켜져 있으면 scope 를 새 허용 범위로 좁힌다.
+	if !contains(grantTypes, "client_credentials") {
+		c.ClientCredentials = false
+	}
+	if !c.ClientCredentials {
+		c.ClientCredentialsScopes = nil
+	} else {
+		c.ClientCredentialsScopes = scope.Intersect(c.ClientCredentialsScopes, allowed)
+	}
+	return true
+}
+
+// validRegisteredRedirectURI: https 절대 URL (fragment 없음). 로컬 개발용 http://localhost / 127.0.0.1 만 예외.
+func validRegisteredRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		h := u.Hostname()
+		return h == "localhost" || h == "127.0.0.1" || h == "::1"
+	}
+	return false
+}
+
+func isSecretBasicOrPost(m string) bool {
+	return m == models.AuthMethodClientSecretBasic || m == models.AuthMethodClientSecretPost
+}
+
+func validHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// clientInfo: 저장된 client → 응답 (secret / 등록 토큰 제외).
+func clientInfo(c *models.Client) clientInformation {
+	var never int64 // client_secret_expires_at=0: 만료 없음
+	info := clientInformation{
+		ClientID:              c.ClientID,
+		ClientIDIssuedAt:      c.CreatedAt.Unix(),
+		RegistrationClientURI: config.IssuerForDiscovery() + clientRegistrationPath + "/" + url.PathEscape(c.ClientID),
+		clientMetadata: clientMetadata{
+			RedirectURIs:            c.RedirectURIs,
+			GrantTypes:              c.GrantTypes,
+			ResponseTypes:           []string{"code"},
+			TokenEndpointAuthMethod: c.AuthMethod(),
+			ClientName:              c.Name,
+			ClientURI:               c.MainURL,
+			LogoURI:                 c.LogoURI,
+			Scope:                   strings.Join(c.AllowedScopes, " "),
+			JWKSURI:                 c.JWKSURI,
+
+			BackchannelLogoutURI:              c.BackchannelLogoutURI,
+			BackchannelLogoutSessionRequired:  c.BackchannelLogoutURI != "",
+			FrontchannelLogoutURI:             c.FrontchannelLogoutURI,
+			FrontchannelLogoutSessionRequired: c.FrontchannelLogoutURI != "",
+		},
+	}
+	if c.JWKS != "" {
+		info.JWKS = json.RawMessage(c.JWKS)
+	}
+	// client_secret_expires_at 은 secret 을 발급한 client 에만 (RFC 7591 §3.2.1)
+	if c.UsesSecret() {
+		info.ClientSecretExpiresAt = &never
+	}
+	return info
+}
+
+// deleteClient: refresh token · 동의 기록 정리 후 client 삭제 (어드민 / 관리 API 공통).
+func deleteClient(ctx context.Context, clientID string) (tokens, consents int, err error) {
+	if err = revokeAccessTokensIssuedBefore(ctx, "", clientID); err != nil {
+		return 0, 0, err
+	}
+	if tokens, err = store.RefreshTokens.RevokeByClient(ctx, clientID); err != nil {
+		return 0, 0, err
+	}
+	if consents, err = store.Consents.DeleteByClient(ctx, clientID); err != nil {
+		return tokens, 0, err
+	}
+	return tokens, consents, store.Clients.Delete(clientID)
+}
+
+// bearerToken: Authorization: Bearer <token>. 없으면 "".
+func bearerToken(r *http.Request) string {
+	scheme, tok, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(tok)
+}
+
+// bearerMatches: Bearer 토큰이 want 와 같은가 (상수 시간 비교).
+func bearerMatches(r *http.Request, want string) bool {
+	tok := bearerToken(r)
+	return tok != "" && subtle.ConstantTimeCompare([]byte(tok), []byte(want)) == 1
+}
+
+// bearerError: RFC 6750 §3 — 401 + WWW-Authenticate.
+func bearerError(w http.ResponseWriter, code, desc string) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="`+code+`"`)
+	registrationError(w, http.StatusUnauthorized, code, desc)
+}
+
+// registrationError: RFC 7591 §3.2.2 에러 본문.
+func registrationError(w http.ResponseWriter, status int, code, desc string) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, map[string]string{"error": code, "error_description": desc})
+}
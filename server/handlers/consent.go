@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/policy"
+	"github.com/ftery0/ouath/server/scope"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// consentPageData: consent.html 템플릿 데이터. authRequest 는 hidden 필드로 왕복.
+type consentPageData struct {
+	authRequest
+	ClientName string
+	ClientURL  string
+	Scopes     []scope.Scope
+	CSRFToken  string
+	ErrorMsg   string
+}
+
+// consentGranted: userID 가 clientID 에 scope 전부를 이미 동의했는가.
+// store 오류는 "동의 없음" 으로 취급 — 다시 묻는 쪽이 안전.
+func consentGranted(ctx context.Context, userID, clientID, scope string) bool {
+	g, err := store.Consents.Get(ctx, userID, clientID)
+	if err != nil {
+		return false
+	}
+	return g.Covers(splitScope(scope))
+}
+
+// continueAfterLogin: 로그인 / 가입 직후 — 동의가 필요하면 동의 화면, 아니면 바로 code 발급.
+func continueAfterLogin(w http.ResponseWriter, r *http.Request, tmpl *template.Template, client *models.Client, req authRequest, userID, sessionID string, amr []string) {
+	if policy.NeedsConsent(policy.Inputs{
+		HasSession:     true,
+		Client:         client,
+		Prompt:         req.Prompt,
+		ConsentGranted: consentGranted(r.Context(), userID, client.ClientID, req.Scope),
+	}) {
+		renderConsent(w, tmpl, client, req, "")
+		return
+	}
+	issueCodeRedirect(w, r, tmpl, client, req, userID, sessionID, time.Now(), amr)
+}
+
+// renderConsent: 동의 화면 + 새 CSRF 토큰.
+// X-Frame-Options: 동의 버튼을 투명 iframe 으로 덮어 누르게 하는 clickjacking 차단.
+func renderConsent(w http.ResponseWriter, tmpl *template.Template, client *models.Client, req authRequest, errMsg string) {
+	csrfToken, err := NewCSRFToken(w)
+	if err != nil {
+		http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	// 설명은 scope 레지스트리에서. authorize 에서 이미 검증됐으므로 빠지는 항목은 없다.
+	scopes := make([]scope.Scope, 0)
+	for _, s := range splitScope(req.Scope) {
+		if desc, ok := scope.Lookup(s); ok {
+			scopes = append(scopes, desc)
+		}
+	}
+	w.Header().Set("X-Frame-Options", "DENY")
+	data := consentPageData{
+		authRequest: req,
+		ClientName:  client.Name,
+		ClientURL:   client.MainURL,
+		Scopes:      scopes,
+		CSRFToken:   csrfToken,
+		ErrorMsg:    errMsg,
+	}
+	if err := tmpl.ExecuteTemplate(w, "consent.html", data); err != nil {
+		http.Error(w, "템플릿 렌더링 실패", http.StatusInternalServerError)
+	}
+}
+
+// ConsentHandler: POST /oauth/consent — 동의 / 거부.
+//
+// 순서:
+//  0. CSRF 검증
+//  1. client_id / redirect_uri / scope 재검증 (요청 변조 방어)
+//  2. IdP 세션 확인 — 동의 주체는 폼이 아니라 세션의 사용자
+//  3. action=deny → access_denied 로 redirect
+//  4. 동의 기록 (기존 scope 에 합침) → auth code 발급
+func ConsentHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+
+		req := authRequestFromForm(r)
+		client, ok := verifyAuthRequest(w, r, tmpl, req)
+		if !ok {
+			return
+		}
+
+		sess, ok := currentIdPSession(r)
+		if !ok {
+			renderError(w, tmpl, "로그인 세션이 만료되었습니다. 앱에서 다시 시도하세요")
+			return
+		}
+		ClearCSRFToken(w)
+
+		if r.FormValue("action") != "approve" {
+			AuditEvent(r, "consent.denied", "sub", sess.UserID, "client_id", client.ClientID, "scope", req.Scope)
+			safeOAuthRedirect(w, r, req.RedirectURI, map[string]string{
+				"error": "access_denied",
+				"state": req.State,
+			})
+			return
+		}
+
+		if err := store.Consents.Grant(r.Context(), sess.UserID, client.ClientID, splitScope(req.Scope)); err != nil {
+			http.Error(w, "동의 저장 실패", http.StatusInternalServerError)
+			return
+		}
+		AuditEvent(r, "consent.granted", "sub", sess.UserID, "client_id", client.ClientID, "scope", req.Scope)
+		issueCodeRedirect(w, r, tmpl, client, req, sess.UserID, sess.SessionID, time.Now(), sess.AMR)
+	}
+}
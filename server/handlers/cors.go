@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// /oauth/token CORS — 브라우저에서 직접 code 를 교환하는 public client (SPA) 용.
+//
+// 허용 origin = public client 의 redirect_uris / main_url 의 origin (scheme://host[:port]).
+// 별도 목록을 두지 않는 이유: SPA 는 자기 origin 으로 redirect 를 받으므로 이미 등록된 값과 같다.
+// confidential client 의 origin 은 열지 않는다 — secret 을 브라우저에 둘 일이 없으므로.
+// 쿠키를 쓰지 않으니 Allow-Credentials 도 없다.
+//
+// POST 는 form 의 client_id 한 곳의 origin 만 본다. preflight 에는 client_id 가 없으므로
+// 모든 public client origin 을 publicOriginsTTL 동안 캐시한 집합으로 판단한다.
+
+// TokenPreflightHandler: OPTIONS /oauth/token — CORS preflight.
+func TokenPreflightHandler(w http.ResponseWriter, r *http.Request) {
+	if setTokenCORS(w, r) {
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Max-Age", "600")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setTokenCORS: Origin 이 public client 에 등록된 origin 이면 허용 헤더를 단다.
+// 에러 응답도 브라우저가 읽을 수 있도록 client 인증 전에 부른다.
+func setTokenCORS(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	allowed := false
+	if r.Method == http.MethodOptions {
+		allowed = publicClientOrigin(origin)
+	} else if c, ok := store.Clients.GetByClientID(r.FormValue("client_id")); ok {
+		allowed = c.IsPublic() && contains(clientOrigins(c), origin)
+	}
+	if !allowed {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	return true
+}
+
+// publicOriginsTTL: preflight 용 origin 집합 재사용 기간. 새 public client 의 preflight 는 최대 이만큼 늦게 열린다.
+const publicOriginsTTL = time.Minute
+
+var (
+	publicOrigins   map[string]bool
+	publicOriginsAt time.Time
+	publicOriginsMu sync.Mutex
+)
+
+// publicClientOrigin: 어느 public client 든 origin 을 등록했는가 (캐시된 집합).
+func publicClientOrigin(origin string) bool {
+	publicOriginsMu.Lock()
+	defer publicOriginsMu.Unlock()
+	if publicOrigins == nil || time.Since(publicOriginsAt) >= publicOriginsTTL {
+		set := make(map[string]bool)
+		for _, c := range store.Clients.All() {
+			if c.IsPublic() {
+				for _, o := range clientOrigins(c) {
+					set[o] = true
+				}
+			}
+		}
+		publicOrigins, publicOriginsAt = set, time.Now()
+	}
+	return publicOrigins[origin]
+}
+
+// clientOrigins: redirect_uris + main_url 의 origin (중복 제거).
+func clientOrigins(c *models.Client) []string {
+	out := make([]string, 0, len(c.RedirectURIs)+1)
+	for _, raw := range append([]string{c.MainURL}, c.RedirectURIs...) {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			continue
+		}
+		o := u.Scheme + "://" + u.Host
+		if !contains(out, o) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
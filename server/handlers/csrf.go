@@ -24,15 +24,20 @@ var ErrCSRFMismatch = errors.New("csrf token mismatch")
 //
 // /oauth/authorize 가 로그인 폼을 렌더링하기 직전에 호출.
 func NewCSRFToken(w http.ResponseWriter) (string, error) {
+	return newCSRFCookie(w, csrfCookieName, "/oauth")
+}
+
+// newCSRFCookie: Double-Submit 토큰을 name / path 쿠키로 굽는다 (어드민 로그인 폼도 같은 방식).
+func newCSRFCookie(w http.ResponseWriter, name, path string) (string, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
 	token := hex.EncodeToString(b)
 	http.SetCookie(w, &http.Cookie{
-		Name:     csrfCookieName,
+		Name:     name,
 		Value:    token,
-		Path:     "/oauth",
+		Path:     path,
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
 		Secure:   isProduction,
@@ -44,8 +49,13 @@ func NewCSRFToken(w http.ResponseWriter) (string, error) {
 // VerifyCSRFToken: /oauth/login POST 첫 단계에서 호출.
 // form value 와 cookie value 가 같지 않으면 거부.
 func VerifyCSRFToken(r *http.Request) error {
+	return verifyCSRFCookie(r, csrfCookieName)
+}
+
+// verifyCSRFCookie: form 의 csrf_token 과 name 쿠키 값 비교.
+func verifyCSRFCookie(r *http.Request, name string) error {
 	formToken := r.FormValue("csrf_token")
-	c, cookieErr := r.Cookie(csrfCookieName)
+	c, cookieErr := r.Cookie(name)
 	cookieVal := ""
 	if cookieErr == nil {
 		cookieVal = c.Value
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"html/template"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ftery0/ouath/server/config"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// Device Authorization Grant (RFC 8628) — CLI / TV 처럼 브라우저 입력이 어려운 기기용.
+//
+// 흐름:
+//  1. 기기 → POST /oauth/device_authorization : device_code + user_code 발급
+//  2. 사용자 → GET /oauth/device (다른 기기 브라우저) : user_code 입력 → client 확인
+//  3. 사용자 → POST /oauth/device : IdP 세션 (없으면 로그인 폼) + CSRF 로 승인/거부
+//  4. 기기 → POST /oauth/token (grant_type=device_code) polling : 승인되면 토큰
+const (
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	deviceCodeTTL       = 10 * time.Minute
+	devicePollInterval  = 5 * time.Second
+
+	// userCodeAlphabet: 모음 / 헷갈리는 문자 제외 20자 (RFC 8628 §6.1).
+	// 8자 → 20^8 ≈ 2.5e10. 10분 수명 + POST /oauth/device rate limit 이면 추측 불가.
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+// devicePageData: device.html 템플릿 데이터.
+//
+//	Step "enter"  : user_code 입력 폼
+//	Step "confirm": client / scope 확인 + 승인·거부 (세션 없으면 로그인 필드 포함)
+//	Step "done"   : 결과 안내 (Approved 로 분기)
+type devicePageData struct {
+	Step       string
+	UserCode   string // 표시용 (XXXX-XXXX)
+	ClientName string
+	Scope      string
+	CSRFToken  string
+	LoggedIn   bool
+	Username   string
+	Approved   bool
+	NeedOTP    bool // 로그인 필드에 2단계 인증 코드 칸
+	ErrorMsg   string
+}
+
+// DeviceAuthorizationHandler: POST /oauth/device_authorization (RFC 8628 §3.1~3.2).
+func DeviceAuthorizationHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := authenticateClient(w, r, "device")
+	if !ok {
+		return
+	}
+	// public client 의 device_code 는 /oauth/token 이 받지 않는다 — 여기서 먼저 막는다
+	if client.IsPublic() {
+		AuditWarn(r, "device.public_client_denied", "client_id", client.ClientID)
+		tokenError(w, "unauthorized_client", "public client 는 device flow 를 쓸 수 없습니다", http.StatusBadRequest)
+		return
+	}
+	if !client.AllowsGrant(deviceCodeGrantType) {
+		AuditWarn(r, "device.undeclared_grant_denied", "client_id", client.ClientID)
+		tokenError(w, "unauthorized_client", "device_code grant 를 선언하지 않은 client", http.StatusBadRequest)
+		return
+	}
+	// scope 검증은 authorize 와 같은 규칙 (레지스트리 + client 허용 목록)
+	scope, ok := allowedScope(r, "device", client, r.FormValue("scope"))
+	if !ok {
+		tokenError(w, "invalid_scope", "허용되지 않은 scope", http.StatusBadRequest)
+		return
+	}
+
+	deviceCode, err := generateCode()
+	if err != nil {
+		tokenError(w, "server_error", "device_code 생성 실패", http.StatusInternalServerError)
+		return
+	}
+
+	// user_code 충돌 (진행 중 요청과 동일) 은 극히 드물지만 몇 번 재시도.
+	var userCode string
+	now := time.Now()
+	for attempt := 0; ; attempt++ {
+		userCode, err = generateUserCode()
+		if err == nil {
+			err = store.DeviceCodes.Save(r.Context(), &models.DeviceCode{
+				DeviceCode: deviceCode,
+				UserCode:   userCode,
+				ClientID:   client.ClientID,
+				Scope:      scope,
+				Status:     models.DeviceCodePending,
+				Interval:   devicePollInterval,
+				ExpiresAt:  now.Add(deviceCodeTTL),
+				CreatedAt:  now,
+			})
+		}
+		if !errors.Is(err, store.ErrUserCodeConflict) || attempt >= 4 {
+			break
+		}
+	}
+	if err != nil {
+		tokenError(w, "server_error", "device 요청 저장 실패", http.StatusInternalServerError)
+		return
+	}
+
+	AuditEvent(r, "device.authorization_requested", "client_id", client.ClientID, "scope", scope)
+
+	verificationURI := config.IssuerForDiscovery() + "/oauth/device"
+	display := formatUserCode(userCode)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"device_code":               deviceCode,
+		"user_code":                 display,
+		"verification_uri":          verificationURI,
+		"verification_uri_complete": verificationURI + "?user_code=" + display,
+		"expires_in":                int(deviceCodeTTL / time.Second),
+		"interval":                  int(devicePollInterval / time.Second),
+	})
+}
+
+// DeviceVerifyGetHandler: GET /oauth/device[?user_code=...].
+// user_code 가 없거나 틀리면 입력 폼, 맞으면 확인 화면 (verification_uri_complete 로 바로 진입 가능).
+func DeviceVerifyGetHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw := r.URL.Query().Get("user_code")
+		if raw == "" {
+			renderDevice(w, tmpl, devicePageData{Step: "enter"})
+			return
+		}
+		dc, err := store.DeviceCodes.GetByUserCode(r.Context(), normalizeUserCode(raw))
+		if err != nil {
+			renderDevice(w, tmpl, devicePageData{
+				Step:     "enter",
+				UserCode: raw,
+				ErrorMsg: "코드가 올바르지 않거나 만료되었습니다",
+			})
+			return
+		}
+		renderDeviceConfirm(w, r, tmpl, dc, "", false)
+	}
+}
+
+// DeviceVerifyPostHandler: POST /oauth/device — 승인 / 거부.
+//
+// 순서:
+//  0. CSRF 검증 (Double-Submit Cookie — /oauth/login 과 같은 쿠키)
+//  1. user_code → pending 요청 조회
+//  2. action=deny → 거부 기록
+//  3. IdP 세션이 있으면 그 사용자로, 없으면 폼의 id/password 로 로그인 (+ 새 IdP 세션)
+//     TOTP 등록 사용자는 같은 폼의 otp (인증 앱 코드 / 복구 코드) 까지 맞아야 한다
+//  4. 승인 기록 → 기기의 다음 poll 에 토큰 발급
+func DeviceVerifyPostHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+
+		userCode := normalizeUserCode(r.FormValue("user_code"))
+		dc, err := store.DeviceCodes.GetByUserCode(r.Context(), userCode)
+		if err != nil {
+			renderDevice(w, tmpl, devicePageData{
+				Step:     "enter",
+				ErrorMsg: "코드가 올바르지 않거나 만료되었습니다",
+			})
+			return
+		}
+
+		if r.FormValue("action") == "deny" {
+			if err := store.DeviceCodes.Deny(r.Context(), userCode); err != nil {
+				renderDevice(w, tmpl, devicePageData{Step: "enter", ErrorMsg: "코드가 올바르지 않거나 만료되었습니다"})
+				return
+			}
+			ClearCSRFToken(w)
+			AuditEvent(r, "device.denied", "client_id", dc.ClientID)
+			renderDevice(w, tmpl, devicePageData{Step: "done", ClientName: deviceClientName(dc)})
+			return
+		}
+
+		var (
+			userID   string
+			authTime time.Time
+			amr      []string
+		)
+		if sess, ok := currentIdPSession(r); ok {
+			userID, authTime, amr = sess.UserID, sess.LoginAt, sess.AMR
+		} else {
+			// otp 칸을 한 번 보여준 뒤에는 비밀번호가 틀려도 계속 보여준다
+			needOTP := r.FormValue("otp_step") == "1"
+			user, err := authenticateUser(r, r.FormValue("id"), r.FormValue("password"))
+			if err != nil {
+				renderDeviceConfirm(w, r, tmpl, dc, loginFailureMessage(err, "아이디 또는 비밀번호가 틀렸습니다"), needOTP)
+				return
+			}
+			amr = amrPassword
+			audit := []any{"sub", user.ID, "client_id", dc.ClientID, "username", user.Username}
+			if user.TOTPEnabled() {
+				method, ok := verifySecondFactor(r.Context(), user, r.FormValue("otp"))
+				if !ok {
+					msg := "인증 앱의 6자리 코드를 입력하세요"
+					if r.FormValue("otp") != "" {
+						AuditWarn(r, "login.mfa_failed", "sub", user.ID, "client_id", dc.ClientID)
+						msg = loginFailureMessage(recordLoginFailure(r, user.Username), "인증 코드가 올바르지 않습니다")
+					}
+					renderDeviceConfirm(w, r, tmpl, dc, msg, true)
+					return
+				}
+				amr = amrPasswordOTP
+				audit = append(audit, "second_factor", method)
+			}
+			if _, err := startIdPSession(w, r, user.ID, amr); err != nil {
+				http.Error(w, "세션 생성 실패", http.StatusInternalServerError)
+				return
+			}
+			clearLoginFailures(r.Context(), user.Username)
+			AuditEvent(r, "login.success", audit...)
+			userID, authTime = user.ID, time.Now()
+		}
+
+		// authorize 와 같은 규칙 — 인증된 이메일을 요구하는 client 는 승인 전에 막는다
+		if client, ok := store.Clients.GetByClientID(dc.ClientID); ok && client.RequireVerifiedEmail {
+			user, err := store.Users.GetByID(r.Context(), userID)
+			if err != nil {
+				http.Error(w, "사용자 정보를 찾을 수 없습니다", http.StatusUnauthorized)
+				return
+			}
+			if !user.EmailVerified {
+				AuditWarn(r, "device.email_unverified", "sub", userID, "client_id", dc.ClientID)
+				renderEmailRequired(w, tmpl, user, client.Name, "/oauth/device?user_code="+formatUserCode(dc.UserCode))
+				return
+			}
+		}
+
+		if err := store.DeviceCodes.Approve(r.Context(), userCode, userID, authTime, amr); err != nil {
+			renderDevice(w, tmpl, devicePageData{Step: "enter", ErrorMsg: "코드가 올바르지 않거나 만료되었습니다"})
+			return
+		}
+		ClearCSRFToken(w)
+		AuditEvent(r, "device.approved", "sub", userID, "client_id", dc.ClientID, "scope", dc.Scope)
+		renderDevice(w, tmpl, devicePageData{Step: "done", Approved: true, ClientName: deviceClientName(dc)})
+	}
+}
+
+// handleDeviceCode: grant_type=urn:ietf:params:oauth:grant-type:device_code (RFC 8628 §3.4~3.5).
+func handleDeviceCode(w http.ResponseWriter, r *http.Request, clientID string) {
+	deviceCode := r.FormValue("device_code")
+	if deviceCode == "" {
+		tokenError(w, "invalid_request", "device_code 가 필요합니다", http.StatusBadRequest)
+		return
+	}
+
+	dc, err := store.DeviceCodes.Poll(r.Context(), deviceCode, clientID)
+	switch {
+	case errors.Is(err, store.ErrDeviceAuthorizationPending):
+		tokenError(w, "authorization_pending", "사용자 승인 대기 중", http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrDeviceSlowDown):
+		tokenError(w, "slow_down", "polling 간격을 5초 늘리세요", http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrDeviceAccessDenied):
+		tokenError(w, "access_denied", "사용자가 거부했습니다", http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrDeviceCodeExpired):
+		tokenError(w, "expired_token", "device_code 만료", http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrDeviceCodeNotFound):
+		tokenError(w, "invalid_grant", "유효하지 않은 device_code", http.StatusBadRequest)
+		return
+	case err != nil:
+		tokenError(w, "server_error", "device_code 조회 실패", http.StatusInternalServerError)
+		return
+	}
+
+	// 승인 뒤 비활성화 / 삭제된 사용자에게는 발급하지 않는다
+	gone, err := userUnavailable(r.Context(), dc.UserID)
+	if err != nil {
+		tokenError(w, "server_error", "사용자 조회 실패", http.StatusInternalServerError)
+		return
+	}
+	if gone {
+		AuditWarn(r, "token.device_denied", "sub", dc.UserID, "client_id", clientID, "reason", "user_unavailable")
+		tokenError(w, "invalid_grant", "유효하지 않은 device_code", http.StatusBadRequest)
+		return
+	}
+
+	familyID, err := generateFamilyID()
+	if err != nil {
+		tokenError(w, "server_error", "family 생성 실패", http.StatusInternalServerError)
+		return
+	}
+
+	auditTokenIssued(r, "device_code", dc.UserID, dc.ClientID, dc.Scope)
+	issueTokens(w, r, tokenGrant{
+		UserID:   dc.UserID,
+		ClientID: dc.ClientID,
+		Scope:    dc.Scope,
+		FamilyID: familyID,
+		AuthTime: dc.AuthTime,
+		AMR:      dc.AMR,
+	})
+}
+
+// renderDeviceConfirm: 확인 화면 + 새 CSRF 토큰. IdP 세션이 있으면 로그인 필드 없이 승인만.
+// needOTP: 로그인 필드에 2단계 인증 코드 칸 추가 (TOTP 등록 사용자가 비밀번호를 통과한 뒤).
+func renderDeviceConfirm(w http.ResponseWriter, r *http.Request, tmpl *template.Template, dc *models.DeviceCode, errMsg string, needOTP bool) {
+	csrfToken, err := NewCSRFToken(w)
+	if err != nil {
+		http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	data := devicePageData{
+		Step:       "confirm",
+		UserCode:   formatUserCode(dc.UserCode),
+		ClientName: deviceClientName(dc),
+		Scope:      dc.Scope,
+		CSRFToken:  csrfToken,
+		ErrorMsg:   errMsg,
+		NeedOTP:    needOTP,
+	}
+	if sess, ok := currentIdPSession(r); ok {
+		data.LoggedIn = true
+		if u, err := store.Users.GetByID(r.Context(), sess.UserID); err == nil {
+			data.Username = u.Username
+		}
+	}
+	renderDevice(w, tmpl, data)
+}
+
+func renderDevice(w http.ResponseWriter, tmpl *template.Template, data devicePageData) {
+	if err := tmpl.ExecuteTemplate(w, "device.html", data); err != nil {
+		http.Error(w, "템플릿 렌더링 실패", http.StatusInternalServerError)
+	}
+}
+
+// currentIdPSession: 쿠키 → 유효한 IdP 세션.
+func currentIdPSession(r *http.Request) (*models.IdPSession, bool) {
+	sid, ok := GetIdPSessionID(r)
+	if !ok {
+		return nil, false
+	}
+	return store.IdPSessions.Get(sid)
+}
+
+func deviceClientName(dc *models.DeviceCode) string {
+	if c, ok := store.Clients.GetByClientID(dc.ClientID); ok {
+		return c.Name
+	}
+	return dc.ClientID
+}
+
+// generateUserCode: userCodeAlphabet 에서 균등 추출 (crypto/rand.Int — modulo bias 없음).
+func generateUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	b := make([]byte, userCodeLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// normalizeUserCode: 대소문자 / 하이픈 / 공백 무시 — "wdjb-mjht" == "WDJBMJHT".
+func normalizeUserCode(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(s) {
+		if strings.ContainsRune(userCodeAlphabet, c) {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// formatUserCode: 표시용 XXXX-XXXX.
+func formatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
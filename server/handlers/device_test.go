@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// device.html 대체 — 확인 화면의 csrf / user_code 와 결과 단계만 노출.
+const testDeviceTpl = `step={{.Step}} approved={{.Approved}}
+<input name="csrf_token" value="{{.CSRFToken}}">
+<input name="user_code" value="{{.UserCode}}">`
+
+// 기기 코드 발급 → pending → slow_down → 브라우저에서 로그인 + 승인 → 토큰 발급 → 재사용 불가.
+func TestDevice_FullFlow(t *testing.T) {
+	IdPCookieInit("integration-test-secret-32bytes!!")
+	tmpl := template.Must(template.New("device.html").Parse(testDeviceTpl))
+	srv := newTokenTestServer(t, func(mux *http.ServeMux) {
+		mux.HandleFunc("POST /oauth/device_authorization", DeviceAuthorizationHandler)
+		mux.HandleFunc("GET /oauth/device", DeviceVerifyGetHandler(tmpl))
+		mux.HandleFunc("POST /oauth/device", DeviceVerifyPostHandler(tmpl))
+	})
+	defer srv.Close()
+
+	// 1) 기기: 코드 발급
+	req, _ := http.NewRequest("POST", srv.URL+"/oauth/device_authorization", strings.NewReader("scope=openid"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("app1", "app1-secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var da struct {
+		DeviceCode string `json:"device_code"`
+		UserCode   string `json:"user_code"`
+		Interval   int    `json:"interval"`
+	}
+	json.NewDecoder(resp.Body).Decode(&da)
+	resp.Body.Close()
+	if da.DeviceCode == "" || len(da.UserCode) != 9 || da.Interval != 5 {
+		t.Fatalf("device_authorization 응답 비정상: %+v", da)
+	}
+
+	poll := url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {da.DeviceCode}}
+
+	// 2) 승인 전 polling: pending → 곧바로 다시 → slow_down
+	if _, tr := postToken(t, srv, poll); tr.Error != "authorization_pending" {
+		t.Fatalf("got %q, want authorization_pending", tr.Error)
+	}
+	if _, tr := postToken(t, srv, poll); tr.Error != "slow_down" {
+		t.Fatalf("got %q, want slow_down", tr.Error)
+	}
+
+	// 3) 브라우저: verification_uri_complete (소문자 입력도 허용) → 확인 화면 → 로그인 + 승인
+	browser := newTestClient(t)
+	resp, err = browser.Get(srv.URL + "/oauth/device?user_code=" + strings.ToLower(da.UserCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "step=confirm") {
+		t.Fatalf("확인 화면이 아님:\n%s", body)
+	}
+	csrf := extractCSRF(t, string(body))
+
+	resp, err = browser.PostForm(srv.URL+"/oauth/device", url.Values{
+		"user_code":  {da.UserCode},
+		"csrf_token": {csrf},
+		"action":     {"approve"},
+		"id":         {"alice"},
+		"password":   {"password123"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "step=done approved=true") {
+		t.Fatalf("승인 실패:\n%s", body)
+	}
+
+	// 4) 승인 후 첫 poll → 토큰, 두 번째 → invalid_grant (1회 소비)
+	status, tr := postToken(t, srv, poll)
+	if status != http.StatusOK || tr.AccessToken == "" || tr.RefreshToken == "" {
+		t.Fatalf("토큰 발급 실패: status=%d err=%s", status, tr.Error)
+	}
+	if _, tr := postToken(t, srv, poll); tr.Error != "invalid_grant" {
+		t.Fatalf("소비된 device_code 재사용: got %q", tr.Error)
+	}
+}
+
+// CSRF 없이 승인 POST → 403.
+func TestDevice_ApproveWithoutCSRF_Blocked(t *testing.T) {
+	tmpl := template.Must(template.New("device.html").Parse(testDeviceTpl))
+	srv := newTokenTestServer(t, func(mux *http.ServeMux) {
+		mux.HandleFunc("POST /oauth/device", DeviceVerifyPostHandler(tmpl))
+	})
+	defer srv.Close()
+
+	resp, err := http.PostForm(srv.URL+"/oauth/device", url.Values{"user_code": {"BCDF-GHJK"}, "action": {"approve"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("got %d, want 403", resp.StatusCode)
+	}
+}
+
+// 인증된 이메일을 요구하는 client: 미인증 사용자는 승인 대신 이메일 안내, device_code 는 pending 그대로.
+func TestDevice_RequireVerifiedEmail(t *testing.T) {
+	IdPCookieInit("integration-test-secret-32bytes!!")
+	tmpl := template.Must(template.New("device.html").Parse(testDeviceTpl))
+	template.Must(tmpl.New("email.html").Parse(testEmailTpl))
+	srv := newTokenTestServer(t, func(mux *http.ServeMux) {
+		mux.HandleFunc("GET /oauth/device", DeviceVerifyGetHandler(tmpl))
+		mux.HandleFunc("POST /oauth/device", DeviceVerifyPostHandler(tmpl))
+	})
+	defer srv.Close()
+	ctx := context.Background()
+
+	erin := &models.User{Username: "erin", PasswordHash: models.TestUsers["alice"].PasswordHash, DisplayName: "Erin", Email: "erin@example.com"}
+	if err := store.Users.Create(ctx, erin); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Clients.Register(&models.Client{
+		ClientID:             "verified-device",
+		Name:                 "Verified Device",
+		AllowedScopes:        []string{"openid"},
+		RequireVerifiedEmail: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Clients.Delete("verified-device") })
+	if err := store.DeviceCodes.Save(ctx, &models.DeviceCode{
+		DeviceCode: "verified-device-code",
+		UserCode:   "BCDFGHJK",
+		ClientID:   "verified-device",
+		Scope:      "openid",
+		Status:     models.DeviceCodePending,
+		Interval:   devicePollInterval,
+		ExpiresAt:  time.Now().Add(time.Minute),
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	browser := newTestClient(t)
+	resp, err := browser.Get(srv.URL + "/oauth/device?user_code=BCDF-GHJK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp, err = browser.PostForm(srv.URL+"/oauth/device", url.Values{
+		"user_code":  {"BCDF-GHJK"},
+		"csrf_token": {extractCSRF(t, string(body))},
+		"action":     {"approve"},
+		"id":         {"erin"},
+		"password":   {"password123"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "step=required") {
+		t.Fatalf("미인증 사용자 승인: %s", body)
+	}
+	if _, err := store.DeviceCodes.Poll(ctx, "verified-device-code", "verified-device"); !errors.Is(err, store.ErrDeviceAuthorizationPending) {
+		t.Errorf("미인증 사용자 승인 후 poll: err=%v, want authorization_pending", err)
+	}
+}
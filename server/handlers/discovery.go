@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/ftery0/ouath/server/config"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/scope"
 )
 
 // DiscoveryHandler: GET /.well-known/openid-configuration (OIDC Discovery 1.0).
@@ -13,28 +15,40 @@ func DiscoveryHandler(w http.ResponseWriter, r *http.Request) {
 	issuer := config.IssuerForDiscovery()
 
 	resp := map[string]any{
-		"issuer":                                issuer,
-		"authorization_endpoint":                issuer + "/oauth/authorize",
-		"token_endpoint":                        issuer + "/oauth/token",
-		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
-		"jwks_uri":                              issuer + "/oauth/jwks",
-		"registration_endpoint":                 issuer + "/oauth/register",
-		"revocation_endpoint":                   issuer + "/oauth/revoke",
-		"introspection_endpoint":                issuer + "/oauth/introspect",
-		"end_session_endpoint":                  issuer + "/oauth/logout",
-		"scopes_supported":                      []string{"openid", "profile", "email"},
-		"response_types_supported":              []string{"code"},
-		"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
-		"subject_types_supported":               []string{"public"},
-		"id_token_signing_alg_values_supported": []string{"RS256"},
-		"token_endpoint_auth_methods_supported": []string{"client_secret_basic"},
-		"code_challenge_methods_supported":      []string{"S256"},
+		"issuer":                                           issuer,
+		"authorization_endpoint":                           issuer + "/oauth/authorize",
+		"token_endpoint":                                   issuer + "/oauth/token",
+		"userinfo_endpoint":                                issuer + "/oauth/userinfo",
+		"jwks_uri":                                         issuer + "/oauth/jwks",
+		"revocation_endpoint":                              issuer + "/oauth/revoke",
+		"introspection_endpoint":                           issuer + "/oauth/introspect",
+		"device_authorization_endpoint":                    issuer + "/oauth/device_authorization",
+		"end_session_endpoint":                             issuer + "/oauth/logout",
+		"scopes_supported":                                 scope.Supported(),
+		"response_types_supported":                         []string{"code"},
+		"grant_types_supported":                            []string{"authorization_code", "refresh_token", "client_credentials", deviceCodeGrantType},
+		"subject_types_supported":                          []string{"public"},
+		"id_token_signing_alg_values_supported":            []string{"RS256"},
+		"token_endpoint_auth_methods_supported":            append(clientAuthMethods(), models.AuthMethodNone),
+		"token_endpoint_auth_signing_alg_values_supported": clientAuthSigningAlgs(),
+		"revocation_endpoint_auth_methods_supported":       append(clientAuthMethods(), models.AuthMethodNone),
+		"introspection_endpoint_auth_methods_supported":    clientAuthMethods(),
+		"code_challenge_methods_supported":                 []string{"S256"},
+		"backchannel_logout_supported":                     true,
+		"backchannel_logout_session_supported":             true,
+		"frontchannel_logout_supported":                    true,
+		"frontchannel_logout_session_supported":            true,
 		"claims_supported": []string{
-			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce",
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "amr", "sid",
 			"name", "preferred_username", "email", "email_verified",
 		},
 	}
 
+	// 동적 등록은 initial access token 을 설정했을 때만 (client_registration.go)
+	if dcrEnabled() {
+		resp["registration_endpoint"] = issuer + clientRegistrationPath
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "public, max-age=3600")
 	json.NewEncoder(w).Encode(resp)
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ftery0/ouath/server/config"
+	"github.com/ftery0/ouath/server/mail"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// 이메일 인증 — users.email_verified 를 켜는 유일한 경로.
+//
+//	가입 / 이메일 변경 직후   인증 메일 발송 (goroutine)
+//	GET  /oauth/email/verify  ?token= 서명 · 만료 · 현재 이메일 일치 확인 → email_verified=true
+//	GET  /oauth/email         (세션) 이메일 / 인증 상태
+//	POST /oauth/email         action=change: 이메일 교체 (미인증으로 돌아감) + 인증 메일
+//	                          action=resend: 인증 메일 다시 보내기
+//
+// 링크 token 은 IdP 쿠키와 같은 SecureCookie 로 서명·암호화한 {사용자, 이메일, 만료} — 서버에 저장하지 않는다.
+// 이메일을 payload 에 넣으므로 이메일을 바꾸면 옛 링크는 저절로 무효 (MarkEmailVerified 가 현재 값과 비교).
+
+const (
+	emailVerifyTokenName = "email_verify" // SecureCookie name — 다른 용도의 값과 MAC 이 섞이지 않게
+	emailVerifyTTL       = 24 * time.Hour
+)
+
+// emailVerifyClaims: 인증 링크 token 내용.
+type emailVerifyClaims struct {
+	UserID  string
+	Email   string
+	Expires int64
+}
+
+// emailPageData: email.html 템플릿 데이터.
+//
+//	Step: manage (상태 + 변경 / 재발송) · required (client 가 인증 요구) · verified · invalid (만료 / 옛 링크)
+type emailPageData struct {
+	Step          string
+	Username      string
+	Email         string
+	EmailVerified bool
+	ClientName    string // required
+	ContinueURL   string // required: 인증 후 다시 시도할 /oauth/authorize (또는 /oauth/device)
+	CSRFToken     string
+	ErrorMsg      string
+	Notice        string
+}
+
+// sendEmailVerification: 인증 링크 메일 발송. 요청 처리와 분리된 goroutine 에서 실행 — 실패는 로그로만.
+func sendEmailVerification(user *models.User) {
+	if user.Email == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+	defer cancel()
+
+	tok, err := secureCookie.Encode(emailVerifyTokenName, emailVerifyClaims{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Expires: time.Now().Add(emailVerifyTTL).Unix(),
+	})
+	if err != nil {
+		log.Printf("[email_verify] token 생성 실패: %v", err)
+		return
+	}
+	link := config.IssuerForDiscovery() + "/oauth/email/verify?token=" + url.QueryEscape(tok)
+	body := fmt.Sprintf(`%s 님,
+
+아래 링크를 열어 이메일 주소 (%s) 인증을 마치세요.
+링크는 %d시간 동안 유효합니다.
+
+%s
+
+직접 요청하지 않았다면 이 메일을 무시하세요.
+`, user.Username, user.Email, int(emailVerifyTTL/time.Hour), link)
+
+	if err := mailer.Send(ctx, mail.Message{To: user.Email, Subject: "이메일 인증 안내", Body: body}); err != nil {
+		log.Printf("[email_verify] 메일 발송 실패 sub=%s: %v", user.ID, err)
+	}
+}
+
+// EmailVerifyHandler: GET /oauth/email/verify?token= — 세션 없이도 (다른 기기의 메일 앱에서 열어도) 동작.
+// 같은 링크를 다시 열어도 결과는 같다 (멱등).
+func EmailVerifyHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var claims emailVerifyClaims
+		if err := secureCookie.Decode(emailVerifyTokenName, r.URL.Query().Get("token"), &claims); err != nil ||
+			time.Now().Unix() > claims.Expires {
+			renderEmail(w, tmpl, emailPageData{Step: "invalid"})
+			return
+		}
+		if err := store.Users.MarkEmailVerified(r.Context(), claims.UserID, claims.Email); err != nil {
+			if !errors.Is(err, store.ErrUserNotFound) {
+				http.Error(w, "이메일 인증 처리 실패", http.StatusInternalServerError)
+				return
+			}
+			AuditWarn(r, "email.verify_failed", "sub", claims.UserID, "reason", "email_changed")
+			renderEmail(w, tmpl, emailPageData{Step: "invalid"})
+			return
+		}
+		AuditEvent(r, "email.verified", "sub", claims.UserID)
+		renderEmail(w, tmpl, emailPageData{Step: "verified", Email: claims.Email, EmailVerified: true})
+	}
+}
+
+// EmailGetHandler: GET /oauth/email — 현재 이메일 / 인증 상태.
+func EmailGetHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := sessionUser(w, r, tmpl)
+		if !ok {
+			return
+		}
+		renderEmailManage(w, tmpl, user, "", "")
+	}
+}
+
+// EmailPostHandler: POST /oauth/email — action=change / action=resend. 둘 다 CSRF + IdP 세션 필수.
+func EmailPostHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		user, ok := sessionUser(w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		switch r.FormValue("action") {
+		case "change":
+			email := strings.ToLower(strings.TrimSpace(r.FormValue("email")))
+			if !emailPattern.MatchString(email) {
+				renderEmailManage(w, tmpl, user, "이메일 형식이 올바르지 않습니다", "")
+				return
+			}
+			if email == user.Email {
+				renderEmailManage(w, tmpl, user, "지금 쓰는 이메일과 같습니다", "")
+				return
+			}
+			if err := store.Users.UpdateEmail(r.Context(), user.ID, email); err != nil {
+				if errors.Is(err, store.ErrEmailAlreadyUsed) {
+					renderEmailManage(w, tmpl, user, "다른 계정에서 사용 중인 이메일입니다", "")
+					return
+				}
+				http.Error(w, "이메일 변경 실패", http.StatusInternalServerError)
+				return
+			}
+			AuditWarn(r, "email.changed", "sub", user.ID)
+			user.Email, user.EmailVerified = email, false
+			go sendEmailVerification(user)
+			renderEmailManage(w, tmpl, user, "", "이메일을 변경했습니다. 새 주소로 보낸 링크를 열어 인증을 마치세요")
+
+		case "resend":
+			if user.Email == "" || user.EmailVerified {
+				renderEmailManage(w, tmpl, user, "", "")
+				return
+			}
+			go sendEmailVerification(user)
+			AuditEvent(r, "email.verification_sent", "sub", user.ID)
+			renderEmailManage(w, tmpl, user, "", "인증 메일을 다시 보냈습니다")
+
+		default:
+			http.Error(w, "알 수 없는 action", http.StatusBadRequest)
+		}
+	}
+}
+
+// requireVerifiedEmail: client 가 인증된 이메일을 요구하는데 사용자가 아직이면 응답까지 쓰고 false.
+// prompt=none 은 화면을 띄울 수 없으므로 redirect_uri 로 access_denied.
+func requireVerifiedEmail(w http.ResponseWriter, r *http.Request, tmpl *template.Template, client *models.Client, req authRequest, userID string) bool {
+	if !client.RequireVerifiedEmail {
+		return true
+	}
+	user, err := store.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "사용자 정보를 찾을 수 없습니다", http.StatusUnauthorized)
+		return false
+	}
+	if user.EmailVerified {
+		return true
+	}
+	AuditWarn(r, "authorize.email_unverified", "sub", userID, "client_id", client.ClientID)
+	if req.Prompt == "none" {
+		safeOAuthRedirect(w, r, req.RedirectURI, map[string]string{
+			"error":             "access_denied",
+			"error_description": "verified email required",
+			"state":             req.State,
+		})
+		return false
+	}
+	renderEmailRequired(w, tmpl, user, client.Name, authorizeURLFor(req))
+	return false
+}
+
+// renderEmailRequired: 이메일 인증 안내 + 재발송 폼. continueURL 은 인증 후 다시 시도할 곳.
+func renderEmailRequired(w http.ResponseWriter, tmpl *template.Template, user *models.User, clientName, continueURL string) {
+	csrfToken, err := NewCSRFToken(w)
+	if err != nil {
+		http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	renderEmail(w, tmpl, emailPageData{
+		Step:          "required",
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: false,
+		ClientName:    clientName,
+		ContinueURL:   continueURL,
+		CSRFToken:     csrfToken,
+	})
+}
+
+// authorizeURLFor: 같은 요청으로 /oauth/authorize 를 다시 시작하는 상대 URL (인증 후 "계속" 버튼).
+func authorizeURLFor(req authRequest) string {
+	q := url.Values{"response_type": {"code"}, "client_id": {req.ClientID}, "redirect_uri": {req.RedirectURI}}
+	for k, v := range map[string]string{
+		"state":                 req.State,
+		"scope":                 req.Scope,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+		"nonce":                 req.Nonce,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	return "/oauth/authorize?" + q.Encode()
+}
+
+// renderEmailManage: manage 단계 — 새 CSRF 토큰과 함께.
+func renderEmailManage(w http.ResponseWriter, tmpl *template.Template, user *models.User, errMsg, notice string) {
+	csrfToken, err := NewCSRFToken(w)
+	if err != nil {
+		http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	renderEmail(w, tmpl, emailPageData{
+		Step:          "manage",
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		CSRFToken:     csrfToken,
+		ErrorMsg:      errMsg,
+		Notice:        notice,
+	})
+}
+
+// renderEmail: 링크 token 이 URL 에 실리는 페이지 — Referer 로 새지 않게 + 캐시 금지 + clickjacking 차단.
+func renderEmail(w http.ResponseWriter, tmpl *template.Template, data emailPageData) {
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	if err := tmpl.ExecuteTemplate(w, "email.html", data); err != nil {
+		http.Error(w, "템플릿 렌더링 실패", http.StatusInternalServerError)
+	}
+}
@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"html/template"
 	"io"
 	"net/http"
@@ -10,6 +14,17 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ftery0/ouath/server/mail"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+	"github.com/ftery0/ouath/server/token"
+	"github.com/ftery0/ouath/server/totp"
+	"github.com/ftery0/ouath/server/webauthn"
+	"github.com/ftery0/ouath/server/webauthn/webauthntest"
 )
 
 // 통합 테스트는 AuthorizeHandler + LoginHandler 의 협력 흐름을 검증한다.
@@ -30,6 +45,48 @@ const testLoginTpl = `<form method="POST" action="/oauth/login">
 
 const testErrorTpl = `<div class="error">{{.ErrorMsg}}</div>`
 
+const testConsentTpl = `<form method="POST" action="/oauth/consent">
+consent-for={{.ClientName}}
+<input name="csrf_token" value="{{.CSRFToken}}">
+<input name="client_id" value="{{.ClientID}}">
+<input name="redirect_uri" value="{{.RedirectURI}}">
+<input name="state" value="{{.State}}">
+<input name="scope" value="{{.Scope}}">
+</form>`
+
+const testMFATpl = `<form method="POST" action="/oauth/login/mfa">
+mfa-for={{.ClientName}}
+<input name="csrf_token" value="{{.CSRFToken}}">
+<input name="client_id" value="{{.ClientID}}">
+<input name="redirect_uri" value="{{.RedirectURI}}">
+<input name="state" value="{{.State}}">
+<input name="scope" value="{{.Scope}}">
+{{if .ErrorMsg}}<div class="error">{{.ErrorMsg}}</div>{{end}}
+</form>`
+
+const testPasskeysTpl = `passkeys={{len .Passkeys}}
+<input name="csrf_token" value="{{.CSRFToken}}">
+{{if .ErrorMsg}}<div class="error">{{.ErrorMsg}}</div>{{end}}`
+
+const testPasswordResetTpl = `step={{.Step}}
+<input name="csrf_token" value="{{.CSRFToken}}">
+{{if .ErrorMsg}}<div class="error">{{.ErrorMsg}}</div>{{end}}`
+
+const testEmailTpl = `step={{.Step}} verified={{.EmailVerified}}
+<input name="csrf_token" value="{{.CSRFToken}}">
+{{if .ErrorMsg}}<div class="error">{{.ErrorMsg}}</div>{{end}}`
+
+const testAccountTpl = `{{range .Sessions}}session={{.SID}} current={{.Current}} device={{.Device}}
+{{end}}{{range .Apps}}app={{.ClientID}} tokens={{.Tokens}}
+{{end}}<input name="csrf_token" value="{{.CSRFToken}}">
+{{if .ErrorMsg}}<div class="error">{{.ErrorMsg}}</div>{{end}}`
+
+const testTwoFactorTpl = `step={{.Step}} error={{.ErrorMsg}}
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">`
+
+const testLogoutTpl = `{{range .Frames}}frame={{.}}
+{{end}}next={{.RedirectURI}}`
+
 // ───── 헬퍼 ─────
 
 func newTestServer(t *testing.T) *httptest.Server {
@@ -40,10 +97,37 @@ func newTestServer(t *testing.T) *httptest.Server {
 	tmpl := template.New("")
 	template.Must(tmpl.New("login.html").Parse(testLoginTpl))
 	template.Must(tmpl.New("error.html").Parse(testErrorTpl))
+	template.Must(tmpl.New("consent.html").Parse(testConsentTpl))
+	template.Must(tmpl.New("mfa.html").Parse(testMFATpl))
+	template.Must(tmpl.New("passkeys.html").Parse(testPasskeysTpl))
+	template.Must(tmpl.New("password_reset.html").Parse(testPasswordResetTpl))
+	template.Must(tmpl.New("email.html").Parse(testEmailTpl))
+	template.Must(tmpl.New("logout.html").Parse(testLogoutTpl))
+	template.Must(tmpl.New("account.html").Parse(testAccountTpl))
+	template.Must(tmpl.New("two_factor.html").Parse(testTwoFactorTpl))
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /oauth/authorize", AuthorizeHandler(tmpl))
 	mux.HandleFunc("POST /oauth/login", LoginHandler(tmpl))
+	mux.HandleFunc("POST /oauth/consent", ConsentHandler(tmpl))
+	mux.HandleFunc("POST /oauth/login/mfa", MFAHandler(tmpl))
+	mux.HandleFunc("POST /oauth/passkey/login/options", PasskeyLoginOptionsHandler)
+	mux.HandleFunc("POST /oauth/passkey/login", PasskeyLoginHandler(tmpl))
+	mux.HandleFunc("GET /oauth/passkeys", PasskeysGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/passkeys/options", PasskeyRegisterOptionsHandler)
+	mux.HandleFunc("POST /oauth/passkeys", PasskeysPostHandler(tmpl))
+	mux.HandleFunc("GET /oauth/email/verify", EmailVerifyHandler(tmpl))
+	mux.HandleFunc("GET /oauth/email", EmailGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/email", EmailPostHandler(tmpl))
+	mux.HandleFunc("GET /oauth/password/forgot", PasswordForgotGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/password/forgot", PasswordForgotPostHandler(tmpl))
+	mux.HandleFunc("GET /oauth/password/reset", PasswordResetGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/password/reset", PasswordResetPostHandler(tmpl))
+	mux.HandleFunc("GET /oauth/logout", LogoutHandler(tmpl))
+	mux.HandleFunc("GET /oauth/account", AccountGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/account", AccountPostHandler(tmpl))
+	mux.HandleFunc("GET /oauth/2fa", TwoFactorGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/2fa", TwoFactorPostHandler(tmpl))
 	return httptest.NewServer(mux)
 }
 
@@ -262,3 +346,1570 @@ func TestIntegration_OpenRedirect_StateEscaped(t *testing.T) {
 		t.Errorf("iss 인젝션됨: %s", qq.Get("iss"))
 	}
 }
+
+// third-party client: 로그인 후 동의 화면 → 허용 → code. 이후 같은 scope 는 묻지 않고 silent.
+func TestIntegration_ThirdPartyConsent_Remembered(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	client := newTestClient(t)
+	loginViaForm(t, srv, client) // first-party app1 로 IdP 세션 확보
+
+	if err := store.Clients.Register(&models.Client{
+		ClientID:      "consent-3p",
+		Name:          "Third Party",
+		RedirectURIs:  []string{"http://localhost:9999/callback"},
+		SilentSSO:     true,
+		AllowedScopes: []string{"openid", "profile", "email"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	authz := authorizeURL(srv.URL, url.Values{
+		"client_id":    {"consent-3p"},
+		"redirect_uri": {"http://localhost:9999/callback"},
+		"scope":        {"openid email"},
+	})
+
+	// 1) 세션 있음 + 동의 없음 → 동의 화면 (code 없음)
+	resp, err := client.Get(authz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "consent-for=Third Party") {
+		t.Fatalf("동의 화면이 아님: status=%d\n%s", resp.StatusCode, body)
+	}
+
+	// 2) 허용 → 302 + code
+	resp, err = client.PostForm(srv.URL+"/oauth/consent", url.Values{
+		"csrf_token":   {extractCSRF(t, string(body))},
+		"client_id":    {"consent-3p"},
+		"redirect_uri": {"http://localhost:9999/callback"},
+		"state":        {"t"},
+		"scope":        {"openid email"},
+		"action":       {"approve"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if loc := resp.Header.Get("Location"); resp.StatusCode != http.StatusFound || !strings.Contains(loc, "code=") {
+		t.Fatalf("동의 후 code 없음: status=%d loc=%s", resp.StatusCode, loc)
+	}
+
+	// 3) 같은 scope 재요청 → 기억된 동의로 silent
+	resp, err = client.Get(authz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if loc := resp.Header.Get("Location"); resp.StatusCode != http.StatusFound || !strings.Contains(loc, "code=") {
+		t.Fatalf("기억된 동의가 적용 안 됨: status=%d", resp.StatusCode)
+	}
+
+	// 4) 새 scope 추가 + prompt=none → consent_required
+	resp, err = client.Get(authorizeURL(srv.URL, url.Values{
+		"client_id":    {"consent-3p"},
+		"redirect_uri": {"http://localhost:9999/callback"},
+		"scope":        {"openid email profile"},
+		"prompt":       {"none"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "error=consent_required") {
+		t.Fatalf("consent_required 아님: %s", loc)
+	}
+}
+
+// scope 검증: 레지스트리 밖 / client 허용 목록 밖 scope 는 로그인 폼 전에 invalid_scope 로 redirect.
+func TestIntegration_InvalidScope_Redirects(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	c, _ := store.Clients.GetByClientID("app1")
+	orig := c.AllowedScopes
+	c.AllowedScopes = []string{"openid"}
+	t.Cleanup(func() { c.AllowedScopes = orig })
+
+	for _, scope := range []string{"openid admin", "openid email"} {
+		client := newTestClient(t)
+		resp, err := client.Get(authorizeURL(srv.URL, url.Values{"scope": {scope}}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusFound {
+			t.Fatalf("scope=%q: got %d, want 302", scope, resp.StatusCode)
+		}
+		loc, _ := url.Parse(resp.Header.Get("Location"))
+		if got := loc.Query().Get("error"); got != "invalid_scope" {
+			t.Errorf("scope=%q: error=%q, want invalid_scope", scope, got)
+		}
+	}
+
+	// 허용 범위 안이면 평소대로 로그인 폼
+	resp, err := newTestClient(t).Get(authorizeURL(srv.URL, url.Values{"scope": {"openid"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("허용 scope 가 거부됨: %d", resp.StatusCode)
+	}
+}
+
+// TOTP 등록 사용자: 비밀번호만으로는 세션 / code 없음 → 2단계 코드 통과 후 code (amr=pwd otp).
+// 같은 코드 재사용은 거부.
+func TestIntegration_TOTP_SecondFactor(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	ctx := context.Background()
+	user, err := store.Users.GetByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.EnableTOTP(ctx, user.ID, secret, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Users.DisableTOTP(ctx, user.ID) })
+
+	client := newTestClient(t)
+	resp, err := client.Get(authorizeURL(srv.URL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	form := url.Values{
+		"client_id":    {"app1"},
+		"redirect_uri": {"http://localhost:8011/callback"},
+		"state":        {"t"},
+		"scope":        {""},
+	}
+	login := url.Values{"id": {"alice"}, "password": {"password123"}, "csrf_token": {extractCSRF(t, string(body))}}
+	for k, v := range form {
+		login[k] = v
+	}
+	resp, err = client.PostForm(srv.URL+"/oauth/login", login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "mfa-for=") {
+		t.Fatalf("비밀번호 후 2단계 화면이 아님: status=%d body=%s", resp.StatusCode, body)
+	}
+	for _, c := range resp.Cookies() {
+		if c.Name == "idp_session" && c.Value != "" {
+			t.Fatal("2단계 전에 idp_session 이 발급됨")
+		}
+	}
+
+	postMFA := func(code, csrf string) *http.Response {
+		t.Helper()
+		v := url.Values{"code": {code}, "csrf_token": {csrf}}
+		for k, vv := range form {
+			v[k] = vv
+		}
+		resp, err := client.PostForm(srv.URL+"/oauth/login/mfa", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// 틀린 코드 → 같은 화면 + 에러
+	resp = postMFA("000000", extractCSRF(t, string(body)))
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "class=\"error\"") {
+		t.Fatalf("틀린 코드가 통과됨: status=%d", resp.StatusCode)
+	}
+
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = postMFA(code, extractCSRF(t, string(body)))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("올바른 코드: got %d, want 302", resp.StatusCode)
+	}
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	ac, err := store.AuthCodes.LoadAndDelete(ctx, loc.Query().Get("code"))
+	if err != nil {
+		t.Fatalf("code 없음: %v", err)
+	}
+	if strings.Join(ac.AMR, " ") != "pwd otp" {
+		t.Errorf("amr = %v, want [pwd otp]", ac.AMR)
+	}
+
+	// 같은 step 재사용 → replay 거부
+	if err := store.Users.ConsumeTOTPStep(ctx, user.ID, step); err != store.ErrTOTPReplay {
+		t.Errorf("재사용 err = %v, want ErrTOTPReplay", err)
+	}
+}
+
+// 패스키: 비밀번호 세션으로 등록 → 세션 없는 새 브라우저에서 소프트웨어 인증기로 로그인 → code (amr=hwk mfa).
+func TestIntegration_Passkey_RegisterThenLogin(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	WebAuthnInit(webauthn.RelyingParty{ID: "localhost", Name: "oauth", Origins: []string{"http://localhost:8080"}})
+	auth := webauthntest.New("http://localhost:8080")
+	ctx := context.Background()
+
+	postJSON := func(client *http.Client, path, csrf string, out any) {
+		t.Helper()
+		resp, err := client.PostForm(srv.URL+path, url.Values{"csrf_token": {csrf}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status=%d", path, resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 1) 등록 — 비밀번호 로그인으로 얻은 IdP 세션
+	browser := newTestClient(t)
+	loginViaForm(t, srv, browser)
+	resp, err := browser.Get(srv.URL + "/oauth/passkeys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	csrf := extractCSRF(t, string(body))
+
+	var creation webauthn.CreationOptions
+	postJSON(browser, "/oauth/passkeys/options", csrf, &creation)
+	clientData, attObj, err := auth.Create(creation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = browser.PostForm(srv.URL+"/oauth/passkeys", url.Values{
+		"action":             {"register"},
+		"name":               {"test key"},
+		"csrf_token":         {csrf},
+		"client_data_json":   {webauthn.B64.EncodeToString(clientData)},
+		"attestation_object": {webauthn.B64.EncodeToString(attObj)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "passkeys=1") {
+		t.Fatalf("등록 실패: %s", body)
+	}
+	pk, err := store.Passkeys.Get(ctx, webauthn.B64.EncodeToString(auth.CredentialID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Passkeys.Delete(ctx, pk.UserID, pk.ID) })
+
+	// 2) 로그인 — 세션 없는 새 브라우저, 사용자 이름 / 비밀번호 없이
+	fresh := newTestClient(t)
+	resp, err = fresh.Get(authorizeURL(srv.URL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	csrf = extractCSRF(t, string(body))
+
+	var request webauthn.RequestOptions
+	postJSON(fresh, "/oauth/passkey/login/options", csrf, &request)
+	a, err := auth.Get(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = fresh.PostForm(srv.URL+"/oauth/passkey/login", url.Values{
+		"client_id":          {"app1"},
+		"redirect_uri":       {"http://localhost:8011/callback"},
+		"state":              {"t"},
+		"scope":              {""},
+		"csrf_token":         {csrf},
+		"credential_id":      {webauthn.B64.EncodeToString(a.CredentialID)},
+		"client_data_json":   {webauthn.B64.EncodeToString(a.ClientDataJSON)},
+		"authenticator_data": {webauthn.B64.EncodeToString(a.AuthenticatorData)},
+		"signature":          {webauthn.B64.EncodeToString(a.Signature)},
+		"user_handle":        {webauthn.B64.EncodeToString(a.UserHandle)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("패스키 로그인: got %d, want 302", resp.StatusCode)
+	}
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	ac, err := store.AuthCodes.LoadAndDelete(ctx, loc.Query().Get("code"))
+	if err != nil {
+		t.Fatalf("code 없음: %v", err)
+	}
+	if ac.UserID != pk.UserID || strings.Join(ac.AMR, " ") != "hwk mfa" {
+		t.Errorf("code = user %q amr %v, want %q [hwk mfa]", ac.UserID, ac.AMR, pk.UserID)
+	}
+	if got, _ := store.Passkeys.Get(ctx, pk.ID); got.SignCount != 1 || got.LastUsedAt.IsZero() {
+		t.Errorf("sign count / last used 미갱신: %+v", got)
+	}
+}
+
+// 사용자 이름 잠금: 5번째 실패부터 잠김 → 맞는 비밀번호도 거부. 없는 이름도 같은 응답 (계정 존재 비노출).
+// 매 시도마다 새 클라이언트 (= 다른 브라우저 / IP 흉내) — 잠금은 쿠키·IP 가 아닌 사용자 이름 기준.
+func TestIntegration_UsernameLockout(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+
+	attempt := func(username, password string) (int, string) {
+		t.Helper()
+		client := newTestClient(t)
+		resp, err := client.Get(authorizeURL(srv.URL, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp, err = client.PostForm(srv.URL+"/oauth/login", url.Values{
+			"id":           {username},
+			"password":     {password},
+			"client_id":    {"app1"},
+			"redirect_uri": {"http://localhost:8011/callback"},
+			"state":        {"t"},
+			"scope":        {""},
+			"csrf_token":   {extractCSRF(t, string(body))},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode, string(body)
+	}
+
+	for _, username := range []string{"bob", "no-such-user"} {
+		t.Cleanup(func() { _ = store.Users.ResetLoginFailures(ctx, username) })
+		for i := 1; i <= lockoutFreeAttempts; i++ {
+			_, body := attempt(username, "wrong-password")
+			locked := strings.Contains(body, "너무 많습니다")
+			if locked != (i == lockoutFreeAttempts) {
+				t.Fatalf("%s: %d번째 실패 후 잠금=%v", username, i, locked)
+			}
+		}
+		status, body := attempt(username, "password123")
+		if status == http.StatusFound || !strings.Contains(body, "30초 후") {
+			t.Errorf("%s: 잠금 중 로그인 status=%d body=%s", username, status, body)
+		}
+	}
+
+	// 초기화 후에는 정상 로그인 + 성공 시 누적 초기화
+	_ = store.Users.ResetLoginFailures(ctx, "bob")
+	attempt("bob", "wrong-password")
+	if status, _ := attempt("bob", "password123"); status != http.StatusFound {
+		t.Fatalf("초기화 후 로그인: got %d, want 302", status)
+	}
+	if th, _ := store.Users.GetLoginThrottle(ctx, "bob"); th.Failures != 0 {
+		t.Errorf("성공 후 failures = %d, want 0", th.Failures)
+	}
+
+	for failures, want := range map[int]time.Duration{4: 0, 5: 30 * time.Second, 6: time.Minute, 9: 8 * time.Minute, 10: 15 * time.Minute, 50: 15 * time.Minute} {
+		if got := lockoutDelay(failures); got != want {
+			t.Errorf("lockoutDelay(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+// 2단계 인증 해제: 틀린 코드는 로그인과 같은 사용자 이름 잠금에 누적 — 잠긴 뒤엔 맞는 코드도 거부.
+func TestIntegration_TOTPDisable_Lockout(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+
+	user, err := store.Users.GetByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t)
+	loginViaForm(t, srv, client)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.EnableTOTP(ctx, user.ID, secret, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.Users.DisableTOTP(ctx, user.ID)
+		_ = store.Users.ResetLoginFailures(ctx, user.Username)
+	})
+
+	disable := func(code string) string {
+		t.Helper()
+		resp, err := client.Get(srv.URL + "/oauth/2fa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp, err = client.PostForm(srv.URL+"/oauth/2fa", url.Values{
+			"action":     {"disable"},
+			"code":       {code},
+			"csrf_token": {extractCSRF(t, string(body))},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return string(body)
+	}
+
+	for i := 1; i <= lockoutFreeAttempts; i++ {
+		body := disable("000000")
+		if locked := strings.Contains(body, "너무 많습니다"); locked != (i == lockoutFreeAttempts) {
+			t.Fatalf("%d번째 실패 후 잠금=%v body=%s", i, locked, body)
+		}
+	}
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := disable(code); !strings.Contains(body, "step=enabled") || !strings.Contains(body, "너무 많습니다") {
+		t.Errorf("잠금 중 맞는 코드: %s", body)
+	}
+	if u, _ := store.Users.GetByID(ctx, user.ID); !u.TOTPEnabled() {
+		t.Error("잠금 중인데 2단계 인증이 해제됨")
+	}
+
+	// 잠금이 풀리면 맞는 코드로 해제 + 누적 초기화
+	_ = store.Users.ResetLoginFailures(ctx, user.Username)
+	if body := disable(code); !strings.Contains(body, "step=disabled") {
+		t.Fatalf("잠금 해제 후: %s", body)
+	}
+	if th, _ := store.Users.GetLoginThrottle(ctx, user.Username); th.Failures != 0 {
+		t.Errorf("해제 성공 후 failures = %d, want 0", th.Failures)
+	}
+}
+
+// chanMailer: 보낸 메일을 채널로 — 발송이 goroutine 이라 테스트가 기다릴 수 있게.
+type chanMailer chan mail.Message
+
+func (c chanMailer) Send(ctx context.Context, msg mail.Message) error {
+	c <- msg
+	return nil
+}
+
+var resetLinkRe = regexp.MustCompile(`/oauth/password/reset\?token=([0-9a-f]+)`)
+
+// 비밀번호 재설정: 메일 링크 → 새 비밀번호 → 기존 세션 / refresh token 폐기, 링크는 1회용.
+// 없는 계정도 같은 "sent" 응답 (메일은 나가지 않음).
+func TestIntegration_PasswordReset(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+
+	sent := make(chanMailer, 1)
+	prevMailer := mailer
+	MailerInit(sent)
+	defer MailerInit(prevMailer)
+
+	carol, _ := store.Users.GetByUsername(ctx, "carol")
+	defer store.Users.UpdatePassword(ctx, carol.ID, carol.PasswordHash)
+
+	// 재설정 전: carol 의 IdP 세션 + refresh token
+	sid, _ := store.IdPSessions.Create(carol.ID, amrPassword, "", "")
+	_ = store.RefreshTokens.Save(ctx, &models.RefreshToken{
+		Token: "carol-refresh", FamilyID: "carol-family", UserID: carol.ID, ClientID: "app1",
+		ExpiresAt: time.Now().Add(time.Hour), CreatedAt: time.Now(),
+	})
+
+	client := newTestClient(t)
+	forgot := func(login string) string {
+		t.Helper()
+		resp, err := client.Get(srv.URL + "/oauth/password/forgot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp, err = client.PostForm(srv.URL+"/oauth/password/forgot", url.Values{
+			"login": {login}, "csrf_token": {extractCSRF(t, string(body))},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return string(body)
+	}
+
+	if body := forgot("nobody@example.com"); !strings.Contains(body, "step=sent") {
+		t.Fatalf("없는 계정: %s", body)
+	}
+	if body := forgot("CAROL@example.com"); !strings.Contains(body, "step=sent") {
+		t.Fatalf("carol: %s", body)
+	}
+	var msg mail.Message
+	select {
+	case msg = <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("재설정 메일 미발송")
+	}
+	if msg.To != "carol@example.com" {
+		t.Errorf("수신자 = %q", msg.To)
+	}
+	m := resetLinkRe.FindStringSubmatch(msg.Body)
+	if m == nil {
+		t.Fatalf("메일에 링크 없음:\n%s", msg.Body)
+	}
+	tok := m[1]
+
+	reset := func(password string) string {
+		t.Helper()
+		resp, err := client.Get(srv.URL + "/oauth/password/reset?token=" + tok)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), "step=reset") {
+			return string(body)
+		}
+		if got := resp.Header.Get("Referrer-Policy"); got != "no-referrer" {
+			t.Errorf("Referrer-Policy = %q", got)
+		}
+		resp, err = client.PostForm(srv.URL+"/oauth/password/reset", url.Values{
+			"token": {tok}, "password": {password}, "password_confirm": {password},
+			"csrf_token": {extractCSRF(t, string(body))},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return string(body)
+	}
+
+	// 규칙 위반은 토큰을 쓰지 않는다
+	if body := reset("short"); !strings.Contains(body, "8 자 이상") {
+		t.Fatalf("짧은 비밀번호: %s", body)
+	}
+	if body := reset("new-password-456"); !strings.Contains(body, "step=done") {
+		t.Fatalf("재설정: %s", body)
+	}
+	if body := reset("another-password"); !strings.Contains(body, "step=invalid") {
+		t.Errorf("같은 링크 재사용: %s", body)
+	}
+
+	if _, ok := store.IdPSessions.Get(sid); ok {
+		t.Error("재설정 후에도 IdP 세션이 남아 있음")
+	}
+	if _, err := store.RefreshTokens.Load(ctx, "carol-refresh"); err == nil {
+		t.Error("재설정 후에도 refresh token 이 남아 있음")
+	}
+	req := httptest.NewRequest(http.MethodPost, "/oauth/login", nil)
+	if _, err := authenticateUser(req, "carol", "password123"); err == nil {
+		t.Error("옛 비밀번호로 로그인됨")
+	}
+	if _, err := authenticateUser(req, "carol", "new-password-456"); err != nil {
+		t.Errorf("새 비밀번호 로그인 실패: %v", err)
+	}
+}
+
+var verifyLinkRe = regexp.MustCompile(`/oauth/email/verify\?token=(\S+)`)
+
+// 이메일 인증 필수 client: 미인증이면 code 대신 안내 화면 (prompt=none 은 access_denied) →
+// 메일 링크로 인증 → 같은 요청이 code 로. 이메일을 바꾸면 옛 링크는 무효.
+func TestIntegration_RequireVerifiedEmail(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+
+	sent := make(chanMailer, 1)
+	prevMailer := mailer
+	MailerInit(sent)
+	defer MailerInit(prevMailer)
+
+	dave := &models.User{Username: "dave", PasswordHash: models.TestUsers["alice"].PasswordHash, DisplayName: "Dave", Email: "dave@example.com"}
+	if err := store.Users.Create(ctx, dave); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Clients.Register(&models.Client{
+		ClientID:             "verified-only",
+		Name:                 "Verified Only",
+		RedirectURIs:         []string{"http://localhost:9998/callback"},
+		SilentSSO:            true,
+		FirstParty:           true,
+		AllowedScopes:        []string{"openid", "email"},
+		RequireVerifiedEmail: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	authz := func(extra url.Values) string {
+		q := url.Values{"client_id": {"verified-only"}, "redirect_uri": {"http://localhost:9998/callback"}, "scope": {"openid"}}
+		for k, v := range extra {
+			q[k] = v
+		}
+		return authorizeURL(srv.URL, q)
+	}
+	read := func(resp *http.Response, err error) (*http.Response, string) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	client := newTestClient(t)
+	_, body := read(client.Get(authz(nil)))
+	resp, body := read(client.PostForm(srv.URL+"/oauth/login", url.Values{
+		"id": {"dave"}, "password": {"password123"},
+		"client_id": {"verified-only"}, "redirect_uri": {"http://localhost:9998/callback"},
+		"state": {"t"}, "scope": {"openid"}, "csrf_token": {extractCSRF(t, body)},
+	}))
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "step=required") {
+		t.Fatalf("미인증 로그인: status=%d body=%s", resp.StatusCode, body)
+	}
+
+	resp, _ = read(client.Get(authz(url.Values{"prompt": {"none"}})))
+	if loc, _ := url.Parse(resp.Header.Get("Location")); loc == nil || loc.Query().Get("error") != "access_denied" {
+		t.Fatalf("prompt=none: status=%d location=%q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// 안내 화면에서 재발송 → 링크
+	_, body = read(client.Get(authz(nil)))
+	read(client.PostForm(srv.URL+"/oauth/email", url.Values{"action": {"resend"}, "csrf_token": {extractCSRF(t, body)}}))
+	var msg mail.Message
+	select {
+	case msg = <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("인증 메일 미발송")
+	}
+	m := verifyLinkRe.FindStringSubmatch(msg.Body)
+	if msg.To != "dave@example.com" || m == nil {
+		t.Fatalf("인증 메일: to=%q body=%s", msg.To, msg.Body)
+	}
+	link := srv.URL + "/oauth/email/verify?token=" + m[1]
+
+	if _, body := read(newTestClient(t).Get(link)); !strings.Contains(body, "step=verified") {
+		t.Fatalf("링크 인증: %s", body)
+	}
+	resp, _ = read(client.Get(authz(nil)))
+	if loc, _ := url.Parse(resp.Header.Get("Location")); resp.StatusCode != http.StatusFound || loc.Query().Get("code") == "" {
+		t.Fatalf("인증 후 authorize: status=%d location=%q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// 이메일 변경 → 미인증으로 돌아가고 옛 링크는 무효
+	_, body = read(client.Get(srv.URL + "/oauth/email"))
+	_, body = read(client.PostForm(srv.URL+"/oauth/email", url.Values{
+		"action": {"change"}, "email": {"dave@example.org"}, "csrf_token": {extractCSRF(t, body)},
+	}))
+	if !strings.Contains(body, "verified=false") {
+		t.Fatalf("이메일 변경: %s", body)
+	}
+	select {
+	case msg = <-sent:
+		if msg.To != "dave@example.org" {
+			t.Errorf("변경 후 인증 메일 수신자 = %q", msg.To)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("변경 후 인증 메일 미발송")
+	}
+	if _, body := read(newTestClient(t).Get(link)); !strings.Contains(body, "step=invalid") {
+		t.Errorf("옛 링크: %s", body)
+	}
+	if u, _ := store.Users.GetByID(ctx, dave.ID); u.EmailVerified {
+		t.Error("옛 링크로 새 이메일이 인증됨")
+	}
+}
+
+// 어드민 비활성화: 기존 세션 / refresh token 즉시 무효, 비밀번호 로그인 거부. 다시 활성화하면 로그인 가능.
+// 목록 검색 / 페이지도 여기서 (memory store).
+func TestIntegration_AdminDisableUser(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	tokenSrv := newTokenTestServer(t)
+	defer tokenSrv.Close()
+	ctx := context.Background()
+
+	alice, err := store.Users.GetByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setDisabled := func(val string) {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/admin/users/"+alice.ID+"/disable", strings.NewReader(url.Values{"disabled": {val}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("id", alice.ID)
+		rec := httptest.NewRecorder()
+		AdminUserDisableHandler(rec, req)
+		if rec.Code != http.StatusSeeOther || strings.Contains(rec.Header().Get("Location"), "error=") {
+			t.Fatalf("disabled=%s: status=%d location=%s", val, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+	t.Cleanup(func() { _ = store.Users.Disable(ctx, alice.ID, false) })
+
+	client := newTestClient(t)
+	loginViaForm(t, srv, client)
+	if err := store.AuthCodes.Save(ctx, &models.AuthCode{
+		Code:        "disable-test-code",
+		ClientID:    "app1",
+		UserID:      alice.ID,
+		RedirectURI: "http://localhost:8011/callback",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	status, tr := postToken(t, tokenSrv, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {"disable-test-code"},
+		"redirect_uri": {"http://localhost:8011/callback"},
+	})
+	if status != http.StatusOK || tr.RefreshToken == "" {
+		t.Fatalf("code 교환 실패: status=%d err=%s", status, tr.Error)
+	}
+
+	// 사용자 조회가 일시적으로 실패하면 500 — refresh token / IdP 세션은 그대로
+	realUsers := store.Users
+	store.Users = failingUserStore{realUsers}
+	status, failed := postToken(t, tokenSrv, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tr.RefreshToken},
+	})
+	resp, err := client.Get(authorizeURL(srv.URL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	store.Users = realUsers
+	if status != http.StatusInternalServerError || failed.Error != "server_error" {
+		t.Errorf("조회 실패 중 refresh: status=%d err=%s", status, failed.Error)
+	}
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("조회 실패 중 authorize: status=%d", resp.StatusCode)
+	}
+	if _, err := store.RefreshTokens.Load(ctx, tr.RefreshToken); err != nil {
+		t.Errorf("조회 실패로 refresh token 이 사라짐: %v", err)
+	}
+	sessReq := httptest.NewRequest("GET", srv.URL, nil)
+	srvURL, _ := url.Parse(srv.URL)
+	for _, c := range client.Jar.Cookies(srvURL) {
+		sessReq.AddCookie(c)
+	}
+	if sid, ok := GetIdPSessionID(sessReq); !ok {
+		t.Error("조회 실패로 IdP 세션 쿠키가 지워짐")
+	} else if _, live := store.IdPSessions.Get(sid); !live {
+		t.Error("조회 실패로 IdP 세션이 삭제됨")
+	}
+
+	// 비활성화 전에 승인된 code / device_code
+	if err := store.AuthCodes.Save(ctx, &models.AuthCode{
+		Code:        "disable-test-code-2",
+		ClientID:    "app1",
+		UserID:      alice.ID,
+		RedirectURI: "http://localhost:8011/callback",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeviceCodes.Save(ctx, &models.DeviceCode{
+		DeviceCode: "disable-test-device-code",
+		UserCode:   "DISABLED",
+		ClientID:   "app1",
+		UserID:     alice.ID,
+		Status:     models.DeviceCodeApproved,
+		Interval:   devicePollInterval,
+		ExpiresAt:  time.Now().Add(time.Minute),
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	setDisabled("true")
+
+	// 비활성화 전에 승인된 code / device_code 로도 발급 거부
+	for _, form := range []url.Values{
+		{"grant_type": {"authorization_code"}, "code": {"disable-test-code-2"}, "redirect_uri": {"http://localhost:8011/callback"}},
+		{"grant_type": {deviceCodeGrantType}, "device_code": {"disable-test-device-code"}},
+	} {
+		if status, denied := postToken(t, tokenSrv, form); status != http.StatusBadRequest || denied.Error != "invalid_grant" {
+			t.Errorf("비활성 계정 %s: status=%d err=%s", form.Get("grant_type"), status, denied.Error)
+		}
+	}
+
+	// silent SSO: 세션이 있어도 code 없이 로그인 폼
+	resp, err = client.Get(authorizeURL(srv.URL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `action="/oauth/login"`) {
+		t.Fatalf("비활성 계정의 silent authorize: status=%d location=%s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// 비밀번호가 맞아도 로그인 거부
+	resp, err = client.PostForm(srv.URL+"/oauth/login", url.Values{
+		"id":           {"alice"},
+		"password":     {"password123"},
+		"client_id":    {"app1"},
+		"redirect_uri": {"http://localhost:8011/callback"},
+		"state":        {"t"},
+		"scope":        {""},
+		"csrf_token":   {extractCSRF(t, string(body))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusFound || !strings.Contains(string(body), "사용이 중지된 계정") {
+		t.Fatalf("비활성 계정 로그인: status=%d body=%s", resp.StatusCode, body)
+	}
+
+	// refresh 거부
+	status, tr = postToken(t, tokenSrv, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tr.RefreshToken},
+	})
+	if status != http.StatusBadRequest || tr.Error != "invalid_grant" {
+		t.Fatalf("비활성 계정 refresh: status=%d err=%s", status, tr.Error)
+	}
+
+	setDisabled("false")
+	loginViaForm(t, srv, newTestClient(t))
+
+	// 목록: 검색은 대소문자 무시, 페이지는 total 유지
+	users, total, err := store.Users.List(ctx, store.UserListOptions{Query: "ALICE@"})
+	if err != nil || total != 1 || len(users) != 1 || users[0].ID != alice.ID {
+		t.Fatalf("검색: total=%d users=%d err=%v", total, len(users), err)
+	}
+	all, total, _ := store.Users.List(ctx, store.UserListOptions{})
+	page, pageTotal, _ := store.Users.List(ctx, store.UserListOptions{Offset: 1, Limit: 1})
+	if pageTotal != total || len(page) != 1 || page[0].ID != all[1].ID {
+		t.Errorf("페이지: total=%d/%d page=%v", pageTotal, total, page)
+	}
+}
+
+// failingUserStore: GetByID 가 늘 실패하는 UserStore (일시적 DB 장애 흉내).
+type failingUserStore struct{ store.UserStore }
+
+func (failingUserStore) GetByID(context.Context, string) (*models.User, error) {
+	return nil, errors.New("db unavailable")
+}
+
+// Back-Channel Logout: code 를 받아 간 client 의 ID Token 에 sid, /oauth/logout 시 같은 sid 의 logout_token 이 RP 로.
+// RP 가 처음엔 500 — 재시도로 전달되는지도 본다.
+func TestIntegration_BackchannelLogout(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	tokenSrv := newTokenTestServer(t)
+	defer tokenSrv.Close()
+
+	delays := backchannelRetryDelays
+	backchannelRetryDelays = []time.Duration{0, 10 * time.Millisecond, 10 * time.Millisecond}
+	t.Cleanup(func() { backchannelRetryDelays = delays })
+
+	received := make(chan string, 4)
+	var attempts int
+	rp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		received <- r.PostFormValue("logout_token")
+	}))
+	defer rp.Close()
+
+	app1, _ := store.Clients.GetByClientID("app1")
+	orig := *app1
+	app1.BackchannelLogoutURI = rp.URL
+	if err := store.Clients.Update(app1); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Clients.Update(&orig) })
+
+	// 로그인 → silent authorize (openid) → code 교환
+	client := newTestClient(t)
+	loginViaForm(t, srv, client)
+	resp, err := client.Get(authorizeURL(srv.URL, url.Values{"scope": {"openid"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	status, tr := postToken(t, tokenSrv, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {loc.Query().Get("code")},
+		"redirect_uri": {"http://localhost:8011/callback"},
+	})
+	if status != http.StatusOK || tr.IDToken == "" {
+		t.Fatalf("code 교환: status=%d err=%s", status, tr.Error)
+	}
+	idc, err := token.ParseIDToken(tr.IDToken)
+	if err != nil || idc.SID == "" {
+		t.Fatalf("ID Token sid 누락: %+v err=%v", idc, err)
+	}
+
+	resp, err = client.Get(srv.URL + "/oauth/logout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	select {
+	case raw := <-received:
+		lc, err := token.ParseLogoutToken(raw)
+		if err != nil {
+			t.Fatalf("logout_token 검증 실패: %v", err)
+		}
+		if lc.SID != idc.SID || lc.Subject != idc.Subject || len(lc.Audience) != 1 || lc.Audience[0] != "app1" {
+			t.Errorf("logout_token claims: sid=%s sub=%s aud=%v", lc.SID, lc.Subject, lc.Audience)
+		}
+		if _, ok := lc.Events[token.BackchannelLogoutEvent]; !ok || lc.ID == "" {
+			t.Errorf("logout_token events / jti 누락: %+v", lc)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("logout_token 이 RP 에 도착하지 않음")
+	}
+
+	// IdP 세션도 끝났다 — silent authorize 대신 로그인 폼
+	resp, err = client.Get(authorizeURL(srv.URL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("logout 후 authorize: status=%d, want 200 (로그인 폼)", resp.StatusCode)
+	}
+}
+
+// production 에서는 loopback backchannel_logout_uri 로 보내지 않는다 (dial 단계에서 차단, 재시도도 같은 결과).
+func TestBackchannelLogout_BlocksLoopbackInProduction(t *testing.T) {
+	delays := backchannelRetryDelays
+	backchannelRetryDelays = []time.Duration{0, time.Millisecond}
+	t.Cleanup(func() { backchannelRetryDelays = delays })
+
+	var hits int
+	rp := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hits++ }))
+	defer rp.Close()
+
+	SetProduction(true)
+	t.Cleanup(func() { SetProduction(false) })
+	deliverLogoutToken(rp.URL, "x", []any{"client_id", "app1"})
+	if hits != 0 {
+		t.Errorf("production 에서 loopback 으로 %d 번 전송됨", hits)
+	}
+	if validOutboundURI(rp.URL) {
+		t.Errorf("production 에서 loopback URI 등록 허용: %s", rp.URL)
+	}
+}
+
+// Front-Channel Logout: 참여 client 의 frontchannel_logout_uri 를 iss / sid 와 함께 iframe 목록으로 렌더하고,
+// 검증된 post_logout_redirect_uri 는 페이지가 이어 간다. 알릴 곳이 없으면 바로 302.
+func TestIntegration_FrontchannelLogout(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	app1, _ := store.Clients.GetByClientID("app1")
+	orig := *app1
+	app1.FrontchannelLogoutURI = "http://localhost:8011/frontchannel-logout"
+	if err := store.Clients.Update(app1); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Clients.Update(&orig) })
+
+	client := newTestClient(t)
+	loginViaForm(t, srv, client)
+	// jar 의 세션 쿠키 → sid claim 기대값
+	req := httptest.NewRequest("GET", srv.URL, nil)
+	srvURL, _ := url.Parse(srv.URL)
+	for _, c := range client.Jar.Cookies(srvURL) {
+		req.AddCookie(c)
+	}
+	sessionID, _ := GetIdPSessionID(req)
+	sess, ok := store.IdPSessions.Get(sessionID)
+	if !ok {
+		t.Fatal("IdP 세션 없음")
+	}
+
+	logoutURL := srv.URL + "/oauth/logout?" + url.Values{
+		"post_logout_redirect_uri": {"http://localhost:8011/callback"},
+		"state":                    {"s"},
+	}.Encode()
+	resp, err := client.Get(logoutURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	frame := "frame=http://localhost:8011/frontchannel-logout?iss=&amp;sid=" + sess.SID()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), frame) ||
+		!strings.Contains(string(body), "next=http://localhost:8011/callback?state=s") {
+		t.Fatalf("logout 페이지: status=%d body=%s", resp.StatusCode, body)
+	}
+	if resp.Header.Get("X-Frame-Options") != "DENY" {
+		t.Error("logout 페이지 X-Frame-Options 누락")
+	}
+
+	// 세션이 없으면 띄울 iframe 도 없다 — 곧장 redirect
+	resp, err = client.Get(logoutURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "http://localhost:8011/callback?state=s" {
+		t.Errorf("두 번째 logout: status=%d location=%s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+// post_logout_redirect_uri: host 있는 http(s) 절대 URL 만. MainURL 이 빈 client 가 있어도 `\\evil.com` 같은 값은 통과하지 못한다.
+func TestPostLogoutURIAllowed(t *testing.T) {
+	app1, _ := store.Clients.GetByClientID("app1")
+	orig := *app1
+	app1.MainURL = ""
+	if err := store.Clients.Update(app1); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Clients.Update(&orig) })
+
+	cases := []struct {
+		uri  string
+		want bool
+	}{
+		{"http://localhost:8011/callback", true},
+		{`\\evil.com`, false},
+		{`\\evil.com/path`, false},
+		{"//evil.com", false},
+		{"/relative", false},
+		{"javascript:alert(1)", false},
+		{"https://evil.com/", false},
+	}
+	for _, tc := range cases {
+		for _, hint := range []string{"", "app1"} {
+			if got := isPostLogoutURIAllowed(tc.uri, hint); got != tc.want {
+				t.Errorf("isPostLogoutURIAllowed(%q, %q) = %v, want %v", tc.uri, hint, got, tc.want)
+			}
+		}
+	}
+}
+
+// /oauth/account: 본인 세션 목록 (다른 기기 세션 포함) → 다른 세션 로그아웃 → 앱 연결 해제 시 refresh 거부,
+// 지금 세션 로그아웃은 /oauth/logout 으로 넘긴다.
+func TestIntegration_Account(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	tokenSrv := newTokenTestServer(t)
+	defer tokenSrv.Close()
+	ctx := context.Background()
+
+	alice, err := store.Users.GetByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t)
+	loginViaForm(t, srv, client)
+
+	// 다른 기기의 세션
+	otherID, err := store.IdPSessions.Create(alice.ID, amrPassword, "10.0.0.7",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := models.SessionSID(otherID)
+
+	if err := store.AuthCodes.Save(ctx, &models.AuthCode{
+		Code:        "account-test-code",
+		ClientID:    "app1",
+		UserID:      alice.ID,
+		RedirectURI: "http://localhost:8011/callback",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	status, tr := postToken(t, tokenSrv, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {"account-test-code"},
+		"redirect_uri": {"http://localhost:8011/callback"},
+	})
+	if status != http.StatusOK || tr.RefreshToken == "" {
+		t.Fatalf("code 교환 실패: status=%d err=%s", status, tr.Error)
+	}
+
+	getAccount := func() string {
+		t.Helper()
+		resp, err := client.Get(srv.URL + "/oauth/account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /oauth/account: status=%d body=%s", resp.StatusCode, body)
+		}
+		return string(body)
+	}
+	postAccount := func(body string, v url.Values) *http.Response {
+		t.Helper()
+		v.Set("csrf_token", extractCSRF(t, body))
+		resp, err := client.PostForm(srv.URL+"/oauth/account", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	body := getAccount()
+	if !strings.Contains(body, "session="+other+" current=false device=Firefox · Linux") ||
+		!strings.Contains(body, "current=true") || !strings.Contains(body, "app=app1") {
+		t.Fatalf("목록: %s", body)
+	}
+
+	// 다른 세션 로그아웃
+	resp := postAccount(body, url.Values{"action": {"signout_session"}, "sid": {other}})
+	body2, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || strings.Contains(string(body2), other) {
+		t.Fatalf("세션 로그아웃: status=%d body=%s", resp.StatusCode, body2)
+	}
+	if _, ok := store.IdPSessions.Get(otherID); ok {
+		t.Error("종료한 세션이 남아 있음")
+	}
+
+	// 앱 연결 해제 → refresh 거부
+	resp = postAccount(string(body2), url.Values{"action": {"revoke_app"}, "client_id": {"app1"}})
+	body3, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || strings.Contains(string(body3), "app=app1") {
+		t.Fatalf("앱 연결 해제: status=%d body=%s", resp.StatusCode, body3)
+	}
+	status, tr = postToken(t, tokenSrv, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tr.RefreshToken},
+	})
+	if status != http.StatusBadRequest || tr.Error != "invalid_grant" {
+		t.Fatalf("연결 해제 후 refresh: status=%d err=%s", status, tr.Error)
+	}
+
+	// 지금 세션은 /oauth/logout 으로
+	var current string
+	for _, line := range strings.Split(string(body3), "\n") {
+		if strings.Contains(line, "current=true") {
+			current = strings.TrimPrefix(strings.Fields(line)[0], "session=")
+		}
+	}
+	resp = postAccount(string(body3), url.Values{"action": {"signout_session"}, "sid": {current}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/oauth/logout" {
+		t.Errorf("현재 세션 로그아웃: status=%d location=%s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// CSRF 없으면 거부
+	resp, err = client.PostForm(srv.URL+"/oauth/account", url.Values{"action": {"revoke_app"}, "client_id": {"app1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("CSRF 없는 POST: status=%d", resp.StatusCode)
+	}
+}
+
+// 어드민 kill switch: 사용자·client 쌍 단위 폐기는 그 쌍의 refresh / access token 만,
+// client 단위는 모든 사용자의 access token 을, 세션 종료는 IdP 세션을 끝낸다.
+func TestIntegration_AdminKillSwitch(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	tokenSrv := newTokenTestServer(t)
+	defer tokenSrv.Close()
+	ctx := context.Background()
+
+	alice, err := store.Users.GetByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminPost := func(h http.HandlerFunc, path, id string, form url.Values) {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != http.StatusSeeOther || strings.Contains(rec.Header().Get("Location"), "error=") {
+			t.Fatalf("%s: status=%d location=%s", path, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+	revoked := func(accessToken string) bool {
+		claims, err := token.Parse(accessToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return IsAccessTokenRevoked(ctx, accessToken, claims)
+	}
+
+	if err := store.AuthCodes.Save(ctx, &models.AuthCode{
+		Code:        "kill-switch-code",
+		ClientID:    "app1",
+		UserID:      alice.ID,
+		RedirectURI: "http://localhost:8011/callback",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	status, tr := postToken(t, tokenSrv, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {"kill-switch-code"},
+		"redirect_uri": {"http://localhost:8011/callback"},
+	})
+	if status != http.StatusOK || tr.RefreshToken == "" {
+		t.Fatalf("code 교환 실패: status=%d err=%s", status, tr.Error)
+	}
+
+	// 다른 client 와의 쌍은 영향 없음
+	tokensPath := "/admin/users/" + alice.ID + "/tokens/revoke"
+	adminPost(AdminUserTokensRevokeHandler, tokensPath, alice.ID, url.Values{"client_id": {"app2"}})
+	if revoked(tr.AccessToken) {
+		t.Fatal("alice·app2 폐기가 app1 access token 을 막음")
+	}
+
+	adminPost(AdminUserTokensRevokeHandler, tokensPath, alice.ID, url.Values{"client_id": {"app1"}})
+	if !revoked(tr.AccessToken) {
+		t.Error("alice·app1 폐기 후에도 access token 유효")
+	}
+	status, tr = postToken(t, tokenSrv, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tr.RefreshToken},
+	})
+	if status != http.StatusBadRequest || tr.Error != "invalid_grant" {
+		t.Fatalf("폐기 후 refresh: status=%d err=%s", status, tr.Error)
+	}
+
+	// client 단위: 누구의 토큰이든 그 client 것만
+	issued := jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	app3Claims := &token.Claims{UserID: "someone", ClientID: "app3", RegisteredClaims: jwt.RegisteredClaims{IssuedAt: issued}}
+	app2Claims := &token.Claims{UserID: "someone", ClientID: "app2", RegisteredClaims: jwt.RegisteredClaims{IssuedAt: issued}}
+	adminPost(AdminClientTokensRevokeHandler, "/admin/clients/app3/tokens/revoke", "app3", nil)
+	if !IsAccessTokenRevoked(ctx, "", app3Claims) || IsAccessTokenRevoked(ctx, "", app2Claims) {
+		t.Error("client 단위 폐기 범위가 틀림")
+	}
+
+	// 세션 종료
+	client := newTestClient(t)
+	loginViaForm(t, srv, client)
+	adminPost(AdminUserSessionsRevokeHandler, "/admin/users/"+alice.ID+"/sessions/revoke", alice.ID, nil)
+	if sessions, _ := store.IdPSessions.ListByUser(alice.ID); len(sessions) != 0 {
+		t.Errorf("세션 종료 후 남은 세션 %d개", len(sessions))
+	}
+	resp, err := client.Get(authorizeURL(srv.URL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("세션 종료 후 authorize: status=%d, want 200 (로그인 폼)", resp.StatusCode)
+	}
+}
+
+// 어드민 역할 — IdP 계정 로그인, 역할별 403, 세션 CSRF.
+func TestIntegration_AdminRoles(t *testing.T) {
+	AdminInit("admin-test-secret-32bytes!!!!!!!")
+	SetProduction(false)
+	ctx := context.Background()
+
+	alice, err := store.Users.GetByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := store.Users.GetByUsername(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.Users.SetAdminRole(ctx, alice.ID, "")
+		_ = store.Users.SetAdminRole(ctx, bob.ID, "")
+		_ = store.Users.Disable(ctx, bob.ID, false)
+	})
+
+	tmpl := template.Must(template.New("admin_login.html").Parse(
+		`error={{.ErrorMsg}}<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">`))
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/login", AdminLoginGetHandler(tmpl))
+	mux.HandleFunc("POST /admin/login", AdminLoginPostHandler(tmpl))
+	mux.Handle("GET /admin", RequireAdmin(AdminView, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<input type="hidden" name="csrf_token" value="`+adminChromeFor(r).CSRFToken+`">`)
+	})))
+	mux.Handle("POST /admin/users/{id}/disable", RequireAdmin(AdminManageUsers, http.HandlerFunc(AdminUserDisableHandler)))
+	mux.Handle("POST /admin/users/{id}/delete", RequireAdmin(AdminManageUsers, http.HandlerFunc(AdminUserDeleteHandler)))
+	mux.Handle("POST /admin/users/{id}/role", RequireAdmin(AdminSuper, http.HandlerFunc(AdminUserRoleHandler)))
+	mux.Handle("POST /admin/logout", RequireAdmin(AdminView, http.HandlerFunc(AdminLogoutHandler)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	login := func(client *http.Client, username string) *http.Response {
+		t.Helper()
+		resp, err := client.Get(srv.URL + "/admin/login")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp, err = client.PostForm(srv.URL+"/admin/login", url.Values{
+			"username":   {username},
+			"password":   {"password123"},
+			"csrf_token": {extractCSRF(t, string(body))},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	post := func(client *http.Client, path string, form url.Values) *http.Response {
+		t.Helper()
+		resp, err := client.PostForm(srv.URL+path, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// 역할 없는 계정은 비밀번호가 맞아도 거부
+	resp := login(newTestClient(t), "bob")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "어드민 권한이 없는") {
+		t.Fatalf("역할 없는 로그인: status=%d body=%s", resp.StatusCode, body)
+	}
+
+	// 로그인 CSRF 쿠키 없이 POST → 403
+	resp = post(newTestClient(t), "/admin/login", url.Values{"username": {"alice"}, "password": {"password123"}})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("CSRF 없는 로그인: status=%d, want 403", resp.StatusCode)
+	}
+
+	if err := store.Users.SetAdminRole(ctx, alice.ID, models.AdminRoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t)
+	resp = login(client, "alice")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/admin" {
+		t.Fatalf("viewer 로그인: status=%d location=%s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	resp, err = client.Get(srv.URL + "/admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	csrf := extractCSRF(t, string(body))
+
+	disablePath := "/admin/users/" + bob.ID + "/disable"
+	if resp := post(client, disablePath, url.Values{"disabled": {"true"}, "csrf_token": {csrf}}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer 의 사용자 비활성화: status=%d, want 403", resp.StatusCode)
+	}
+
+	// 역할은 매 요청 다시 읽는다 — 재로그인 없이 superadmin 권한 적용
+	if err := store.Users.SetAdminRole(ctx, alice.ID, models.AdminRoleSuperadmin); err != nil {
+		t.Fatal(err)
+	}
+	if resp := post(client, disablePath, url.Values{"disabled": {"true"}}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("csrf_token 없는 POST: status=%d, want 403", resp.StatusCode)
+	}
+	if resp := post(client, disablePath, url.Values{"disabled": {"true"}, "csrf_token": {"forged"}}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("위조 csrf_token POST: status=%d, want 403", resp.StatusCode)
+	}
+	resp = post(client, disablePath, url.Values{"disabled": {"true"}, "csrf_token": {csrf}})
+	if resp.StatusCode != http.StatusSeeOther || strings.Contains(resp.Header.Get("Location"), "error=") {
+		t.Fatalf("superadmin 의 사용자 비활성화: status=%d location=%s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if got, _ := store.Users.GetByID(ctx, bob.ID); !got.Disabled {
+		t.Error("bob 이 비활성화되지 않음")
+	}
+	_ = store.Users.Disable(ctx, bob.ID, false)
+
+	// 자기 계정 비활성화 / 삭제 — 불가, 계정은 그대로
+	for _, action := range []string{"disable", "delete"} {
+		resp = post(client, "/admin/users/"+alice.ID+"/"+action, url.Values{"disabled": {"true"}, "csrf_token": {csrf}})
+		if !strings.Contains(resp.Header.Get("Location"), "error=own_account") {
+			t.Errorf("자기 계정 %s: location=%s, want error=own_account", action, resp.Header.Get("Location"))
+		}
+	}
+	if got, err := store.Users.GetByID(ctx, alice.ID); err != nil || got.Disabled {
+		t.Errorf("자기 계정 비활성화 / 삭제가 적용됨: user=%+v err=%v", got, err)
+	}
+
+	// 역할 변경 — 자기 자신은 불가, 다른 계정은 기록된다
+	resp = post(client, "/admin/users/"+alice.ID+"/role", url.Values{"role": {""}, "csrf_token": {csrf}})
+	if !strings.Contains(resp.Header.Get("Location"), "error=own_role") {
+		t.Errorf("자기 역할 변경: location=%s, want error=own_role", resp.Header.Get("Location"))
+	}
+	resp = post(client, "/admin/users/"+bob.ID+"/role", url.Values{"role": {models.AdminRoleUserManager}, "csrf_token": {csrf}})
+	if !strings.Contains(resp.Header.Get("Location"), "notice=role_changed") {
+		t.Errorf("bob 역할 부여: location=%s", resp.Header.Get("Location"))
+	}
+	if got, _ := store.Users.GetByID(ctx, bob.ID); got.AdminRole != models.AdminRoleUserManager {
+		t.Errorf("bob AdminRole=%q, want %q", got.AdminRole, models.AdminRoleUserManager)
+	}
+
+	// 역할 회수 → 기존 세션도 즉시 로그인 페이지로
+	if err := store.Users.SetAdminRole(ctx, alice.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = client.Get(srv.URL + "/admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/admin/login" {
+		t.Errorf("역할 회수 후: status=%d location=%s, want 302 /admin/login", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// 로그아웃은 서버 세션도 지운다 — 미리 복사해 둔 쿠키로는 다시 들어오지 못한다
+	if err := store.Users.SetAdminRole(ctx, alice.ID, models.AdminRoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	client = newTestClient(t)
+	login(client, "alice").Body.Close()
+	adminURL, _ := url.Parse(srv.URL + "/admin")
+	copied := client.Jar.Cookies(adminURL)
+	if len(copied) == 0 {
+		t.Fatal("어드민 세션 쿠키 없음")
+	}
+	resp, err = client.Get(srv.URL + "/admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp := post(client, "/admin/logout", url.Values{"csrf_token": {extractCSRF(t, string(body))}}); resp.Header.Get("Location") != "/admin/login" {
+		t.Fatalf("로그아웃: status=%d location=%s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	replay := newTestClient(t)
+	replay.Jar.SetCookies(adminURL, copied)
+	resp, err = replay.Get(srv.URL + "/admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/admin/login" {
+		t.Errorf("로그아웃 후 복사한 쿠키: status=%d location=%s, want 302 /admin/login", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+// 감사 로그 영속 sink — 이벤트가 store.Audit 에 남고, 어드민 화면 필터 / 내보내기 / 보존 삭제가 동작.
+func TestIntegration_AdminAudit(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+	store.Audit = store.NewMemoryAuditStore(1000)
+	t.Cleanup(func() { store.Audit = nil })
+
+	alice, err := store.Users.GetByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loginViaForm(t, srv, newTestClient(t))
+	AuditSystem("test.system_event", "client_id", "app2")
+	flushAudit()
+
+	tmpl := template.Must(template.New("admin_audit.html").Parse(
+		`error={{.ErrorMsg}} total={{.Total}}
+{{range .Rows}}row={{.Event}} actor={{.Actor}} sub={{.Subject}} client={{.ClientID}}
+{{end}}`))
+	get := func(h http.HandlerFunc, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest("GET", "/admin/audit?"+query, nil))
+		return rec
+	}
+
+	// username 으로 찾아도 sub 로 기록된 login.success 가 나온다
+	body := get(AdminAuditHandler(tmpl), "user=alice&event=login").Body.String()
+	if !strings.Contains(body, "row=login.success") || !strings.Contains(body, "sub="+alice.ID) {
+		t.Errorf("user=alice&event=login: %s", body)
+	}
+	body = get(AdminAuditHandler(tmpl), "client_id=app2").Body.String()
+	if !strings.Contains(body, "row=test.system_event actor=system") || strings.Contains(body, "row=login") {
+		t.Errorf("client_id=app2: %s", body)
+	}
+	future := time.Now().Add(time.Hour).Format("2006-01-02T15:04")
+	if body := get(AdminAuditHandler(tmpl), "since="+future).Body.String(); !strings.Contains(body, "total=0") {
+		t.Errorf("since=미래: %s", body)
+	}
+	if body := get(AdminAuditHandler(tmpl), "until=어제").Body.String(); !strings.Contains(body, "기간 형식") {
+		t.Errorf("잘못된 until: %s", body)
+	}
+
+	rec := get(AdminAuditExportHandler, "format=csv&user="+alice.ID+"&event=login.success")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("csv export: status=%d type=%s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) < 2 || !strings.HasPrefix(lines[0], "time,level,event,actor,subject,client_id") || !strings.Contains(lines[1], ",login.success,") {
+		t.Errorf("csv export body: %s", rec.Body.String())
+	}
+
+	rec = get(AdminAuditExportHandler, "format=json&client_id=app2")
+	var exported []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &exported); err != nil {
+		t.Fatalf("json export: %v body=%s", err, rec.Body.String())
+	}
+	if len(exported) != 1 || exported[0]["event"] != "test.system_event" || exported[0]["actor"] != "system" {
+		t.Errorf("json export: %v", exported)
+	}
+
+	// 내보내기 자체도 기록
+	flushAudit()
+	entries, _, err := store.Audit.List(ctx, store.AuditQuery{Event: "admin.audit_exported"})
+	if err != nil || len(entries) != 2 {
+		t.Errorf("admin.audit_exported 기록 = %d (err=%v), want 2", len(entries), err)
+	}
+
+	// 사용자 입력이 수식으로 실행되지 않게 — 첫 글자가 = + - @ 탭 CR 이면 ' 접두
+	req := httptest.NewRequest("GET", "/oauth/login", nil)
+	req.Header.Set("User-Agent", "=HYPERLINK(\"http://evil.example\")")
+	AuditWarn(req, "test.csv_injection", "username", "@SUM(1)", "client_id", "app3")
+	flushAudit()
+	rec = get(AdminAuditExportHandler, "format=csv&client_id=app3&event=test.csv_injection")
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("csv export: rows=%v err=%v", rows, err)
+	}
+	if ua, actor := rows[1][7], rows[1][3]; ua != "'=HYPERLINK(\"http://evil.example\")" || actor != "'@SUM(1)" {
+		t.Errorf("수식 셀 escape: user_agent=%q actor=%q", ua, actor)
+	}
+
+	// 보존 기간 — 지금 이전은 전부 삭제
+	flushAudit()
+	if n, err := store.Audit.Prune(ctx, time.Now().Add(time.Second)); err != nil || n == 0 {
+		t.Errorf("Prune: n=%d err=%v", n, err)
+	}
+	if _, total, _ := store.Audit.List(ctx, store.AuditQuery{}); total != 0 {
+		t.Errorf("Prune 후 total=%d, want 0", total)
+	}
+
+	// sink 가 꺼져 있으면 내보내기 404
+	store.Audit = nil
+	if rec := get(AdminAuditExportHandler, "format=csv"); rec.Code != http.StatusNotFound {
+		t.Errorf("sink 없음 export: status=%d, want 404", rec.Code)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/ftery0/ouath/server/models"
 	"github.com/ftery0/ouath/server/store"
 	"github.com/ftery0/ouath/server/token"
 )
@@ -14,14 +13,14 @@ import (
 // form: token, token_type_hint=access_token|refresh_token (선택).
 // 클라이언트 인증 필수. 응답: {active: bool, sub, client_id, scope, exp, iat, token_type}
 // active=false 케이스에서는 active 만 반환 (정보 노출 방지).
+// public client 는 자격 증명이 없으므로 받지 않는다 — 아무나 토큰 상태를 캐물을 수 있게 된다.
 func IntrospectHandler(w http.ResponseWriter, r *http.Request) {
-	clientID, clientSecret, ok := r.BasicAuth()
+	client, ok := authenticateClient(w, r, "introspect")
 	if !ok {
-		tokenError(w, "invalid_client", "client 인증 실패", http.StatusUnauthorized)
 		return
 	}
-	client, ok := store.Clients.GetByClientID(clientID)
-	if !ok || !store.VerifySecret(client, clientSecret) {
+	if client.IsPublic() {
+		AuditWarn(r, "introspect.public_client_denied", "client_id", client.ClientID)
 		tokenError(w, "invalid_client", "client 인증 실패", http.StatusUnauthorized)
 		return
 	}
@@ -37,11 +36,8 @@ func IntrospectHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	tryAccess := func() bool {
-		if IsAccessTokenRevoked(tokStr) {
-			return false
-		}
 		claims, err := token.Parse(tokStr)
-		if err != nil {
+		if err != nil || IsAccessTokenRevoked(r.Context(), tokStr, claims) {
 			return false
 		}
 		if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
@@ -62,12 +58,8 @@ func IntrospectHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tryRefresh := func() bool {
-		val, ok := store.RefreshTokens.Load(tokStr)
-		if !ok {
-			return false
-		}
-		rt, ok := val.(models.RefreshToken)
-		if !ok {
+		rt, err := store.RefreshTokens.Load(r.Context(), tokStr)
+		if err != nil {
 			return false
 		}
 		if rt.ExpiresAt.Before(time.Now()) {
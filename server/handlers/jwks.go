@@ -17,6 +17,12 @@ type JWK struct {
 	Kid string `json:"kid"` // Key ID: 키를 구분하는 식별자
 	N   string `json:"n"`   // RSA 공개키 모듈러스 (base64url)
 	E   string `json:"e"`   // RSA 공개키 지수 (base64url)
+
+	// EC 키 (client 가 private_key_jwt 용으로 등록한 JWK 를 읽을 때만 — 우리 JWKS 는 RSA 뿐)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	D   string `json:"d,omitempty"` // 비밀키 성분. 등록 검증에서 거부하려고만 읽는다
 }
 
 type JWKS struct {
@@ -27,20 +33,24 @@ type JWKS struct {
 // 공개키를 JWK Set 형식으로 반환
 // 클라이언트는 이 엔드포인트에서 공개키를 가져와 JWT 서명을 직접 검증할 수 있음
 // → 인가 서버에 매 요청마다 묻지 않아도 됨 (성능 향상)
+//
+// 회전 중 겹침 게시: active + next (회전 전에 미리 캐시되도록) + retired (이미 발급된 토큰 검증용).
 func JWKSHandler(w http.ResponseWriter, r *http.Request) {
-	pub := token.PublicKey
-
-	jwk := JWK{
-		Kty: "RSA",
-		Use: "sig",
-		Alg: "RS256",
-		Kid: "ouath-key-1",
-		// N: 모듈러스를 big-endian 바이트 배열 → base64url 인코딩
-		N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
-		// E: 지수(보통 65537)를 big-endian 바이트 배열 → base64url 인코딩
-		E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	published := token.PublishedKeys()
+	keys := make([]JWK, 0, len(published))
+	for _, k := range published {
+		keys = append(keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: k.Kid, // RFC 7638 thumbprint — 재시작해도 같은 키면 같은 kid
+			// N: 모듈러스를 big-endian 바이트 배열 → base64url 인코딩
+			N: base64.RawURLEncoding.EncodeToString(k.Public.N.Bytes()),
+			// E: 지수(보통 65537)를 big-endian 바이트 배열 → base64url 인코딩
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.Public.E)).Bytes()),
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(JWKS{Keys: []JWK{jwk}})
+	json.NewEncoder(w).Encode(JWKS{Keys: keys})
 }
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ftery0/ouath/server/store"
+)
+
+// 사용자 이름 단위 로그인 잠금 — LoginLimiter (IP 단위) 를 여러 IP 로 나눠 우회하는 password spraying 대응.
+//
+// 실패는 store.Users 에 누적 (재시작 / 여러 인스턴스에서 유지). lockoutFreeAttempts 번째 실패부터 잠금,
+// 이후 실패마다 2배: 5회 30초 → 6회 1분 → 7회 2분 … 최대 15분. 마지막 실패 후 store.LoginFailureWindow 가 지나면 0부터.
+//
+// 계정 존재를 드러내지 않기:
+//   - 없는 사용자 이름도 똑같이 누적·잠금 — 잠금 여부로 구분 불가
+//   - 잠기지 않은 시도는 지금처럼 미존재 이름에도 DummyPasswordHash 로 bcrypt
+//   - 잠긴 동안은 존재 여부와 무관하게 비밀번호를 확인하지 않고 같은 응답 (맞는 비밀번호도 통과 못 함)
+//
+// 정상 사용자도 남의 시도로 잠길 수 있으나 (최대 15분) 패스키 로그인은 사용자 이름을 쓰지 않아 영향이 없다.
+
+const (
+	lockoutFreeAttempts = 5
+	lockoutBaseDelay    = 30 * time.Second
+	lockoutMaxDelay     = 15 * time.Minute
+)
+
+// errBadCredentials: 아이디 / 비밀번호 / 2단계 코드 불일치 (사유는 구분하지 않음).
+var errBadCredentials = errors.New("bad credentials")
+
+// errAccountDisabled: 어드민이 비활성화한 계정. 비밀번호가 맞은 뒤에만 알려 준다 (계정 존재 비노출 유지).
+var errAccountDisabled = errors.New("account disabled")
+
+// loginLockedError: 잠금 중 — until 까지 재시도 불가.
+type loginLockedError struct {
+	until time.Time
+}
+
+func (e *loginLockedError) Error() string {
+	return "login locked until " + e.until.Format(time.RFC3339)
+}
+
+// lockoutDelay: 누적 실패 수 → 잠금 시간. lockoutFreeAttempts 미만이면 0.
+func lockoutDelay(failures int) time.Duration {
+	if failures < lockoutFreeAttempts {
+		return 0
+	}
+	d := lockoutBaseDelay
+	for i := lockoutFreeAttempts; i < failures && d < lockoutMaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, lockoutMaxDelay)
+}
+
+// checkLoginLock: 잠금 중이면 *loginLockedError. store 오류는 로그만 남기고 통과 (IP 제한은 여전히 적용).
+func checkLoginLock(ctx context.Context, username string) error {
+	t, err := store.Users.GetLoginThrottle(ctx, username)
+	if err != nil {
+		log.Printf("[lockout] throttle 조회 실패: %v", err)
+		return nil
+	}
+	if t.Locked(time.Now()) {
+		return &loginLockedError{until: t.LockedUntil}
+	}
+	return nil
+}
+
+// recordLoginFailure: 실패 1회 누적. 이번 실패로 잠기면 login.locked 감사 + *loginLockedError, 아니면 errBadCredentials.
+func recordLoginFailure(r *http.Request, username string) error {
+	t, err := store.Users.RecordLoginFailure(r.Context(), username, lockoutDelay)
+	if err != nil {
+		log.Printf("[lockout] 실패 기록 실패: %v", err)
+		return errBadCredentials
+	}
+	if t.Locked(time.Now()) {
+		AuditWarn(r, "login.locked", "username", username, "failures", t.Failures, "locked_until", t.LockedUntil.UTC().Format(time.RFC3339))
+		return &loginLockedError{until: t.LockedUntil}
+	}
+	return errBadCredentials
+}
+
+// clearLoginFailures: 로그인 (2단계 포함) 완료 시 누적 초기화.
+func clearLoginFailures(ctx context.Context, username string) {
+	if err := store.Users.ResetLoginFailures(ctx, username); err != nil {
+		log.Printf("[lockout] 실패 기록 초기화 실패: %v", err)
+	}
+}
+
+// loginFailureMessage: 화면에 보일 문구. 잠금이면 남은 시간, 비활성 계정이면 안내, 아니면 defaultMsg.
+func loginFailureMessage(err error, defaultMsg string) string {
+	if errors.Is(err, errAccountDisabled) {
+		return "사용이 중지된 계정입니다. 관리자에게 문의하세요"
+	}
+	var locked *loginLockedError
+	if !errors.As(err, &locked) {
+		return defaultMsg
+	}
+	wait := time.Until(locked.until)
+	if wait >= time.Minute {
+		return fmt.Sprintf("로그인 시도가 너무 많습니다. %d분 후 다시 시도하세요", int((wait+time.Minute-1)/time.Minute))
+	}
+	return fmt.Sprintf("로그인 시도가 너무 많습니다. %d초 후 다시 시도하세요", max(int((wait+time.Second-1)/time.Second), 1))
+}
@@ -20,12 +20,13 @@ import (
 // Phase-R R-3: TestUsers map 대신 store.Users (Postgres) 조회.
 //
 // 순서:
-//  0. CSRF 검증 (Double-Submit Cookie)
-//  1. 사용자 확인 + bcrypt — timing attack 방어 (미존재 username 에도 dummy bcrypt)
+//  0. CSRF 검증 (Double-Submit Cookie) + client_id / redirect_uri / scope 재검증
+//  1. 사용자 이름 잠금 (lockout.go) 확인 → 사용자 확인 + bcrypt — timing attack 방어 (미존재 username 에도 dummy bcrypt)
+//     TOTP 등록 사용자는 여기서 멈추고 두 번째 단계 (mfa.go) 로 — 세션은 코드 확인 후
 //  2. 세션 고정 방어 — 기존 sid 명시적 폐기 후 새로 발급
 //  3. IdP 세션 생성 + 쿠키 set
-//  4. auth code 발급
-//  5. redirect_uri 로 안전 redirect (Open Redirect 방어)
+//  4. CSRF 쿠키 폐기
+//  5. 동의 필요 시 consent.html, 아니면 auth code 발급 → redirect_uri 로 안전 redirect
 func LoginHandler(tmpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 0. CSRF 검증 — 어떤 처리보다 먼저
@@ -36,99 +37,116 @@ func LoginHandler(tmpl *template.Template) http.HandlerFunc {
 
 		username := r.FormValue("id") // form field name 은 그대로 (login.html 유지)
 		password := r.FormValue("password")
-		state := r.FormValue("state")
-		clientID := r.FormValue("client_id")
-		redirectURI := r.FormValue("redirect_uri")
-		scope := r.FormValue("scope")
-		codeChallenge := r.FormValue("code_challenge")
-		codeChallengeMethod := r.FormValue("code_challenge_method")
-		nonce := r.FormValue("nonce")
-
-		// 1. 사용자 확인 + bcrypt
-		//    미존재 username 도 dummy hash 로 동일 cost bcrypt → 응답 시간으로 enumeration 불가
-		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
-		defer cancel()
-
-		user, err := store.Users.GetByUsername(ctx, username)
-		hashToCompare := models.DummyPasswordHash
-		found := err == nil
-		if found {
-			hashToCompare = user.PasswordHash
+		req := authRequestFromForm(r)
+
+		// client_id / redirect_uri / scope 재검증 — hidden 필드 변조로 다른 곳에 code 를 보내거나 scope 를 넓히지 못하게
+		client, ok := verifyAuthRequest(w, r, tmpl, req)
+		if !ok {
+			return
 		}
 
-		bcryptErr := bcrypt.CompareHashAndPassword(
-			[]byte(hashToCompare),
-			[]byte(password),
-		)
-		if !found || bcryptErr != nil {
-			AuditWarn(r, "login.failed", "username", username, "reason", map[bool]string{true: "bad_password", false: "user_not_found"}[found])
-			// 미존재 user 케이스에서 ErrUserNotFound 외 다른 에러는 로그
-			if err != nil && !errors.Is(err, store.ErrUserNotFound) {
-				// DB 장애 등은 500 도 합리적이지만 학습 단계 사용자 enumeration 방어 우선
-				_ = err
-			}
-
-			csrfToken, _ := NewCSRFToken(w)
-			tmpl.ExecuteTemplate(w, "login.html", loginPageData{
-				ClientName:          clientID,
-				State:               state,
-				ClientID:            clientID,
-				RedirectURI:         redirectURI,
-				Scope:               scope,
-				ErrorMsg:            "아이디 또는 비밀번호가 틀렸습니다",
-				CSRFToken:           csrfToken,
-				CodeChallenge:       codeChallenge,
-				CodeChallengeMethod: codeChallengeMethod,
-				Nonce:               nonce,
-			})
+		// 1. 사용자 이름 잠금 확인 + 사용자 확인 + bcrypt
+		user, err := authenticateUser(r, username, password)
+		if err != nil {
+			renderLoginError(w, tmpl, client, req, loginFailureMessage(err, "아이디 또는 비밀번호가 틀렸습니다"))
 			return
 		}
 
-		// 2. 세션 고정 방어 — 기존 sid 가 있으면 명시적으로 폐기
-		if oldSid, ok := GetIdPSessionID(r); ok {
-			store.IdPSessions.Delete(oldSid)
+		// 1-b. 2단계 인증 등록 사용자 → 코드 입력 화면 (IdP 세션은 아직 없음)
+		if user.TOTPEnabled() {
+			beginSecondFactor(w, r, tmpl, client, req, user)
+			return
 		}
 
-		// 3. 새 IdP 세션 발급 — UserID 는 DB 의 UUID
-		sid, err := store.IdPSessions.Create(user.ID)
+		// 2~3. 세션 고정 방어 + 새 IdP 세션 + 쿠키
+		sid, err := startIdPSession(w, r, user.ID, amrPassword)
 		if err != nil {
 			http.Error(w, "세션 생성 실패", http.StatusInternalServerError)
 			return
 		}
-		if err := SetIdPSessionCookie(w, sid); err != nil {
-			http.Error(w, "세션 쿠키 설정 실패", http.StatusInternalServerError)
-			return
-		}
 
-		// 5. CSRF 쿠키 폐기 (토큰 재사용 방지)
+		// 4. CSRF 쿠키 폐기 (토큰 재사용 방지) + 실패 누적 초기화
 		ClearCSRFToken(w)
+		clearLoginFailures(r.Context(), user.Username)
+		AuditEvent(r, "login.success", "sub", user.ID, "client_id", client.ClientID, "username", username)
 
-		// 6. auth code 발급
-		code, err := generateCode()
-		if err != nil {
-			http.Error(w, "서버 오류", http.StatusInternalServerError)
-			return
+		// 5. 동의가 필요하면 동의 화면, 아니면 auth code 발급 → 안전 redirect (Open Redirect 방어)
+		continueAfterLogin(w, r, tmpl, client, req, user.ID, sid, amrPassword)
+	}
+}
+
+// renderLoginError: 로그인 폼을 에러 메시지와 새 CSRF 토큰으로 다시 렌더 (비밀번호 / 패스키 실패 공통).
+func renderLoginError(w http.ResponseWriter, tmpl *template.Template, client *models.Client, req authRequest, msg string) {
+	csrfToken, _ := NewCSRFToken(w)
+	tmpl.ExecuteTemplate(w, "login.html", loginPageData{
+		ClientName:          client.Name,
+		State:               req.State,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		Prompt:              req.Prompt,
+		ErrorMsg:            msg,
+		CSRFToken:           csrfToken,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+	})
+}
+
+// authenticateUser: username 잠금 확인 + password 확인.
+// 미존재 username 도 dummy hash 로 동일 cost bcrypt → 응답 시간으로 enumeration 불가.
+// 실패 시 login.failed 감사 + 실패 누적 후 errBadCredentials 또는 (잠겼으면) *loginLockedError —
+// 호출자는 잠금 외의 실패 사유를 구분하지 않는다. 성공 시 누적 초기화는 2단계까지 끝낸 호출자 몫.
+// 비밀번호가 맞아도 비활성 계정이면 errAccountDisabled (실패 누적 없음).
+func authenticateUser(r *http.Request, username, password string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	if err := checkLoginLock(ctx, username); err != nil {
+		AuditWarn(r, "login.failed", "username", username, "reason", "locked")
+		return nil, err
+	}
+
+	user, err := store.Users.GetByUsername(ctx, username)
+	hashToCompare := models.DummyPasswordHash
+	found := err == nil
+	if found {
+		hashToCompare = user.PasswordHash
+	}
+
+	bcryptErr := bcrypt.CompareHashAndPassword(
+		[]byte(hashToCompare),
+		[]byte(password),
+	)
+	if !found || bcryptErr != nil {
+		AuditWarn(r, "login.failed", "username", username, "reason", map[bool]string{true: "bad_password", false: "user_not_found"}[found])
+		// 미존재 user 케이스에서 ErrUserNotFound 외 다른 에러는 로그
+		if err != nil && !errors.Is(err, store.ErrUserNotFound) {
+			// DB 장애 등은 500 도 합리적이지만 학습 단계 사용자 enumeration 방어 우선
+			_ = err
 		}
-		store.AuthCodes.Store(code, models.AuthCode{
-			Code:                code,
-			ClientID:            clientID,
-			UserID:              user.ID, // DB 의 UUID
-			RedirectURI:         redirectURI,
-			Scope:               scope,
-			ExpiresAt:           time.Now().Add(10 * time.Minute),
-			CodeChallenge:       codeChallenge,
-			CodeChallengeMethod: codeChallengeMethod,
-			Nonce:               nonce,
-			AuthTime:            time.Now(),
-		})
-		AuditEvent(r, "login.success", "sub", user.ID, "client_id", clientID, "username", username)
-
-		// 7. 안전 redirect (Open Redirect 방어)
-		safeOAuthRedirect(w, r, redirectURI, map[string]string{
-			"code":  code,
-			"state": state,
-		})
+		return nil, recordLoginFailure(r, username)
+	}
+	if user.Disabled {
+		AuditWarn(r, "login.failed", "sub", user.ID, "username", username, "reason", "disabled")
+		return nil, errAccountDisabled
+	}
+	return user, nil
+}
+
+// startIdPSession: 로그인 성공 직후 IdP 세션 발급.
+// 세션 고정 방어 — 기존 sid 가 있으면 명시적으로 폐기한 뒤 새 sid 로 쿠키를 굽는다.
+// amr: 이 로그인에 쓴 인증 수단 (amrPassword / amrPasswordOTP / amrPasskey).
+// 새 sid 를 돌려준다 — 쿠키는 응답에만 실리므로 같은 요청 안에서 code 를 낼 때는 이 값을 넘긴다.
+func startIdPSession(w http.ResponseWriter, r *http.Request, userID string, amr []string) (string, error) {
+	if oldSid, ok := GetIdPSessionID(r); ok {
+		store.IdPSessions.Delete(oldSid)
+	}
+	sid, err := store.IdPSessions.Create(userID, amr, clientIP(r), r.UserAgent())
+	if err != nil {
+		return "", err
 	}
+	return sid, SetIdPSessionCookie(w, sid)
 }
 
 func generateCode() (string, error) {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/ftery0/ouath/server/config"
 	"github.com/ftery0/ouath/server/models"
 	"github.com/ftery0/ouath/server/store"
 	"github.com/ftery0/ouath/server/token"
@@ -16,7 +17,9 @@ import (
 //  1. id_token_hint (있으면) 파싱해서 어떤 client 인지 파악 (선택적 — RP 식별)
 //  2. IdP 세션 폐기 + 쿠키 만료
 //  3. post_logout_redirect_uri 가 있고, id_token_hint 의 client 가 등록한 RedirectURIs / MainURL 과
-//     일치하면 그 URL 로 redirect (state 동승). 매칭 실패면 안전한 안내 페이지 렌더.
+//     일치하면 그 URL 로 redirect (state 동승). 매칭 실패면 redirect 없이 안내만.
+//  4. 세션에 참여한 client 중 frontchannel_logout_uri 가 있으면 logout.html 이 숨은 iframe 으로
+//     그 URI 들을 (iss / sid 붙여) 띄우고, 다 뜨면 (또는 시간 초과) redirect 를 이어 간다.
 func LogoutHandler(tmpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
@@ -24,9 +27,17 @@ func LogoutHandler(tmpl *template.Template) http.HandlerFunc {
 		postLogoutURI := q.Get("post_logout_redirect_uri")
 		state := q.Get("state")
 
-		// 1. IdP 세션 폐기
+		// 1. IdP 세션 폐기 + 그 세션으로 로그인한 client 들에 Back-Channel Logout
+		var sess *models.IdPSession
 		if sid, ok := GetIdPSessionID(r); ok {
+			if s, live := store.IdPSessions.Get(sid); live {
+				sess = s
+			}
 			store.IdPSessions.Delete(sid)
+			if sess != nil {
+				AuditEvent(r, "logout.success", "sub", sess.UserID, "clients", len(sess.ClientIDs))
+				backchannelLogout("logout", sess)
+			}
 		}
 		ClearIdPSessionCookie(w)
 
@@ -41,8 +52,9 @@ func LogoutHandler(tmpl *template.Template) http.HandlerFunc {
 		// 3. post_logout_redirect_uri 안전 검증.
 		// id_token_hint 가 있으면 그 client 의 MainURL/RedirectURIs 와 매칭.
 		// 없으면 등록된 모든 client 를 훑어 매칭 (학습 단순화 — OIDC 표준은 정확 매칭 권장).
+		var target string
 		if postLogoutURI != "" && isPostLogoutURIAllowed(postLogoutURI, redirectClient) {
-			target := postLogoutURI
+			target = postLogoutURI
 			if state != "" {
 				sep := "?"
 				if containsQuery(target) {
@@ -50,17 +62,64 @@ func LogoutHandler(tmpl *template.Template) http.HandlerFunc {
 				}
 				target += sep + "state=" + url.QueryEscape(state)
 			}
+		}
+
+		// 4. 알릴 front-channel 이 없으면 바로 redirect
+		frames := frontchannelLogoutFrames(sess)
+		if len(frames) == 0 && target != "" {
 			http.Redirect(w, r, target, http.StatusFound)
 			return
 		}
 
-		// 검증 실패하거나 redirect 안 줬을 때: 안내 페이지
-		tmpl.ExecuteTemplate(w, "error.html", loginPageData{
-			ErrorMsg: "로그아웃되었습니다.",
+		// iframe 으로 RP 를 띄우는 쪽이지 띄워지는 쪽이 아니다
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Cache-Control", "no-store")
+		tmpl.ExecuteTemplate(w, "logout.html", logoutPageData{
+			Frames:      frames,
+			RedirectURI: target,
 		})
 	}
 }
 
+// logoutPageData: logout.html. RedirectURI 가 비면 iframe 을 다 띄운 뒤 안내만 남긴다.
+type logoutPageData struct {
+	Frames      []string // frontchannel_logout_uri + iss / sid (OIDC Front-Channel Logout 1.0 §2)
+	RedirectURI string   // 검증된 post_logout_redirect_uri (+state)
+}
+
+// frontchannelLogoutFrames: 세션에 참여한 client 의 frontchannel_logout_uri 에 iss / sid 를 붙인 목록.
+func frontchannelLogoutFrames(sess *models.IdPSession) []string {
+	if sess == nil {
+		return nil
+	}
+	var out []string
+	for _, clientID := range sess.ClientIDs {
+		c, ok := store.Clients.GetByClientID(clientID)
+		if !ok || c.FrontchannelLogoutURI == "" {
+			continue
+		}
+		u, err := url.Parse(c.FrontchannelLogoutURI)
+		if err != nil {
+			continue
+		}
+		q := u.Query()
+		q.Set("iss", config.IssuerForDiscovery())
+		q.Set("sid", sess.SID())
+		u.RawQuery = q.Encode()
+		out = append(out, u.String())
+	}
+	return out
+}
+
+// validFrontchannelLogoutURI: https (로컬 http) 절대 URL + 등록된 redirect_uri 중 하나와 같은 origin (Front-Channel Logout 1.0 §2).
+func validFrontchannelLogoutURI(uri string, redirectURIs []string) bool {
+	if !validRegisteredRedirectURI(uri) {
+		return false
+	}
+	u, _ := url.Parse(uri)
+	return contains(clientOrigins(&models.Client{RedirectURIs: redirectURIs}), u.Scheme+"://"+u.Host)
+}
+
 func containsQuery(s string) bool {
 	for i := 0; i < len(s); i++ {
 		if s[i] == '?' {
@@ -81,9 +140,19 @@ func sameOrigin(a, b string) bool {
 	return au.Scheme == bu.Scheme && au.Host == bu.Host
 }
 
+// absoluteHTTPURL: scheme 이 http / https 이고 host 가 있는 절대 URL 인가.
+func absoluteHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 // isPostLogoutURIAllowed: 특정 client (또는 전체 client) 의 등록 URI 와 비교.
 // hint 가 있으면 그 client 만, 없으면 모든 등록 client 의 MainURL / RedirectURIs / sameOrigin 매칭.
+// host 가 있는 http(s) 절대 URL 만 — logout 페이지가 그대로 location 으로 쓰므로 `\\evil.com` · `//evil.com` 같은 값은 처음부터 거른다.
 func isPostLogoutURIAllowed(uri, hintClientID string) bool {
+	if !absoluteHTTPURL(uri) {
+		return false
+	}
 	if hintClientID != "" {
 		cl, ok := store.Clients.GetByClientID(hintClientID)
 		return ok && clientAllowsLogoutURI(cl, uri)
@@ -96,8 +165,9 @@ func isPostLogoutURIAllowed(uri, hintClientID string) bool {
 	return false
 }
 
+// clientAllowsLogoutURI: MainURL 이 빈 client (client_uri 없이 동적 등록) 는 origin 비교에서 뺀다 — 빈 값끼리 같은 origin 이 된다.
 func clientAllowsLogoutURI(cl *models.Client, uri string) bool {
-	if cl.MainURL == uri || sameOrigin(cl.MainURL, uri) {
+	if cl.MainURL != "" && (cl.MainURL == uri || sameOrigin(cl.MainURL, uri)) {
 		return true
 	}
 	for _, u := range cl.RedirectURIs {
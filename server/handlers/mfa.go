@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"html/template"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+	"github.com/ftery0/ouath/server/totp"
+)
+
+// 2단계 인증 (TOTP) — 비밀번호 확인과 IdP 세션 발급 사이의 중간 단계.
+//
+//	POST /oauth/login      비밀번호 OK + TOTP 등록 사용자 → mfa_pending 쿠키 (사용자 ID, 5분) + mfa.html
+//	POST /oauth/login/mfa  인증 앱 코드 또는 복구 코드 OK → IdP 세션 (amr=pwd otp) → 동의 / code
+//
+// mfa_pending 은 IdP 세션 쿠키와 같은 SecureCookie 로 서명·암호화 — 위조로 비밀번호 단계를 건너뛸 수 없다.
+
+const (
+	mfaPendingCookieName = "mfa_pending"
+	mfaPendingTTL        = 5 * time.Minute
+
+	// 복구 코드: 10개, 10자 (xxxxx-xxxxx). 헷갈리는 문자 (0/o, 1/l/i) 제외.
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// amr 조합. store 에 그대로 들어가므로 호출자는 변형하지 않는다.
+var (
+	amrPassword    = []string{models.AMRPassword}
+	amrPasswordOTP = []string{models.AMRPassword, models.AMROTP}
+)
+
+// mfaPending: mfa_pending 쿠키 내용.
+type mfaPending struct {
+	UserID  string
+	Expires int64
+}
+
+// mfaPageData: mfa.html 템플릿 데이터. authRequest 는 hidden 필드로 왕복.
+type mfaPageData struct {
+	authRequest
+	ClientName string
+	CSRFToken  string
+	ErrorMsg   string
+}
+
+func setMFAPending(w http.ResponseWriter, userID string) error {
+	encoded, err := secureCookie.Encode(mfaPendingCookieName, mfaPending{
+		UserID:  userID,
+		Expires: time.Now().Add(mfaPendingTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     mfaPendingCookieName,
+		Value:    encoded,
+		Path:     "/oauth/login",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   isProduction,
+		MaxAge:   int(mfaPendingTTL / time.Second),
+	})
+	return nil
+}
+
+// getMFAPending: 쿠키 없음 / 서명 실패 / 만료 → ("", false).
+func getMFAPending(r *http.Request) (string, bool) {
+	c, err := r.Cookie(mfaPendingCookieName)
+	if err != nil {
+		return "", false
+	}
+	var p mfaPending
+	if err := secureCookie.Decode(mfaPendingCookieName, c.Value, &p); err != nil {
+		return "", false
+	}
+	if time.Now().Unix() > p.Expires {
+		return "", false
+	}
+	return p.UserID, true
+}
+
+func clearMFAPending(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     mfaPendingCookieName,
+		Value:    "",
+		Path:     "/oauth/login",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
+
+// beginSecondFactor: 비밀번호 통과 직후 — 세션 대신 mfa_pending 쿠키를 굽고 코드 입력 화면.
+func beginSecondFactor(w http.ResponseWriter, r *http.Request, tmpl *template.Template, client *models.Client, req authRequest, user *models.User) {
+	if err := setMFAPending(w, user.ID); err != nil {
+		http.Error(w, "세션 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	AuditEvent(r, "login.mfa_challenge", "sub", user.ID, "client_id", client.ClientID, "username", user.Username)
+	renderMFA(w, tmpl, client, req, "")
+}
+
+// renderMFA: 코드 입력 화면 + 새 CSRF 토큰.
+func renderMFA(w http.ResponseWriter, tmpl *template.Template, client *models.Client, req authRequest, errMsg string) {
+	csrfToken, err := NewCSRFToken(w)
+	if err != nil {
+		http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("X-Frame-Options", "DENY")
+	data := mfaPageData{
+		authRequest: req,
+		ClientName:  client.Name,
+		CSRFToken:   csrfToken,
+		ErrorMsg:    errMsg,
+	}
+	if err := tmpl.ExecuteTemplate(w, "mfa.html", data); err != nil {
+		http.Error(w, "템플릿 렌더링 실패", http.StatusInternalServerError)
+	}
+}
+
+// MFAHandler: POST /oauth/login/mfa — 로그인 두 번째 단계.
+//
+// 순서:
+//  0. CSRF 검증 + client_id / redirect_uri / scope 재검증
+//  1. mfa_pending 쿠키 → 비밀번호를 통과한 사용자
+//  2. 사용자 이름 잠금 확인 → 인증 앱 코드 또는 복구 코드 확인 (둘 다 1회용, 실패는 잠금 누적)
+//  3. IdP 세션 (amr=pwd otp) → 동의 화면 또는 code
+func MFAHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		req := authRequestFromForm(r)
+		client, ok := verifyAuthRequest(w, r, tmpl, req)
+		if !ok {
+			return
+		}
+
+		userID, ok := getMFAPending(r)
+		if !ok {
+			renderError(w, tmpl, "로그인 단계가 만료되었습니다. 앱에서 다시 시도하세요")
+			return
+		}
+		user, err := store.Users.GetByID(r.Context(), userID)
+		if err != nil || !user.TOTPEnabled() || user.Disabled {
+			clearMFAPending(w)
+			renderError(w, tmpl, "로그인 단계가 만료되었습니다. 앱에서 다시 시도하세요")
+			return
+		}
+
+		// 코드 대입도 비밀번호와 같은 사용자 이름 잠금에 누적 — 비밀번호를 아는 공격자의 6자리 대입 차단
+		if err := checkLoginLock(r.Context(), user.Username); err != nil {
+			renderMFA(w, tmpl, client, req, loginFailureMessage(err, ""))
+			return
+		}
+		method, ok := verifySecondFactor(r.Context(), user, r.FormValue("code"))
+		if !ok {
+			AuditWarn(r, "login.mfa_failed", "sub", user.ID, "client_id", client.ClientID)
+			renderMFA(w, tmpl, client, req, loginFailureMessage(recordLoginFailure(r, user.Username), "인증 코드가 올바르지 않습니다"))
+			return
+		}
+
+		clearMFAPending(w)
+		clearLoginFailures(r.Context(), user.Username)
+		sid, err := startIdPSession(w, r, user.ID, amrPasswordOTP)
+		if err != nil {
+			http.Error(w, "세션 생성 실패", http.StatusInternalServerError)
+			return
+		}
+		ClearCSRFToken(w)
+		AuditEvent(r, "login.success", "sub", user.ID, "client_id", client.ClientID, "username", user.Username, "second_factor", method)
+
+		continueAfterLogin(w, r, tmpl, client, req, user.ID, sid, amrPasswordOTP)
+	}
+}
+
+// verifySecondFactor: 인증 앱 6자리 코드 또는 복구 코드. 통과한 수단 ("totp" / "recovery") 을 감사용으로 반환.
+// 둘 다 1회용 — TOTP 는 통과한 step 을, 복구 코드는 hash 자체를 store 에서 소비한다.
+func verifySecondFactor(ctx context.Context, user *models.User, code string) (string, bool) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return "", false
+	}
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		if err := store.Users.ConsumeTOTPStep(ctx, user.ID, step); err != nil {
+			return "", false
+		}
+		return "totp", true
+	}
+	if rc := normalizeRecoveryCode(code); rc != "" {
+		if err := store.Users.ConsumeRecoveryCode(ctx, user.ID, store.HashToken(rc)); err == nil {
+			return "recovery", true
+		}
+	}
+	return "", false
+}
+
+// generateRecoveryCodes: 표시용 (xxxxx-xxxxx) 평문과 저장용 hash 를 같이 만든다.
+func generateRecoveryCodes() (display, hashes []string, err error) {
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeLength)
+		for j := range b {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, nil, err
+			}
+			b[j] = recoveryCodeAlphabet[n.Int64()]
+		}
+		code := string(b)
+		display = append(display, code[:5]+"-"+code[5:])
+		hashes = append(hashes, store.HashToken(code))
+	}
+	return display, hashes, nil
+}
+
+// normalizeRecoveryCode: 소문자 + 하이픈/공백 제거. 길이가 다르면 "".
+func normalizeRecoveryCode(s string) string {
+	s = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(s))
+	if len(s) != recoveryCodeLength {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// client 가 등록한 URL (jwks_uri, backchannel_logout_uri) 로 IdP 가 직접 요청을 보낼 때의 SSRF 방어.
+//
+// 등록 시 validOutboundURI 로 URL 모양을 거르고, 실제 접속은 outboundDialControl 이 dial 직전의 IP 로 다시 본다.
+// 등록 뒤 DNS 가 내부 주소로 바뀌거나 redirect 로 돌아가도 마지막 관문은 dial 이다.
+// loopback 은 production 이 아닐 때만 허용 (로컬 개발 / 테스트). 사설 / link-local 은 항상 막는다.
+
+// newOutboundHTTPClient: outboundDialControl 을 거치는 client.
+// 프록시를 쓰면 프록시 IP 만 검사되므로 쓰지 않고, 요청마다 새로 dial 하도록 연결을 재사용하지 않는다 (호출이 드물다).
+func newOutboundHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: outboundDialControl}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			DisableKeepAlives:   true,
+		},
+	}
+}
+
+// outboundDialControl: net.Dialer.Control — 이름 해석이 끝난 접속 대상 IP 를 검사.
+func outboundDialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !allowedOutboundIP(ip) {
+		return fmt.Errorf("내부 주소로의 접속 차단: %s", ip)
+	}
+	return nil
+}
+
+// allowedOutboundIP: 공인 unicast 만. loopback 은 production 이 아닐 때만.
+func allowedOutboundIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsLoopback() {
+		return !isProduction
+	}
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
+
+// validOutboundURI: 등록 시 검증 — https 절대 URL (fragment 없음), IP 리터럴이면 allowedOutboundIP.
+// production 이 아니면 http://localhost / 127.0.0.1 도 받는다.
+func validOutboundURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return !isProduction && (u.Scheme == "https" || u.Scheme == "http")
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !allowedOutboundIP(ip) {
+			return false
+		}
+		if ip.Unmap().IsLoopback() {
+			return u.Scheme == "https" || u.Scheme == "http"
+		}
+	}
+	return u.Scheme == "https"
+}
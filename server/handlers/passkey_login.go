@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+	"github.com/ftery0/ouath/server/webauthn"
+)
+
+// 패스키 (WebAuthn) 로그인 — 비밀번호 대신 인증기 서명으로 IdP 세션을 만든다.
+//
+//	POST /oauth/passkey/login/options  CSRF 확인 → challenge 를 webauthn_ceremony 쿠키에 + 옵션 JSON
+//	(브라우저)                          navigator.credentials.get() — 사용자가 패스키를 고르고 기기 잠금 해제
+//	POST /oauth/passkey/login          서명 검증 → IdP 세션 (amr=hwk mfa) → 동의 / code (LoginHandler 와 같은 뒷단계)
+//
+// challenge 는 mfa_pending 처럼 SecureCookie 로 서명·암호화해 서버 상태 없이 들고 다니고, 검증 시 바로 지워 1회용.
+// 패스키는 UV (기기 잠금 해제) 를 요구하므로 TOTP 두 번째 단계를 다시 묻지 않는다.
+
+const (
+	webauthnCookieName = "webauthn_ceremony"
+
+	ceremonyLogin    = "login"
+	ceremonyRegister = "register"
+)
+
+// amrPasskey: 기기 소지 (hwk) + 기기 잠금 해제 (UV) → 다중 요소.
+var amrPasskey = []string{models.AMRHardwareKey, models.AMRMultiFactor}
+
+// relyingParty: WebAuthnInit 에서 주입.
+var relyingParty webauthn.RelyingParty
+
+// WebAuthnInit: main 에서 한 번 호출. RP ID / origin 은 config (issuer 에서 도출) 값.
+func WebAuthnInit(rp webauthn.RelyingParty) {
+	relyingParty = rp
+}
+
+// webauthnCeremony: webauthn_ceremony 쿠키 내용. Purpose 로 등록 challenge 를 로그인에 (또는 반대로) 못 쓰게.
+// UserID 는 등록 ceremony 에서만 — 시작한 사용자와 끝내는 세션이 같아야 한다.
+type webauthnCeremony struct {
+	Purpose   string
+	Challenge []byte
+	UserID    string
+	Expires   int64
+}
+
+// beginCeremony: 새 challenge 를 만들어 쿠키에 굽는다.
+func beginCeremony(w http.ResponseWriter, purpose, userID string) ([]byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := secureCookie.Encode(webauthnCookieName, webauthnCeremony{
+		Purpose:   purpose,
+		Challenge: challenge,
+		UserID:    userID,
+		Expires:   time.Now().Add(webauthn.Timeout).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     webauthnCookieName,
+		Value:    encoded,
+		Path:     "/oauth",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   isProduction,
+		MaxAge:   int(webauthn.Timeout / time.Second),
+	})
+	return challenge, nil
+}
+
+// takeCeremony: 쿠키의 challenge 를 꺼내고 즉시 폐기 (성공 / 실패 무관 1회용).
+// 목적 / 사용자 불일치 · 만료 · 위조는 모두 (nil, false).
+func takeCeremony(w http.ResponseWriter, r *http.Request, purpose, userID string) ([]byte, bool) {
+	c, err := r.Cookie(webauthnCookieName)
+	if err != nil {
+		return nil, false
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     webauthnCookieName,
+		Value:    "",
+		Path:     "/oauth",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+	var cer webauthnCeremony
+	if err := secureCookie.Decode(webauthnCookieName, c.Value, &cer); err != nil {
+		return nil, false
+	}
+	if cer.Purpose != purpose || cer.UserID != userID || time.Now().Unix() > cer.Expires {
+		return nil, false
+	}
+	return cer.Challenge, true
+}
+
+// PasskeyLoginOptionsHandler: POST /oauth/passkey/login/options — 로그인 폼의 csrf_token 으로 보호.
+// allowCredentials 는 비워 둔다 — 사용자 이름을 받지 않으므로 계정 존재 여부도 드러나지 않는다.
+func PasskeyLoginOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := VerifyCSRFToken(r); err != nil {
+		http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+		return
+	}
+	challenge, err := beginCeremony(w, ceremonyLogin, "")
+	if err != nil {
+		http.Error(w, "challenge 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, relyingParty.RequestOptions(challenge, nil))
+}
+
+// PasskeyLoginHandler: POST /oauth/passkey/login — navigator.credentials.get() 결과 (base64url 폼 필드).
+//
+// 순서:
+//  0. CSRF 검증 + client_id / redirect_uri / scope 재검증
+//  1. ceremony 쿠키의 challenge (1회용)
+//  2. credential_id 로 패스키 조회 + userHandle 일치 확인
+//  3. 서명 · origin · rpIdHash · UV · 카운터 검증 → 카운터 갱신
+//  4. 이후는 LoginHandler 와 같다 — 세션 고정 방어 + IdP 세션 + 동의 / code
+func PasskeyLoginHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		req := authRequestFromForm(r)
+		client, ok := verifyAuthRequest(w, r, tmpl, req)
+		if !ok {
+			return
+		}
+
+		fail := func(reason string, kv ...any) {
+			AuditWarn(r, "login.failed", append([]any{"method", "passkey", "reason", reason}, kv...)...)
+			renderLoginError(w, tmpl, client, req, "패스키로 로그인하지 못했습니다. 다시 시도하거나 비밀번호로 로그인하세요")
+		}
+
+		challenge, ok := takeCeremony(w, r, ceremonyLogin, "")
+		if !ok {
+			fail("no_ceremony")
+			return
+		}
+		credentialID := r.FormValue("credential_id")
+		clientData, err1 := webauthn.B64.DecodeString(r.FormValue("client_data_json"))
+		authData, err2 := webauthn.B64.DecodeString(r.FormValue("authenticator_data"))
+		signature, err3 := webauthn.B64.DecodeString(r.FormValue("signature"))
+		userHandle, err4 := webauthn.B64.DecodeString(r.FormValue("user_handle"))
+		if credentialID == "" || errors.Join(err1, err2, err3, err4) != nil {
+			fail("malformed")
+			return
+		}
+
+		pk, err := store.Passkeys.Get(r.Context(), credentialID)
+		if err != nil {
+			fail("unknown_credential")
+			return
+		}
+		if len(userHandle) != 0 && !bytes.Equal(userHandle, []byte(pk.UserID)) {
+			fail("user_handle_mismatch", "sub", pk.UserID)
+			return
+		}
+		signCount, err := relyingParty.VerifyAssertion(challenge, pk.PublicKey, pk.SignCount, clientData, authData, signature)
+		if err != nil {
+			reason := "verification"
+			if errors.Is(err, webauthn.ErrSignCount) {
+				reason = "sign_count" // 복제된 인증기 의심
+			}
+			fail(reason, "sub", pk.UserID, "error", err.Error())
+			return
+		}
+		if err := store.Passkeys.UpdateSignCount(r.Context(), pk.ID, signCount); err != nil {
+			http.Error(w, "패스키 갱신 실패", http.StatusInternalServerError)
+			return
+		}
+		user, err := store.Users.GetByID(r.Context(), pk.UserID)
+		if err != nil {
+			fail("user_not_found", "sub", pk.UserID)
+			return
+		}
+		if user.Disabled {
+			AuditWarn(r, "login.failed", "method", "passkey", "reason", "disabled", "sub", user.ID)
+			renderLoginError(w, tmpl, client, req, loginFailureMessage(errAccountDisabled, ""))
+			return
+		}
+
+		sid, err := startIdPSession(w, r, user.ID, amrPasskey)
+		if err != nil {
+			http.Error(w, "세션 생성 실패", http.StatusInternalServerError)
+			return
+		}
+		ClearCSRFToken(w)
+		AuditEvent(r, "login.success", "sub", user.ID, "client_id", client.ClientID, "username", user.Username, "method", "passkey")
+
+		continueAfterLogin(w, r, tmpl, client, req, user.ID, sid, amrPasskey)
+	}
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+	"github.com/ftery0/ouath/server/webauthn"
+)
+
+// 패스키 등록 / 삭제 — GET·POST /oauth/passkeys. IdP 세션이 있는 사용자 본인만.
+//
+//	POST /oauth/passkeys/options  CSRF + 세션 → challenge (사용자 ID 에 묶음) + 등록 옵션 JSON
+//	(브라우저)                     navigator.credentials.create()
+//	POST /oauth/passkeys          action=register: attestation 검증 → webauthn_credentials 저장
+//	                              action=delete  : 본인 credential 삭제
+
+const passkeyNameMaxLen = 64
+
+// passkeysPageData: passkeys.html 템플릿 데이터.
+type passkeysPageData struct {
+	Username  string
+	Passkeys  []*models.Passkey
+	CSRFToken string
+	ErrorMsg  string
+	Notice    string
+}
+
+// PasskeysGetHandler: GET /oauth/passkeys — 등록된 패스키 목록 + 추가 버튼.
+func PasskeysGetHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := sessionUser(w, r, tmpl)
+		if !ok {
+			return
+		}
+		renderPasskeys(w, r, tmpl, user, "", "")
+	}
+}
+
+// PasskeyRegisterOptionsHandler: POST /oauth/passkeys/options — 등록 ceremony 시작 (fetch 로 호출).
+// 이미 등록한 credential 은 excludeCredentials 로 넘겨 같은 인증기에 중복 등록되지 않게.
+func PasskeyRegisterOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := VerifyCSRFToken(r); err != nil {
+		http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+		return
+	}
+	sess, ok := currentIdPSession(r)
+	if !ok {
+		http.Error(w, "로그인 세션이 없습니다", http.StatusUnauthorized)
+		return
+	}
+	user, err := store.Users.GetByID(r.Context(), sess.UserID)
+	if err != nil {
+		http.Error(w, "사용자 정보를 찾을 수 없습니다", http.StatusUnauthorized)
+		return
+	}
+	existing, err := store.Passkeys.ListByUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "패스키 조회 실패", http.StatusInternalServerError)
+		return
+	}
+	var exclude [][]byte
+	for _, pk := range existing {
+		if id, err := webauthn.B64.DecodeString(pk.ID); err == nil {
+			exclude = append(exclude, id)
+		}
+	}
+
+	challenge, err := beginCeremony(w, ceremonyRegister, user.ID)
+	if err != nil {
+		http.Error(w, "challenge 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	displayName := user.DisplayName
+	if displayName == "" {
+		displayName = user.Username
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, relyingParty.CreationOptions(challenge, []byte(user.ID), user.Username, displayName, exclude))
+}
+
+// PasskeysPostHandler: POST /oauth/passkeys — action=register / action=delete. 둘 다 CSRF + IdP 세션 필수.
+func PasskeysPostHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		user, ok := sessionUser(w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		switch r.FormValue("action") {
+		case "register":
+			challenge, ok := takeCeremony(w, r, ceremonyRegister, user.ID)
+			if !ok {
+				renderPasskeys(w, r, tmpl, user, "등록 시간이 지났습니다. 다시 시도하세요", "")
+				return
+			}
+			clientData, err1 := webauthn.B64.DecodeString(r.FormValue("client_data_json"))
+			attObj, err2 := webauthn.B64.DecodeString(r.FormValue("attestation_object"))
+			if errors.Join(err1, err2) != nil {
+				renderPasskeys(w, r, tmpl, user, "패스키 응답 형식이 올바르지 않습니다", "")
+				return
+			}
+			cred, err := relyingParty.VerifyRegistration(challenge, clientData, attObj)
+			if err != nil {
+				AuditWarn(r, "passkey.register_failed", "sub", user.ID, "error", err.Error())
+				renderPasskeys(w, r, tmpl, user, "패스키를 확인하지 못했습니다. 다시 시도하세요", "")
+				return
+			}
+			pk := &models.Passkey{
+				ID:        webauthn.B64.EncodeToString(cred.ID),
+				UserID:    user.ID,
+				Name:      passkeyName(r.FormValue("name")),
+				PublicKey: cred.PublicKey,
+				SignCount: cred.SignCount,
+			}
+			if err := store.Passkeys.Create(r.Context(), pk); err != nil {
+				if errors.Is(err, store.ErrPasskeyExists) {
+					renderPasskeys(w, r, tmpl, user, "이미 등록된 패스키입니다", "")
+					return
+				}
+				http.Error(w, "패스키 저장 실패", http.StatusInternalServerError)
+				return
+			}
+			AuditEvent(r, "passkey.registered", "sub", user.ID, "credential_id", pk.ID, "backup_eligible", cred.BackupEligible)
+			renderPasskeys(w, r, tmpl, user, "", "패스키를 등록했습니다. 다음 로그인부터 비밀번호 없이 쓸 수 있습니다")
+
+		case "delete":
+			credentialID := r.FormValue("credential_id")
+			if err := store.Passkeys.Delete(r.Context(), user.ID, credentialID); err != nil {
+				renderPasskeys(w, r, tmpl, user, "패스키를 찾을 수 없습니다", "")
+				return
+			}
+			AuditWarn(r, "passkey.deleted", "sub", user.ID, "credential_id", credentialID)
+			renderPasskeys(w, r, tmpl, user, "", "패스키를 삭제했습니다. 인증기 (기기 / 비밀번호 관리자) 에 남은 항목도 지워 주세요")
+
+		default:
+			http.Error(w, "알 수 없는 action", http.StatusBadRequest)
+		}
+	}
+}
+
+// passkeyName: 사용자가 붙인 이름 — 공백 정리 + 길이 제한. 비면 기본값.
+func passkeyName(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	for utf8.RuneCountInString(s) > passkeyNameMaxLen {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	if s == "" {
+		return "패스키"
+	}
+	return s
+}
+
+// renderPasskeys: 목록 조회 + 새 CSRF 토큰 + clickjacking 차단 후 렌더.
+func renderPasskeys(w http.ResponseWriter, r *http.Request, tmpl *template.Template, user *models.User, errMsg, notice string) {
+	list, err := store.Passkeys.ListByUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "패스키 조회 실패", http.StatusInternalServerError)
+		return
+	}
+	csrfToken, err := NewCSRFToken(w)
+	if err != nil {
+		http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+	data := passkeysPageData{
+		Username:  user.Username,
+		Passkeys:  list,
+		CSRFToken: csrfToken,
+		ErrorMsg:  errMsg,
+		Notice:    notice,
+	}
+	if err := tmpl.ExecuteTemplate(w, "passkeys.html", data); err != nil {
+		http.Error(w, "템플릿 렌더링 실패", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/ftery0/ouath/server/config"
+	"github.com/ftery0/ouath/server/mail"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// 비밀번호 재설정 (self-service).
+//
+//	GET/POST /oauth/password/forgot  아이디 또는 이메일 → 재설정 링크 메일 (계정 유무와 무관하게 같은 응답)
+//	GET      /oauth/password/reset   ?token= 확인 후 새 비밀번호 폼
+//	POST     /oauth/password/reset   token 소비 (1회용) → 비밀번호 교체 → 해당 사용자의 IdP 세션 / refresh token 전부 폐기
+//
+// 링크의 token 은 AuthCode 와 같이 store 에 hash 로만 남는다.
+
+// passwordResetTTL: 링크 유효 시간.
+const passwordResetTTL = 30 * time.Minute
+
+// mailSendTimeout: 백그라운드 발송 한 건의 제한 시간.
+const mailSendTimeout = 30 * time.Second
+
+// mailer: MailerInit 에서 주입. 기본은 로그 출력 (테스트 / 설정 전).
+var mailer mail.Mailer = &mail.LogMailer{}
+
+// MailerInit: main 에서 한 번 호출 (config 의 SMTP 설정 → SMTPMailer, 없으면 LogMailer).
+func MailerInit(m mail.Mailer) {
+	mailer = m
+}
+
+// passwordResetPageData: password_reset.html 템플릿 데이터.
+//
+//	Step: request (아이디 / 이메일 입력) · sent (메일 안내) · reset (새 비밀번호) · done · invalid (만료 / 사용된 링크)
+type passwordResetPageData struct {
+	Step      string
+	Login     string // request 에러 시 입력값 보존
+	Token     string
+	CSRFToken string
+	ErrorMsg  string
+}
+
+// PasswordForgotGetHandler: GET /oauth/password/forgot — 아이디 / 이메일 입력 폼.
+func PasswordForgotGetHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderPasswordReset(w, tmpl, passwordResetPageData{Step: "request"})
+	}
+}
+
+// PasswordForgotPostHandler: POST /oauth/password/forgot.
+// 계정이 없거나 이메일이 없어도 "sent" 를 그대로 보여 준다 (계정 존재 여부 노출 방지).
+// 조회 이후 (토큰 저장 + 발송) 는 goroutine 으로 — 응답 시간으로도 구분되지 않게.
+func PasswordForgotPostHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		login := strings.TrimSpace(r.FormValue("login"))
+		if login == "" {
+			renderPasswordReset(w, tmpl, passwordResetPageData{Step: "request", ErrorMsg: "아이디 또는 이메일을 입력하세요"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+		user, err := lookupResetUser(ctx, login)
+		switch {
+		case err == nil && user.Email != "":
+			go func(user *models.User, ip string) {
+				ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+				defer cancel()
+				if err := sendPasswordReset(ctx, user, ip); err != nil {
+					log.Printf("[password_reset] sub=%s: %v", user.ID, err)
+				}
+			}(user, clientIP(r))
+			AuditEvent(r, "password.reset_requested", "sub", user.ID)
+		case err == nil:
+			AuditWarn(r, "password.reset_requested", "sub", user.ID, "reason", "no_email")
+		case errors.Is(err, store.ErrUserNotFound):
+			AuditWarn(r, "password.reset_requested", "reason", "unknown_user")
+		default:
+			http.Error(w, "사용자 조회 실패", http.StatusInternalServerError)
+			return
+		}
+		ClearCSRFToken(w)
+		renderPasswordReset(w, tmpl, passwordResetPageData{Step: "sent"})
+	}
+}
+
+// lookupResetUser: '@' 가 있으면 이메일, 아니면 아이디로 조회.
+func lookupResetUser(ctx context.Context, login string) (*models.User, error) {
+	if strings.Contains(login, "@") {
+		return store.Users.GetByEmail(ctx, login)
+	}
+	return store.Users.GetByUsername(ctx, login)
+}
+
+// sendPasswordReset: 새 링크 저장 + 메일 발송.
+// 찾기 화면은 요청 처리와 분리된 goroutine 에서 (실패는 로그로만), 어드민 강제 재설정은 동기로 호출.
+func sendPasswordReset(ctx context.Context, user *models.User, ip string) error {
+	tok, err := generateCode() // 256bit
+	if err != nil {
+		return fmt.Errorf("token 생성 실패: %w", err)
+	}
+	pr := &models.PasswordReset{
+		Token:     tok,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+	if err := store.PasswordResets.Save(ctx, pr); err != nil {
+		return fmt.Errorf("저장 실패: %w", err)
+	}
+	link := config.IssuerForDiscovery() + "/oauth/password/reset?token=" + url.QueryEscape(tok)
+	body := fmt.Sprintf(`%s 님,
+
+비밀번호 재설정 요청을 받았습니다. 아래 링크에서 새 비밀번호를 정하세요.
+링크는 %d분 동안 한 번만 쓸 수 있습니다.
+
+%s
+
+요청한 적이 없다면 이 메일을 무시하세요. 비밀번호는 바뀌지 않습니다.
+(요청 IP: %s)
+`, user.Username, int(passwordResetTTL/time.Minute), link, ip)
+
+	if err := mailer.Send(ctx, mail.Message{To: user.Email, Subject: "비밀번호 재설정 안내", Body: body}); err != nil {
+		return fmt.Errorf("메일 발송 실패: %w", err)
+	}
+	return nil
+}
+
+// PasswordResetGetHandler: GET /oauth/password/reset?token= — 링크 확인 후 새 비밀번호 폼.
+// 여기서는 소비하지 않는다 (메일 클라이언트의 링크 미리보기가 토큰을 써 버리지 않게).
+func PasswordResetGetHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tok := r.URL.Query().Get("token")
+		pr, err := store.PasswordResets.Get(r.Context(), tok)
+		if err != nil || pr.Expired(time.Now()) {
+			renderPasswordReset(w, tmpl, passwordResetPageData{Step: "invalid"})
+			return
+		}
+		csrfToken, err := NewCSRFToken(w)
+		if err != nil {
+			http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+			return
+		}
+		renderPasswordReset(w, tmpl, passwordResetPageData{Step: "reset", Token: tok, CSRFToken: csrfToken})
+	}
+}
+
+// PasswordResetPostHandler: POST /oauth/password/reset.
+//
+// 순서:
+//  0. CSRF 검증
+//  1. 비밀번호 규칙 (register 와 같음) — 틀리면 토큰을 쓰지 않고 폼 재표시
+//  2. token 소비 (atomic, 1회용) + 만료 확인
+//  3. bcrypt → 비밀번호 교체
+//  4. 사용자의 IdP 세션 · refresh token · 남은 재설정 링크 폐기, 로그인 실패 기록 초기화
+func PasswordResetPostHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		tok := r.FormValue("token")
+		password := r.FormValue("password")
+
+		// 1. 비밀번호 규칙
+		formError := func(msg string) {
+			csrfToken, _ := NewCSRFToken(w)
+			renderPasswordReset(w, tmpl, passwordResetPageData{Step: "reset", Token: tok, CSRFToken: csrfToken, ErrorMsg: msg})
+		}
+		if len(password) < minPasswordLen {
+			formError("비밀번호는 8 자 이상")
+			return
+		}
+		if password != r.FormValue("password_confirm") {
+			formError("비밀번호가 일치하지 않습니다")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		// 2. 소비
+		pr, err := store.PasswordResets.Consume(ctx, tok)
+		if err != nil || pr.Expired(time.Now()) {
+			AuditWarn(r, "password.reset_failed", "reason", "invalid_token")
+			renderPasswordReset(w, tmpl, passwordResetPageData{Step: "invalid"})
+			return
+		}
+		user, err := store.Users.GetByID(ctx, pr.UserID)
+		if err != nil {
+			renderPasswordReset(w, tmpl, passwordResetPageData{Step: "invalid"})
+			return
+		}
+
+		// 3. 교체
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+		if err != nil {
+			http.Error(w, "비밀번호 hash 실패", http.StatusInternalServerError)
+			return
+		}
+		if err := store.Users.UpdatePassword(ctx, user.ID, string(hash)); err != nil {
+			http.Error(w, "비밀번호 변경 실패", http.StatusInternalServerError)
+			return
+		}
+
+		// 4. 기존 로그인 전부 끊기 — 비밀번호를 훔친 쪽이 세션 / refresh token 으로 버티지 못하게
+		sessions, err1 := store.IdPSessions.DeleteByUser(user.ID)
+		backchannelLogout("password_reset", sessions...)
+		err2 := revokeAccessTokensIssuedBefore(ctx, user.ID, "")
+		tokens, err3 := store.RefreshTokens.RevokeByUser(ctx, user.ID)
+		_, err4 := store.PasswordResets.DeleteByUser(ctx, user.ID)
+		err5 := store.Users.ResetLoginFailures(ctx, user.Username)
+		_, err6 := store.AdminSessions.DeleteByUser(ctx, user.ID)
+		if err := errors.Join(err1, err2, err3, err4, err5, err6); err != nil {
+			log.Printf("[password_reset] 세션 / 토큰 폐기 실패 sub=%s: %v", user.ID, err)
+		}
+		ClearCSRFToken(w)
+		AuditWarn(r, "password.reset", "sub", user.ID, "revoked_sessions", len(sessions), "revoked_refresh_tokens", tokens)
+
+		renderPasswordReset(w, tmpl, passwordResetPageData{Step: "done"})
+	}
+}
+
+// renderPasswordReset: 토큰이 URL / 폼에 실리는 페이지 — Referer 로 새지 않게 + 캐시 금지 + clickjacking 차단.
+func renderPasswordReset(w http.ResponseWriter, tmpl *template.Template, data passwordResetPageData) {
+	if data.Step == "request" && data.CSRFToken == "" {
+		csrfToken, err := NewCSRFToken(w)
+		if err != nil {
+			http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+			return
+		}
+		data.CSRFToken = csrfToken
+	}
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	if err := tmpl.ExecuteTemplate(w, "password_reset.html", data); err != nil {
+		http.Error(w, "템플릿 렌더링 실패", http.StatusInternalServerError)
+	}
+}
@@ -50,9 +50,8 @@ func RegisterGetHandler(tmpl *template.Template) http.HandlerFunc {
 		state := q.Get("state")
 		scope := q.Get("scope")
 
-		client, ok := store.Clients.GetByClientID(clientID)
-		if !ok || !containsURI(client.RedirectURIs, redirectURI) {
-			renderError(w, tmpl, "유효하지 않은 client_id 또는 redirect_uri 입니다")
+		client, ok := verifyAuthRequest(w, r, tmpl, authRequest{ClientID: clientID, RedirectURI: redirectURI, Scope: scope})
+		if !ok {
 			return
 		}
 
@@ -85,7 +84,7 @@ func RegisterGetHandler(tmpl *template.Template) http.HandlerFunc {
 //  3. 중복 username 체크
 //  4. bcrypt(password) → users INSERT
 //  5. 세션 고정 방어 + IdP 세션 발급 + 쿠키 set
-//  6. auth code 발급 → redirect_uri 로 안전 redirect
+//  6. (동의 필요 시 consent.html) auth code 발급 → redirect_uri 로 안전 redirect
 func RegisterPostHandler(tmpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 0. CSRF
@@ -108,10 +107,9 @@ func RegisterPostHandler(tmpl *template.Template) http.HandlerFunc {
 		state := r.FormValue("state")
 		scope := r.FormValue("scope")
 
-		// 1. client_id / redirect_uri 재검증
-		client, ok := store.Clients.GetByClientID(clientID)
-		if !ok || !containsURI(client.RedirectURIs, redirectURI) {
-			renderError(w, tmpl, "유효하지 않은 client_id 또는 redirect_uri 입니다")
+		// 1. client_id / redirect_uri / scope 재검증
+		client, ok := verifyAuthRequest(w, r, tmpl, authRequestFromForm(r))
+		if !ok {
 			return
 		}
 
@@ -167,7 +165,7 @@ func RegisterPostHandler(tmpl *template.Template) http.HandlerFunc {
 			PasswordHash: string(hash),
 			DisplayName:  username,
 			Email:        email,
-			// EmailVerified: 학습 범위 외 (실 운영에서는 메일 확인 후 true).
+			// EmailVerified: false — 아래에서 보내는 인증 링크를 열어야 true.
 		}
 		if err := store.Users.Create(ctx, newUser); err != nil {
 			if errors.Is(err, store.ErrUserAlreadyExists) {
@@ -179,42 +177,19 @@ func RegisterPostHandler(tmpl *template.Template) http.HandlerFunc {
 		}
 
 		// 5. 세션 고정 방어 + 새 IdP 세션
-		if oldSid, ok := GetIdPSessionID(r); ok {
-			store.IdPSessions.Delete(oldSid)
-		}
-		sid, err := store.IdPSessions.Create(newUser.ID)
+		sid, err := startIdPSession(w, r, newUser.ID, amrPassword)
 		if err != nil {
 			http.Error(w, "세션 생성 실패", http.StatusInternalServerError)
 			return
 		}
-		if err := SetIdPSessionCookie(w, sid); err != nil {
-			http.Error(w, "세션 쿠키 설정 실패", http.StatusInternalServerError)
-			return
-		}
 		ClearCSRFToken(w)
 
-		// 6. auth code 발급 + 안전 redirect
-		code, err := generateCode()
-		if err != nil {
-			http.Error(w, "서버 오류", http.StatusInternalServerError)
-			return
-		}
-		store.AuthCodes.Store(code, models.AuthCode{
-			Code:                code,
-			ClientID:            clientID,
-			UserID:              newUser.ID,
-			RedirectURI:         redirectURI,
-			Scope:               scope,
-			ExpiresAt:           time.Now().Add(10 * time.Minute),
-			CodeChallenge:       r.FormValue("code_challenge"),
-			CodeChallengeMethod: r.FormValue("code_challenge_method"),
-			Nonce:               r.FormValue("nonce"),
-			AuthTime:            time.Now(),
-		})
 		AuditEvent(r, "register.success", "sub", newUser.ID, "client_id", clientID, "username", username)
-		safeOAuthRedirect(w, r, redirectURI, map[string]string{
-			"code":  code,
-			"state": state,
-		})
+		if email != "" {
+			go sendEmailVerification(newUser)
+		}
+
+		// 6. 동의 필요 시 동의 화면, 아니면 auth code 발급 + 안전 redirect
+		continueAfterLogin(w, r, tmpl, client, authRequestFromForm(r), newUser.ID, sid, amrPassword)
 	}
 }
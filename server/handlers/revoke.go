@@ -1,43 +1,88 @@
 package handlers
 
 import (
+	"context"
+	"log"
 	"net/http"
 	"sync"
+	"time"
 
-	"github.com/ftery0/ouath/server/models"
 	"github.com/ftery0/ouath/server/store"
 	"github.com/ftery0/ouath/server/token"
 )
 
 // revokedAccessTokens: access token 이 JWT 라 stateless 검증이지만,
 // revoke 호출 시점부터 만료(<=15분)까지는 블로킹 필요. 메모리 set 으로 충분 (TTL = 만료시각).
+//
+// refresh token family 단위 폐기 (fid claim) 와 어드민 kill switch (사용자 / client / 사용자·client 쌍 단위) 는
+// store.AccessTokenCutoffs — 인스턴스가 여럿이어도, 재시작해도 같은 차단을 보도록 메모리가 아니라 store 에 둔다.
 var (
 	revokedAccessTokens   = make(map[string]struct{})
 	revokedAccessTokensMu sync.RWMutex
 )
 
+// accessTokenMaxTTL: 차단 기준 시각 유지 기한. token.Create 의 access token 수명 (15분) 과 맞춘다.
+const accessTokenMaxTTL = 15 * time.Minute
+
 // IsAccessTokenRevoked: userinfo/introspect 가 부르는 헬퍼.
-func IsAccessTokenRevoked(tokenStr string) bool {
+// 토큰 단위 revoke + (claims 가 있으면) family 단위 revoke + kill switch 기준 시각을 함께 본다.
+// 기준 시각을 읽지 못하면 차단된 것으로 본다 (fail closed).
+func IsAccessTokenRevoked(ctx context.Context, tokenStr string, claims *token.Claims) bool {
 	revokedAccessTokensMu.RLock()
-	defer revokedAccessTokensMu.RUnlock()
-	_, ok := revokedAccessTokens[tokenStr]
-	return ok
+	_, revoked := revokedAccessTokens[tokenStr]
+	revokedAccessTokensMu.RUnlock()
+	if revoked || claims == nil {
+		return revoked
+	}
+	if claims.FamilyID != "" {
+		cutoff, err := store.AccessTokenCutoffs.LatestFamily(ctx, claims.FamilyID)
+		if err != nil {
+			log.Printf("[revoke] family 차단 조회 실패 family_id=%s: %v", claims.FamilyID, err)
+			return true
+		}
+		if !cutoff.IsZero() && (claims.IssuedAt == nil || !claims.IssuedAt.After(cutoff)) {
+			return true
+		}
+	}
+	if claims.IssuedAt == nil {
+		return false
+	}
+	cutoff, err := store.AccessTokenCutoffs.Latest(ctx, claims.UserID, claims.ClientID)
+	if err != nil {
+		log.Printf("[revoke] kill switch 기준 조회 실패 sub=%s client_id=%s: %v", claims.UserID, claims.ClientID, err)
+		return true
+	}
+	// iat 은 초 단위 — 같은 초에 새로 발급된 토큰도 함께 막히는 쪽으로 기운다
+	return !cutoff.IsZero() && !claims.IssuedAt.After(cutoff)
+}
+
+// revokeAccessTokensIssuedBefore: 지금까지 발급된 access token 을 사용자 / client / 쌍 단위로 차단.
+// userID, clientID 중 빈 값은 "전체" — 둘 다 비면 아무것도 하지 않는다.
+// 기준 + accessTokenMaxTTL 이 지나면 그 전 토큰은 모두 만료됐으므로 store 가 정리한다.
+func revokeAccessTokensIssuedBefore(ctx context.Context, userID, clientID string) error {
+	if userID == "" && clientID == "" {
+		return nil
+	}
+	now := time.Now()
+	return store.AccessTokenCutoffs.Set(ctx, userID, clientID, now, now.Add(accessTokenMaxTTL))
+}
+
+// revokeAccessTokenFamily: familyID 로 지금까지 발급된 access token 전부 차단.
+// 기준 + accessTokenMaxTTL 이 지나면 그 family 의 access token 은 모두 만료됐으므로 store 가 정리한다.
+func revokeAccessTokenFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	return store.AccessTokenCutoffs.SetFamily(ctx, familyID, now, now.Add(accessTokenMaxTTL))
 }
 
 // RevokeHandler: POST /oauth/revoke (RFC 7009).
 // form: token, token_type_hint=access_token|refresh_token (선택).
-// 항상 200 반환 (정보 노출 방지). 클라이언트 인증 필수.
+// 항상 200 반환 (정보 노출 방지). 클라이언트 인증 필수 — public client 는 client_id 만으로 (RFC 7009 §2.1).
 func RevokeHandler(w http.ResponseWriter, r *http.Request) {
-	clientID, clientSecret, ok := r.BasicAuth()
+	client, ok := authenticateClient(w, r, "revoke")
 	if !ok {
-		tokenError(w, "invalid_client", "client 인증 실패", http.StatusUnauthorized)
-		return
-	}
-	client, ok := store.Clients.GetByClientID(clientID)
-	if !ok || !store.VerifySecret(client, clientSecret) {
-		tokenError(w, "invalid_client", "client 인증 실패", http.StatusUnauthorized)
 		return
 	}
+	clientID := client.ClientID
 
 	if err := r.ParseForm(); err != nil {
 		tokenError(w, "invalid_request", "form parse 실패", http.StatusBadRequest)
@@ -48,16 +93,11 @@ func RevokeHandler(w http.ResponseWriter, r *http.Request) {
 
 	// refresh token 시도 → 본인 client 의 것만 삭제 (다른 client 가 남의 토큰 폐기 못 함)
 	tryRefresh := func() bool {
-		val, ok := store.RefreshTokens.Load(tokStr)
-		if !ok {
-			return false
-		}
-		rt, ok := val.(models.RefreshToken)
-		if !ok || rt.ClientID != clientID {
+		rt, err := store.RefreshTokens.Load(r.Context(), tokStr)
+		if err != nil || rt.ClientID != clientID {
 			return false
 		}
-		store.RefreshTokens.Delete(tokStr)
-		return true
+		return store.RefreshTokens.Delete(r.Context(), tokStr) == nil
 	}
 
 	// access token (JWT) 시도 → 본인 client 의 것이면 blocklist
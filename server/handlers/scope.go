@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/scope"
+)
+
+// allowedScope: requested 가 전부 scope 레지스트리 + client.AllowedScopes 안인가.
+// 통과하면 중복을 제거한 scope 문자열. 실패 시 <endpoint>.invalid_scope 감사 후 false.
+func allowedScope(r *http.Request, endpoint string, client *models.Client, requested string) (string, bool) {
+	scopes, bad, ok := scope.Validate(requested, client.AllowedScopes)
+	if !ok {
+		AuditWarn(r, endpoint+".invalid_scope", "client_id", client.ClientID, "scope", bad)
+		return "", false
+	}
+	return strings.Join(scopes, " "), true
+}
+
+// refreshScope: refresh_token grant 의 scope 결정 (RFC 6749 §6).
+//
+//	requested 가 비면 → 원래 scope 그대로
+//	requested 가 있으면 → 원래 scope 의 부분집합이어야 함 (넓히기 불가). access token 만 좁아지고
+//	                       새 refresh token 은 원래 scope 를 유지 — 다음 갱신에서 다시 넓게 받을 수 있다.
+//
+// 둘 다 client 가 지금 허용하는 scope 로 다시 자른다 — 어드민이 허용 목록을 줄이면 다음 갱신부터 반영.
+func refreshScope(client *models.Client, granted, requested string) (access, refresh string, ok bool) {
+	grantedScopes := scope.Intersect(splitScope(granted), client.AllowedScopes)
+	refresh = strings.Join(grantedScopes, " ")
+	if strings.TrimSpace(requested) == "" {
+		return refresh, refresh, true
+	}
+	req := splitScope(requested)
+	if !scope.Subset(req, grantedScopes) {
+		return "", "", false
+	}
+	return strings.Join(scope.Intersect(grantedScopes, req), " "), refresh, true
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/ftery0/ouath/server/models"
@@ -25,33 +28,56 @@ func tokenError(w http.ResponseWriter, errorCode, description string, status int
 }
 
 func TokenHandler(w http.ResponseWriter, r *http.Request) {
-	// 클라이언트 앱은 HTTP Basic Auth로 자신을 증명
-	clientID, clientSecret, ok := r.BasicAuth()
+	setTokenCORS(w, r)
+	client, ok := authenticateClient(w, r, "token")
 	if !ok {
-		AuditWarn(r, "token.client_auth_missing")
-		tokenError(w, "invalid_client", "client 인증 실패", http.StatusUnauthorized)
 		return
 	}
-	client, ok := store.Clients.GetByClientID(clientID)
-	if !ok || !store.VerifySecret(client, clientSecret) {
-		AuditWarn(r, "token.client_auth_failed", "client_id", clientID)
-		tokenError(w, "invalid_client", "client 인증 실패", http.StatusUnauthorized)
+	clientID := client.ClientID
+
+	// public client 는 PKCE 로 묶이는 grant 만 — secret 없이 client_credentials / device_code 를 열면 누구나 흉내 낸다.
+	grantType := r.FormValue("grant_type")
+	if client.IsPublic() && grantType != "authorization_code" && grantType != "refresh_token" {
+		AuditWarn(r, "token.public_client_grant_denied", "client_id", clientID, "grant_type", grantType)
+		tokenError(w, "unauthorized_client", "public client 가 쓸 수 없는 grant_type", http.StatusBadRequest)
+		return
+	}
+	// 동적 등록 client 는 grant_types 로 선언한 grant 만 (RFC 7591 §2). 모르는 grant 는 아래 unsupported_grant_type 으로.
+	if contains(dcrGrantTypes, grantType) && !client.AllowsGrant(grantType) {
+		AuditWarn(r, "token.undeclared_grant_denied", "client_id", clientID, "grant_type", grantType)
+		tokenError(w, "unauthorized_client", "등록 시 선언하지 않은 grant_type", http.StatusBadRequest)
 		return
 	}
-	_ = client // 검증 완료
 
-	switch r.FormValue("grant_type") {
+	switch grantType {
 	case "authorization_code":
-		handleAuthorizationCode(w, r, clientID)
+		handleAuthorizationCode(w, r, client)
 	case "refresh_token":
-		handleRefreshToken(w, r, clientID)
+		handleRefreshToken(w, r, client)
+	case "client_credentials":
+		handleClientCredentials(w, r, client)
+	case deviceCodeGrantType:
+		handleDeviceCode(w, r, clientID)
 	default:
 		AuditWarn(r, "token.unsupported_grant_type", "client_id", clientID, "grant_type", r.FormValue("grant_type"))
 		tokenError(w, "unsupported_grant_type", "지원하지 않는 grant_type", http.StatusBadRequest)
 	}
 }
 
-func handleAuthorizationCode(w http.ResponseWriter, r *http.Request, clientID string) {
+// userUnavailable: 사용자가 비활성화됐거나 삭제됐는가. 그 밖의 조회 실패는 error 로 — 호출자는 상태를 건드리지 않는다.
+func userUnavailable(ctx context.Context, userID string) (bool, error) {
+	u, err := store.Users.GetByID(ctx, userID)
+	if errors.Is(err, store.ErrUserNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return u.Disabled, nil
+}
+
+func handleAuthorizationCode(w http.ResponseWriter, r *http.Request, client *models.Client) {
+	clientID    := client.ClientID
 	code        := r.FormValue("code")
 	redirectURI := r.FormValue("redirect_uri")
 	codeVerifier := r.FormValue("code_verifier")
@@ -62,18 +88,29 @@ func handleAuthorizationCode(w http.ResponseWriter, r *http.Request, clientID st
 	}
 
 	// LoadAndDelete: 꺼내는 동시에 삭제 → auth code 재사용 방지
-	val, ok := store.AuthCodes.LoadAndDelete(code)
-	if !ok {
+	ac, err := store.AuthCodes.LoadAndDelete(r.Context(), code)
+	if errors.Is(err, store.ErrAuthCodeNotFound) {
 		tokenError(w, "invalid_grant", "유효하지 않은 code", http.StatusBadRequest)
 		return
 	}
+	if err != nil {
+		tokenError(w, "server_error", "code 조회 실패", http.StatusInternalServerError)
+		return
+	}
 
-	ac := val.(models.AuthCode)
 	if time.Now().After(ac.ExpiresAt) || ac.ClientID != clientID || ac.RedirectURI != redirectURI {
 		tokenError(w, "invalid_grant", "code 검증 실패", http.StatusBadRequest)
 		return
 	}
 
+	// public client 는 secret 대신 PKCE 가 유일한 증명 — S256 challenge 없는 code 는 받지 않는다.
+	// (/authorize 가 이미 막지만 code 가 그 전에 발급됐거나 client 유형이 바뀐 경우 대비)
+	if client.IsPublic() && (ac.CodeChallenge == "" || ac.CodeChallengeMethod != "S256") {
+		AuditWarn(r, "token.pkce_required", "client_id", clientID)
+		tokenError(w, "invalid_grant", "public client 는 S256 PKCE 필수", http.StatusBadRequest)
+		return
+	}
+
 	// PKCE (RFC 7636): /authorize 에서 challenge 가 있었다면 verifier 검증 필수.
 	if ac.CodeChallenge != "" {
 		if codeVerifier == "" {
@@ -86,8 +123,36 @@ func handleAuthorizationCode(w http.ResponseWriter, r *http.Request, clientID st
 		}
 	}
 
+	// code 발급 뒤 비활성화 / 삭제된 사용자에게는 발급하지 않는다
+	gone, err := userUnavailable(r.Context(), ac.UserID)
+	if err != nil {
+		tokenError(w, "server_error", "사용자 조회 실패", http.StatusInternalServerError)
+		return
+	}
+	if gone {
+		AuditWarn(r, "token.code_denied", "sub", ac.UserID, "client_id", clientID, "reason", "user_unavailable")
+		tokenError(w, "invalid_grant", "유효하지 않은 code", http.StatusBadRequest)
+		return
+	}
+
+	// 이 code 에서 시작하는 refresh token 계보. 이후 rotation 은 같은 family 를 잇는다.
+	familyID, err := generateFamilyID()
+	if err != nil {
+		tokenError(w, "server_error", "family 생성 실패", http.StatusInternalServerError)
+		return
+	}
+
 	auditTokenIssued(r, "authorization_code", ac.UserID, ac.ClientID, ac.Scope)
-	issueTokens(w, ac.UserID, ac.ClientID, ac.Scope, ac.Nonce, ac.AuthTime)
+	issueTokens(w, r, tokenGrant{
+		UserID:   ac.UserID,
+		ClientID: ac.ClientID,
+		Scope:    ac.Scope,
+		FamilyID: familyID,
+		Nonce:    ac.Nonce,
+		AuthTime: ac.AuthTime,
+		AMR:      ac.AMR,
+		SID:      ac.SID,
+	})
 }
 
 // verifyPKCE: S256(verifier) == challenge.
@@ -105,32 +170,202 @@ func verifyPKCE(challenge, method, verifier string) bool {
 	}
 }
 
-func handleRefreshToken(w http.ResponseWriter, r *http.Request, clientID string) {
+// refreshUserActive: 비활성화 / 삭제된 사용자의 토큰은 family 째 폐기하고 invalid_grant.
+// 조회 자체가 실패하면 아무것도 건드리지 않고 server_error. 응답을 썼으면 false.
+func refreshUserActive(w http.ResponseWriter, r *http.Request, rt *models.RefreshToken, clientID string) bool {
+	gone, err := userUnavailable(r.Context(), rt.UserID)
+	if err != nil {
+		tokenError(w, "server_error", "사용자 조회 실패", http.StatusInternalServerError)
+		return false
+	}
+	if gone {
+		if rt.FamilyID != "" {
+			_, _ = store.RefreshTokens.RevokeFamily(r.Context(), rt.FamilyID)
+			if err := revokeAccessTokenFamily(r.Context(), rt.FamilyID); err != nil {
+				AuditWarn(r, "token.family_revoke_failed", "family_id", rt.FamilyID, "err", err.Error())
+			}
+		}
+		AuditWarn(r, "token.refresh_denied", "sub", rt.UserID, "client_id", clientID, "reason", "user_unavailable")
+		tokenError(w, "invalid_grant", "유효하지 않은 refresh_token", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// handleRefreshToken: refresh_token grant. scope 파라미터로 access token 을 좁힐 수 있다 (refreshScope).
+func handleRefreshToken(w http.ResponseWriter, r *http.Request, client *models.Client) {
+	clientID := client.ClientID
 	rtStr := r.FormValue("refresh_token")
+	requested := r.FormValue("scope")
+
+	// client / scope 검증은 소비 전에 — 다른 client 의 제시나 범위를 넓히려는 잘못된 요청 하나로
+	// 정상 client 의 refresh token 이 소비되어 (다음 갱신이 재사용 탐지로 family 폐기) 잃지 않도록.
+	if cur, err := store.RefreshTokens.Load(r.Context(), rtStr); err == nil {
+		if cur.ClientID != clientID {
+			AuditWarn(r, "token.refresh_client_mismatch", "sub", cur.UserID, "client_id", cur.ClientID, "presented_by", clientID)
+			tokenError(w, "invalid_grant", "유효하지 않은 refresh_token", http.StatusBadRequest)
+			return
+		}
+		if requested != "" {
+			if _, _, ok := refreshScope(client, cur.Scope, requested); !ok {
+				AuditWarn(r, "token.invalid_scope", "client_id", clientID, "scope", requested)
+				tokenError(w, "invalid_scope", "원래 부여된 scope 를 넘는 요청", http.StatusBadRequest)
+				return
+			}
+		}
+		// 사용자 조회가 일시적으로 실패해도 토큰은 소비되지 않은 채 남는다
+		if !refreshUserActive(w, r, cur, clientID) {
+			return
+		}
+	}
 
-	// LoadAndDelete: refresh token도 1회용으로 처리 (Token Rotation)
+	// Consume: refresh token도 1회용으로 처리 (Token Rotation)
 	// 매 갱신마다 새 refresh token을 발급 → 탈취된 토큰 감지 가능
-	val, ok := store.RefreshTokens.LoadAndDelete(rtStr)
-	if !ok {
+	rt, err := store.RefreshTokens.Consume(r.Context(), rtStr)
+	if errors.Is(err, store.ErrRefreshTokenReused) {
+		// 이미 rotation 된 토큰 재제시 = 공격자와 정상 클라이언트 중 한쪽이 옛 토큰을 쥐고 있음.
+		// 어느 쪽인지 알 수 없으므로 family 전체 (refresh + access) 를 폐기해 둘 다 재로그인시킨다.
+		revokeRefreshFamily(r, rt, clientID)
 		tokenError(w, "invalid_grant", "유효하지 않은 refresh_token", http.StatusBadRequest)
 		return
 	}
+	if errors.Is(err, store.ErrRefreshTokenNotFound) {
+		tokenError(w, "invalid_grant", "유효하지 않은 refresh_token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		tokenError(w, "server_error", "refresh_token 조회 실패", http.StatusInternalServerError)
+		return
+	}
 
-	rt := val.(models.RefreshToken)
 	if time.Now().After(rt.ExpiresAt) || rt.ClientID != clientID {
 		http.Error(w, "refresh_token 검증 실패", http.StatusBadRequest)
 		return
 	}
 
-	auditTokenIssued(r, "refresh_token", rt.UserID, rt.ClientID, rt.Scope)
+	// 소비 전 검사와 소비 사이에 비활성화된 경우
+	if !refreshUserActive(w, r, rt, clientID) {
+		return
+	}
+
+	accessScope, keepScope, ok := refreshScope(client, rt.Scope, requested)
+	if !ok {
+		// 사전 검증과 소비 사이에 client 허용 목록이 줄어든 경우
+		AuditWarn(r, "token.invalid_scope", "client_id", clientID, "scope", requested)
+		tokenError(w, "invalid_scope", "원래 부여된 scope 를 넘는 요청", http.StatusBadRequest)
+		return
+	}
+
+	auditTokenIssued(r, "refresh_token", rt.UserID, rt.ClientID, accessScope)
 	// refresh 시점엔 신선한 nonce 없음. auth_time 도 그대로 유지 (이 grant 에선 시점 기록 X).
-	issueTokens(w, rt.UserID, rt.ClientID, rt.Scope, "", time.Time{})
+	issueTokens(w, r, tokenGrant{
+		UserID:       rt.UserID,
+		ClientID:     rt.ClientID,
+		Scope:        accessScope,
+		RefreshScope: keepScope,
+		FamilyID:     rt.FamilyID,
+	})
+}
+
+// handleClientCredentials: RFC 6749 §4.4 — 사용자 없는 서비스 간 호출.
+// access token 만 발급 (sub = client_id). refresh token 없음 — 만료되면 다시 요청하면 된다.
+func handleClientCredentials(w http.ResponseWriter, r *http.Request, client *models.Client) {
+	if !client.ClientCredentials {
+		AuditWarn(r, "token.client_credentials_denied", "client_id", client.ClientID)
+		tokenError(w, "unauthorized_client", "client_credentials grant 가 허용되지 않은 client", http.StatusBadRequest)
+		return
+	}
+
+	scope, ok := clientCredentialsScope(client, r.FormValue("scope"))
+	if !ok {
+		AuditWarn(r, "token.invalid_scope", "client_id", client.ClientID, "scope", r.FormValue("scope"))
+		tokenError(w, "invalid_scope", "허용되지 않은 scope", http.StatusBadRequest)
+		return
+	}
+
+	accessToken, err := token.CreateForClient(client.ClientID, scope)
+	if err != nil {
+		tokenError(w, "server_error", "토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+
+	auditTokenIssued(r, "client_credentials", client.ClientID, client.ClientID, scope)
+
+	resp := map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   900,
+	}
+	if scope != "" {
+		resp["scope"] = scope
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// clientCredentialsScope: 요청 scope 가 비었으면 허용 목록 전부, 아니면 전부 허용 목록 안에 있어야 한다.
+// openid 는 사용자가 없으므로 항상 거부 (ID Token 을 줄 주체가 없음).
+func clientCredentialsScope(client *models.Client, requested string) (string, bool) {
+	scopes := splitScope(requested)
+	if len(scopes) == 0 {
+		return strings.Join(client.ClientCredentialsScopes, " "), true
+	}
+	for _, s := range scopes {
+		if s == "openid" || !contains(client.ClientCredentialsScopes, s) {
+			return "", false
+		}
+	}
+	return strings.Join(scopes, " "), true
+}
+
+// revokeRefreshFamily: 재사용 탐지 시 family 의 refresh token 전부 삭제 + access token 차단 + 감사.
+func revokeRefreshFamily(r *http.Request, rt *models.RefreshToken, presentedBy string) {
+	revoked := 0
+	if rt.FamilyID != "" { // family 도입 전 발급된 토큰은 계보를 모름 — 감사만
+		n, err := store.RefreshTokens.RevokeFamily(r.Context(), rt.FamilyID)
+		if err != nil {
+			AuditWarn(r, "token.family_revoke_failed", "family_id", rt.FamilyID, "err", err.Error())
+		}
+		revoked = n
+		if err := revokeAccessTokenFamily(r.Context(), rt.FamilyID); err != nil {
+			AuditWarn(r, "token.family_revoke_failed", "family_id", rt.FamilyID, "err", err.Error())
+		}
+	}
+	AuditWarn(r, "token.refresh_reuse_detected",
+		"sub", rt.UserID,
+		"client_id", rt.ClientID,
+		"presented_by", presentedBy,
+		"family_id", rt.FamilyID,
+		"rotated_at", rt.UsedAt,
+		"revoked_refresh_tokens", revoked,
+	)
+}
+
+// tokenGrant: issueTokens 입력.
+// FamilyID: refresh token 계보 — 새 refresh token 과 access token 의 fid claim 에 함께 실린다.
+// RefreshScope: 새 refresh token 에 실을 scope. 비면 Scope 와 같음 (refresh 로 access token 만 좁힌 경우에만 다르다).
+type tokenGrant struct {
+	UserID       string
+	ClientID     string
+	Scope        string
+	RefreshScope string
+	FamilyID     string
+	Nonce        string
+	AuthTime     time.Time
+	AMR          []string
+	SID          string
 }
 
 // issueTokens: access token + refresh token 동시 발급.
 // scope 에 openid 가 있으면 ID Token 도 같이 발급 (P3.1).
-func issueTokens(w http.ResponseWriter, userID, clientID, scope, nonce string, authTime time.Time) {
-	accessToken, err := token.Create(userID, clientID, scope)
+func issueTokens(w http.ResponseWriter, r *http.Request, g tokenGrant) {
+	userID, clientID, scope := g.UserID, g.ClientID, g.Scope
+	refreshScope := g.RefreshScope
+	if refreshScope == "" {
+		refreshScope = scope
+	}
+
+	accessToken, err := token.Create(userID, clientID, scope, g.FamilyID)
 	if err != nil {
 		http.Error(w, "액세스 토큰 생성 실패", http.StatusInternalServerError)
 		return
@@ -143,13 +378,17 @@ func issueTokens(w http.ResponseWriter, userID, clientID, scope, nonce string, a
 	}
 
 	// Refresh token은 장기 유효 (7일), access token보다 훨씬 긺
-	store.RefreshTokens.Store(refreshToken, models.RefreshToken{
+	if err := store.RefreshTokens.Save(r.Context(), &models.RefreshToken{
 		Token:     refreshToken,
+		FamilyID:  g.FamilyID,
 		UserID:    userID,
 		ClientID:  clientID,
-		Scope:     scope,
+		Scope:     refreshScope,
 		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
-	})
+	}); err != nil {
+		http.Error(w, "리프레시 토큰 저장 실패", http.StatusInternalServerError)
+		return
+	}
 
 	resp := map[string]any{
 		"access_token":  accessToken,
@@ -161,7 +400,7 @@ func issueTokens(w http.ResponseWriter, userID, clientID, scope, nonce string, a
 
 	// OIDC: openid scope 가 있으면 ID Token 발급.
 	if hasOpenIDScope(scope) {
-		idToken, err := token.CreateIDToken(userID, clientID, nonce, authTime)
+		idToken, err := token.CreateIDToken(userID, clientID, g.Nonce, g.SID, g.AuthTime, g.AMR)
 		if err != nil {
 			http.Error(w, "ID 토큰 생성 실패", http.StatusInternalServerError)
 			return
@@ -206,6 +445,15 @@ func splitScope(scope string) []string {
 	return out
 }
 
+// generateFamilyID: refresh token family ID (128bit).
+func generateFamilyID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func generateRefreshToken() (string, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
@@ -0,0 +1,815 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ftery0/ouath/server/config"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+	"github.com/ftery0/ouath/server/token"
+)
+
+// /oauth/token grant 별 시나리오 (refresh rotation 재사용 탐지, client_credentials).
+
+// newTokenTestServer: /oauth/token + /oauth/userinfo. routes 로 시나리오별 엔드포인트 추가.
+func newTokenTestServer(t *testing.T, routes ...func(mux *http.ServeMux)) *httptest.Server {
+	t.Helper()
+	if err := token.InitKeys(context.Background(), token.NewMemoryKeyStore()); err != nil {
+		t.Fatal(err)
+	}
+	// 앞 테스트의 kill switch 기준 시각 (초 단위 iat) 이 같은 초에 발급한 토큰을 막지 않게
+	store.AccessTokenCutoffs = store.NewMemoryAccessTokenCutoffStore()
+	// 앞 테스트가 채운 preflight origin 캐시에 이번 테스트의 client 가 빠져 있지 않게
+	publicOriginsMu.Lock()
+	publicOrigins = nil
+	publicOriginsMu.Unlock()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/token", TokenHandler)
+	mux.HandleFunc("GET /oauth/userinfo", UserInfoHandler)
+	for _, route := range routes {
+		route(mux)
+	}
+	return httptest.NewServer(mux)
+}
+
+type tokenResp struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	Error        string `json:"error"`
+}
+
+func postToken(t *testing.T, srv *httptest.Server, form url.Values) (int, tokenResp) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", srv.URL+"/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("app1", "app1-secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var tr tokenResp
+	json.NewDecoder(resp.Body).Decode(&tr)
+	return resp.StatusCode, tr
+}
+
+func userinfoStatus(t *testing.T, srv *httptest.Server, accessToken string) int {
+	t.Helper()
+	req, _ := http.NewRequest("GET", srv.URL+"/oauth/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// 회전된 refresh token 재제시 → invalid_grant + 같은 family 의 최신 refresh/access 토큰까지 폐기.
+func TestToken_RefreshReuse_RevokesFamily(t *testing.T) {
+	srv := newTokenTestServer(t)
+	defer srv.Close()
+
+	user, err := store.Users.GetByUsername(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("seed 사용자 alice 없음: %v", err)
+	}
+	err = store.AuthCodes.Save(context.Background(), &models.AuthCode{
+		Code:        "reuse-test-code",
+		ClientID:    "app1",
+		UserID:      user.ID,
+		RedirectURI: "http://localhost:8011/callback",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, first := postToken(t, srv, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {"reuse-test-code"},
+		"redirect_uri": {"http://localhost:8011/callback"},
+	})
+	if status != http.StatusOK || first.RefreshToken == "" {
+		t.Fatalf("code 교환 실패: status=%d err=%s", status, first.Error)
+	}
+
+	// 정상 rotation
+	status, second := postToken(t, srv, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {first.RefreshToken},
+	})
+	if status != http.StatusOK || second.RefreshToken == "" {
+		t.Fatalf("refresh 실패: status=%d err=%s", status, second.Error)
+	}
+	if got := userinfoStatus(t, srv, second.AccessToken); got != http.StatusOK {
+		t.Fatalf("rotation 직후 access token 거부됨: %d", got)
+	}
+
+	// 옛 토큰 재제시 → 재사용 탐지
+	status, replay := postToken(t, srv, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {first.RefreshToken},
+	})
+	if status != http.StatusBadRequest || replay.Error != "invalid_grant" {
+		t.Fatalf("재사용이 거부되지 않음: status=%d err=%s", status, replay.Error)
+	}
+
+	// family 전체 폐기: 정상 쪽이 쥔 최신 refresh token 도 무효
+	status, _ = postToken(t, srv, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {second.RefreshToken},
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("family 의 최신 refresh token 이 살아있음: status=%d", status)
+	}
+	// 이미 발급된 access token 도 차단
+	if got := userinfoStatus(t, srv, second.AccessToken); got != http.StatusUnauthorized {
+		t.Fatalf("family 의 access token 이 살아있음: %d", got)
+	}
+	// 차단은 다른 인스턴스도 보는 store 에 남는다
+	claims, err := token.Parse(second.AccessToken)
+	if err != nil || claims.FamilyID == "" {
+		t.Fatalf("access token fid: %v", err)
+	}
+	if cutoff, err := store.AccessTokenCutoffs.LatestFamily(context.Background(), claims.FamilyID); err != nil || cutoff.IsZero() {
+		t.Errorf("store 에 family 차단 없음: cutoff=%v err=%v", cutoff, err)
+	}
+}
+
+// 다른 client 가 제시한 refresh token 은 소비하지 않고 거부 — 정상 client 의 다음 갱신이 재사용 탐지에 걸리지 않게.
+func TestToken_RefreshWrongClient_NotConsumed(t *testing.T) {
+	srv := newTokenTestServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+
+	user, err := store.Users.GetByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RefreshTokens.Save(ctx, &models.RefreshToken{
+		Token:     "wrong-client-test-rt",
+		FamilyID:  "wrong-client-test-family",
+		UserID:    user.ID,
+		ClientID:  "app1",
+		Scope:     "openid",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"wrong-client-test-rt"}}
+	req, _ := http.NewRequest("POST", srv.URL+"/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("app2", "app2-secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tr tokenResp
+	json.NewDecoder(resp.Body).Decode(&tr)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || tr.Error != "invalid_grant" {
+		t.Fatalf("app2 의 제시: status=%d err=%s, want 400 invalid_grant", resp.StatusCode, tr.Error)
+	}
+
+	status, ok := postToken(t, srv, form)
+	if status != http.StatusOK || ok.RefreshToken == "" {
+		t.Fatalf("app1 의 정상 갱신: status=%d err=%s", status, ok.Error)
+	}
+}
+
+// client_credentials: 명시적으로 켠 client 만, 허용 scope 안에서만, refresh token 없이.
+func TestToken_ClientCredentials(t *testing.T) {
+	srv := newTokenTestServer(t)
+	defer srv.Close()
+
+	status, resp := postToken(t, srv, url.Values{"grant_type": {"client_credentials"}})
+	if status != http.StatusBadRequest || resp.Error != "unauthorized_client" {
+		t.Fatalf("비허용 client 가 통과: status=%d err=%s", status, resp.Error)
+	}
+
+	c, _ := store.Clients.GetByClientID("app1")
+	c.ClientCredentials = true
+	c.ClientCredentialsScopes = []string{"tickets.read", "tickets.write"}
+	t.Cleanup(func() {
+		c.ClientCredentials = false
+		c.ClientCredentialsScopes = nil
+	})
+
+	status, resp = postToken(t, srv, url.Values{"grant_type": {"client_credentials"}, "scope": {"tickets.admin"}})
+	if status != http.StatusBadRequest || resp.Error != "invalid_scope" {
+		t.Fatalf("허용 밖 scope 가 통과: status=%d err=%s", status, resp.Error)
+	}
+
+	status, resp = postToken(t, srv, url.Values{"grant_type": {"client_credentials"}, "scope": {"tickets.read"}})
+	if status != http.StatusOK || resp.AccessToken == "" {
+		t.Fatalf("발급 실패: status=%d err=%s", status, resp.Error)
+	}
+	if resp.RefreshToken != "" {
+		t.Error("client_credentials 에 refresh token 이 발급됨")
+	}
+	claims, err := token.Parse(resp.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != "app1" || claims.Scope != "tickets.read" {
+		t.Errorf("sub=%q scope=%q, want sub=app1 scope=tickets.read", claims.UserID, claims.Scope)
+	}
+	if got := userinfoStatus(t, srv, resp.AccessToken); got != http.StatusForbidden {
+		t.Errorf("userinfo 가 client 토큰을 받음: %d", got)
+	}
+}
+
+// refresh 의 scope 파라미터: 원래 scope 의 부분집합만 (access token 만 좁아짐), 넓히면 invalid_scope.
+func TestToken_RefreshDownscoping(t *testing.T) {
+	srv := newTokenTestServer(t)
+	defer srv.Close()
+
+	user, err := store.Users.GetByUsername(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.RefreshTokens.Save(context.Background(), &models.RefreshToken{
+		Token:     "downscope-test-rt",
+		FamilyID:  "downscope-family",
+		UserID:    user.ID,
+		ClientID:  "app1",
+		Scope:     "openid profile",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 넓히기 → 거부. 사전 검증이라 refresh token 은 소비되지 않는다.
+	status, resp := postToken(t, srv, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {"downscope-test-rt"},
+		"scope":         {"openid email"},
+	})
+	if status != http.StatusBadRequest || resp.Error != "invalid_scope" {
+		t.Fatalf("scope 확장이 통과: status=%d err=%s", status, resp.Error)
+	}
+
+	status, resp = postToken(t, srv, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {"downscope-test-rt"},
+		"scope":         {"profile"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("downscoping 실패: status=%d err=%s", status, resp.Error)
+	}
+	claims, err := token.Parse(resp.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Scope != "profile" {
+		t.Errorf("access token scope=%q, want profile", claims.Scope)
+	}
+
+	// 새 refresh token 은 원래 scope 유지 → 다음 갱신에서 다시 전체 scope
+	rt, err := store.RefreshTokens.Load(context.Background(), resp.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt.Scope != "openid profile" {
+		t.Errorf("refresh token scope=%q, want %q", rt.Scope, "openid profile")
+	}
+}
+
+// client secret 회전: 유예 중에는 옛 / 새 secret 둘 다, 유예 없이 회전하면 직전 secret 즉시 거부.
+func TestToken_ClientSecretRotationGrace(t *testing.T) {
+	srv := newTokenTestServer(t)
+	defer srv.Close()
+
+	tmpl := template.Must(template.New("admin_client_created.html").Parse(`secret={{.Client.ClientSecret}}`))
+	rotate := func(graceHours string) string {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/admin/clients/app2/rotate-secret", strings.NewReader(url.Values{"grace_hours": {graceHours}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("id", "app2")
+		rec := httptest.NewRecorder()
+		AdminClientRotateSecretHandler(tmpl)(rec, req)
+		secret := strings.TrimPrefix(rec.Body.String(), "secret=")
+		if rec.Code != http.StatusOK || secret == "" {
+			t.Fatalf("회전 실패: status=%d location=%s", rec.Code, rec.Header().Get("Location"))
+		}
+		return secret
+	}
+	// 인증 통과 여부만 본다: 통과하면 가짜 refresh_token 에 대한 invalid_grant (400), 실패하면 invalid_client (401)
+	authStatus := func(secret string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", srv.URL+"/oauth/token", strings.NewReader(url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {"no-such-token"},
+		}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("app2", secret)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	t.Cleanup(func() {
+		seed := &models.Client{ClientSecret: "app2-secret"}
+		_ = store.EnsureSecretHash(seed)
+		_ = store.Clients.RotateSecret("app2", seed.ClientSecretHash, "", 0)
+	})
+
+	second := rotate("24")
+	if got := authStatus("app2-secret"); got != http.StatusBadRequest {
+		t.Errorf("유예 중 옛 secret: got %d, want 400", got)
+	}
+	if got := authStatus(second); got != http.StatusBadRequest {
+		t.Errorf("새 secret: got %d, want 400", got)
+	}
+
+	third := rotate("0")
+	if got := authStatus(second); got != http.StatusUnauthorized {
+		t.Errorf("유예 없는 회전 후 직전 secret: got %d, want 401", got)
+	}
+	if got := authStatus("app2-secret"); got != http.StatusUnauthorized {
+		t.Errorf("두 번 전 secret: got %d, want 401", got)
+	}
+	if got := authStatus(third); got != http.StatusBadRequest {
+		t.Errorf("최신 secret: got %d, want 400", got)
+	}
+
+	// 범위 밖 유예 시간은 Duration 변환 전에 거부되고 secret 은 그대로다.
+	for _, h := range []string{"-1", "169", "9223372036854775807"} {
+		req := httptest.NewRequest("POST", "/admin/clients/app2/rotate-secret", strings.NewReader(url.Values{"grace_hours": {h}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("id", "app2")
+		rec := httptest.NewRecorder()
+		AdminClientRotateSecretHandler(tmpl)(rec, req)
+		if loc := rec.Header().Get("Location"); rec.Code != http.StatusSeeOther || !strings.Contains(loc, "error=invalid_grace") {
+			t.Errorf("grace_hours=%s: status=%d location=%s, want 303 invalid_grace", h, rec.Code, loc)
+		}
+	}
+	if got := authStatus(third); got != http.StatusBadRequest {
+		t.Errorf("거부된 회전 후 최신 secret: got %d, want 400", got)
+	}
+}
+
+// 서버가 직접 요청할 URL: loopback 은 production 이 아닐 때만, 사설 / link-local 은 항상 거부.
+func TestValidOutboundURI(t *testing.T) {
+	cases := []struct {
+		uri       string
+		dev, prod bool
+	}{
+		{"https://rp.example.com/jwks", true, true},
+		{"http://rp.example.com/jwks", false, false},
+		{"http://localhost:8080/jwks", true, false},
+		{"http://127.0.0.1:8080/jwks", true, false},
+		{"https://[::1]/jwks", true, false},
+		{"https://10.0.0.5/jwks", false, false},
+		{"https://192.168.1.10/jwks", false, false},
+		{"https://169.254.169.254/latest/meta-data", false, false},
+		{"https://[fe80::1]/jwks", false, false},
+		{"https://0.0.0.0/jwks", false, false},
+		{"https://rp.example.com/jwks#frag", false, false},
+	}
+	t.Cleanup(func() { SetProduction(false) })
+	for _, tc := range cases {
+		SetProduction(false)
+		if got := validOutboundURI(tc.uri); got != tc.dev {
+			t.Errorf("dev %s: got %v, want %v", tc.uri, got, tc.dev)
+		}
+		SetProduction(true)
+		if got := validOutboundURI(tc.uri); got != tc.prod {
+			t.Errorf("production %s: got %v, want %v", tc.uri, got, tc.prod)
+		}
+	}
+}
+
+// 동적 client 등록 (RFC 7591 / 7592): initial access token 없이는 거부, 발급된 secret 으로 /token 인증,
+// registration access token 으로 읽기 / 갱신 / 삭제.
+func TestToken_DynamicClientRegistration(t *testing.T) {
+	srv := newTokenTestServer(t, func(mux *http.ServeMux) {
+		mux.HandleFunc("POST /oauth/clients/register", ClientRegisterHandler)
+		mux.HandleFunc("GET /oauth/clients/register/{client_id}", ClientConfigGetHandler)
+		mux.HandleFunc("PUT /oauth/clients/register/{client_id}", ClientConfigPutHandler)
+		mux.HandleFunc("DELETE /oauth/clients/register/{client_id}", ClientConfigDeleteHandler)
+		mux.HandleFunc("POST /oauth/device_authorization", DeviceAuthorizationHandler)
+	})
+	defer srv.Close()
+	DCRInit("dcr-initial-token")
+	t.Cleanup(func() { DCRInit("") })
+
+	call := func(method, path, bearer string, body any) (int, map[string]any) {
+		t.Helper()
+		var rd *strings.Reader
+		if body != nil {
+			b, _ := json.Marshal(body)
+			rd = strings.NewReader(string(b))
+		} else {
+			rd = strings.NewReader("")
+		}
+		req, _ := http.NewRequest(method, srv.URL+path, rd)
+		req.Header.Set("Content-Type", "application/json")
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		out := map[string]any{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	metadata := map[string]any{
+		"client_name":   "RP",
+		"redirect_uris": []string{"https://rp.example.com/cb"},
+		"grant_types":   []string{"authorization_code", "refresh_token"},
+		"scope":         "openid email",
+	}
+	if status, _ := call("POST", "/oauth/clients/register", "wrong", metadata); status != http.StatusUnauthorized {
+		t.Fatalf("잘못된 initial access token: got %d, want 401", status)
+	}
+	bad := map[string]any{"redirect_uris": []string{"http://evil.example.com/cb"}}
+	if status, out := call("POST", "/oauth/clients/register", "dcr-initial-token", bad); status != http.StatusBadRequest || out["error"] != "invalid_redirect_uri" {
+		t.Fatalf("http redirect_uri: status=%d out=%v", status, out)
+	}
+
+	status, reg := call("POST", "/oauth/clients/register", "dcr-initial-token", metadata)
+	if status != http.StatusCreated {
+		t.Fatalf("등록 실패: status=%d out=%v", status, reg)
+	}
+	clientID, _ := reg["client_id"].(string)
+	secret, _ := reg["client_secret"].(string)
+	regToken, _ := reg["registration_access_token"].(string)
+	if clientID == "" || secret == "" || regToken == "" || !strings.HasSuffix(reg["registration_client_uri"].(string), "/oauth/clients/register/"+clientID) {
+		t.Fatalf("등록 응답 누락: %v", reg)
+	}
+	c, ok := store.Clients.GetByClientID(clientID)
+	if !ok || c.FirstParty || strings.Join(c.AllowedScopes, " ") != "openid email" {
+		t.Fatalf("저장된 client: %+v", c)
+	}
+
+	// 발급된 secret 으로 /token client 인증 통과 (가짜 refresh_token → invalid_grant)
+	req, _ := http.NewRequest("POST", srv.URL+"/oauth/token", strings.NewReader("grant_type=refresh_token&refresh_token=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, secret)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("등록 secret 으로 /token 인증: got %d, want 400", resp.StatusCode)
+	}
+
+	path := "/oauth/clients/register/" + clientID
+	if status, _ := call("GET", path, "dcr-initial-token", nil); status != http.StatusUnauthorized {
+		t.Errorf("initial token 으로 관리 API: got %d, want 401", status)
+	}
+	if status, out := call("GET", path, regToken, nil); status != http.StatusOK || out["client_name"] != "RP" || out["client_secret"] != nil {
+		t.Errorf("읽기: status=%d out=%v", status, out)
+	}
+	metadata["client_id"] = clientID
+	metadata["redirect_uris"] = []string{"https://rp.example.com/cb2"}
+	if status, out := call("PUT", path, regToken, metadata); status != http.StatusOK {
+		t.Fatalf("갱신: status=%d out=%v", status, out)
+	}
+	if c, _ := store.Clients.GetByClientID(clientID); strings.Join(c.RedirectURIs, " ") != "https://rp.example.com/cb2" {
+		t.Errorf("갱신 후 redirect_uris = %v", c.RedirectURIs)
+	}
+	if status, _ := call("DELETE", path, regToken, nil); status != http.StatusNoContent {
+		t.Fatalf("삭제: got %d, want 204", status)
+	}
+	if _, ok := store.Clients.GetByClientID(clientID); ok {
+		t.Error("삭제 후에도 client 가 남아 있음")
+	}
+	if status, _ := call("GET", path, regToken, nil); status != http.StatusUnauthorized {
+		t.Errorf("삭제 후 읽기: got %d, want 401", status)
+	}
+
+	// client_credentials 선언은 받되 꺼진 채 등록 — 관리자가 켜기 전엔 unauthorized_client
+	cc := map[string]any{"client_name": "svc", "grant_types": []string{"client_credentials"}, "scope": "openid email"}
+	status, reg = call("POST", "/oauth/clients/register", "dcr-initial-token", cc)
+	if status != http.StatusCreated {
+		t.Fatalf("client_credentials 등록: status=%d out=%v", status, reg)
+	}
+	ccID, _ := reg["client_id"].(string)
+	t.Cleanup(func() { store.Clients.Delete(ccID) })
+	if c, _ := store.Clients.GetByClientID(ccID); c.ClientCredentials || len(c.ClientCredentialsScopes) != 0 {
+		t.Errorf("동적 등록이 client_credentials 를 켬: %+v", c)
+	}
+	req, _ = http.NewRequest("POST", srv.URL+"/oauth/token", strings.NewReader("grant_type=client_credentials"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(ccID, reg["client_secret"].(string))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tr struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&tr)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || tr.Error != "unauthorized_client" {
+		t.Errorf("켜지 않은 client_credentials: status=%d err=%s", resp.StatusCode, tr.Error)
+	}
+
+	// grant_types 기본값 (authorization_code) 만 선언 — refresh_token / device_code 는 unauthorized_client
+	ac := map[string]any{"client_name": "web", "redirect_uris": []string{"https://rp.example.com/cb"}}
+	status, reg = call("POST", "/oauth/clients/register", "dcr-initial-token", ac)
+	if status != http.StatusCreated {
+		t.Fatalf("authorization_code 등록: status=%d out=%v", status, reg)
+	}
+	acID, _ := reg["client_id"].(string)
+	t.Cleanup(func() { store.Clients.Delete(acID) })
+	for _, tc := range []struct{ path, body string }{
+		{"/oauth/token", "grant_type=refresh_token&refresh_token=x"},
+		{"/oauth/token", "grant_type=" + url.QueryEscape(deviceCodeGrantType) + "&device_code=x"},
+		{"/oauth/device_authorization", "scope=openid"},
+	} {
+		req, _ = http.NewRequest("POST", srv.URL+tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(acID, reg["client_secret"].(string))
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.Error = ""
+		json.NewDecoder(resp.Body).Decode(&tr)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || tr.Error != "unauthorized_client" {
+			t.Errorf("선언하지 않은 grant %s %s: status=%d err=%s", tc.path, tc.body, resp.StatusCode, tr.Error)
+		}
+	}
+}
+
+// public client: Basic 없이 client_id + S256 PKCE. 등록 origin 에만 CORS, 사용자 없는 grant 거부.
+func TestToken_PublicClientPKCE(t *testing.T) {
+	srv := newTokenTestServer(t, func(mux *http.ServeMux) {
+		mux.HandleFunc("OPTIONS /oauth/token", TokenPreflightHandler)
+	})
+	defer srv.Close()
+
+	const redirect = "http://localhost:5199/callback"
+	spa := &models.Client{
+		ClientID:      "spa-test",
+		Name:          "SPA",
+		RedirectURIs:  []string{redirect},
+		ClientType:    models.ClientTypePublic,
+		AllowedScopes: []string{"openid"},
+	}
+	if err := store.Clients.Register(spa); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Clients.Delete("spa-test") })
+
+	user, err := store.Users.GetByUsername(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("seed 사용자 alice 없음: %v", err)
+	}
+	const verifier = "public-client-test-verifier-0123456789abcdefghijk"
+	saveCode := func(code, challenge string) {
+		t.Helper()
+		ac := &models.AuthCode{
+			Code:        code,
+			ClientID:    "spa-test",
+			UserID:      user.ID,
+			RedirectURI: redirect,
+			ExpiresAt:   time.Now().Add(time.Minute),
+		}
+		if challenge != "" {
+			ac.CodeChallenge, ac.CodeChallengeMethod = challenge, "S256"
+		}
+		if err := store.AuthCodes.Save(context.Background(), ac); err != nil {
+			t.Fatal(err)
+		}
+	}
+	post := func(form url.Values) (int, tokenResp, string) {
+		t.Helper()
+		req, _ := http.NewRequest("POST", srv.URL+"/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", "http://localhost:5199")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var tr tokenResp
+		json.NewDecoder(resp.Body).Decode(&tr)
+		return resp.StatusCode, tr, resp.Header.Get("Access-Control-Allow-Origin")
+	}
+	exchange := func(code, v string) url.Values {
+		return url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {"spa-test"},
+			"code":          {code},
+			"redirect_uri":  {redirect},
+			"code_verifier": {v},
+		}
+	}
+
+	// preflight: 등록된 origin 만
+	for origin, want := range map[string]string{"http://localhost:5199": "http://localhost:5199", "https://evil.example": ""} {
+		req, _ := http.NewRequest("OPTIONS", srv.URL+"/oauth/token", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("preflight origin=%s: Allow-Origin=%q, want %q", origin, got, want)
+		}
+	}
+
+	// PKCE 없이 발급된 code → invalid_grant
+	saveCode("public-no-pkce", "")
+	if status, tr, _ := post(exchange("public-no-pkce", verifier)); status != http.StatusBadRequest || tr.Error != "invalid_grant" {
+		t.Errorf("PKCE 없는 code: status=%d err=%s", status, tr.Error)
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	saveCode("public-wrong-verifier", challenge)
+	if status, tr, _ := post(exchange("public-wrong-verifier", "not-the-verifier")); status != http.StatusBadRequest || tr.Error != "invalid_grant" {
+		t.Errorf("틀린 verifier: status=%d err=%s", status, tr.Error)
+	}
+
+	saveCode("public-ok", challenge)
+	status, tr, acao := post(exchange("public-ok", verifier))
+	if status != http.StatusOK || tr.AccessToken == "" {
+		t.Fatalf("public client code 교환 실패: status=%d err=%s", status, tr.Error)
+	}
+	if acao != "http://localhost:5199" {
+		t.Errorf("응답 Allow-Origin=%q", acao)
+	}
+	if status, tr, _ := post(url.Values{"grant_type": {"refresh_token"}, "client_id": {"spa-test"}, "refresh_token": {tr.RefreshToken}}); status != http.StatusOK {
+		t.Errorf("public client refresh: status=%d err=%s", status, tr.Error)
+	}
+
+	// secret 을 보내는 public client / client_id 만 보내는 confidential client → invalid_client
+	if status, _, _ := post(url.Values{"grant_type": {"refresh_token"}, "client_id": {"spa-test"}, "client_secret": {"x"}, "refresh_token": {"x"}}); status != http.StatusUnauthorized {
+		t.Errorf("secret 을 보낸 public client: status=%d, want 401", status)
+	}
+	if status, _, acao := post(url.Values{"grant_type": {"refresh_token"}, "client_id": {"app1"}, "refresh_token": {"x"}}); status != http.StatusUnauthorized || acao != "" {
+		t.Errorf("client_id 만 보낸 confidential client: status=%d Allow-Origin=%q, want 401 without CORS", status, acao)
+	}
+	// 사용자 없는 grant 는 public client 에 닫혀 있다
+	if status, tr, _ := post(url.Values{"grant_type": {"client_credentials"}, "client_id": {"spa-test"}}); status != http.StatusBadRequest || tr.Error != "unauthorized_client" {
+		t.Errorf("public client 의 client_credentials: status=%d err=%s", status, tr.Error)
+	}
+}
+
+// 공통 client 인증: client_secret_post, client_secret_jwt, private_key_jwt (jwks / jwks_uri), jti 재사용 차단.
+func TestToken_ClientAssertionAuth(t *testing.T) {
+	srv := newTokenTestServer(t)
+	defer srv.Close()
+
+	ClientAuthInit("client-auth-test-key-0123456789abcdef")
+	t.Cleanup(func() { ClientAuthInit("") })
+
+	// client_credentials 로 인증 통과 여부를 본다 (통과하면 200)
+	post := func(form url.Values, basic bool) int {
+		t.Helper()
+		form.Set("grant_type", "client_credentials")
+		req, _ := http.NewRequest("POST", srv.URL+"/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if basic {
+			req.SetBasicAuth(form.Get("client_id"), "x")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	register := func(c *models.Client) {
+		t.Helper()
+		c.ClientCredentials = true
+		if err := store.Clients.Register(c); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = store.Clients.Delete(c.ClientID) })
+	}
+	aud := config.IssuerForDiscovery() + "/oauth/token"
+	assertion := func(clientID, jti, audience string, method jwt.SigningMethod, key any, kid string) url.Values {
+		t.Helper()
+		tok := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+			Issuer:    clientID,
+			Subject:   clientID,
+			Audience:  jwt.ClaimStrings{audience},
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		})
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		signed, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return url.Values{"client_assertion_type": {clientAssertionType}, "client_assertion": {signed}}
+	}
+
+	// client_secret_post — Basic 과 섞으면 거부
+	register(&models.Client{ClientID: "post-test", ClientSecret: "post-secret", TokenEndpointAuthMethod: models.AuthMethodClientSecretPost})
+	if got := post(url.Values{"client_id": {"post-test"}, "client_secret": {"post-secret"}}, false); got != http.StatusOK {
+		t.Errorf("client_secret_post: got %d", got)
+	}
+	if got := post(url.Values{"client_id": {"post-test"}, "client_secret": {"post-secret"}}, true); got != http.StatusUnauthorized {
+		t.Errorf("Basic + client_secret 동시: got %d, want 401", got)
+	}
+
+	// private_key_jwt (등록된 JWKS)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, _ := json.Marshal(JWKS{Keys: []JWK{{
+		Kty: "RSA", Kid: "k1",
+		N: base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}}})
+	register(&models.Client{ClientID: "pkjwt-test", TokenEndpointAuthMethod: models.AuthMethodPrivateKeyJWT, JWKS: string(jwks)})
+
+	first := assertion("pkjwt-test", "jti-1", aud, jwt.SigningMethodRS256, rsaKey, "k1")
+	if got := post(first, false); got != http.StatusOK {
+		t.Errorf("private_key_jwt: got %d", got)
+	}
+	if got := post(first, false); got != http.StatusUnauthorized {
+		t.Errorf("같은 jti 재사용: got %d, want 401", got)
+	}
+	if got := post(assertion("pkjwt-test", "jti-2", "https://other.example/token", jwt.SigningMethodRS256, rsaKey, "k1"), false); got != http.StatusUnauthorized {
+		t.Errorf("다른 aud: got %d, want 401", got)
+	}
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if got := post(assertion("pkjwt-test", "jti-3", aud, jwt.SigningMethodRS256, otherKey, "k1"), false); got != http.StatusUnauthorized {
+		t.Errorf("등록되지 않은 키로 서명: got %d, want 401", got)
+	}
+
+	// private_key_jwt (jwks_uri, EC P-256)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPub, _ := ecKey.PublicKey.Bytes() // 0x04 || X || Y
+	jwksSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{{
+			Kty: "EC", Crv: "P-256", Kid: "ec1",
+			X: base64.RawURLEncoding.EncodeToString(ecPub[1:33]),
+			Y: base64.RawURLEncoding.EncodeToString(ecPub[33:]),
+		}}})
+	}))
+	defer jwksSrv.Close()
+	register(&models.Client{ClientID: "pkjwt-uri-test", TokenEndpointAuthMethod: models.AuthMethodPrivateKeyJWT, JWKSURI: jwksSrv.URL})
+	if got := post(assertion("pkjwt-uri-test", "jti-1", aud, jwt.SigningMethodES256, ecKey, "ec1"), false); got != http.StatusOK {
+		t.Errorf("private_key_jwt (jwks_uri): got %d", got)
+	}
+	// production 에서는 loopback jwks_uri 로 나가지 않는다 (dial 단계에서 차단)
+	SetProduction(true)
+	register(&models.Client{ClientID: "pkjwt-uri-prod", TokenEndpointAuthMethod: models.AuthMethodPrivateKeyJWT, JWKSURI: jwksSrv.URL})
+	got := post(assertion("pkjwt-uri-prod", "jti-1", aud, jwt.SigningMethodES256, ecKey, "ec1"), false)
+	SetProduction(false)
+	if got != http.StatusUnauthorized {
+		t.Errorf("production 의 loopback jwks_uri: got %d, want 401", got)
+	}
+
+	// client_secret_jwt — secret 은 봉인해 둔 평문으로 HMAC 검증. Basic 으로는 안 된다
+	csjwt := &models.Client{ClientID: "csjwt-test", ClientSecret: "csjwt-secret", TokenEndpointAuthMethod: models.AuthMethodClientSecretJWT}
+	if csjwt.ClientSecretSealed, err = sealClientSecret(csjwt, csjwt.ClientSecret); err != nil {
+		t.Fatal(err)
+	}
+	register(csjwt)
+	if got := post(assertion("csjwt-test", "jti-1", aud, jwt.SigningMethodHS256, []byte("csjwt-secret"), ""), false); got != http.StatusOK {
+		t.Errorf("client_secret_jwt: got %d", got)
+	}
+	if got := post(assertion("csjwt-test", "jti-2", aud, jwt.SigningMethodHS256, []byte("wrong-secret"), ""), false); got != http.StatusUnauthorized {
+		t.Errorf("틀린 secret 으로 서명: got %d, want 401", got)
+	}
+	if got := post(url.Values{"client_id": {"csjwt-test"}, "client_secret": {"csjwt-secret"}}, false); got != http.StatusUnauthorized {
+		t.Errorf("client_secret_jwt client 의 client_secret_post: got %d, want 401", got)
+	}
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ftery0/ouath/server/config"
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+	"github.com/ftery0/ouath/server/totp"
+)
+
+// 2단계 인증 등록 / 해제 — GET·POST /oauth/2fa. IdP 세션이 있는 사용자 본인만.
+//
+// 등록: secret 생성 → totp_enroll 쿠키 (10분) 에 보관 + QR / otpauth URI 표시
+//
+//	→ 인증 앱의 첫 코드로 확인 → users 에 secret 저장 + 복구 코드 10개 1회 노출
+//
+// 확인 전까지 secret 은 서버에 저장하지 않는다 — 앱 등록을 마치지 않은 채 잠기는 일이 없도록.
+
+const (
+	totpEnrollCookieName = "totp_enroll"
+	totpEnrollTTL        = 10 * time.Minute
+)
+
+// totpEnroll: totp_enroll 쿠키 내용. UserID 로 다른 사용자 세션에서 재사용 차단.
+type totpEnroll struct {
+	UserID  string
+	Secret  string
+	Expires int64
+}
+
+// twoFactorPageData: two_factor.html 템플릿 데이터.
+//
+//	Step "setup"   : QR + secret + 확인 코드 입력
+//	Step "recovery": 등록 완료 + 복구 코드 1회 노출
+//	Step "enabled" : 이미 등록됨 — 해제 폼
+//	Step "disabled": 해제 완료
+type twoFactorPageData struct {
+	Step          string
+	Username      string
+	Secret        string
+	OTPAuthURI    string
+	RecoveryCodes []string
+	RecoveryLeft  int
+	CSRFToken     string
+	ErrorMsg      string
+}
+
+// TwoFactorGetHandler: GET /oauth/2fa — 등록 상태에 따라 설정 화면 또는 해제 화면.
+func TwoFactorGetHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := sessionUser(w, r, tmpl)
+		if !ok {
+			return
+		}
+		if user.TOTPEnabled() {
+			renderTwoFactor(w, tmpl, twoFactorPageData{Step: "enabled", Username: user.Username, RecoveryLeft: len(user.RecoveryCodeHashes)})
+			return
+		}
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			http.Error(w, "secret 생성 실패", http.StatusInternalServerError)
+			return
+		}
+		if err := setTOTPEnroll(w, user.ID, secret); err != nil {
+			http.Error(w, "쿠키 생성 실패", http.StatusInternalServerError)
+			return
+		}
+		renderTwoFactorSetup(w, tmpl, user, secret, "")
+	}
+}
+
+// TwoFactorPostHandler: POST /oauth/2fa — action=enable (등록 확인) / action=disable (해제).
+//
+// 둘 다 CSRF + IdP 세션 필수. 해제는 현재 코드 (또는 복구 코드) 를 한 번 더 요구 —
+// 잠깐 비운 자리에서 세션만 가진 사람이 2단계 인증을 끄지 못하게.
+func TwoFactorPostHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyCSRFToken(r); err != nil {
+			http.Error(w, "CSRF 검증 실패", http.StatusForbidden)
+			return
+		}
+		user, ok := sessionUser(w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		switch r.FormValue("action") {
+		case "enable":
+			secret, ok := getTOTPEnroll(r, user.ID)
+			if !ok || user.TOTPEnabled() {
+				http.Redirect(w, r, "/oauth/2fa", http.StatusSeeOther)
+				return
+			}
+			step, ok := totp.Validate(secret, r.FormValue("code"), time.Now())
+			if !ok {
+				renderTwoFactorSetup(w, tmpl, user, secret, "코드가 올바르지 않습니다. 인증 앱의 현재 코드를 입력하세요")
+				return
+			}
+			codes, hashes, err := generateRecoveryCodes()
+			if err != nil {
+				http.Error(w, "복구 코드 생성 실패", http.StatusInternalServerError)
+				return
+			}
+			if err := store.Users.EnableTOTP(r.Context(), user.ID, secret, hashes); err != nil {
+				http.Error(w, "2단계 인증 저장 실패", http.StatusInternalServerError)
+				return
+			}
+			// 확인에 쓴 코드는 곧바로 로그인에 재사용하지 못하게 소비
+			_ = store.Users.ConsumeTOTPStep(r.Context(), user.ID, step)
+			clearTOTPEnroll(w)
+			ClearCSRFToken(w)
+			AuditEvent(r, "mfa.enrolled", "sub", user.ID, "username", user.Username)
+			renderTwoFactor(w, tmpl, twoFactorPageData{Step: "recovery", Username: user.Username, RecoveryCodes: codes})
+
+		case "disable":
+			if !user.TOTPEnabled() {
+				http.Redirect(w, r, "/oauth/2fa", http.StatusSeeOther)
+				return
+			}
+			// 해제 코드 대입도 로그인과 같은 사용자 이름 잠금에 누적 — 세션만 가로챈 공격자의 6자리 대입 차단
+			renderDisableError := func(msg string) {
+				renderTwoFactor(w, tmpl, twoFactorPageData{
+					Step:         "enabled",
+					Username:     user.Username,
+					RecoveryLeft: len(user.RecoveryCodeHashes),
+					ErrorMsg:     msg,
+				})
+			}
+			if err := checkLoginLock(r.Context(), user.Username); err != nil {
+				renderDisableError(loginFailureMessage(err, ""))
+				return
+			}
+			if _, ok := verifySecondFactor(r.Context(), user, r.FormValue("code")); !ok {
+				AuditWarn(r, "mfa.disable_failed", "sub", user.ID)
+				renderDisableError(loginFailureMessage(recordLoginFailure(r, user.Username), "인증 코드가 올바르지 않습니다"))
+				return
+			}
+			clearLoginFailures(r.Context(), user.Username)
+			if err := store.Users.DisableTOTP(r.Context(), user.ID); err != nil {
+				http.Error(w, "2단계 인증 해제 실패", http.StatusInternalServerError)
+				return
+			}
+			ClearCSRFToken(w)
+			AuditWarn(r, "mfa.disabled", "sub", user.ID, "username", user.Username)
+			renderTwoFactor(w, tmpl, twoFactorPageData{Step: "disabled", Username: user.Username})
+
+		default:
+			http.Error(w, "알 수 없는 action", http.StatusBadRequest)
+		}
+	}
+}
+
+// sessionUser: IdP 세션의 사용자. 없으면 에러 페이지까지 렌더하고 false.
+func sessionUser(w http.ResponseWriter, r *http.Request, tmpl *template.Template) (*models.User, bool) {
+	sess, ok := currentIdPSession(r)
+	if !ok {
+		renderError(w, tmpl, "로그인 세션이 없습니다. 앱에서 로그인한 뒤 다시 시도하세요")
+		return nil, false
+	}
+	user, err := store.Users.GetByID(r.Context(), sess.UserID)
+	if err != nil {
+		renderError(w, tmpl, "사용자 정보를 찾을 수 없습니다")
+		return nil, false
+	}
+	return user, true
+}
+
+func renderTwoFactorSetup(w http.ResponseWriter, tmpl *template.Template, user *models.User, secret, errMsg string) {
+	renderTwoFactor(w, tmpl, twoFactorPageData{
+		Step:       "setup",
+		Username:   user.Username,
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuerLabel(), user.Username, secret),
+		ErrorMsg:   errMsg,
+	})
+}
+
+// renderTwoFactor: 새 CSRF 토큰 + clickjacking 차단 후 렌더.
+func renderTwoFactor(w http.ResponseWriter, tmpl *template.Template, data twoFactorPageData) {
+	csrfToken, err := NewCSRFToken(w)
+	if err != nil {
+		http.Error(w, "csrf 토큰 생성 실패", http.StatusInternalServerError)
+		return
+	}
+	data.CSRFToken = csrfToken
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store") // secret / 복구 코드가 뒤로가기 캐시에 남지 않게
+	if err := tmpl.ExecuteTemplate(w, "two_factor.html", data); err != nil {
+		http.Error(w, "템플릿 렌더링 실패", http.StatusInternalServerError)
+	}
+}
+
+// totpIssuerLabel: 인증 앱에 표시될 발급자 이름 — issuer URL 의 host.
+func totpIssuerLabel() string {
+	if u, err := url.Parse(config.IssuerForDiscovery()); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "oauth"
+}
+
+func setTOTPEnroll(w http.ResponseWriter, userID, secret string) error {
+	encoded, err := secureCookie.Encode(totpEnrollCookieName, totpEnroll{
+		UserID:  userID,
+		Secret:  secret,
+		Expires: time.Now().Add(totpEnrollTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     totpEnrollCookieName,
+		Value:    encoded,
+		Path:     "/oauth/2fa",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   isProduction,
+		MaxAge:   int(totpEnrollTTL / time.Second),
+	})
+	return nil
+}
+
+// getTOTPEnroll: 같은 사용자가 만든, 만료 전 등록 쿠키의 secret.
+func getTOTPEnroll(r *http.Request, userID string) (string, bool) {
+	c, err := r.Cookie(totpEnrollCookieName)
+	if err != nil {
+		return "", false
+	}
+	var e totpEnroll
+	if err := secureCookie.Decode(totpEnrollCookieName, c.Value, &e); err != nil {
+		return "", false
+	}
+	if e.UserID != userID || time.Now().Unix() > e.Expires {
+		return "", false
+	}
+	return e.Secret, true
+}
+
+func clearTOTPEnroll(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     totpEnrollCookieName,
+		Value:    "",
+		Path:     "/oauth/2fa",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   -1,
+	})
+}
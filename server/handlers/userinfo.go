@@ -28,14 +28,20 @@ func UserInfoHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if IsAccessTokenRevoked(tokenStr) {
+	claims, err := token.Parse(tokenStr)
+	if err != nil {
+		http.Error(w, "유효하지 않은 토큰", http.StatusUnauthorized)
+		return
+	}
+
+	if IsAccessTokenRevoked(r.Context(), tokenStr, claims) {
 		http.Error(w, "토큰이 폐기됨", http.StatusUnauthorized)
 		return
 	}
 
-	claims, err := token.Parse(tokenStr)
-	if err != nil {
-		http.Error(w, "유효하지 않은 토큰", http.StatusUnauthorized)
+	// client_credentials 토큰은 사용자가 없다 — UserInfo 대상 아님.
+	if claims.GrantType == token.GrantClientCredentials {
+		http.Error(w, "사용자 토큰이 아님", http.StatusForbidden)
 		return
 	}
 
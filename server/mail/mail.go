@@ -0,0 +1,108 @@
+// Package mail 은 IdP 가 사용자에게 보내는 메일 (비밀번호 재설정 등) 의 발송 인터페이스.
+//
+//	SMTPMailer: 운영용. net/smtp — 서버가 STARTTLS 를 지원하면 자동으로 암호화
+//	LogMailer : 로컬 개발용. 디렉토리가 있으면 .eml 파일로, 없으면 로그로 — 링크를 바로 눌러볼 수 있게
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message: 평문 메일 한 통. Body 는 UTF-8 text/plain.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer: 메일 발송. 호출자는 구현체를 모른다 (main 이 config 보고 주입).
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer: Addr (host:port) 로 발송. Username 이 비어 있으면 인증 없이 (사내 relay 등).
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send: net/smtp 는 context 를 받지 않으므로 ctx 는 취소 확인에만 쓴다.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("smtp addr: %w", err)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	if err := smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, compose(m.From, msg, time.Now())); err != nil {
+		return fmt.Errorf("smtp send: %w", err)
+	}
+	return nil
+}
+
+// LogMailer: 발송 대신 기록. Dir 이 있으면 <Dir>/<시각>-<수신자>.eml, 비어 있으면 표준 로그.
+type LogMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	raw := compose(m.From, msg, time.Now())
+	if m.Dir == "" {
+		log.Printf("[mail] to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), sanitizeFileName(msg.To))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		return err
+	}
+	log.Printf("[mail] to=%s subject=%q → %s", msg.To, msg.Subject, path)
+	return nil
+}
+
+// compose: RFC 5322 메시지. 헤더 인젝션 방지를 위해 CR/LF 는 제거, 제목은 RFC 2047 인코딩.
+func compose(from string, msg Message, now time.Time) []byte {
+	var b strings.Builder
+	header := func(k, v string) {
+		b.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+	}
+	header("From", from)
+	header("To", msg.To)
+	header("Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=UTF-8")
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompose_StripsHeaderInjection(t *testing.T) {
+	raw := string(compose("idp@example.com", Message{
+		To:      "alice@example.com\r\nBcc: evil@example.com",
+		Subject: "비밀번호 재설정",
+		Body:    "line1\nline2",
+	}, time.Unix(0, 0)))
+
+	head, body, _ := strings.Cut(raw, "\r\n\r\n")
+	if strings.Contains(head, "\r\nBcc:") {
+		t.Errorf("헤더 인젝션 통과:\n%s", head)
+	}
+	if !strings.Contains(head, "Subject: =?UTF-8?b?") {
+		t.Errorf("제목이 RFC 2047 인코딩되지 않음:\n%s", head)
+	}
+	if body != "line1\r\nline2" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestLogMailer_WritesEML(t *testing.T) {
+	dir := t.TempDir()
+	m := &LogMailer{Dir: dir, From: "idp@example.com"}
+	if err := m.Send(context.Background(), Message{To: "alice@example.com", Subject: "hi", Body: "link"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), "-alice@example.com.eml") {
+		t.Fatalf("파일 = %v", entries)
+	}
+}
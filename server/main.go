@@ -3,19 +3,24 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/ftery0/ouath/server/config"
 	"github.com/ftery0/ouath/server/db"
 	"github.com/ftery0/ouath/server/handlers"
+	"github.com/ftery0/ouath/server/mail"
 	"github.com/ftery0/ouath/server/router"
+	"github.com/ftery0/ouath/server/scope"
 	"github.com/ftery0/ouath/server/store"
 	pgstore "github.com/ftery0/ouath/server/store/postgres"
 	"github.com/ftery0/ouath/server/token"
+	"github.com/ftery0/ouath/server/webauthn"
 )
 
 // go:embed 지시어: 빌드 시 frontend 폴더 전체를 바이너리 안에 포함시킴
@@ -28,9 +33,27 @@ func main() {
 	cfg := config.Load()
 	token.Init(cfg.JWTSecret, cfg.Issuer)
 	handlers.IdPCookieInit(cfg.IdPSessionSecret)
-	handlers.AdminInit(cfg.AdminPasswordHash, cfg.AdminSessionSecret)
+	handlers.AdminInit(cfg.AdminSessionSecret)
 	handlers.SetProduction(cfg.Env == "production")
-	store.IdPSessions.StartCleanup()
+	handlers.DCRInit(cfg.DCRInitialAccessToken)
+	handlers.ClientAuthInit(cfg.ClientSecretKey)
+	handlers.WebAuthnInit(webauthn.RelyingParty{ID: cfg.WebAuthnRPID, Name: cfg.WebAuthnRPID, Origins: cfg.WebAuthnOrigins})
+	// 메일: SMTP 설정이 있으면 실제 발송, 없으면 (개발) MailDir 의 .eml 또는 로그
+	if cfg.SMTPAddr != "" {
+		handlers.MailerInit(&mail.SMTPMailer{Addr: cfg.SMTPAddr, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.MailFrom})
+	} else {
+		handlers.MailerInit(&mail.LogMailer{Dir: cfg.MailDir, From: cfg.MailFrom})
+	}
+	for _, s := range cfg.ExtraScopes {
+		scope.Register(s)
+	}
+
+	// 서명키 저장소: 기본은 PEM 파일. DB 모드에서는 아래에서 Postgres 로 교체
+	// (단, OAUTH_SIGNING_KEY_FILE 을 명시했으면 파일 우선).
+	var keyStore token.KeyStore = token.NewFileKeyStore(config.DefaultSigningKeyFile)
+	if cfg.SigningKeyFile != "" {
+		keyStore = token.NewFileKeyStore(cfg.SigningKeyFile)
+	}
 
 	// Postgres 연결 + 마이그레이션 + store 교체 + 시드.
 	if cfg.DatabaseURL != "" {
@@ -58,10 +81,63 @@ func main() {
 			}
 			store.Clients = pgClients
 			store.Users = pgUsers
+			store.AuthCodes = pgstore.NewAuthCodeStore(db.Pool)
+			store.RefreshTokens = pgstore.NewRefreshTokenStore(db.Pool)
+			store.IdPSessions = pgstore.NewIdPSessionStore(db.Pool)
+			store.DeviceCodes = pgstore.NewDeviceCodeStore(db.Pool)
+			store.Consents = pgstore.NewConsentStore(db.Pool)
+			store.Passkeys = pgstore.NewPasskeyStore(db.Pool)
+			store.PasswordResets = pgstore.NewPasswordResetStore(db.Pool)
+			store.ClientAssertions = pgstore.NewClientAssertionStore(db.Pool)
+			store.AccessTokenCutoffs = pgstore.NewAccessTokenCutoffStore(db.Pool)
+			store.AdminSessions = pgstore.NewAdminSessionStore(db.Pool)
+			if cfg.AuditStore == "postgres" {
+				store.Audit = pgstore.NewAuditStore(db.Pool)
+			}
+			if cfg.SigningKeyFile == "" {
+				keyStore = pgstore.NewSigningKeyStore(db.Pool)
+			}
 			log.Println("Postgres 연결 + 시드 OK · store=postgres")
 		}
 	}
 
+	// 감사 로그 영속 sink: stdout 은 항상, 그 위에 memory / postgres (DB 연결 실패 시 stdout 만).
+	if cfg.AuditStore == "memory" {
+		store.Audit = store.NewMemoryAuditStore(store.AuditExportLimit)
+	}
+	if store.Audit != nil {
+		log.Printf("감사 로그 sink=%s · 보존 %s", cfg.AuditStore, cfg.AuditRetention)
+	}
+	store.StartAuditPrune(cfg.AuditRetention)
+
+	// 첫 어드민: 역할이 없는 bootstrap 사용자만 superadmin 으로 (감사 로그로 남긴다).
+	if promoted, err := store.BootstrapAdmins(context.Background(), store.Users, cfg.AdminBootstrap); errors.Is(err, store.ErrAdminsExist) {
+		log.Printf("WARNING: 어드민이 이미 있어 OAUTH_ADMIN_BOOTSTRAP=%s 를 무시함 — 설정에서 지우세요", strings.Join(cfg.AdminBootstrap, ","))
+	} else if err != nil {
+		log.Fatal("admin bootstrap: ", err)
+	} else if len(promoted) > 0 {
+		handlers.AuditSystem("admin.bootstrapped", "usernames", promoted, "role", "superadmin")
+	}
+
+	// store 교체가 끝난 뒤 만료 IdP 세션 / code / refresh token / device code 청소 goroutine 기동.
+	store.StartIdPSessionCleanup()
+	store.StartTokenCleanup()
+
+	// 서명키 로드 (없으면 생성 + 저장). 재시작해도 같은 키 → 발급된 토큰 유지.
+	if err := token.InitKeys(context.Background(), keyStore); err != nil {
+		log.Fatal("서명키 로드 실패: ", err)
+	}
+	log.Printf("서명키 로드 OK · kid=%s", token.KeyID())
+	// 주기적 Reload (+ 설정 시 스케줄 회전). 회전 결과는 감사 로그로.
+	token.StartKeyRotation(cfg.KeyRotation, func(res token.RotationResult) {
+		handlers.AuditSystem("signing_key.rotated",
+			"trigger", "schedule",
+			"retired_kid", res.RetiredKid,
+			"active_kid", res.ActiveKid,
+			"next_kid", res.NextKid,
+		)
+	})
+
 	// 템플릿 파싱: embed된 FS에서 templates/*.html 파일을 모두 읽어서 파싱
 	tmpl, err := template.ParseFS(frontendFS, "frontend/templates/*.html")
 	if err != nil {
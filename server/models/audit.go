@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// AuditEntry: 감사 로그 한 건 (영속 sink 용). stdout JSON 로그와 같은 내용을 조회 열로 나눈 것.
+//
+//	Actor   : 행위자 — 어드민 username (by) / "system" / 로그인 시도 username
+//	Subject : 대상 사용자 sub
+//	Attrs   : 위 열로 빠지지 않은 나머지 속성
+type AuditEntry struct {
+	ID        int64
+	Time      time.Time
+	Level     string // "INFO" | "WARN"
+	Event     string
+	Actor     string
+	Subject   string
+	ClientID  string
+	IP        string
+	UserAgent string
+	Attrs     map[string]any
+}
@@ -2,6 +2,8 @@ package models
 
 import "time"
 
+// AuthCode: /authorize → /token 사이 1회용 교환 코드 (10분).
+// Code 는 redirect 에 싣는 평문 — store 는 HashToken(Code) 로만 보관하고 LoadAndDelete 결과엔 비어 있다.
 type AuthCode struct {
 	Code        string
 	ClientID    string
@@ -23,4 +25,11 @@ type AuthCode struct {
 	// AuthTime — 사용자가 IdP 에서 실제 로그인 (또는 silent SSO 통과) 한 시각.
 	// ID Token auth_time claim 으로 전달.
 	AuthTime time.Time
+
+	// AMR — 그 로그인에 쓴 인증 수단. ID Token amr claim 으로 전달.
+	AMR []string
+
+	// SID — code 를 내준 IdP 세션의 sid (models.SessionSID). ID Token sid claim 으로 전달 —
+	// RP 는 Back-Channel Logout 의 logout_token sid 와 맞춰 자기 세션을 찾는다.
+	SID string
 }
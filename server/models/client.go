@@ -27,4 +27,106 @@ type Client struct {
 	// true  → IdP 세션이 있으면 폼 없이 즉시 code 발급
 	// false → 매번 로그인 폼 요구
 	SilentSSO bool
+
+	// first-party (우리 조직 앱) 여부. true 면 동의 화면 생략 — 사용자에게 scope 승인을 묻지 않는다.
+	// 기본 false: /admin/clients/new 로 등록한 외부 앱은 첫 authorize 때 consent.html 을 거친다.
+	FirstParty bool
+
+	// client_credentials grant (사용자 없는 서비스 간 호출) 허용 여부. 기본 false — 명시적으로 켠 client 만.
+	// ClientCredentialsScopes: 이 grant 로 요청 가능한 scope 목록. 요청에 scope 가 없으면 전부 부여.
+	ClientCredentials       bool
+	ClientCredentialsScopes []string
+
+	// 사용자 위임 흐름 (authorize / device / refresh) 에서 요청 가능한 scope. scope 레지스트리에 있는 것만 의미가 있다.
+	// 목록 밖 scope 를 요청하면 /oauth/authorize 는 invalid_scope. 줄이면 기존 refresh token 도 다음 갱신부터 좁아진다.
+	AllowedScopes []string
+
+	// true 면 이메일 인증을 마친 사용자에게만 code 발급. 미인증이면 인증 안내 화면 (prompt=none 은 access_denied).
+	RequireVerifiedEmail bool
+
+	// secret 회전 유예: 회전 직전 hash 를 PreviousSecretExpiresAt 까지 함께 인정한다.
+	// 앱 배포가 끝나기 전 옛 secret 으로 들어오는 /token 호출을 끊지 않기 위함. 유예 없이 회전하면 비어 있다.
+	PreviousSecretHash      string
+	PreviousSecretExpiresAt time.Time
+
+	// 동적 등록 (RFC 7591) 메타데이터. 어드민 등록 client 는 비어 있거나 기본값.
+	//   - GrantTypes: 등록 시 선언한 grant 목록 (client_credentials 는 ClientCredentials 로도 반영)
+	//   - TokenEndpointAuthMethod: 비어 있으면 client_secret_basic
+	//   - RegistrationAccessTokenHash: RFC 7592 관리 API 용 토큰의 HashToken. 비어 있으면 관리 API 대상 아님
+	LogoURI                     string
+	GrantTypes                  []string
+	TokenEndpointAuthMethod     string
+	RegistrationAccessTokenHash string
+
+	// client 유형 (RFC 6749 §2.1). 비어 있으면 confidential.
+	// public (SPA / 모바일) 은 secret 이 없다 — /oauth/token 에 client_id 만 보내고 S256 PKCE 로 code 소유를 증명한다.
+	// 허용 grant 도 authorization_code / refresh_token 뿐.
+	ClientType string
+
+	// JWT client 인증 (RFC 7523) 재료.
+	//   - JWKS / JWKSURI: private_key_jwt 서명 검증용 공개키. JWKS 는 JWK Set JSON 원문, 둘 중 하나만
+	//   - ClientSecretSealed: client_secret_jwt 의 HMAC 키는 secret 평문이라 bcrypt hash 로는 검증할 수 없다.
+	//     이 방식을 쓰는 client 만 서버 키로 암호화 (AES-GCM) 한 secret 을 따로 둔다. 나머지는 비어 있다
+	JWKS               string
+	JWKSURI            string
+	ClientSecretSealed string
+
+	// OIDC Back-Channel Logout 1.0: IdP 세션이 끝나면 이 URI 로 logout_token 을 POST 한다.
+	// 비어 있으면 통지하지 않는다 (client 세션은 자기 만료까지 남는다).
+	BackchannelLogoutURI string
+
+	// OIDC Front-Channel Logout 1.0: 서버 간 호출을 받을 수 없는 client 용. IdP 로그아웃 페이지가
+	// 숨은 iframe 으로 이 URI 를 (iss / sid 쿼리와 함께) 띄운다. redirect_uri 중 하나와 같은 origin 이어야 한다.
+	FrontchannelLogoutURI string
 }
+
+// IsPublic: secret 없는 public client 인가.
+func (c *Client) IsPublic() bool {
+	return c.ClientType == ClientTypePublic
+}
+
+// AuthMethod: 등록된 token_endpoint_auth_method. 비어 있으면 client_secret_basic (public 은 none).
+func (c *Client) AuthMethod() string {
+	switch {
+	case c.IsPublic():
+		return AuthMethodNone
+	case c.TokenEndpointAuthMethod == "":
+		return AuthMethodClientSecretBasic
+	}
+	return c.TokenEndpointAuthMethod
+}
+
+// UsesSecret: client_secret 을 발급하는 방식인가. public / private_key_jwt 는 secret 이 없다.
+func (c *Client) UsesSecret() bool {
+	m := c.AuthMethod()
+	return m != AuthMethodNone && m != AuthMethodPrivateKeyJWT
+}
+
+// AllowsGrant: 등록 시 선언한 grant 인가. GrantTypes 가 비어 있으면 (어드민 등록 client) 제한 없음.
+func (c *Client) AllowsGrant(grantType string) bool {
+	if len(c.GrantTypes) == 0 {
+		return true
+	}
+	for _, g := range c.GrantTypes {
+		if g == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// client 유형.
+const (
+	ClientTypeConfidential = "confidential"
+	ClientTypePublic       = "public"
+)
+
+// 토큰 엔드포인트 client 인증 방식 (RFC 7591 token_endpoint_auth_method).
+// none = public client (secret 없음). *_jwt 는 RFC 7523 client assertion.
+const (
+	AuthMethodClientSecretBasic = "client_secret_basic"
+	AuthMethodClientSecretPost  = "client_secret_post"
+	AuthMethodClientSecretJWT   = "client_secret_jwt"
+	AuthMethodPrivateKeyJWT     = "private_key_jwt"
+	AuthMethodNone              = "none"
+)
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ConsentGrant: 사용자가 client 에 동의한 scope 기록 (user × client 당 1 건).
+// 새 scope 를 동의하면 기존 목록에 합쳐진다 — 이미 동의한 범위 안의 요청은 다시 묻지 않음.
+type ConsentGrant struct {
+	UserID    string
+	ClientID  string
+	Scopes    []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Covers: 요청 scope 가 전부 이미 동의된 범위 안인가.
+func (g *ConsentGrant) Covers(requested []string) bool {
+	for _, s := range requested {
+		found := false
+		for _, granted := range g.Scopes {
+			if granted == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// DeviceCodeStatus: device authorization 요청 상태.
+type DeviceCodeStatus string
+
+const (
+	DeviceCodePending  DeviceCodeStatus = "pending"  // 사용자 확인 대기
+	DeviceCodeApproved DeviceCodeStatus = "approved" // 사용자가 승인 → 다음 poll 에 토큰 발급
+	DeviceCodeDenied   DeviceCodeStatus = "denied"   // 사용자가 거부 → 다음 poll 에 access_denied
+)
+
+// DeviceCode: RFC 8628 Device Authorization Grant 의 진행 상태 (10분).
+//   - DeviceCode: 기기가 /token polling 에 쓰는 비밀값. store 는 HashToken(DeviceCode) 로만 보관
+//   - UserCode  : 사용자가 /oauth/device 에 입력하는 짧은 코드 (정규화된 형태, 하이픈 없음)
+//   - Interval  : 최소 polling 간격. slow_down 마다 5초씩 늘어난다 (§3.5)
+type DeviceCode struct {
+	DeviceCode   string
+	UserCode     string
+	ClientID     string
+	Scope        string
+	Status       DeviceCodeStatus
+	UserID       string    // 승인한 사용자 (approved 일 때만)
+	AuthTime     time.Time // 승인한 사용자의 로그인 시각
+	AMR          []string  // 승인한 사용자의 인증 수단
+	Interval     time.Duration
+	LastPolledAt time.Time
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
 
 // IdPSession: IdP 가 사용자별로 유지하는 글로벌 세션.
 //
@@ -11,6 +15,25 @@ type IdPSession struct {
 	UserID    string    // users.id (UUID)
 	LoginAt   time.Time
 	ExpiresAt time.Time
+	AMR       []string // 로그인에 쓴 인증 수단 (pwd / pwd otp). 이 세션으로 발급되는 ID Token 의 amr
+	ClientIDs []string // 이 세션으로 code 를 받아 간 client (발급 순, 중복 없음). Back-Channel Logout 통지 대상
+
+	// /account 세션 목록 표시용. IP / UserAgent 는 로그인 시점 값, LastSeenAt 은 마지막으로 code 를 발급한 시각.
+	IP         string
+	UserAgent  string
+	LastSeenAt time.Time
+}
+
+// SID: ID Token / logout_token 의 sid claim.
+// 세션 쿠키 값 (SessionID) 을 그대로 실으면 ID Token 을 본 쪽이 세션을 훔칠 수 있으므로 sha256 파생값을 쓴다.
+func (s *IdPSession) SID() string {
+	return SessionSID(s.SessionID)
+}
+
+// SessionSID: 세션 ID → sid claim. 세션을 조회하지 않고 code 발급 시점에 계산할 때 쓴다.
+func SessionSID(sessionID string) string {
+	sum := sha256.Sum256([]byte("sid:" + sessionID))
+	return hex.EncodeToString(sum[:16])
 }
 
 // Expired: 만료 여부 확인.
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// LoginThrottle: 사용자 이름 단위 로그인 실패 누적. 존재하지 않는 이름도 같은 방식으로 기록된다.
+//
+//	Failures    : 마지막 초기화 이후 연속 실패 수
+//	LockedUntil : 이 시각 전에는 비밀번호를 확인하지 않고 거부 (zero = 잠금 없음)
+type LoginThrottle struct {
+	Username     string
+	Failures     int
+	LastFailedAt time.Time
+	LockedUntil  time.Time
+}
+
+// Locked: now 시점에 잠겨 있는가.
+func (t *LoginThrottle) Locked(now time.Time) bool {
+	return now.Before(t.LockedUntil)
+}
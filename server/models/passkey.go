@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Passkey: 사용자에 묶인 WebAuthn credential 1개. 비밀번호 대신 로그인에 쓴다.
+//
+//	ID        : credential ID (base64url). 인증 응답의 rawId 로 조회
+//	PublicKey : COSE_Key 원본 — 서명 검증용
+//	SignCount : 인증기 서명 카운터. 줄어들면 복제 의심으로 거부 (동기화 패스키는 보통 항상 0)
+type Passkey struct {
+	ID         string
+	UserID     string
+	Name       string // 사용자가 붙인 이름 (예: "MacBook Touch ID")
+	PublicKey  []byte
+	SignCount  uint32
+	CreatedAt  time.Time
+	LastUsedAt time.Time // zero = 아직 로그인에 쓰지 않음
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PasswordReset: 비밀번호 재설정 링크 1건 (1회용, 30분).
+// Token 은 메일 링크에 싣는 평문 — store 는 HashToken(Token) 으로만 보관하고 조회 결과엔 비어 있다.
+type PasswordReset struct {
+	Token     string
+	UserID    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// Expired: now 시점에 만료되었는가.
+func (p *PasswordReset) Expired(now time.Time) bool {
+	return now.After(p.ExpiresAt)
+}
@@ -2,10 +2,21 @@ package models
 
 import "time"
 
+// RefreshToken: 장기 (7일) 토큰. rotation 으로 매 갱신마다 교체.
+//
+// Token 은 발급 직후 응답에 싣기 위한 평문 — store 에는 들어가지 않는다.
+// 영속 키는 TokenHash (SHA-256). Load 결과에는 TokenHash 만 채워져 있다.
+//
+// FamilyID: 같은 authorization code 에서 rotation 으로 이어진 토큰들의 계보 ID.
+// 이미 rotation 된 토큰(UsedAt != zero) 이 다시 제시되면 탈취 신호로 보고 family 전체를 폐기한다.
 type RefreshToken struct {
 	Token     string
+	TokenHash string
+	FamilyID  string
 	UserID    string
 	ClientID  string
 	Scope     string
 	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    time.Time // rotation 으로 소비된 시각. zero 면 아직 유효
 }
@@ -13,8 +13,50 @@ type User struct {
 	EmailVerified bool  // OIDC email_verified claim
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+
+	// 2단계 인증 (TOTP, RFC 6238). TOTPSecret 이 비어 있으면 미등록 — 비밀번호만으로 로그인.
+	//   - TOTPLastStep      : 마지막으로 통과한 time step. 같은 코드를 30초 안에 다시 쓰는 replay 차단
+	//   - RecoveryCodeHashes: 1회용 복구 코드의 HashToken. 쓰면 목록에서 빠진다 (평문은 등록 직후 1회만 노출)
+	TOTPSecret         string
+	TOTPLastStep       int64
+	RecoveryCodeHashes []string
+
+	// Disabled: 어드민이 비활성화한 계정. 로그인 / silent SSO / refresh 모두 거부 (데이터는 유지).
+	Disabled bool
+
+	// AdminRole: /admin 접근 권한. 빈 값이면 어드민 아님 (AdminRole* 상수).
+	AdminRole string
+}
+
+// 어드민 역할 — 위로 갈수록 좁다. superadmin 만 역할 부여와 서명키 회전을 한다.
+const (
+	AdminRoleViewer        = "viewer"         // 조회만
+	AdminRoleClientManager = "client-manager" // 조회 + client 관리
+	AdminRoleUserManager   = "user-manager"   // 조회 + 사용자 관리
+	AdminRoleSuperadmin    = "superadmin"     // 전부
+)
+
+// AdminRoles: 어드민 화면의 역할 선택지 순서.
+var AdminRoles = []string{AdminRoleViewer, AdminRoleClientManager, AdminRoleUserManager, AdminRoleSuperadmin}
+
+// IsAdmin: 활성 계정이면서 어드민 역할이 있는가.
+func (u *User) IsAdmin() bool {
+	return !u.Disabled && u.AdminRole != ""
 }
 
+// TOTPEnabled: 로그인에 두 번째 단계 (인증 앱 코드) 가 필요한가.
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPSecret != ""
+}
+
+// amr (RFC 8176) — ID Token 에 싣는 인증 수단.
+const (
+	AMRPassword    = "pwd"
+	AMROTP         = "otp"
+	AMRHardwareKey = "hwk" // 패스키 (WebAuthn) — 기기 소지 증명
+	AMRMultiFactor = "mfa" // 패스키는 기기 잠금 해제 (UV) 까지 요구하므로 다중 요소
+)
+
 // seedPasswordHash: 학습용 시드 사용자 공통 비밀번호("password123") 의 bcrypt(cost=12) hash.
 // 실제 운영에서는 각 사용자마다 고유 salt 가 적용된 hash 가 저장된다.
 const seedPasswordHash = "$2a$12$dC674FROOYvOF.OPHSWntuvU2QjhGHtIUe2LvUFJepN.DmSXQibvq"
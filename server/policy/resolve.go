@@ -1,9 +1,9 @@
 // Package policy 는 /oauth/authorize 진입 시 IdP 세션과 client 의 silent_sso 토글
-// 입력으로 받아 silent SSO / 로그인 폼 / 에러 중 어느 결정을 내릴지 계산한다.
+// 입력으로 받아 silent SSO / 로그인 폼 / 동의 화면 / 에러 중 어느 결정을 내릴지 계산한다.
 //
 // Phase-R 단순화: 그룹/Realm 모델 폐기. 글로벌 user pool + client 단위 토글.
 //
-// 입력 3 개로 6 케이스 진리표:
+// 진리표 (consent 는 세션 + silent 가 모두 통과한 뒤에만 본다):
 //
 //	HasSession=false, prompt=""      → PROMPT
 //	HasSession=false, prompt=none    → ERROR (login_required)
@@ -11,6 +11,12 @@
 //	HasSession=true,  silent=true,  prompt=login → PROMPT (강제 폼)
 //	HasSession=true,  silent=false, prompt=""    → PROMPT
 //	HasSession=true,  silent=false, prompt=none  → ERROR
+//	HasSession=true,  silent=true,  동의 필요, prompt=""      → CONSENT
+//	HasSession=true,  silent=true,  동의 필요, prompt=none    → ERROR (consent_required)
+//	HasSession=true,  silent=true,  prompt=consent           → CONSENT (first-party 여도)
+//
+// "동의 필요" = first-party 가 아니고 요청 scope 를 아직 전부 동의하지 않음.
+// PROMPT 로 로그인한 직후에도 같은 판단 (NeedsConsent) 으로 동의 화면을 거친다.
 package policy
 
 import "github.com/ftery0/ouath/server/models"
@@ -19,17 +25,19 @@ import "github.com/ftery0/ouath/server/models"
 type Decision string
 
 const (
-	DecisionSilent Decision = "SILENT" // 폼 없이 즉시 auth code 발급
-	DecisionPrompt Decision = "PROMPT" // 로그인 폼 렌더링
-	DecisionError  Decision = "ERROR"  // prompt=none 인데 silent 불가 → login_required
+	DecisionSilent  Decision = "SILENT"  // 폼 없이 즉시 auth code 발급
+	DecisionPrompt  Decision = "PROMPT"  // 로그인 폼 렌더링
+	DecisionConsent Decision = "CONSENT" // 세션은 유효, scope 동의 화면 렌더링
+	DecisionError   Decision = "ERROR"   // prompt=none 인데 silent 불가 → login_required / consent_required
 )
 
 // Inputs: Resolve 가 결정을 내리기 위한 입력.
 // Phase-R 단순화: SessionGroupID, Group 제거.
 type Inputs struct {
-	HasSession bool
-	Client     *models.Client // .SilentSSO 만 본다
-	Prompt     string         // "" / "none" / "login"
+	HasSession     bool
+	Client         *models.Client // .SilentSSO / .FirstParty 만 본다
+	Prompt         string         // "" / "none" / "login" / "consent"
+	ConsentGranted bool           // 세션 사용자가 요청 scope 를 이미 전부 동의했는가
 }
 
 // Resolve: 정책 결정 트리.
@@ -55,6 +63,34 @@ func Resolve(in Inputs) Decision {
 		return DecisionPrompt
 	}
 
-	// 4) HasSession=true + Client.silent_sso=true + prompt 보통 → silent ✨
+	// 4) 로그인은 됐지만 동의가 필요 → 동의 화면
+	if NeedsConsent(in) {
+		if in.Prompt == "none" {
+			return DecisionError
+		}
+		return DecisionConsent
+	}
+
+	// 5) HasSession=true + Client.silent_sso=true + 동의 완료 + prompt 보통 → silent ✨
 	return DecisionSilent
 }
+
+// NeedsConsent: 동의 화면을 거쳐야 하는가. prompt=consent 는 first-party 여도 강제.
+func NeedsConsent(in Inputs) bool {
+	if in.Prompt == "consent" {
+		return true
+	}
+	if in.Client != nil && in.Client.FirstParty {
+		return false
+	}
+	return !in.ConsentGranted
+}
+
+// ErrorCode: DecisionError 의 OIDC 에러 코드 (Core §3.1.2.6).
+// 세션 + silent 는 통과했는데 동의가 없어 막힌 경우만 consent_required.
+func ErrorCode(in Inputs) string {
+	if in.HasSession && in.Client != nil && in.Client.SilentSSO && NeedsConsent(in) {
+		return "consent_required"
+	}
+	return "login_required"
+}
@@ -6,16 +6,18 @@ import (
 	"github.com/ftery0/ouath/server/models"
 )
 
-// TestResolve: Phase-R 6 케이스 진리표.
+// TestResolve: Phase-R 6 케이스 진리표 + consent 케이스.
 func TestResolve(t *testing.T) {
-	silentOn := &models.Client{ClientID: "c-on", SilentSSO: true}
-	silentOff := &models.Client{ClientID: "c-off", SilentSSO: false}
+	silentOn := &models.Client{ClientID: "c-on", SilentSSO: true, FirstParty: true}
+	silentOff := &models.Client{ClientID: "c-off", SilentSSO: false, FirstParty: true}
+	thirdParty := &models.Client{ClientID: "c-3p", SilentSSO: true}
 
 	cases := []struct {
 		name       string
 		hasSession bool
 		client     *models.Client
 		prompt     string
+		consented  bool
 		want       Decision
 	}{
 		{
@@ -55,14 +57,47 @@ func TestResolve(t *testing.T) {
 			client:     silentOff,
 			want:       DecisionError,
 		},
+		{
+			name:       "세션 있음 + third-party + 동의 없음 → CONSENT",
+			hasSession: true,
+			client:     thirdParty,
+			want:       DecisionConsent,
+		},
+		{
+			name:       "세션 있음 + third-party + 동의 완료 → SILENT",
+			hasSession: true,
+			client:     thirdParty,
+			consented:  true,
+			want:       DecisionSilent,
+		},
+		{
+			name:       "세션 있음 + third-party + 동의 없음 + prompt=none → ERROR",
+			hasSession: true,
+			prompt:     "none",
+			client:     thirdParty,
+			want:       DecisionError,
+		},
+		{
+			name:       "세션 있음 + first-party + prompt=consent → CONSENT",
+			hasSession: true,
+			prompt:     "consent",
+			client:     silentOn,
+			want:       DecisionConsent,
+		},
+		{
+			name:   "세션 없음 + third-party → PROMPT (동의는 로그인 후)",
+			client: thirdParty,
+			want:   DecisionPrompt,
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			got := Resolve(Inputs{
-				HasSession: tc.hasSession,
-				Client:     tc.client,
-				Prompt:     tc.prompt,
+				HasSession:     tc.hasSession,
+				Client:         tc.client,
+				Prompt:         tc.prompt,
+				ConsentGranted: tc.consented,
 			})
 			if got != tc.want {
 				t.Errorf("got %s, want %s", got, tc.want)
@@ -70,3 +105,19 @@ func TestResolve(t *testing.T) {
 		})
 	}
 }
+
+// TestErrorCode: prompt=none 실패 사유 구분.
+func TestErrorCode(t *testing.T) {
+	thirdParty := &models.Client{ClientID: "c-3p", SilentSSO: true}
+	silentOff := &models.Client{ClientID: "c-off", FirstParty: true}
+
+	if got := ErrorCode(Inputs{HasSession: true, Client: thirdParty, Prompt: "none"}); got != "consent_required" {
+		t.Errorf("동의 없음: got %s, want consent_required", got)
+	}
+	if got := ErrorCode(Inputs{HasSession: true, Client: silentOff, Prompt: "none"}); got != "login_required" {
+		t.Errorf("silent off: got %s, want login_required", got)
+	}
+	if got := ErrorCode(Inputs{Client: thirdParty, Prompt: "none"}); got != "login_required" {
+		t.Errorf("세션 없음: got %s, want login_required", got)
+	}
+}
@@ -15,7 +15,15 @@ func New(tmpl *template.Template) *http.ServeMux {
 	// Go 1.22부터 "METHOD /path" 형식으로 메서드별 라우팅 가능
 	mux.HandleFunc("GET /oauth/authorize", handlers.AuthorizeHandler(tmpl))
 	mux.HandleFunc("POST /oauth/login", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.LoginHandler(tmpl)))
+	mux.HandleFunc("POST /oauth/login/mfa", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.MFAHandler(tmpl)))
+
+	// 패스키 (WebAuthn) 로그인: options (challenge) → 브라우저 ceremony → 서명 검증 후 LoginHandler 와 같은 뒷단계.
+	mux.HandleFunc("POST /oauth/passkey/login/options", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.PasskeyLoginOptionsHandler))
+	mux.HandleFunc("POST /oauth/passkey/login", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.PasskeyLoginHandler(tmpl)))
+	mux.HandleFunc("POST /oauth/consent", handlers.ConsentHandler(tmpl))
 	mux.HandleFunc("POST /oauth/token", handlers.RateLimitedFunc(handlers.TokenLimiter(), handlers.TokenHandler))
+	// public client (SPA) 가 브라우저에서 직접 code 교환 — 등록된 origin 만 CORS 허용.
+	mux.HandleFunc("OPTIONS /oauth/token", handlers.TokenPreflightHandler)
 	mux.HandleFunc("GET /oauth/userinfo", handlers.UserInfoHandler)
 	// JWKS: 공개키 배포 엔드포인트 (클라이언트가 JWT 서명을 자체 검증할 때 사용)
 	mux.HandleFunc("GET /oauth/jwks", handlers.JWKSHandler)
@@ -27,18 +35,70 @@ func New(tmpl *template.Template) *http.ServeMux {
 	mux.HandleFunc("GET /oauth/logout", handlers.LogoutHandler(tmpl))
 	mux.HandleFunc("POST /oauth/logout", handlers.LogoutHandler(tmpl))
 
+	// RFC 8628 Device Authorization Grant: 기기가 코드 발급 → 사용자가 /oauth/device 에서 승인.
+	// 토큰 polling 은 /oauth/token (grant_type=urn:ietf:params:oauth:grant-type:device_code).
+	mux.HandleFunc("POST /oauth/device_authorization", handlers.RateLimitedFunc(handlers.TokenLimiter(), handlers.DeviceAuthorizationHandler))
+	mux.HandleFunc("GET /oauth/device", handlers.DeviceVerifyGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/device", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.DeviceVerifyPostHandler(tmpl)))
+
+	// 2단계 인증 (TOTP) 등록 / 해제 — IdP 세션 사용자 본인.
+	mux.HandleFunc("GET /oauth/2fa", handlers.TwoFactorGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/2fa", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.TwoFactorPostHandler(tmpl)))
+
+	// 패스키 등록 / 삭제 — IdP 세션 사용자 본인.
+	mux.HandleFunc("GET /oauth/passkeys", handlers.PasskeysGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/passkeys/options", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.PasskeyRegisterOptionsHandler))
+	mux.HandleFunc("POST /oauth/passkeys", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.PasskeysPostHandler(tmpl)))
+
+	// 계정 — 로그인된 세션 / 연결된 앱 확인 및 종료. IdP 세션 사용자 본인.
+	mux.HandleFunc("GET /account", handlers.AccountRedirectHandler)
+	mux.HandleFunc("GET /oauth/account", handlers.AccountGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/account", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.AccountPostHandler(tmpl)))
+
 	// Phase-R R-4: 회원가입
 	mux.HandleFunc("GET /oauth/register", handlers.RegisterGetHandler(tmpl))
 	mux.HandleFunc("POST /oauth/register", handlers.RateLimitedFunc(handlers.RegisterLimiter(), handlers.RegisterPostHandler(tmpl)))
+	mux.HandleFunc("POST /oauth/clients/register", handlers.RateLimitedFunc(handlers.RegisterLimiter(), handlers.ClientRegisterHandler))
+	mux.HandleFunc("GET /oauth/clients/register/{client_id}", handlers.RateLimitedFunc(handlers.TokenLimiter(), handlers.ClientConfigGetHandler))
+	mux.HandleFunc("PUT /oauth/clients/register/{client_id}", handlers.RateLimitedFunc(handlers.TokenLimiter(), handlers.ClientConfigPutHandler))
+	mux.HandleFunc("DELETE /oauth/clients/register/{client_id}", handlers.RateLimitedFunc(handlers.TokenLimiter(), handlers.ClientConfigDeleteHandler))
+
+	// 이메일 인증: 가입 / 변경 시 서명된 링크 메일 → email_verified. client 별로 인증된 이메일을 요구할 수 있다.
+	mux.HandleFunc("GET /oauth/email/verify", handlers.EmailVerifyHandler(tmpl))
+	mux.HandleFunc("GET /oauth/email", handlers.EmailGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/email", handlers.RateLimitedFunc(handlers.RegisterLimiter(), handlers.EmailPostHandler(tmpl)))
+
+	// 비밀번호 재설정: 메일 링크 (1회용, 30분) → 새 비밀번호 → 기존 세션 / refresh token 폐기.
+	mux.HandleFunc("GET /oauth/password/forgot", handlers.PasswordForgotGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/password/forgot", handlers.RateLimitedFunc(handlers.RegisterLimiter(), handlers.PasswordForgotPostHandler(tmpl)))
+	mux.HandleFunc("GET /oauth/password/reset", handlers.PasswordResetGetHandler(tmpl))
+	mux.HandleFunc("POST /oauth/password/reset", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.PasswordResetPostHandler(tmpl)))
 
-	// 어드민 (Phase 2-A): 단일 비밀번호 게이트 + read-only 시드 표시
+	// 어드민: 역할 (AdminRole) 이 있는 IdP 사용자. 라우트마다 필요한 권한, POST 는 세션 CSRF 토큰 필수.
 	mux.HandleFunc("GET /admin/login", handlers.AdminLoginGetHandler(tmpl))
-	mux.HandleFunc("POST /admin/login", handlers.AdminLoginPostHandler(tmpl))
-	mux.HandleFunc("POST /admin/logout", handlers.AdminLogoutHandler)
-	mux.Handle("GET /admin", handlers.RequireAdminAuth(handlers.AdminMainHandler(tmpl)))
-	mux.Handle("GET /admin/clients/new", handlers.RequireAdminAuth(handlers.AdminClientNewFormHandler(tmpl)))
-	mux.Handle("POST /admin/clients", handlers.RequireAdminAuth(handlers.AdminClientCreateHandler(tmpl)))
-	mux.Handle("POST /admin/clients/{id}/silent-sso", handlers.RequireAdminAuth(http.HandlerFunc(handlers.AdminClientSilentSSOHandler)))
+	mux.HandleFunc("POST /admin/login", handlers.RateLimitedFunc(handlers.LoginLimiter(), handlers.AdminLoginPostHandler(tmpl)))
+	mux.Handle("POST /admin/logout", handlers.RequireAdmin(handlers.AdminView, http.HandlerFunc(handlers.AdminLogoutHandler)))
+	mux.Handle("GET /admin", handlers.RequireAdmin(handlers.AdminView, handlers.AdminMainHandler(tmpl)))
+	mux.Handle("GET /admin/clients/new", handlers.RequireAdmin(handlers.AdminManageClients, handlers.AdminClientNewFormHandler(tmpl)))
+	mux.Handle("POST /admin/clients", handlers.RequireAdmin(handlers.AdminManageClients, handlers.AdminClientCreateHandler(tmpl)))
+	mux.Handle("GET /admin/clients/{id}/edit", handlers.RequireAdmin(handlers.AdminManageClients, handlers.AdminClientEditFormHandler(tmpl)))
+	mux.Handle("POST /admin/clients/{id}", handlers.RequireAdmin(handlers.AdminManageClients, handlers.AdminClientUpdateHandler(tmpl)))
+	mux.Handle("POST /admin/clients/{id}/rotate-secret", handlers.RequireAdmin(handlers.AdminManageClients, handlers.AdminClientRotateSecretHandler(tmpl)))
+	mux.Handle("POST /admin/clients/{id}/delete", handlers.RequireAdmin(handlers.AdminManageClients, http.HandlerFunc(handlers.AdminClientDeleteHandler)))
+	mux.Handle("POST /admin/clients/{id}/tokens/revoke", handlers.RequireAdmin(handlers.AdminManageClients, http.HandlerFunc(handlers.AdminClientTokensRevokeHandler)))
+	mux.Handle("POST /admin/clients/{id}/silent-sso", handlers.RequireAdmin(handlers.AdminManageClients, http.HandlerFunc(handlers.AdminClientSilentSSOHandler)))
+	mux.Handle("POST /admin/signing-keys/rotate", handlers.RequireAdmin(handlers.AdminSuper, http.HandlerFunc(handlers.AdminSigningKeyRotateHandler)))
+	mux.Handle("GET /admin/users", handlers.RequireAdmin(handlers.AdminView, handlers.AdminUsersHandler(tmpl)))
+	mux.Handle("GET /admin/users/{id}", handlers.RequireAdmin(handlers.AdminView, handlers.AdminUserHandler(tmpl)))
+	mux.Handle("POST /admin/users/{id}", handlers.RequireAdmin(handlers.AdminManageUsers, http.HandlerFunc(handlers.AdminUserUpdateHandler)))
+	mux.Handle("POST /admin/users/{id}/disable", handlers.RequireAdmin(handlers.AdminManageUsers, http.HandlerFunc(handlers.AdminUserDisableHandler)))
+	mux.Handle("POST /admin/users/{id}/password-reset", handlers.RequireAdmin(handlers.AdminManageUsers, http.HandlerFunc(handlers.AdminUserPasswordResetHandler)))
+	mux.Handle("POST /admin/users/{id}/delete", handlers.RequireAdmin(handlers.AdminManageUsers, http.HandlerFunc(handlers.AdminUserDeleteHandler)))
+	mux.Handle("POST /admin/users/{id}/sessions/revoke", handlers.RequireAdmin(handlers.AdminManageUsers, http.HandlerFunc(handlers.AdminUserSessionsRevokeHandler)))
+	mux.Handle("POST /admin/users/{id}/tokens/revoke", handlers.RequireAdmin(handlers.AdminManageUsers, http.HandlerFunc(handlers.AdminUserTokensRevokeHandler)))
+	mux.Handle("POST /admin/users/{id}/role", handlers.RequireAdmin(handlers.AdminSuper, http.HandlerFunc(handlers.AdminUserRoleHandler)))
+	mux.Handle("GET /admin/audit", handlers.RequireAdmin(handlers.AdminView, handlers.AdminAuditHandler(tmpl)))
+	mux.Handle("GET /admin/audit/export", handlers.RequireAdmin(handlers.AdminView, http.HandlerFunc(handlers.AdminAuditExportHandler)))
 
 	return mux
 }
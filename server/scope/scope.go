@@ -0,0 +1,149 @@
+// Package scope 는 IdP 가 아는 scope 의 전역 레지스트리.
+//
+// discovery 의 scopes_supported, 동의 화면의 설명, /oauth/authorize 의 invalid_scope 판정이
+// 모두 이 목록 하나를 본다. 레지스트리에 없는 scope 는 어떤 client 도 요청할 수 없다.
+//
+// 기본은 OIDC 표준 3 개. 앱 전용 scope (예: notes.read) 는 main 이 OAUTH_EXTRA_SCOPES 로 Register.
+package scope
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Scope: 레지스트리 한 항목.
+type Scope struct {
+	Name        string
+	Description string // 동의 화면에 그대로 노출 (사용자 언어)
+}
+
+// Defaults: 새 client 의 AllowedScopes 기본값 + 레지스트리 초기 내용.
+var Defaults = []Scope{
+	{Name: "openid", Description: "로그인 식별자 (계정 ID)"},
+	{Name: "profile", Description: "이름과 아이디"},
+	{Name: "email", Description: "이메일 주소와 인증 여부"},
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Scope{}
+)
+
+func init() {
+	for _, s := range Defaults {
+		registry[s.Name] = s
+	}
+}
+
+// Register: scope 추가 (같은 이름이면 설명 덮어씀). 부팅 시 main 에서만 호출.
+func Register(s Scope) {
+	if s.Description == "" {
+		s.Description = s.Name
+	}
+	mu.Lock()
+	registry[s.Name] = s
+	mu.Unlock()
+}
+
+// Lookup: 등록된 scope 인가.
+func Lookup(name string) (Scope, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := registry[name]
+	return s, ok
+}
+
+// All: 등록된 scope 전부. openid → 표준 → 나머지 이름순 (화면 / discovery 출력 순서 고정).
+func All() []Scope {
+	mu.RLock()
+	out := make([]Scope, 0, len(registry))
+	for _, s := range registry {
+		out = append(out, s)
+	}
+	mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		ri, rj := rank(out[i].Name), rank(out[j].Name)
+		if ri != rj {
+			return ri < rj
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Supported: discovery scopes_supported.
+func Supported() []string {
+	all := All()
+	names := make([]string, len(all))
+	for i, s := range all {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// DefaultNames: Defaults 의 이름만.
+func DefaultNames() []string {
+	names := make([]string, len(Defaults))
+	for i, s := range Defaults {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// Validate: requested (공백 구분) 가 전부 레지스트리에 있고 allowed 안에 있는가.
+// 통과하면 중복을 제거한 scope 목록을 돌려준다. 실패 시 문제의 scope 이름과 false.
+func Validate(requested string, allowed []string) ([]string, string, bool) {
+	out := make([]string, 0)
+	seen := map[string]bool{}
+	for _, s := range strings.Fields(requested) {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		if _, ok := Lookup(s); !ok || !contains(allowed, s) {
+			return nil, s, false
+		}
+		out = append(out, s)
+	}
+	return out, "", true
+}
+
+// Subset: requested 가 전부 granted 안에 있는가 (refresh downscoping).
+func Subset(requested, granted []string) bool {
+	for _, s := range requested {
+		if !contains(granted, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersect: a 중 b 에도 있는 것만 (a 의 순서 유지).
+func Intersect(a, b []string) []string {
+	out := make([]string, 0, len(a))
+	for _, s := range a {
+		if contains(b, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func rank(name string) int {
+	for i, s := range Defaults {
+		if s.Name == name {
+			return i
+		}
+	}
+	return len(Defaults)
+}
+
+func contains(ss []string, target string) bool {
+	for _, s := range ss {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}
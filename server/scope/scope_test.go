@@ -0,0 +1,49 @@
+package scope
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	allowed := []string{"openid", "email"}
+
+	cases := []struct {
+		name      string
+		requested string
+		want      []string
+		bad       string
+		ok        bool
+	}{
+		{name: "빈 scope", requested: "", want: []string{}, ok: true},
+		{name: "허용 안", requested: "openid email", want: []string{"openid", "email"}, ok: true},
+		{name: "중복 제거", requested: "openid openid", want: []string{"openid"}, ok: true},
+		{name: "레지스트리엔 있지만 client 허용 밖", requested: "openid profile", bad: "profile"},
+		{name: "지어낸 scope", requested: "admin", bad: "admin"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, bad, ok := Validate(tc.requested, allowed)
+			if ok != tc.ok || bad != tc.bad || (ok && !reflect.DeepEqual(got, tc.want)) {
+				t.Errorf("got (%v, %q, %v), want (%v, %q, %v)", got, bad, ok, tc.want, tc.bad, tc.ok)
+			}
+		})
+	}
+}
+
+func TestSupported_OrderAndRegister(t *testing.T) {
+	Register(Scope{Name: "notes.read"})
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(registry, "notes.read")
+		mu.Unlock()
+	})
+
+	want := []string{"openid", "profile", "email", "notes.read"}
+	if got := Supported(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if s, _ := Lookup("notes.read"); s.Description != "notes.read" {
+		t.Errorf("설명 기본값: got %q", s.Description)
+	}
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AccessTokenCutoffs: 외부 노출. 기본은 인메모리, main 이 DATABASE_URL 보고 Postgres 로 교체.
+var AccessTokenCutoffs AccessTokenCutoffStore = NewMemoryAccessTokenCutoffStore()
+
+// NewMemoryAccessTokenCutoffStore: 단일 인스턴스 / 테스트용.
+func NewMemoryAccessTokenCutoffStore() AccessTokenCutoffStore {
+	return &memoryAccessTokenCutoffStore{m: make(map[string]accessTokenCutoff), families: make(map[string]accessTokenCutoff)}
+}
+
+type accessTokenCutoff struct {
+	at        time.Time
+	expiresAt time.Time
+}
+
+// memoryAccessTokenCutoffStore: m 의 key = user_id + "\x00" + client_id, families 의 key = family_id.
+type memoryAccessTokenCutoffStore struct {
+	mu       sync.RWMutex
+	m        map[string]accessTokenCutoff
+	families map[string]accessTokenCutoff
+}
+
+func (s *memoryAccessTokenCutoffStore) Set(ctx context.Context, userID, clientID string, cutoff, expiresAt time.Time) error {
+	s.mu.Lock()
+	s.m[userID+"\x00"+clientID] = accessTokenCutoff{at: cutoff, expiresAt: expiresAt}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryAccessTokenCutoffStore) Latest(ctx context.Context, userID, clientID string) (time.Time, error) {
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var latest time.Time
+	for _, key := range []string{userID + "\x00", "\x00" + clientID, userID + "\x00" + clientID} {
+		if c, ok := s.m[key]; ok && now.Before(c.expiresAt) && c.at.After(latest) {
+			latest = c.at
+		}
+	}
+	return latest, nil
+}
+
+func (s *memoryAccessTokenCutoffStore) SetFamily(ctx context.Context, familyID string, cutoff, expiresAt time.Time) error {
+	s.mu.Lock()
+	s.families[familyID] = accessTokenCutoff{at: cutoff, expiresAt: expiresAt}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryAccessTokenCutoffStore) LatestFamily(ctx context.Context, familyID string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if c, ok := s.families[familyID]; ok && time.Now().Before(c.expiresAt) {
+		return c.at, nil
+	}
+	return time.Time{}, nil
+}
+
+func (s *memoryAccessTokenCutoffStore) SweepExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for _, m := range []map[string]accessTokenCutoff{s.m, s.families} {
+		for k, c := range m {
+			if now.After(c.expiresAt) {
+				delete(m, k)
+				removed++
+			}
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemoryAccessTokenCutoffStore_Latest: 사용자 / client / 쌍 중 만료 전 가장 늦은 기준, 다른 키는 무관.
+func TestMemoryAccessTokenCutoffStore_Latest(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryAccessTokenCutoffStore()
+	now := time.Now()
+	live := now.Add(time.Minute)
+	_ = s.Set(ctx, "u1", "", now.Add(-3*time.Second), live)
+	_ = s.Set(ctx, "", "app1", now.Add(-2*time.Second), live)
+	_ = s.Set(ctx, "u1", "app1", now.Add(-time.Second), now.Add(-time.Millisecond)) // 만료
+	_ = s.Set(ctx, "u2", "app2", now, live)
+
+	tests := []struct {
+		name           string
+		userID, client string
+		want           time.Time
+	}{
+		{"사용자·client 둘 다", "u1", "app1", now.Add(-2 * time.Second)},
+		{"사용자만", "u1", "app2", now.Add(-3 * time.Second)},
+		{"client 만", "u3", "app1", now.Add(-2 * time.Second)},
+		{"쌍 단위는 그 쌍만", "u2", "app1", now.Add(-2 * time.Second)},
+		{"해당 없음", "u3", "app3", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Latest(ctx, tt.userID, tt.client)
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("Latest(%s, %s) = %v, %v; want %v", tt.userID, tt.client, got, err, tt.want)
+			}
+		})
+	}
+
+	if n, _ := s.SweepExpired(ctx); n != 1 {
+		t.Errorf("SweepExpired = %d, want 1", n)
+	}
+}
+
+// TestMemoryAccessTokenCutoffStore_Family: family 단위 기준은 그 family 만, 만료되면 zero 로 정리.
+func TestMemoryAccessTokenCutoffStore_Family(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryAccessTokenCutoffStore()
+	now := time.Now()
+	_ = s.SetFamily(ctx, "f1", now, now.Add(time.Minute))
+	_ = s.SetFamily(ctx, "f2", now, now.Add(-time.Millisecond)) // 만료
+
+	if got, err := s.LatestFamily(ctx, "f1"); err != nil || !got.Equal(now) {
+		t.Errorf("LatestFamily(f1) = %v, %v; want %v", got, err, now)
+	}
+	for _, fid := range []string{"f2", "f3"} {
+		if got, err := s.LatestFamily(ctx, fid); err != nil || !got.IsZero() {
+			t.Errorf("LatestFamily(%s) = %v, %v; want zero", fid, got, err)
+		}
+	}
+	if got, _ := s.Latest(ctx, "f1", ""); !got.IsZero() {
+		t.Errorf("family 기준이 사용자 기준에 섞임: %v", got)
+	}
+	if n, _ := s.SweepExpired(ctx); n != 1 {
+		t.Errorf("SweepExpired = %d, want 1", n)
+	}
+}
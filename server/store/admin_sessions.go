@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AdminSessions: 외부 노출. 기본은 인메모리, main 이 DATABASE_URL 보고 Postgres 로 교체.
+var AdminSessions AdminSessionStore = NewMemoryAdminSessionStore()
+
+// NewMemoryAdminSessionStore: 단일 인스턴스 / 테스트용.
+func NewMemoryAdminSessionStore() AdminSessionStore {
+	return &memoryAdminSessionStore{m: make(map[string]adminSessionEntry)}
+}
+
+type adminSessionEntry struct {
+	userID    string
+	expiresAt time.Time
+}
+
+// memoryAdminSessionStore: key = HashToken(id).
+type memoryAdminSessionStore struct {
+	mu sync.Mutex
+	m  map[string]adminSessionEntry
+}
+
+func (s *memoryAdminSessionStore) Create(ctx context.Context, id, userID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	s.m[HashToken(id)] = adminSessionEntry{userID: userID, expiresAt: expiresAt}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryAdminSessionStore) Get(ctx context.Context, id string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.m[HashToken(id)]
+	if !ok || !time.Now().Before(e.expiresAt) {
+		return "", ErrAdminSessionNotFound
+	}
+	return e.userID, nil
+}
+
+func (s *memoryAdminSessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.m, HashToken(id))
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryAdminSessionStore) DeleteByUser(ctx context.Context, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, e := range s.m {
+		if e.userID == userID {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *memoryAdminSessionStore) SweepExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, e := range s.m {
+		if now.After(e.expiresAt) {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+)
+
+// Audit: 외부 노출. 기본 nil — 감사 로그는 stdout 으로만 흐른다.
+// main 이 OAUTH_AUDIT_STORE 를 보고 인메모리 / Postgres 로 주입.
+var Audit AuditStore
+
+// NewMemoryAuditStore: 개발용. 최근 max 건만 유지 (오래된 것부터 버림).
+func NewMemoryAuditStore(max int) AuditStore {
+	return &memoryAuditStore{max: max}
+}
+
+// memoryAuditStore: 시간순 slice. 조회는 뒤에서부터 (최신순).
+type memoryAuditStore struct {
+	mu      sync.RWMutex
+	max     int
+	nextID  int64
+	entries []*models.AuditEntry
+}
+
+func (s *memoryAuditStore) Append(ctx context.Context, e *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	cp := *e
+	cp.ID = s.nextID
+	s.entries = append(s.entries, &cp)
+	if s.max > 0 && len(s.entries) > s.max {
+		s.entries = append([]*models.AuditEntry(nil), s.entries[len(s.entries)-s.max:]...)
+	}
+	return nil
+}
+
+func (s *memoryAuditStore) List(ctx context.Context, q AuditQuery) ([]*models.AuditEntry, int, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultAuditPageSize
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*models.AuditEntry{}
+	total := 0
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if !auditMatches(e, q) {
+			continue
+		}
+		if total >= q.Offset && len(out) < q.Limit {
+			cp := *e
+			out = append(out, &cp)
+		}
+		total++
+	}
+	return out, total, nil
+}
+
+// auditMatches: AuditQuery 조건 — Postgres 구현의 WHERE 와 같은 의미.
+func auditMatches(e *models.AuditEntry, q AuditQuery) bool {
+	if len(q.Users) > 0 {
+		found := false
+		for _, u := range q.Users {
+			if u != "" && (e.Actor == u || e.Subject == u) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.ClientID != "" && e.ClientID != q.ClientID {
+		return false
+	}
+	if q.Event != "" && !strings.HasPrefix(e.Event, q.Event) {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+func (s *memoryAuditStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := 0
+	for i < len(s.entries) && s.entries[i].Time.Before(before) {
+		i++
+	}
+	s.entries = append([]*models.AuditEntry(nil), s.entries[i:]...)
+	return i, nil
+}
+
+// StartAuditPrune: 보존 기간이 지난 감사 로그를 한 시간마다 삭제. Audit 주입 후 main 에서 한 번 호출.
+func StartAuditPrune(retention time.Duration) {
+	if Audit == nil || retention <= 0 {
+		return
+	}
+	prune := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if n, err := Audit.Prune(ctx, time.Now().Add(-retention)); err != nil {
+			log.Printf("[audit] prune failed: %v", err)
+		} else if n > 0 {
+			log.Printf("[audit] pruned %d events older than %s", n, retention)
+		}
+	}
+	go func() {
+		prune()
+		t := time.NewTicker(time.Hour)
+		defer t.Stop()
+		for range t.C {
+			prune()
+		}
+	}()
+}
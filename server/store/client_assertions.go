@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ClientAssertions: 외부 노출. 기본은 인메모리, main 이 DATABASE_URL 보고 Postgres 로 교체.
+var ClientAssertions ClientAssertionStore = &memoryClientAssertionStore{m: make(map[string]time.Time)}
+
+// memoryClientAssertionStore: key = client_id + "\x00" + jti, 값 = 만료 시각.
+type memoryClientAssertionStore struct {
+	mu sync.Mutex
+	m  map[string]time.Time
+}
+
+func (s *memoryClientAssertionStore) Use(ctx context.Context, clientID, jti string, expiresAt time.Time) error {
+	key := clientID + "\x00" + jti
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if exp, ok := s.m[key]; ok && time.Now().Before(exp) {
+		return ErrClientAssertionReplayed
+	}
+	s.m[key] = expiresAt
+	return nil
+}
+
+func (s *memoryClientAssertionStore) SweepExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, exp := range s.m {
+		if now.After(exp) {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
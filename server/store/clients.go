@@ -11,6 +11,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/scope"
 )
 
 // defaultMemoryClients: 인메모리 기본 인스턴스. main 이 DATABASE_URL 보고
@@ -74,11 +75,18 @@ func EnsureSecretHash(c *models.Client) error {
 }
 
 // VerifySecret: 평문과 저장된 hash 를 bcrypt 비교. /token Basic auth 검증용.
+// 회전 유예 중이면 직전 secret 도 통과. secret 을 쓰지 않는 client (public / private_key_jwt) 는 항상 false.
 func VerifySecret(c *models.Client, plain string) bool {
-	if c == nil || c.ClientSecretHash == "" {
+	if c == nil || !c.UsesSecret() || c.ClientSecretHash == "" {
 		return false
 	}
-	return bcrypt.CompareHashAndPassword([]byte(c.ClientSecretHash), []byte(plain)) == nil
+	if bcrypt.CompareHashAndPassword([]byte(c.ClientSecretHash), []byte(plain)) == nil {
+		return true
+	}
+	if c.PreviousSecretHash == "" || !time.Now().Before(c.PreviousSecretExpiresAt) {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(c.PreviousSecretHash), []byte(plain)) == nil
 }
 
 func mustEnsureSecretHash(c *models.Client) {
@@ -102,6 +110,9 @@ func seedClient(id, name string, backendPort, frontendPort int) *models.Client {
 		OwnerID:      "",
 		CreatedAt:    time.Now(),
 		SilentSSO:    true,
+		FirstParty:   true,
+
+		AllowedScopes: scope.DefaultNames(),
 	}
 }
 
@@ -122,12 +133,74 @@ func (s *clientStore) UpdateSilentSSO(clientID string, silentSSO bool) error {
 	defer clientMutex.Unlock()
 	c, ok := s.byID[clientID]
 	if !ok {
-		return errors.New("client not found")
+		return ErrClientNotFound
 	}
 	c.SilentSSO = silentSSO
 	return nil
 }
 
+// Update: 편집 가능한 필드만 기존 인스턴스에 복사 (인메모리). secret / 등록 토큰 / created_at 은 그대로.
+func (s *clientStore) Update(u *models.Client) error {
+	clientMutex.Lock()
+	defer clientMutex.Unlock()
+	c, ok := s.byID[u.ClientID]
+	if !ok {
+		return ErrClientNotFound
+	}
+	c.Name = u.Name
+	c.Description = u.Description
+	c.MainURL = u.MainURL
+	c.ServerURLs = u.ServerURLs
+	c.RedirectURIs = u.RedirectURIs
+	c.SilentSSO = u.SilentSSO
+	c.FirstParty = u.FirstParty
+	c.ClientCredentials = u.ClientCredentials
+	c.ClientCredentialsScopes = u.ClientCredentialsScopes
+	c.AllowedScopes = u.AllowedScopes
+	c.RequireVerifiedEmail = u.RequireVerifiedEmail
+	c.LogoURI = u.LogoURI
+	c.GrantTypes = u.GrantTypes
+	c.TokenEndpointAuthMethod = u.TokenEndpointAuthMethod
+	c.ClientType = u.ClientType
+	c.JWKS = u.JWKS
+	c.JWKSURI = u.JWKSURI
+	c.BackchannelLogoutURI = u.BackchannelLogoutURI
+	c.FrontchannelLogoutURI = u.FrontchannelLogoutURI
+	return nil
+}
+
+// RotateSecret: 현재 hash → PreviousSecretHash (grace 동안), newHash → 현재 (인메모리).
+func (s *clientStore) RotateSecret(clientID, newHash, newSealed string, grace time.Duration) error {
+	clientMutex.Lock()
+	defer clientMutex.Unlock()
+	c, ok := s.byID[clientID]
+	if !ok {
+		return ErrClientNotFound
+	}
+	if grace > 0 {
+		c.PreviousSecretHash = c.ClientSecretHash
+		c.PreviousSecretExpiresAt = time.Now().Add(grace)
+	} else {
+		c.PreviousSecretHash = ""
+		c.PreviousSecretExpiresAt = time.Time{}
+	}
+	c.ClientSecretHash = newHash
+	c.ClientSecretSealed = newSealed
+	c.ClientSecret = ""
+	return nil
+}
+
+// Delete: client 제거 (인메모리).
+func (s *clientStore) Delete(clientID string) error {
+	clientMutex.Lock()
+	defer clientMutex.Unlock()
+	if _, ok := s.byID[clientID]; !ok {
+		return ErrClientNotFound
+	}
+	delete(s.byID, clientID)
+	return nil
+}
+
 // GetByClientID: client_id 로 클라이언트 조회
 func (s *clientStore) GetByClientID(clientID string) (*models.Client, bool) {
 	clientMutex.RLock()
@@ -157,11 +230,14 @@ func (s *clientStore) Register(c *models.Client) error {
 	if c.ClientID == "" {
 		c.ClientID = "client-" + randomHex(8)
 	}
-	if c.ClientSecret == "" && c.ClientSecretHash == "" {
-		c.ClientSecret = randomHex(32)
-	}
-	if err := EnsureSecretHash(c); err != nil {
-		return err
+	// public / private_key_jwt client 는 secret 자체가 없다
+	if c.UsesSecret() {
+		if c.ClientSecret == "" && c.ClientSecretHash == "" {
+			c.ClientSecret = randomHex(32)
+		}
+		if err := EnsureSecretHash(c); err != nil {
+			return err
+		}
 	}
 	s.register(c)
 	return nil
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+)
+
+// Consents: 외부 노출. 기본은 인메모리, main 이 DATABASE_URL 보고 Postgres 로 교체.
+var Consents ConsentStore = &memoryConsentStore{m: make(map[consentKey]*models.ConsentGrant)}
+
+type consentKey struct{ userID, clientID string }
+
+// memoryConsentStore: map + Mutex. key = (user, client).
+type memoryConsentStore struct {
+	mu sync.Mutex
+	m  map[consentKey]*models.ConsentGrant
+}
+
+func (s *memoryConsentStore) Get(ctx context.Context, userID, clientID string) (*models.ConsentGrant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.m[consentKey{userID, clientID}]
+	if !ok {
+		return nil, ErrConsentNotFound
+	}
+	cp := *g
+	cp.Scopes = append([]string(nil), g.Scopes...)
+	return &cp, nil
+}
+
+func (s *memoryConsentStore) Grant(ctx context.Context, userID, clientID string, scopes []string) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := consentKey{userID, clientID}
+	g, ok := s.m[key]
+	if !ok {
+		g = &models.ConsentGrant{UserID: userID, ClientID: clientID, Scopes: []string{}, CreatedAt: now}
+		s.m[key] = g
+	}
+	for _, sc := range scopes {
+		if !g.Covers([]string{sc}) {
+			g.Scopes = append(g.Scopes, sc)
+		}
+	}
+	g.UpdatedAt = now
+	return nil
+}
+
+func (s *memoryConsentStore) Revoke(ctx context.Context, userID, clientID string) error {
+	s.mu.Lock()
+	delete(s.m, consentKey{userID, clientID})
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryConsentStore) DeleteByUser(ctx context.Context, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k := range s.m {
+		if k.userID == userID {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *memoryConsentStore) DeleteByClient(ctx context.Context, clientID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k := range s.m {
+		if k.clientID == clientID {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+)
+
+// DeviceCodes: 외부 노출. 기본은 인메모리, main 이 DATABASE_URL 보고 Postgres 로 교체.
+var DeviceCodes DeviceCodeStore = &memoryDeviceCodeStore{m: make(map[string]*models.DeviceCode)}
+
+// Device flow polling 결과. Poll 이 토큰 발급 가능 (approved) 이 아닐 때 돌려주는 에러 —
+// /token 이 RFC 8628 §3.5 에러 코드로 그대로 옮긴다.
+var (
+	ErrDeviceCodeNotFound         = errors.New("device code not found")
+	ErrDeviceCodeExpired          = errors.New("device code expired")
+	ErrDeviceAuthorizationPending = errors.New("device authorization pending")
+	ErrDeviceSlowDown             = errors.New("device polling too fast")
+	ErrDeviceAccessDenied         = errors.New("device authorization denied")
+)
+
+// DeviceSlowDownStep: slow_down 응답마다 늘어나는 polling 간격 (RFC 8628 §3.5).
+const DeviceSlowDownStep = 5 * time.Second
+
+// ApplyDevicePoll: Poll 의 상태 전이 (구현체 공통). dc 를 제자리에서 갱신한다.
+//
+//	client 불일치         → ErrDeviceCodeNotFound (남의 device_code 로는 상태를 건드리지 않음)
+//	만료                  → ErrDeviceCodeExpired
+//	approved / denied     → consume=true (호출자가 행 삭제), denied 면 ErrDeviceAccessDenied
+//	pending + 간격 미달   → Interval += 5s, ErrDeviceSlowDown
+//	pending               → LastPolledAt = now, ErrDeviceAuthorizationPending
+func ApplyDevicePoll(dc *models.DeviceCode, clientID string, now time.Time) (consume bool, err error) {
+	if dc.ClientID != clientID {
+		return false, ErrDeviceCodeNotFound
+	}
+	if now.After(dc.ExpiresAt) {
+		return true, ErrDeviceCodeExpired
+	}
+	switch dc.Status {
+	case models.DeviceCodeApproved:
+		return true, nil
+	case models.DeviceCodeDenied:
+		return true, ErrDeviceAccessDenied
+	}
+	if !dc.LastPolledAt.IsZero() && now.Sub(dc.LastPolledAt) < dc.Interval {
+		dc.Interval += DeviceSlowDownStep
+		dc.LastPolledAt = now
+		return false, ErrDeviceSlowDown
+	}
+	dc.LastPolledAt = now
+	return false, ErrDeviceAuthorizationPending
+}
+
+// memoryDeviceCodeStore: map + Mutex. key = HashToken(device_code).
+// user_code 조회는 선형 탐색 — 동시 진행 중인 device 요청 수는 작고 10분이면 사라진다.
+type memoryDeviceCodeStore struct {
+	mu sync.Mutex
+	m  map[string]*models.DeviceCode
+}
+
+func (s *memoryDeviceCodeStore) Save(ctx context.Context, dc *models.DeviceCode) error {
+	cp := *dc
+	cp.DeviceCode = ""
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.m {
+		if existing.UserCode == dc.UserCode {
+			return ErrUserCodeConflict
+		}
+	}
+	s.m[HashToken(dc.DeviceCode)] = &cp
+	return nil
+}
+
+func (s *memoryDeviceCodeStore) GetByUserCode(ctx context.Context, userCode string) (*models.DeviceCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dc := s.pendingByUserCode(userCode, time.Now())
+	if dc == nil {
+		return nil, ErrDeviceCodeNotFound
+	}
+	cp := *dc
+	return &cp, nil
+}
+
+func (s *memoryDeviceCodeStore) Approve(ctx context.Context, userCode, userID string, authTime time.Time, amr []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dc := s.pendingByUserCode(userCode, time.Now())
+	if dc == nil {
+		return ErrDeviceCodeNotFound
+	}
+	dc.Status = models.DeviceCodeApproved
+	dc.UserID = userID
+	dc.AuthTime = authTime
+	dc.AMR = amr
+	return nil
+}
+
+func (s *memoryDeviceCodeStore) Deny(ctx context.Context, userCode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dc := s.pendingByUserCode(userCode, time.Now())
+	if dc == nil {
+		return ErrDeviceCodeNotFound
+	}
+	dc.Status = models.DeviceCodeDenied
+	return nil
+}
+
+// Poll: ApplyDevicePoll 을 임계구역 안에서 — 같은 승인으로 두 번 토큰이 나가지 않는다.
+func (s *memoryDeviceCodeStore) Poll(ctx context.Context, deviceCode, clientID string) (*models.DeviceCode, error) {
+	key := HashToken(deviceCode)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dc, ok := s.m[key]
+	if !ok {
+		return nil, ErrDeviceCodeNotFound
+	}
+	consume, err := ApplyDevicePoll(dc, clientID, time.Now())
+	if consume {
+		delete(s.m, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp := *dc
+	return &cp, nil
+}
+
+func (s *memoryDeviceCodeStore) SweepExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, dc := range s.m {
+		if now.After(dc.ExpiresAt) {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// pendingByUserCode: 호출자가 mu 를 잡고 있어야 한다.
+func (s *memoryDeviceCodeStore) pendingByUserCode(userCode string, now time.Time) *models.DeviceCode {
+	for _, dc := range s.m {
+		if dc.UserCode == userCode && dc.Status == models.DeviceCodePending && now.Before(dc.ExpiresAt) {
+			return dc
+		}
+	}
+	return nil
+}
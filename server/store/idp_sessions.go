@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"log"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,43 +16,73 @@ import (
 // 너무 길면 탈취 위험, 너무 짧으면 사용자 불편. 학습용 8시간.
 const IdPSessionTTL = 8 * time.Hour
 
-// idpSessionStore: 단순 map + Mutex.
+// memoryIdPSessionStore: 단순 map + Mutex. DATABASE_URL 이 없을 때의 기본값.
 //
 // sync.Map 이 아니라 Mutex 를 쓰는 이유:
 //   - Get → expired 검사 → 삭제 같은 조합 연산이 atomic 해야 한다
 //   - 이걸 sync.Map 단독으로 하면 race 로 좀비 세션 부활 가능
-type idpSessionStore struct {
-	mu sync.Mutex
-	m  map[string]*models.IdPSession
+//
+// byUser: userID → sid 집합. 사용자별 조회 / 일괄 폐기가 전체 map 을 훑지 않도록. m 과 항상 같이 갱신한다.
+type memoryIdPSessionStore struct {
+	mu     sync.Mutex
+	m      map[string]*models.IdPSession
+	byUser map[string]map[string]struct{}
+}
+
+// IdPSessions: 패키지 진입점. 기본은 인메모리, main 이 DATABASE_URL 보고 Postgres 로 교체.
+// 청소 goroutine 은 main 이 StartIdPSessionCleanup 으로 기동.
+var IdPSessions IdPSessionStore = &memoryIdPSessionStore{
+	m:      make(map[string]*models.IdPSession),
+	byUser: make(map[string]map[string]struct{}),
 }
 
-// IdPSessions: 패키지 진입점. main 에서 StartCleanup 으로 청소 goroutine 기동.
-var IdPSessions = &idpSessionStore{m: make(map[string]*models.IdPSession)}
+// remove: m + byUser 에서 함께 삭제. mu 를 잡은 상태에서 호출.
+func (s *memoryIdPSessionStore) remove(sid string) {
+	sess, ok := s.m[sid]
+	if !ok {
+		return
+	}
+	delete(s.m, sid)
+	if set := s.byUser[sess.UserID]; set != nil {
+		delete(set, sid)
+		if len(set) == 0 {
+			delete(s.byUser, sess.UserID)
+		}
+	}
+}
 
 // Create: 새 sessionID 발급 + 저장. 세션 고정 공격 방어를 위해 매 로그인마다 호출.
 // 기존 sid 가 있었다면 호출자(login handler) 가 Delete 로 명시적으로 폐기해야 한다.
 //
 // Phase-R: groupID 인자 제거 (글로벌 user pool).
-func (s *idpSessionStore) Create(userID string) (string, error) {
-	sid, err := randomHex32()
+func (s *memoryIdPSessionStore) Create(userID string, amr []string, ip, userAgent string) (string, error) {
+	sid, err := NewSessionID()
 	if err != nil {
 		return "", err
 	}
 	now := time.Now()
 	s.mu.Lock()
 	s.m[sid] = &models.IdPSession{
-		SessionID: sid,
-		UserID:    userID,
-		LoginAt:   now,
-		ExpiresAt: now.Add(IdPSessionTTL),
+		SessionID:  sid,
+		UserID:     userID,
+		LoginAt:    now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(IdPSessionTTL),
+		AMR:        amr,
+		IP:         ip,
+		UserAgent:  TruncateUserAgent(userAgent),
+	}
+	if s.byUser[userID] == nil {
+		s.byUser[userID] = make(map[string]struct{})
 	}
+	s.byUser[userID][sid] = struct{}{}
 	s.mu.Unlock()
 	return sid, nil
 }
 
 // Get: sid 로 세션 조회. 만료된 세션은 자동으로 제거하고 (nil, false) 반환.
 // 복사본을 반환해 호출자의 변형이 store 를 오염시키지 않도록 한다.
-func (s *idpSessionStore) Get(sid string) (*models.IdPSession, bool) {
+func (s *memoryIdPSessionStore) Get(sid string) (*models.IdPSession, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	sess, ok := s.m[sid]
@@ -58,15 +90,16 @@ func (s *idpSessionStore) Get(sid string) (*models.IdPSession, bool) {
 		return nil, false
 	}
 	if sess.Expired() {
-		delete(s.m, sid)
+		s.remove(sid)
 		return nil, false
 	}
 	clone := *sess
+	clone.ClientIDs = slices.Clone(sess.ClientIDs)
 	return &clone, true
 }
 
 // Touch: 만료시간 갱신 (Phase-R: groupID 인자 제거).
-func (s *idpSessionStore) Touch(sid string) {
+func (s *memoryIdPSessionStore) Touch(sid string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if sess, ok := s.m[sid]; ok && !sess.Expired() {
@@ -74,43 +107,102 @@ func (s *idpSessionStore) Touch(sid string) {
 	}
 }
 
+// AddClient: code 발급 client 기록 + LastSeenAt 갱신. 만료 세션엔 붙이지 않는다.
+func (s *memoryIdPSessionStore) AddClient(sid, clientID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.m[sid]
+	if !ok || sess.Expired() {
+		return
+	}
+	sess.LastSeenAt = time.Now()
+	if !slices.Contains(sess.ClientIDs, clientID) {
+		sess.ClientIDs = append(sess.ClientIDs, clientID)
+	}
+}
+
+// ListByUser: 사용자의 만료 전 세션 (복사본), 최근 사용 순.
+func (s *memoryIdPSessionStore) ListByUser(userID string) ([]*models.IdPSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*models.IdPSession
+	for sid := range s.byUser[userID] {
+		sess := s.m[sid]
+		if sess == nil || sess.Expired() {
+			continue
+		}
+		clone := *sess
+		clone.ClientIDs = slices.Clone(sess.ClientIDs)
+		out = append(out, &clone)
+	}
+	slices.SortFunc(out, func(a, b *models.IdPSession) int { return b.LastSeenAt.Compare(a.LastSeenAt) })
+	return out, nil
+}
+
 // Delete: 명시적 세션 폐기 (logout / 세션 고정 방어).
-func (s *idpSessionStore) Delete(sid string) {
+func (s *memoryIdPSessionStore) Delete(sid string) {
 	s.mu.Lock()
-	delete(s.m, sid)
+	s.remove(sid)
 	s.mu.Unlock()
 }
 
+func (s *memoryIdPSessionStore) DeleteByUser(userID string) ([]*models.IdPSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed []*models.IdPSession
+	for sid := range s.byUser[userID] {
+		removed = append(removed, s.m[sid])
+		delete(s.m, sid)
+	}
+	delete(s.byUser, userID)
+	return removed, nil
+}
+
 // SweepExpired: 만료된 세션 일괄 정리. 청소 goroutine 이 주기적으로 호출.
-func (s *idpSessionStore) SweepExpired() int {
+func (s *memoryIdPSessionStore) SweepExpired() (int, error) {
 	now := time.Now()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	removed := 0
 	for sid, sess := range s.m {
 		if now.After(sess.ExpiresAt) {
-			delete(s.m, sid)
+			s.remove(sid)
 			removed++
 		}
 	}
-	return removed
+	return removed, nil
+}
+
+// maxUserAgentLen: 세션에 남기는 User-Agent 상한. 표시용이라 잘려도 무방하다.
+const maxUserAgentLen = 512
+
+// TruncateUserAgent: 인메모리 / Postgres 공용. 비정상적으로 긴 헤더로 저장소를 부풀리지 못하게.
+func TruncateUserAgent(ua string) string {
+	if len(ua) <= maxUserAgentLen {
+		return ua
+	}
+	return strings.ToValidUTF8(ua[:maxUserAgentLen], "")
 }
 
-// StartCleanup: 5분 주기로 SweepExpired 실행하는 goroutine 기동.
-// main 에서 한 번 호출. 학습용으로 context 종료는 따로 처리하지 않는다.
-func (s *idpSessionStore) StartCleanup() {
+// StartIdPSessionCleanup: 5분 주기로 IdPSessions.SweepExpired 실행하는 goroutine 기동.
+// main 에서 store 교체 후 한 번 호출. 학습용으로 context 종료는 따로 처리하지 않는다.
+func StartIdPSessionCleanup() {
 	go func() {
 		t := time.NewTicker(5 * time.Minute)
 		defer t.Stop()
 		for range t.C {
-			if n := s.SweepExpired(); n > 0 {
+			n, err := IdPSessions.SweepExpired()
+			if err != nil {
+				log.Printf("[idp_sessions] sweep failed: %v", err)
+			} else if n > 0 {
 				log.Printf("[idp_sessions] swept %d expired sessions", n)
 			}
 		}
 	}()
 }
 
-func randomHex32() (string, error) {
+// NewSessionID: IdP 세션 ID. 인메모리 / Postgres 구현체 공용.
+func NewSessionID() (string, error) {
 	b := make([]byte, 32) // 32바이트 = 256bit 엔트로피
 	if _, err := rand.Read(b); err != nil {
 		return "", err
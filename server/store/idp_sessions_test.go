@@ -0,0 +1,131 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+)
+
+func newTestIdPSessionStore() *memoryIdPSessionStore {
+	return &memoryIdPSessionStore{
+		m:      make(map[string]*models.IdPSession),
+		byUser: make(map[string]map[string]struct{}),
+	}
+}
+
+// expire: 테스트용 — 세션을 이미 만료된 상태로.
+func (s *memoryIdPSessionStore) expire(sid string) {
+	s.mu.Lock()
+	s.m[sid].ExpiresAt = time.Now().Add(-time.Second)
+	s.mu.Unlock()
+}
+
+// TestMemoryIdPSessionStore_ListByUser: 본인 것 중 만료 전 세션만, 최근 사용 순.
+func TestMemoryIdPSessionStore_ListByUser(t *testing.T) {
+	s := newTestIdPSessionStore()
+	older, _ := s.Create("u1", []string{models.AMRPassword}, "10.0.0.1", "ua-1")
+	newer, _ := s.Create("u1", []string{models.AMRPassword}, "10.0.0.2", "ua-2")
+	expired, _ := s.Create("u1", nil, "", "")
+	_, _ = s.Create("u2", nil, "", "")
+	s.expire(expired)
+	time.Sleep(time.Millisecond)
+	s.AddClient(newer, "app1")
+
+	list, err := s.ListByUser("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].SessionID != newer || list[1].SessionID != older {
+		t.Fatalf("ListByUser = %d 개, want [newer older]", len(list))
+	}
+	if list[1].IP != "10.0.0.1" || list[1].UserAgent != "ua-1" {
+		t.Errorf("ip / ua = %q / %q", list[1].IP, list[1].UserAgent)
+	}
+}
+
+// TestMemoryIdPSessionStore_AddClient: 중복 없이 발급 순, 만료 세션에는 붙지 않는다.
+func TestMemoryIdPSessionStore_AddClient(t *testing.T) {
+	s := newTestIdPSessionStore()
+	sid, _ := s.Create("u1", nil, "", "")
+	for _, c := range []string{"app1", "app2", "app1"} {
+		s.AddClient(sid, c)
+	}
+	sess, ok := s.Get(sid)
+	if !ok || len(sess.ClientIDs) != 2 || sess.ClientIDs[0] != "app1" || sess.ClientIDs[1] != "app2" {
+		t.Fatalf("ClientIDs = %v, want [app1 app2]", sess.ClientIDs)
+	}
+
+	// 반환값은 복사본
+	sess.ClientIDs[0] = "tampered"
+	if again, _ := s.Get(sid); again.ClientIDs[0] != "app1" {
+		t.Error("Get 결과 수정이 store 에 반영됨")
+	}
+
+	s.expire(sid)
+	s.AddClient(sid, "app3")
+	if got := s.m[sid].ClientIDs; len(got) != 2 {
+		t.Errorf("만료 세션에 client 추가됨: %v", got)
+	}
+}
+
+// TestMemoryIdPSessionStore_DeleteByUser: 지운 세션을 (client 목록째) 돌려주고 다른 사용자는 건드리지 않는다.
+func TestMemoryIdPSessionStore_DeleteByUser(t *testing.T) {
+	s := newTestIdPSessionStore()
+	a, _ := s.Create("u1", nil, "", "")
+	b, _ := s.Create("u1", nil, "", "")
+	other, _ := s.Create("u2", nil, "", "")
+	s.AddClient(a, "app1")
+
+	removed, err := s.DeleteByUser("u1")
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("DeleteByUser = %d 개 (err=%v), want 2", len(removed), err)
+	}
+	clients := 0
+	for _, sess := range removed {
+		if sess.SessionID != a && sess.SessionID != b {
+			t.Errorf("엉뚱한 세션 반환: %s", sess.SessionID)
+		}
+		clients += len(sess.ClientIDs)
+	}
+	if clients != 1 {
+		t.Errorf("반환된 세션의 client 수 = %d, want 1 (logout 통지 대상)", clients)
+	}
+	if _, ok := s.Get(a); ok {
+		t.Error("삭제된 세션이 Get 됨")
+	}
+	if _, ok := s.Get(other); !ok {
+		t.Error("다른 사용자 세션이 지워짐")
+	}
+	if again, _ := s.DeleteByUser("u1"); len(again) != 0 {
+		t.Errorf("두 번째 DeleteByUser = %d 개, want 0", len(again))
+	}
+}
+
+// TestMemoryIdPSessionStore_Expiry: 만료 세션은 Get 이 지우고, Touch 는 되살리지 않으며, Sweep 은 만료분만 정리.
+func TestMemoryIdPSessionStore_Expiry(t *testing.T) {
+	s := newTestIdPSessionStore()
+	live, _ := s.Create("u1", nil, "", "")
+	touched, _ := s.Create("u1", nil, "", "")
+	swept, _ := s.Create("u2", nil, "", "")
+
+	s.expire(touched)
+	s.Touch(touched)
+	if _, ok := s.Get(touched); ok {
+		t.Error("만료 세션이 Touch 로 되살아남")
+	}
+	if _, ok := s.m[touched]; ok {
+		t.Error("Get 이 만료 세션을 지우지 않음")
+	}
+
+	s.expire(swept)
+	if n, err := s.SweepExpired(); err != nil || n != 1 {
+		t.Errorf("SweepExpired = %d (err=%v), want 1", n, err)
+	}
+	if _, ok := s.byUser["u2"]; ok {
+		t.Error("Sweep 후 byUser 인덱스가 남음")
+	}
+	if _, ok := s.Get(live); !ok {
+		t.Error("살아 있는 세션이 사라짐")
+	}
+}
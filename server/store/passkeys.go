@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+)
+
+// Passkeys: 외부 노출. 기본은 인메모리, main 이 DATABASE_URL 보고 Postgres 로 교체.
+var Passkeys PasskeyStore = &memoryPasskeyStore{m: make(map[string]*models.Passkey)}
+
+// memoryPasskeyStore: credential ID → Passkey. 사용자별 조회는 전체 순회 (학습 규모).
+type memoryPasskeyStore struct {
+	mu sync.Mutex
+	m  map[string]*models.Passkey
+}
+
+func (s *memoryPasskeyStore) Create(ctx context.Context, pk *models.Passkey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.m[pk.ID]; ok {
+		return ErrPasskeyExists
+	}
+	if pk.CreatedAt.IsZero() {
+		pk.CreatedAt = time.Now()
+	}
+	s.m[pk.ID] = copyPasskey(pk)
+	return nil
+}
+
+func (s *memoryPasskeyStore) Get(ctx context.Context, credentialID string) (*models.Passkey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pk, ok := s.m[credentialID]
+	if !ok {
+		return nil, ErrPasskeyNotFound
+	}
+	return copyPasskey(pk), nil
+}
+
+func (s *memoryPasskeyStore) ListByUser(ctx context.Context, userID string) ([]*models.Passkey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*models.Passkey
+	for _, pk := range s.m {
+		if pk.UserID == userID {
+			out = append(out, copyPasskey(pk))
+		}
+	}
+	sortPasskeys(out)
+	return out, nil
+}
+
+func (s *memoryPasskeyStore) UpdateSignCount(ctx context.Context, credentialID string, signCount uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pk, ok := s.m[credentialID]
+	if !ok {
+		return ErrPasskeyNotFound
+	}
+	pk.SignCount = signCount
+	pk.LastUsedAt = time.Now()
+	return nil
+}
+
+func (s *memoryPasskeyStore) Delete(ctx context.Context, userID, credentialID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pk, ok := s.m[credentialID]
+	if !ok || pk.UserID != userID {
+		return ErrPasskeyNotFound
+	}
+	delete(s.m, credentialID)
+	return nil
+}
+
+func copyPasskey(pk *models.Passkey) *models.Passkey {
+	cp := *pk
+	cp.PublicKey = append([]byte(nil), pk.PublicKey...)
+	return &cp
+}
+
+// sortPasskeys: 등록 순 (같은 시각이면 ID 순) — map 순회 순서가 화면에 드러나지 않게.
+func sortPasskeys(list []*models.Passkey) {
+	slices.SortFunc(list, func(a, b *models.Passkey) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
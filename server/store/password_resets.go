@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+)
+
+// PasswordResets: 외부 노출. 기본은 인메모리, main 이 DATABASE_URL 보고 Postgres 로 교체.
+var PasswordResets PasswordResetStore = &memoryPasswordResetStore{m: make(map[string]*models.PasswordReset)}
+
+// memoryPasswordResetStore: map + Mutex. key = HashToken(token).
+type memoryPasswordResetStore struct {
+	mu sync.Mutex
+	m  map[string]*models.PasswordReset
+}
+
+func (s *memoryPasswordResetStore) Save(ctx context.Context, pr *models.PasswordReset) error {
+	cp := *pr
+	cp.Token = "" // 평문은 보관하지 않음
+	if cp.CreatedAt.IsZero() {
+		cp.CreatedAt = time.Now()
+	}
+	s.mu.Lock()
+	s.m[HashToken(pr.Token)] = &cp
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryPasswordResetStore) Get(ctx context.Context, token string) (*models.PasswordReset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pr, ok := s.m[HashToken(token)]
+	if !ok {
+		return nil, ErrPasswordResetNotFound
+	}
+	cp := *pr
+	return &cp, nil
+}
+
+func (s *memoryPasswordResetStore) Consume(ctx context.Context, token string) (*models.PasswordReset, error) {
+	key := HashToken(token)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pr, ok := s.m[key]
+	if !ok {
+		return nil, ErrPasswordResetNotFound
+	}
+	delete(s.m, key)
+	return pr, nil
+}
+
+func (s *memoryPasswordResetStore) DeleteByUser(ctx context.Context, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, pr := range s.m {
+		if pr.UserID == userID {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *memoryPasswordResetStore) SweepExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, pr := range s.m {
+		if pr.Expired(now) {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
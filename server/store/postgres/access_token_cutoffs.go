@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AccessTokenCutoffStore: access_token_cutoffs 테이블 ((user_id, client_id) 가 PK, 빈 문자열 = 전체)
+// + access_token_family_cutoffs 테이블 (family_id 가 PK).
+type AccessTokenCutoffStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewAccessTokenCutoffStore(pool *pgxpool.Pool) *AccessTokenCutoffStore {
+	return &AccessTokenCutoffStore{pool: pool}
+}
+
+func (s *AccessTokenCutoffStore) Set(ctx context.Context, userID, clientID string, cutoff, expiresAt time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO access_token_cutoffs (user_id, client_id, cutoff, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET cutoff = EXCLUDED.cutoff, expires_at = EXCLUDED.expires_at
+	`, userID, clientID, cutoff, expiresAt)
+	return err
+}
+
+// Latest: 세 키를 한 번에. 행이 없으면 MAX 가 NULL → zero time.
+func (s *AccessTokenCutoffStore) Latest(ctx context.Context, userID, clientID string) (time.Time, error) {
+	var latest *time.Time
+	err := s.pool.QueryRow(ctx, `
+		SELECT MAX(cutoff) FROM access_token_cutoffs
+		WHERE expires_at > now()
+		  AND ((user_id = $1 AND client_id = '') OR (user_id = '' AND client_id = $2) OR (user_id = $1 AND client_id = $2))
+	`, userID, clientID).Scan(&latest)
+	if err != nil || latest == nil {
+		return time.Time{}, err
+	}
+	return *latest, nil
+}
+
+func (s *AccessTokenCutoffStore) SetFamily(ctx context.Context, familyID string, cutoff, expiresAt time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO access_token_family_cutoffs (family_id, cutoff, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (family_id) DO UPDATE SET cutoff = EXCLUDED.cutoff, expires_at = EXCLUDED.expires_at
+	`, familyID, cutoff, expiresAt)
+	return err
+}
+
+func (s *AccessTokenCutoffStore) LatestFamily(ctx context.Context, familyID string) (time.Time, error) {
+	var cutoff time.Time
+	err := s.pool.QueryRow(ctx, `
+		SELECT cutoff FROM access_token_family_cutoffs WHERE family_id = $1 AND expires_at > now()
+	`, familyID).Scan(&cutoff)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return cutoff, err
+}
+
+func (s *AccessTokenCutoffStore) SweepExpired(ctx context.Context) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM access_token_cutoffs WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	fam, err := s.pool.Exec(ctx, `DELETE FROM access_token_family_cutoffs WHERE expires_at < now()`)
+	if err != nil {
+		return int(res.RowsAffected()), err
+	}
+	return int(res.RowsAffected() + fam.RowsAffected()), nil
+}
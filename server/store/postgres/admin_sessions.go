@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/store"
+)
+
+// AdminSessionStore: admin_sessions 테이블. id_hash = HashToken(세션 nonce) 가 PK.
+// 모든 조회가 expires_at > now() 를 같은 문장에서 본다.
+type AdminSessionStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewAdminSessionStore(pool *pgxpool.Pool) *AdminSessionStore {
+	return &AdminSessionStore{pool: pool}
+}
+
+func (s *AdminSessionStore) Create(ctx context.Context, id, userID string, expiresAt time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO admin_sessions (id_hash, user_id, expires_at) VALUES ($1, $2, $3)
+	`, store.HashToken(id), userID, expiresAt)
+	return err
+}
+
+func (s *AdminSessionStore) Get(ctx context.Context, id string) (string, error) {
+	var userID string
+	err := s.pool.QueryRow(ctx, `
+		SELECT user_id FROM admin_sessions WHERE id_hash = $1 AND expires_at > now()
+	`, store.HashToken(id)).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", store.ErrAdminSessionNotFound
+	}
+	return userID, err
+}
+
+func (s *AdminSessionStore) Delete(ctx context.Context, id string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM admin_sessions WHERE id_hash = $1`, store.HashToken(id))
+	return err
+}
+
+func (s *AdminSessionStore) DeleteByUser(ctx context.Context, userID string) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM admin_sessions WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func (s *AdminSessionStore) SweepExpired(ctx context.Context) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM admin_sessions WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
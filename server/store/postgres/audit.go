@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// AuditStore: audit_events 테이블. 나머지 속성은 attrs JSONB.
+type AuditStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditStore(pool *pgxpool.Pool) *AuditStore {
+	return &AuditStore{pool: pool}
+}
+
+func (s *AuditStore) Append(ctx context.Context, e *models.AuditEntry) error {
+	attrs := e.Attrs
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO audit_events (at, level, event, actor, subject, client_id, ip, user_agent, attrs)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, e.Time, e.Level, e.Event, e.Actor, e.Subject, e.ClientID, e.IP, e.UserAgent, attrs)
+	return err
+}
+
+// List: 조건을 인자 번호와 함께 쌓아 WHERE 를 만든다. event 는 접두 일치 (LIKE 'x%').
+func (s *AuditStore) List(ctx context.Context, q store.AuditQuery) ([]*models.AuditEntry, int, error) {
+	if q.Limit <= 0 {
+		q.Limit = store.DefaultAuditPageSize
+	}
+	var conds []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if len(q.Users) > 0 {
+		p := arg(q.Users)
+		conds = append(conds, "(actor = ANY("+p+") OR subject = ANY("+p+"))")
+	}
+	if q.ClientID != "" {
+		conds = append(conds, "client_id = "+arg(q.ClientID))
+	}
+	if q.Event != "" {
+		conds = append(conds, "event LIKE "+arg(likeEscaper.Replace(q.Event)+"%"))
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, "at >= "+arg(q.Since))
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "at < "+arg(q.Until))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT count(*) FROM audit_events `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	limit, offset := arg(q.Limit), arg(q.Offset)
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, at, level, event, actor, subject, client_id, ip, user_agent, attrs
+		FROM audit_events `+where+`
+		ORDER BY at DESC, id DESC
+		LIMIT `+limit+` OFFSET `+offset, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := make([]*models.AuditEntry, 0, q.Limit)
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.Time, &e.Level, &e.Event, &e.Actor, &e.Subject, &e.ClientID, &e.IP, &e.UserAgent, &e.Attrs); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, &e)
+	}
+	return out, total, rows.Err()
+}
+
+func (s *AuditStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM audit_events WHERE at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
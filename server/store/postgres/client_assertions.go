@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/store"
+)
+
+// ClientAssertionStore: client_assertion_jtis 테이블. (client_id, jti) 가 PK.
+type ClientAssertionStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewClientAssertionStore(pool *pgxpool.Pool) *ClientAssertionStore {
+	return &ClientAssertionStore{pool: pool}
+}
+
+// Use: INSERT 한 문장 — 만료된 옛 행이면 덮어쓰고, 살아 있는 행이면 아무것도 안 바뀐다 (= 재사용).
+// 동시에 같은 jti 두 건이 와도 한쪽만 행을 바꾼다.
+func (s *ClientAssertionStore) Use(ctx context.Context, clientID, jti string, expiresAt time.Time) error {
+	res, err := s.pool.Exec(ctx, `
+		INSERT INTO client_assertion_jtis (client_id, jti, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (client_id, jti) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE client_assertion_jtis.expires_at < now()
+	`, clientID, jti, expiresAt)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrClientAssertionReplayed
+	}
+	return nil
+}
+
+func (s *ClientAssertionStore) SweepExpired(ctx context.Context) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM client_assertion_jtis WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
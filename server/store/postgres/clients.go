@@ -1,4 +1,5 @@
-// Package postgres 는 store.ClientStore / store.UserStore 의 Postgres 구현체.
+// Package postgres 는 store 패키지 인터페이스 (ClientStore / UserStore / IdPSessionStore /
+// AuthCodeStore / RefreshTokenStore / DeviceCodeStore / ConsentStore) 와 token.KeyStore 의 Postgres 구현체.
 package postgres
 
 import (
@@ -10,8 +11,17 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
 )
 
+// clientColumns: scanClient 순서. 평문 secret 컬럼은 읽지 않는다.
+const clientColumns = `id, client_id, COALESCE(client_secret_hash, ''), name, description,
+		       main_url, server_urls, redirect_uris, owner_id,
+		       silent_sso, first_party, client_credentials, client_credentials_scopes, allowed_scopes, require_verified_email,
+		       COALESCE(previous_secret_hash, ''), previous_secret_expires_at,
+		       logo_uri, grant_types, token_endpoint_auth_method, registration_access_token_hash, client_type,
+		       jwks, jwks_uri, client_secret_sealed, backchannel_logout_uri, frontchannel_logout_uri, created_at`
+
 // ClientStore: pgxpool 기반 영속 구현체.
 type ClientStore struct {
 	pool *pgxpool.Pool
@@ -26,9 +36,7 @@ func (s *ClientStore) GetByClientID(clientID string) (*models.Client, bool) {
 	defer cancel()
 
 	row := s.pool.QueryRow(ctx, `
-		SELECT id, client_id, COALESCE(client_secret_hash, ''), name, description,
-		       main_url, server_urls, redirect_uris, owner_id,
-		       silent_sso, created_at
+		SELECT `+clientColumns+`
 		FROM clients WHERE client_id = $1
 	`, clientID)
 
@@ -44,9 +52,7 @@ func (s *ClientStore) All() []*models.Client {
 	defer cancel()
 
 	rows, err := s.pool.Query(ctx, `
-		SELECT id, client_id, COALESCE(client_secret_hash, ''), name, description,
-		       main_url, server_urls, redirect_uris, owner_id,
-		       silent_sso, created_at
+		SELECT `+clientColumns+`
 		FROM clients ORDER BY created_at ASC
 	`)
 	if err != nil {
@@ -75,6 +81,12 @@ func (s *ClientStore) Register(c *models.Client) error {
 	if c.CreatedAt.IsZero() {
 		c.CreatedAt = time.Now()
 	}
+	// 인메모리 Register 와 같게 — 평문만 들고 온 client 도 hash 를 채워 저장. public / private_key_jwt 는 secret 없음.
+	if c.UsesSecret() {
+		if err := store.EnsureSecretHash(c); err != nil {
+			return err
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -83,13 +95,18 @@ func (s *ClientStore) Register(c *models.Client) error {
 		INSERT INTO clients (
 		    id, client_id, client_secret_hash, name, description,
 		    main_url, server_urls, redirect_uris, owner_id,
-		    silent_sso, created_at
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		    silent_sso, first_party, client_credentials, client_credentials_scopes, allowed_scopes, require_verified_email,
+		    logo_uri, grant_types, token_endpoint_auth_method, registration_access_token_hash, client_type,
+		    jwks, jwks_uri, client_secret_sealed, backchannel_logout_uri, frontchannel_logout_uri, created_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26)
 		ON CONFLICT (client_id) DO NOTHING
 	`,
 		c.ID, c.ClientID, c.ClientSecretHash, c.Name, c.Description,
 		c.MainURL, c.ServerURLs, c.RedirectURIs, c.OwnerID,
-		c.SilentSSO, c.CreatedAt,
+		c.SilentSSO, c.FirstParty, c.ClientCredentials, nonNilStrings(c.ClientCredentialsScopes),
+		nonNilStrings(c.AllowedScopes), c.RequireVerifiedEmail,
+		c.LogoURI, nonNilStrings(c.GrantTypes), c.TokenEndpointAuthMethod, c.RegistrationAccessTokenHash, c.ClientType,
+		c.JWKS, c.JWKSURI, c.ClientSecretSealed, c.BackchannelLogoutURI, c.FrontchannelLogoutURI, c.CreatedAt,
 	)
 	return err
 }
@@ -106,7 +123,78 @@ func (s *ClientStore) UpdateSilentSSO(clientID string, silentSSO bool) error {
 		return err
 	}
 	if res.RowsAffected() == 0 {
-		return errors.New("client not found")
+		return store.ErrClientNotFound
+	}
+	return nil
+}
+
+// Update: 어드민 편집 폼의 필드만. secret / owner / created_at 은 건드리지 않는다.
+func (s *ClientStore) Update(c *models.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `
+		UPDATE clients SET
+		    name = $2, description = $3, main_url = $4, server_urls = $5, redirect_uris = $6,
+		    silent_sso = $7, first_party = $8, client_credentials = $9, client_credentials_scopes = $10,
+		    allowed_scopes = $11, require_verified_email = $12,
+		    logo_uri = $13, grant_types = $14, token_endpoint_auth_method = $15, client_type = $16,
+		    jwks = $17, jwks_uri = $18, backchannel_logout_uri = $19, frontchannel_logout_uri = $20
+		WHERE client_id = $1
+	`,
+		c.ClientID, c.Name, c.Description, c.MainURL, nonNilStrings(c.ServerURLs), nonNilStrings(c.RedirectURIs),
+		c.SilentSSO, c.FirstParty, c.ClientCredentials, nonNilStrings(c.ClientCredentialsScopes),
+		nonNilStrings(c.AllowedScopes), c.RequireVerifiedEmail,
+		c.LogoURI, nonNilStrings(c.GrantTypes), c.TokenEndpointAuthMethod, c.ClientType,
+		c.JWKS, c.JWKSURI, c.BackchannelLogoutURI, c.FrontchannelLogoutURI,
+	)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrClientNotFound
+	}
+	return nil
+}
+
+// RotateSecret: 한 문장으로 현재 hash → previous, newHash → 현재.
+// SET 우변의 client_secret_hash 는 갱신 전 값이다.
+func (s *ClientStore) RotateSecret(clientID, newHash, newSealed string, grace time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var graceUntil *time.Time
+	if grace > 0 {
+		t := time.Now().Add(grace)
+		graceUntil = &t
+	}
+	res, err := s.pool.Exec(ctx, `
+		UPDATE clients SET
+		    previous_secret_hash = CASE WHEN $3::timestamptz IS NULL THEN NULL ELSE client_secret_hash END,
+		    previous_secret_expires_at = $3,
+		    client_secret_hash = $2,
+		    client_secret_sealed = $4
+		WHERE client_id = $1
+	`, clientID, newHash, graceUntil, newSealed)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrClientNotFound
+	}
+	return nil
+}
+
+func (s *ClientStore) Delete(clientID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `DELETE FROM clients WHERE client_id = $1`, clientID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrClientNotFound
 	}
 	return nil
 }
@@ -115,12 +203,27 @@ func (s *ClientStore) UpdateSilentSSO(clientID string, silentSSO bool) error {
 // 평문 ClientSecret 은 DB 에서 안 읽어옴 (영속 안 함). ClientSecretHash 만.
 func scanClient(row pgx.Row) (*models.Client, error) {
 	var c models.Client
+	var prevExpires *time.Time
 	if err := row.Scan(
 		&c.ID, &c.ClientID, &c.ClientSecretHash, &c.Name, &c.Description,
 		&c.MainURL, &c.ServerURLs, &c.RedirectURIs, &c.OwnerID,
-		&c.SilentSSO, &c.CreatedAt,
+		&c.SilentSSO, &c.FirstParty, &c.ClientCredentials, &c.ClientCredentialsScopes, &c.AllowedScopes, &c.RequireVerifiedEmail,
+		&c.PreviousSecretHash, &prevExpires,
+		&c.LogoURI, &c.GrantTypes, &c.TokenEndpointAuthMethod, &c.RegistrationAccessTokenHash, &c.ClientType,
+		&c.JWKS, &c.JWKSURI, &c.ClientSecretSealed, &c.BackchannelLogoutURI, &c.FrontchannelLogoutURI, &c.CreatedAt,
 	); err != nil {
 		return nil, err
 	}
+	if prevExpires != nil {
+		c.PreviousSecretExpiresAt = *prevExpires
+	}
 	return &c, nil
 }
+
+// nonNilStrings: nil 슬라이스는 NULL 로 들어가 NOT NULL 컬럼에서 실패 → 빈 배열로.
+func nonNilStrings(ss []string) []string {
+	if ss == nil {
+		return []string{}
+	}
+	return ss
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// ConsentStore: consents 테이블. PK = (user_id, client_id).
+type ConsentStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewConsentStore(pool *pgxpool.Pool) *ConsentStore {
+	return &ConsentStore{pool: pool}
+}
+
+func (s *ConsentStore) Get(ctx context.Context, userID, clientID string) (*models.ConsentGrant, error) {
+	var g models.ConsentGrant
+	err := s.pool.QueryRow(ctx, `
+		SELECT user_id, client_id, scopes, created_at, updated_at
+		FROM consents WHERE user_id = $1 AND client_id = $2
+	`, userID, clientID).Scan(&g.UserID, &g.ClientID, &g.Scopes, &g.CreatedAt, &g.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrConsentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Grant: UPSERT — 충돌 시 기존 scopes 와 합집합 (중복 제거, 정렬).
+func (s *ConsentStore) Grant(ctx context.Context, userID, clientID string, scopes []string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO consents (user_id, client_id, scopes, created_at, updated_at)
+		VALUES ($1, $2, $3, now(), now())
+		ON CONFLICT (user_id, client_id) DO UPDATE SET
+		    scopes = ARRAY(SELECT DISTINCT unnest(consents.scopes || EXCLUDED.scopes) ORDER BY 1),
+		    updated_at = now()
+	`, userID, clientID, nonNilStrings(scopes))
+	return err
+}
+
+func (s *ConsentStore) Revoke(ctx context.Context, userID, clientID string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM consents WHERE user_id = $1 AND client_id = $2`, userID, clientID)
+	return err
+}
+
+func (s *ConsentStore) DeleteByUser(ctx context.Context, userID string) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM consents WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func (s *ConsentStore) DeleteByClient(ctx context.Context, clientID string) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM consents WHERE client_id = $1`, clientID)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
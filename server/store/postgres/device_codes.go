@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// DeviceCodeStore: device_codes 테이블. PK = sha256(device_code).
+type DeviceCodeStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewDeviceCodeStore(pool *pgxpool.Pool) *DeviceCodeStore {
+	return &DeviceCodeStore{pool: pool}
+}
+
+const deviceCodeColumns = `user_code, client_id, scope, status, user_id, auth_time, amr,
+	interval_seconds, last_polled_at, expires_at, created_at`
+
+// Save: user_code UNIQUE 충돌 시 ErrUserCodeConflict. 만료된 행이 코드를 붙잡고 있으면 먼저 비운다.
+func (s *DeviceCodeStore) Save(ctx context.Context, dc *models.DeviceCode) error {
+	if dc.CreatedAt.IsZero() {
+		dc.CreatedAt = time.Now()
+	}
+	if _, err := s.pool.Exec(ctx, `
+		DELETE FROM device_codes WHERE user_code = $1 AND expires_at < now()
+	`, dc.UserCode); err != nil {
+		return err
+	}
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO device_codes (device_code_hash, `+deviceCodeColumns+`)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	`,
+		store.HashToken(dc.DeviceCode), dc.UserCode, dc.ClientID, dc.Scope, string(dc.Status),
+		dc.UserID, nullTime(dc.AuthTime), nonNilStrings(dc.AMR), int(dc.Interval/time.Second), nullTime(dc.LastPolledAt),
+		dc.ExpiresAt, dc.CreatedAt,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return store.ErrUserCodeConflict
+	}
+	return err
+}
+
+func (s *DeviceCodeStore) GetByUserCode(ctx context.Context, userCode string) (*models.DeviceCode, error) {
+	return scanDeviceCode(s.pool.QueryRow(ctx, `
+		SELECT `+deviceCodeColumns+` FROM device_codes
+		WHERE user_code = $1 AND status = 'pending' AND expires_at > now()
+	`, userCode))
+}
+
+func (s *DeviceCodeStore) Approve(ctx context.Context, userCode, userID string, authTime time.Time, amr []string) error {
+	res, err := s.pool.Exec(ctx, `
+		UPDATE device_codes SET status = 'approved', user_id = $2, auth_time = $3, amr = $4
+		WHERE user_code = $1 AND status = 'pending' AND expires_at > now()
+	`, userCode, userID, authTime, nonNilStrings(amr))
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrDeviceCodeNotFound
+	}
+	return nil
+}
+
+func (s *DeviceCodeStore) Deny(ctx context.Context, userCode string) error {
+	res, err := s.pool.Exec(ctx, `
+		UPDATE device_codes SET status = 'denied'
+		WHERE user_code = $1 AND status = 'pending' AND expires_at > now()
+	`, userCode)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrDeviceCodeNotFound
+	}
+	return nil
+}
+
+// Poll: SELECT ... FOR UPDATE 로 행을 잠근 뒤 store.ApplyDevicePoll 결과를 반영.
+// 여러 replica 가 같은 device_code 를 동시에 받아도 승인은 한 번만 소비된다.
+func (s *DeviceCodeStore) Poll(ctx context.Context, deviceCode, clientID string) (*models.DeviceCode, error) {
+	hash := store.HashToken(deviceCode)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	dc, err := scanDeviceCode(tx.QueryRow(ctx, `
+		SELECT `+deviceCodeColumns+` FROM device_codes
+		WHERE device_code_hash = $1 FOR UPDATE
+	`, hash))
+	if err != nil {
+		return nil, err
+	}
+
+	consume, pollErr := store.ApplyDevicePoll(dc, clientID, time.Now())
+	if errors.Is(pollErr, store.ErrDeviceCodeNotFound) {
+		return nil, pollErr // client 불일치 — 아무것도 바꾸지 않음
+	}
+	if consume {
+		_, err = tx.Exec(ctx, `DELETE FROM device_codes WHERE device_code_hash = $1`, hash)
+	} else {
+		_, err = tx.Exec(ctx, `
+			UPDATE device_codes SET interval_seconds = $2, last_polled_at = $3
+			WHERE device_code_hash = $1
+		`, hash, int(dc.Interval/time.Second), dc.LastPolledAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	if pollErr != nil {
+		return nil, pollErr
+	}
+	return dc, nil
+}
+
+func (s *DeviceCodeStore) SweepExpired(ctx context.Context) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM device_codes WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func scanDeviceCode(row pgx.Row) (*models.DeviceCode, error) {
+	var (
+		dc         models.DeviceCode
+		status     string
+		authTime   *time.Time
+		lastPolled *time.Time
+		interval   int
+	)
+	err := row.Scan(&dc.UserCode, &dc.ClientID, &dc.Scope, &status, &dc.UserID, &authTime, &dc.AMR,
+		&interval, &lastPolled, &dc.ExpiresAt, &dc.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrDeviceCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	dc.Status = models.DeviceCodeStatus(status)
+	dc.Interval = time.Duration(interval) * time.Second
+	if authTime != nil {
+		dc.AuthTime = *authTime
+	}
+	if lastPolled != nil {
+		dc.LastPolledAt = *lastPolled
+	}
+	return &dc, nil
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// IdPSessionStore: idp_sessions 테이블 기반 구현체.
+// 배포 / 재시작 후에도 silent SSO 가 유지되고, 여러 IdP 인스턴스가 세션을 공유한다.
+//
+// 좀비 세션 방지는 SQL 조건으로: 모든 조회·갱신이 expires_at > now() 를 같은 문장 안에서 검사한다.
+type IdPSessionStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdPSessionStore(pool *pgxpool.Pool) *IdPSessionStore {
+	return &IdPSessionStore{pool: pool}
+}
+
+// idpSessionColumns: scanIdPSession 순서.
+const idpSessionColumns = `sid, user_id, login_at, expires_at, amr, client_ids, ip, user_agent, last_seen_at`
+
+// Create: 새 sid 발급 + INSERT. 세션 고정 방어를 위해 매 로그인마다 호출.
+func (s *IdPSessionStore) Create(userID string, amr []string, ip, userAgent string) (string, error) {
+	sid, err := store.NewSessionID()
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO idp_sessions (sid, user_id, login_at, expires_at, amr, ip, user_agent, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $3)
+	`, sid, userID, now, now.Add(store.IdPSessionTTL), nonNilStrings(amr), ip, store.TruncateUserAgent(userAgent))
+	if err != nil {
+		return "", err
+	}
+	return sid, nil
+}
+
+// Get: 만료되지 않은 세션만. 만료 행은 여기서 지우지 않고 SweepExpired 에 맡긴다
+// (WHERE 조건만으로 부활 불가가 보장되므로).
+func (s *IdPSessionStore) Get(sid string) (*models.IdPSession, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	sess, err := scanIdPSession(s.pool.QueryRow(ctx, `
+		SELECT `+idpSessionColumns+`
+		FROM idp_sessions WHERE sid = $1 AND expires_at > now()
+	`, sid))
+	if err != nil {
+		return nil, false
+	}
+	return sess, true
+}
+
+// Touch: 만료 전인 세션만 연장. 단일 UPDATE 라 "만료 확인 후 연장" 사이의 race 가 없다.
+func (s *IdPSessionStore) Touch(sid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := s.pool.Exec(ctx, `
+		UPDATE idp_sessions SET expires_at = $2
+		WHERE sid = $1 AND expires_at > now()
+	`, sid, time.Now().Add(store.IdPSessionTTL)); err != nil {
+		log.Printf("[idp_sessions] touch failed: %v", err)
+	}
+}
+
+// AddClient: 배열에 없을 때만 추가 + last_seen_at. 동시 발급이 겹쳐도 한 문장이라 중복이 생기지 않는다.
+func (s *IdPSessionStore) AddClient(sid, clientID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := s.pool.Exec(ctx, `
+		UPDATE idp_sessions SET
+		    client_ids = CASE WHEN $2 = ANY(client_ids) THEN client_ids ELSE array_append(client_ids, $2) END,
+		    last_seen_at = now()
+		WHERE sid = $1 AND expires_at > now()
+	`, sid, clientID); err != nil {
+		log.Printf("[idp_sessions] add client failed: %v", err)
+	}
+}
+
+// ListByUser: idx_idp_sessions_user_id 로 조회. 만료 행은 SweepExpired 전이라도 빼고 돌려준다.
+func (s *IdPSessionStore) ListByUser(userID string) ([]*models.IdPSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+idpSessionColumns+`
+		FROM idp_sessions WHERE user_id = $1 AND expires_at > now()
+		ORDER BY last_seen_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectIdPSessions(rows)
+}
+
+// Delete: 명시적 세션 폐기 (logout / 세션 고정 방어).
+func (s *IdPSessionStore) Delete(sid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := s.pool.Exec(ctx, `DELETE FROM idp_sessions WHERE sid = $1`, sid); err != nil {
+		log.Printf("[idp_sessions] delete failed: %v", err)
+	}
+}
+
+// DeleteByUser: DELETE ... RETURNING — 지운 행 그대로 돌려준다 (만료된 행도 포함, 통지 대상 판단은 호출자 몫).
+func (s *IdPSessionStore) DeleteByUser(userID string) ([]*models.IdPSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		DELETE FROM idp_sessions WHERE user_id = $1
+		RETURNING `+idpSessionColumns, userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectIdPSessions(rows)
+}
+
+func (s *IdPSessionStore) SweepExpired() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `DELETE FROM idp_sessions WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func scanIdPSession(row pgx.Row) (*models.IdPSession, error) {
+	var sess models.IdPSession
+	if err := row.Scan(&sess.SessionID, &sess.UserID, &sess.LoginAt, &sess.ExpiresAt, &sess.AMR, &sess.ClientIDs,
+		&sess.IP, &sess.UserAgent, &sess.LastSeenAt); err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+func collectIdPSessions(rows pgx.Rows) ([]*models.IdPSession, error) {
+	defer rows.Close()
+	var out []*models.IdPSession
+	for rows.Next() {
+		sess, err := scanIdPSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sess)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// PasskeyStore: webauthn_credentials 테이블. 사용자 삭제 시 FK CASCADE 로 함께 사라진다.
+type PasskeyStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPasskeyStore(pool *pgxpool.Pool) *PasskeyStore {
+	return &PasskeyStore{pool: pool}
+}
+
+const passkeyColumns = `credential_id, user_id::text, name, public_key, sign_count, created_at, last_used_at`
+
+// Create: credential_id PK 충돌 시 ErrPasskeyExists.
+func (s *PasskeyStore) Create(ctx context.Context, pk *models.Passkey) error {
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO webauthn_credentials (credential_id, user_id, name, public_key, sign_count)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`, pk.ID, pk.UserID, pk.Name, pk.PublicKey, int64(pk.SignCount)).Scan(&pk.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return store.ErrPasskeyExists
+	}
+	return err
+}
+
+func (s *PasskeyStore) Get(ctx context.Context, credentialID string) (*models.Passkey, error) {
+	row := s.pool.QueryRow(ctx, `SELECT `+passkeyColumns+` FROM webauthn_credentials WHERE credential_id = $1`, credentialID)
+	pk, err := scanPasskey(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrPasskeyNotFound
+	}
+	return pk, err
+}
+
+func (s *PasskeyStore) ListByUser(ctx context.Context, userID string) ([]*models.Passkey, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+passkeyColumns+` FROM webauthn_credentials
+		WHERE user_id = $1 ORDER BY created_at, credential_id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*models.Passkey
+	for rows.Next() {
+		pk, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pk)
+	}
+	return out, rows.Err()
+}
+
+func (s *PasskeyStore) UpdateSignCount(ctx context.Context, credentialID string, signCount uint32) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE webauthn_credentials SET sign_count = $2, last_used_at = now() WHERE credential_id = $1
+	`, credentialID, int64(signCount))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrPasskeyNotFound
+	}
+	return nil
+}
+
+func (s *PasskeyStore) Delete(ctx context.Context, userID, credentialID string) error {
+	tag, err := s.pool.Exec(ctx, `
+		DELETE FROM webauthn_credentials WHERE credential_id = $1 AND user_id = $2
+	`, credentialID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrPasskeyNotFound
+	}
+	return nil
+}
+
+func scanPasskey(row pgx.Row) (*models.Passkey, error) {
+	var (
+		pk        models.Passkey
+		signCount int64
+		lastUsed  *time.Time
+	)
+	if err := row.Scan(&pk.ID, &pk.UserID, &pk.Name, &pk.PublicKey, &signCount, &pk.CreatedAt, &lastUsed); err != nil {
+		return nil, err
+	}
+	pk.SignCount = uint32(signCount)
+	if lastUsed != nil {
+		pk.LastUsedAt = *lastUsed
+	}
+	return &pk, nil
+}
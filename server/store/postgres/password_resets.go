@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// PasswordResetStore: password_resets 테이블. token 은 HashToken 으로만 저장.
+type PasswordResetStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPasswordResetStore(pool *pgxpool.Pool) *PasswordResetStore {
+	return &PasswordResetStore{pool: pool}
+}
+
+func (s *PasswordResetStore) Save(ctx context.Context, pr *models.PasswordReset) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES ($1, $2, $3)
+	`, store.HashToken(pr.Token), pr.UserID, pr.ExpiresAt)
+	return err
+}
+
+func (s *PasswordResetStore) Get(ctx context.Context, token string) (*models.PasswordReset, error) {
+	row := s.pool.QueryRow(ctx, `
+		SELECT user_id::text, expires_at, created_at FROM password_resets WHERE token_hash = $1
+	`, store.HashToken(token))
+	return scanPasswordReset(row)
+}
+
+// Consume: DELETE ... RETURNING 한 문장 — 동시에 두 요청이 와도 한쪽만 행을 받는다.
+func (s *PasswordResetStore) Consume(ctx context.Context, token string) (*models.PasswordReset, error) {
+	row := s.pool.QueryRow(ctx, `
+		DELETE FROM password_resets WHERE token_hash = $1
+		RETURNING user_id::text, expires_at, created_at
+	`, store.HashToken(token))
+	return scanPasswordReset(row)
+}
+
+func (s *PasswordResetStore) DeleteByUser(ctx context.Context, userID string) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM password_resets WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func (s *PasswordResetStore) SweepExpired(ctx context.Context) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM password_resets WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func scanPasswordReset(row pgx.Row) (*models.PasswordReset, error) {
+	var pr models.PasswordReset
+	if err := row.Scan(&pr.UserID, &pr.ExpiresAt, &pr.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrPasswordResetNotFound
+		}
+		return nil, err
+	}
+	return &pr, nil
+}
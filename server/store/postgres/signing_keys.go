@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/token"
+)
+
+// SigningKeyStore: signing_keys 테이블 기반 token.KeyStore 구현체.
+// 여러 IdP 인스턴스가 같은 DB 를 보면 같은 키링을 공유한다.
+type SigningKeyStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewSigningKeyStore(pool *pgxpool.Pool) *SigningKeyStore {
+	return &SigningKeyStore{pool: pool}
+}
+
+func (s *SigningKeyStore) ListSigningKeys(ctx context.Context) ([]*token.SigningKey, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT kid, private_key_pem, status, created_at, activated_at, retired_at
+		FROM signing_keys ORDER BY created_at ASC, kid ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]*token.SigningKey, 0)
+	for rows.Next() {
+		var (
+			k                    token.SigningKey
+			pemText, status      string
+			activatedAt, retired *time.Time
+		)
+		if err := rows.Scan(&k.Kid, &pemText, &status, &k.CreatedAt, &activatedAt, &retired); err != nil {
+			return nil, err
+		}
+		priv, err := token.DecodePrivateKeyPEM([]byte(pemText))
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", k.Kid, err)
+		}
+		k.Private = priv
+		k.Status = token.KeyStatus(status)
+		if activatedAt != nil {
+			k.ActivatedAt = *activatedAt
+		}
+		if retired != nil {
+			k.RetiredAt = *retired
+		}
+		out = append(out, &k)
+	}
+	return out, rows.Err()
+}
+
+// AddSigningKey: kid(thumbprint) 를 PK 로 INSERT. 같은 키 재저장은 무시.
+// active / next 가 이미 있으면 idx_signing_keys_one_per_status 위반 → token.ErrKeyStatusTaken.
+// 학습 단계라 PEM 을 평문 컬럼에 둔다 — 운영에서는 KMS 봉투 암호화 권장.
+func (s *SigningKeyStore) AddSigningKey(ctx context.Context, k *token.SigningKey) error {
+	pemBytes, err := token.EncodePrivateKeyPEM(k.Private)
+	if err != nil {
+		return err
+	}
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO signing_keys (kid, private_key_pem, status, created_at, activated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (kid) DO NOTHING
+	`, k.Kid, string(pemBytes), string(k.Status), k.CreatedAt, nullTime(k.ActivatedAt))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_signing_keys_one_per_status" {
+		return token.ErrKeyStatusTaken
+	}
+	return err
+}
+
+// RotateSigningKeys: 한 트랜잭션 안에서 회전. active 행을 FOR UPDATE 로 잠가
+// 두 인스턴스가 동시에 회전해도 한쪽만 성공하고 나머지는 ErrRotationConflict.
+func (s *SigningKeyStore) RotateSigningKeys(ctx context.Context, expectedActiveKid string, newNext *token.SigningKey, now, pruneBefore time.Time) error {
+	pemBytes, err := token.EncodePrivateKeyPEM(newNext.Private)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// 가장 먼저 활성화된 active 가 기준 (token.pickActive 와 같은 순서)
+	var curKid string
+	err = tx.QueryRow(ctx, `
+		SELECT kid FROM signing_keys WHERE status = 'active'
+		ORDER BY COALESCE(activated_at, created_at) ASC, kid ASC
+		LIMIT 1 FOR UPDATE
+	`).Scan(&curKid)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && curKid != expectedActiveKid) {
+		return token.ErrRotationConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	var promoteKid string
+	err = tx.QueryRow(ctx, `
+		SELECT kid FROM signing_keys WHERE status = 'next'
+		ORDER BY created_at ASC, kid ASC LIMIT 1 FOR UPDATE
+	`).Scan(&promoteKid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return token.ErrNoNextKey
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE signing_keys SET status = 'retired', retired_at = $1 WHERE status = 'active'
+	`, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE signing_keys SET status = 'active', activated_at = $1 WHERE kid = $2
+	`, now, promoteKid); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO signing_keys (kid, private_key_pem, status, created_at)
+		VALUES ($1, $2, 'next', $3)
+		ON CONFLICT (kid) DO NOTHING
+	`, newNext.Kid, string(pemBytes), newNext.CreatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM signing_keys WHERE status = 'retired' AND retired_at < $1
+	`, pruneBefore); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// nullTime: zero time 은 NULL 로.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
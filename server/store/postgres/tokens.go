@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ftery0/ouath/server/models"
+	"github.com/ftery0/ouath/server/store"
+)
+
+// AuthCodeStore: auth_codes 테이블. PK = sha256(code).
+type AuthCodeStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuthCodeStore(pool *pgxpool.Pool) *AuthCodeStore {
+	return &AuthCodeStore{pool: pool}
+}
+
+func (s *AuthCodeStore) Save(ctx context.Context, ac *models.AuthCode) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO auth_codes (
+		    code_hash, client_id, user_id, redirect_uri, scope, expires_at,
+		    code_challenge, code_challenge_method, nonce, auth_time, amr, sid
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	`,
+		store.HashToken(ac.Code), ac.ClientID, ac.UserID, ac.RedirectURI, ac.Scope, ac.ExpiresAt,
+		ac.CodeChallenge, ac.CodeChallengeMethod, ac.Nonce, nullTime(ac.AuthTime), nonNilStrings(ac.AMR), ac.SID,
+	)
+	return err
+}
+
+// LoadAndDelete: DELETE ... RETURNING 한 문장 → 동시 교환 요청 중 하나만 행을 받는다.
+func (s *AuthCodeStore) LoadAndDelete(ctx context.Context, code string) (*models.AuthCode, error) {
+	var (
+		ac       models.AuthCode
+		authTime *time.Time
+	)
+	err := s.pool.QueryRow(ctx, `
+		DELETE FROM auth_codes WHERE code_hash = $1
+		RETURNING client_id, user_id, redirect_uri, scope, expires_at,
+		          code_challenge, code_challenge_method, nonce, auth_time, amr, sid
+	`, store.HashToken(code)).Scan(
+		&ac.ClientID, &ac.UserID, &ac.RedirectURI, &ac.Scope, &ac.ExpiresAt,
+		&ac.CodeChallenge, &ac.CodeChallengeMethod, &ac.Nonce, &authTime, &ac.AMR, &ac.SID,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrAuthCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if authTime != nil {
+		ac.AuthTime = *authTime
+	}
+	return &ac, nil
+}
+
+func (s *AuthCodeStore) SweepExpired(ctx context.Context) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM auth_codes WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+// RefreshTokenStore: refresh_tokens 테이블. PK = sha256(token) — 평문은 DB 에 없다.
+type RefreshTokenStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewRefreshTokenStore(pool *pgxpool.Pool) *RefreshTokenStore {
+	return &RefreshTokenStore{pool: pool}
+}
+
+const refreshTokenColumns = `token_hash, family_id, user_id, client_id, scope, expires_at, created_at, used_at`
+
+func (s *RefreshTokenStore) Save(ctx context.Context, rt *models.RefreshToken) error {
+	if rt.CreatedAt.IsZero() {
+		rt.CreatedAt = time.Now()
+	}
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO refresh_tokens (`+refreshTokenColumns+`)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,NULL)
+	`, store.HashToken(rt.Token), rt.FamilyID, rt.UserID, rt.ClientID, rt.Scope, rt.ExpiresAt, rt.CreatedAt)
+	return err
+}
+
+func (s *RefreshTokenStore) Load(ctx context.Context, tok string) (*models.RefreshToken, error) {
+	row := s.pool.QueryRow(ctx, `
+		SELECT `+refreshTokenColumns+` FROM refresh_tokens
+		WHERE token_hash = $1 AND used_at IS NULL
+	`, store.HashToken(tok))
+	return scanRefreshToken(row)
+}
+
+// Consume: UPDATE ... WHERE used_at IS NULL RETURNING — 두 replica 가 같은 토큰을 동시에
+// rotation 해도 한쪽만 행을 받는다. 못 받았으면 tombstone 이 있는지 확인해 재사용과 미존재를 구분.
+func (s *RefreshTokenStore) Consume(ctx context.Context, tok string) (*models.RefreshToken, error) {
+	hash := store.HashToken(tok)
+	rt, err := scanRefreshToken(s.pool.QueryRow(ctx, `
+		UPDATE refresh_tokens SET used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL
+		RETURNING `+refreshTokenColumns+`
+	`, hash))
+	if !errors.Is(err, store.ErrRefreshTokenNotFound) {
+		return rt, err
+	}
+	used, err := scanRefreshToken(s.pool.QueryRow(ctx, `
+		SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1
+	`, hash))
+	if err != nil {
+		return nil, err
+	}
+	return used, store.ErrRefreshTokenReused
+}
+
+func (s *RefreshTokenStore) Delete(ctx context.Context, tok string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE token_hash = $1`, store.HashToken(tok))
+	return err
+}
+
+func (s *RefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE family_id = $1`, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func (s *RefreshTokenStore) RevokeByUser(ctx context.Context, userID string) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func (s *RefreshTokenStore) RevokeByClient(ctx context.Context, clientID string) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE client_id = $1`, clientID)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func (s *RefreshTokenStore) ListByUser(ctx context.Context, userID string) ([]*models.RefreshToken, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+refreshTokenColumns+` FROM refresh_tokens
+		WHERE user_id = $1 AND used_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*models.RefreshToken
+	for rows.Next() {
+		rt, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rt)
+	}
+	return out, rows.Err()
+}
+
+func (s *RefreshTokenStore) RevokeByUserClient(ctx context.Context, userID, clientID string) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1 AND client_id = $2`, userID, clientID)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func (s *RefreshTokenStore) CountLiveByClient(ctx context.Context) (map[string]int, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT client_id, count(*) FROM refresh_tokens
+		WHERE used_at IS NULL AND expires_at > now()
+		GROUP BY client_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]int)
+	for rows.Next() {
+		var clientID string
+		var n int
+		if err := rows.Scan(&clientID, &n); err != nil {
+			return nil, err
+		}
+		out[clientID] = n
+	}
+	return out, rows.Err()
+}
+
+func (s *RefreshTokenStore) SweepExpired(ctx context.Context) (int, error) {
+	res, err := s.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+func scanRefreshToken(row pgx.Row) (*models.RefreshToken, error) {
+	var (
+		rt     models.RefreshToken
+		usedAt *time.Time
+	)
+	err := row.Scan(&rt.TokenHash, &rt.FamilyID, &rt.UserID, &rt.ClientID, &rt.Scope, &rt.ExpiresAt, &rt.CreatedAt, &usedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usedAt != nil {
+		rt.UsedAt = *usedAt
+	}
+	return &rt, nil
+}
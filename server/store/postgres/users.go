@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -22,9 +23,12 @@ func NewUserStore(pool *pgxpool.Pool) *UserStore {
 	return &UserStore{pool: pool}
 }
 
+const userColumns = `id::text, username, password_hash, display_name, COALESCE(email, ''), email_verified, created_at, updated_at,
+		       totp_secret, totp_last_step, recovery_code_hashes, disabled, admin_role`
+
 func (s *UserStore) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	row := s.pool.QueryRow(ctx, `
-		SELECT id::text, username, password_hash, display_name, COALESCE(email, ''), email_verified, created_at, updated_at
+		SELECT `+userColumns+`
 		FROM users WHERE username = $1
 	`, username)
 	u, err := scanUser(row)
@@ -36,7 +40,7 @@ func (s *UserStore) GetByUsername(ctx context.Context, username string) (*models
 
 func (s *UserStore) GetByID(ctx context.Context, id string) (*models.User, error) {
 	row := s.pool.QueryRow(ctx, `
-		SELECT id::text, username, password_hash, display_name, COALESCE(email, ''), email_verified, created_at, updated_at
+		SELECT `+userColumns+`
 		FROM users WHERE id = $1
 	`, id)
 	u, err := scanUser(row)
@@ -46,6 +50,20 @@ func (s *UserStore) GetByID(ctx context.Context, id string) (*models.User, error
 	return u, err
 }
 
+// GetByEmail: 대소문자 무시 비교 (비밀번호 재설정 입력용).
+func (s *UserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	row := s.pool.QueryRow(ctx, `
+		SELECT `+userColumns+`
+		FROM users WHERE lower(email) = lower($1)
+		ORDER BY created_at LIMIT 1
+	`, email)
+	u, err := scanUser(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrUserNotFound
+	}
+	return u, err
+}
+
 // Create: INSERT users. username UNIQUE 충돌 시 ErrUserAlreadyExists.
 // 호출자가 u.ID 채워 보내면 그것 사용, 빈 값이면 DB default(uuid) 적용.
 func (s *UserStore) Create(ctx context.Context, u *models.User) error {
@@ -89,10 +107,256 @@ func (s *UserStore) Create(ctx context.Context, u *models.User) error {
 	return nil
 }
 
+func (s *UserStore) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	return s.execUser(ctx, `UPDATE users SET password_hash = $2, updated_at = now() WHERE id = $1`, userID, passwordHash)
+}
+
+// UpdateEmail: idx_users_email (UNIQUE) 충돌 시 ErrEmailAlreadyUsed.
+func (s *UserStore) UpdateEmail(ctx context.Context, userID, email string) error {
+	err := s.execUser(ctx, `
+		UPDATE users SET email = $2, email_verified = false, updated_at = now() WHERE id = $1
+	`, userID, email)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return store.ErrEmailAlreadyUsed
+	}
+	return err
+}
+
+// MarkEmailVerified: email 조건을 WHERE 에 — 링크 발급 뒤 이메일이 바뀌었으면 0 행 → ErrUserNotFound.
+func (s *UserStore) MarkEmailVerified(ctx context.Context, userID, email string) error {
+	return s.execUser(ctx, `
+		UPDATE users SET email_verified = true, updated_at = now() WHERE id = $1 AND email = $2
+	`, userID, email)
+}
+
+// List: 검색어는 ILIKE 패턴으로 — %, _ 는 이스케이프해 글자 그대로 찾는다.
+func (s *UserStore) List(ctx context.Context, opts store.UserListOptions) ([]*models.User, int, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = store.DefaultUserPageSize
+	}
+	pattern := "%" + likeEscaper.Replace(strings.TrimSpace(opts.Query)) + "%"
+	const where = `WHERE username ILIKE $1 OR display_name ILIKE $1 OR COALESCE(email, '') ILIKE $1`
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT count(*) FROM users `+where, pattern).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+userColumns+`
+		FROM users `+where+`
+		ORDER BY created_at, username
+		LIMIT $2 OFFSET $3
+	`, pattern, opts.Limit, opts.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := make([]*models.User, 0, opts.Limit)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, u)
+	}
+	return out, total, rows.Err()
+}
+
+// likeEscaper: ILIKE 메타문자 이스케이프 (기본 ESCAPE 문자 '\').
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Update: 빈 이메일은 NULL 로 (idx_users_email 은 NULL 을 여럿 허용).
+func (s *UserStore) Update(ctx context.Context, u *models.User) error {
+	emailArg := any(nil)
+	if u.Email != "" {
+		emailArg = u.Email
+	}
+	err := s.execUser(ctx, `
+		UPDATE users SET display_name = $2, email = $3, email_verified = $4, updated_at = now() WHERE id = $1
+	`, u.ID, u.DisplayName, emailArg, u.EmailVerified)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return store.ErrEmailAlreadyUsed
+	}
+	return err
+}
+
+func (s *UserStore) Disable(ctx context.Context, userID string, disabled bool) error {
+	return s.execUser(ctx, `UPDATE users SET disabled = $2, updated_at = now() WHERE id = $1`, userID, disabled)
+}
+
+func (s *UserStore) SetAdminRole(ctx context.Context, userID, role string) error {
+	return s.execUser(ctx, `UPDATE users SET admin_role = $2, updated_at = now() WHERE id = $1`, userID, role)
+}
+
+func (s *UserStore) ListAdmins(ctx context.Context) ([]*models.User, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+userColumns+`
+		FROM users WHERE admin_role <> ''
+		ORDER BY username
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*models.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// Delete: webauthn_credentials / password_resets 는 FK CASCADE.
+func (s *UserStore) Delete(ctx context.Context, userID string) error {
+	res, err := s.pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrUserNotFound
+	}
+	return nil
+}
+
+// EnableTOTP: secret + 복구 코드 교체. last_step 도 0 으로 — 새 secret 의 step 은 옛 것과 무관.
+func (s *UserStore) EnableTOTP(ctx context.Context, userID, secret string, recoveryCodeHashes []string) error {
+	return s.execUser(ctx, `
+		UPDATE users SET totp_secret = $2, totp_last_step = 0, recovery_code_hashes = $3, updated_at = now()
+		WHERE id = $1
+	`, userID, secret, nonNilStrings(recoveryCodeHashes))
+}
+
+func (s *UserStore) DisableTOTP(ctx context.Context, userID string) error {
+	return s.execUser(ctx, `
+		UPDATE users SET totp_secret = '', totp_last_step = 0, recovery_code_hashes = '{}', updated_at = now()
+		WHERE id = $1
+	`, userID)
+}
+
+// ConsumeTOTPStep: 조건부 단일 UPDATE — 같은 코드로 동시에 두 번 들어와도 하나만 통과.
+func (s *UserStore) ConsumeTOTPStep(ctx context.Context, userID string, step int64) error {
+	res, err := s.pool.Exec(ctx, `
+		UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrTOTPReplay
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode: array_remove + ANY 조건을 한 문장에 — 같은 코드는 한 번만 빠진다.
+func (s *UserStore) ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	res, err := s.pool.Exec(ctx, `
+		UPDATE users SET recovery_code_hashes = array_remove(recovery_code_hashes, $2), updated_at = now()
+		WHERE id = $1 AND $2 = ANY(recovery_code_hashes)
+	`, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+func (s *UserStore) GetLoginThrottle(ctx context.Context, username string) (*models.LoginThrottle, error) {
+	t := &models.LoginThrottle{Username: username}
+	var lockedUntil *time.Time
+	err := s.pool.QueryRow(ctx, `
+		SELECT failures, last_failed_at, locked_until FROM login_throttles WHERE username = $1
+	`, username).Scan(&t.Failures, &t.LastFailedAt, &lockedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil != nil {
+		t.LockedUntil = *lockedUntil
+	}
+	return t, nil
+}
+
+// RecordLoginFailure: 트랜잭션 — 행을 UPSERT 로 잠그고 (동시 실패 요청은 직렬화) 누적 수 / 잠금 시각을 계산해 갱신.
+func (s *UserStore) RecordLoginFailure(ctx context.Context, username string, lockFor func(failures int) time.Duration) (*models.LoginThrottle, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	t := &models.LoginThrottle{Username: username}
+	var lockedUntil *time.Time
+	err = tx.QueryRow(ctx, `
+		INSERT INTO login_throttles (username, failures, last_failed_at) VALUES ($1, 0, $2)
+		ON CONFLICT (username) DO UPDATE SET username = EXCLUDED.username
+		RETURNING failures, last_failed_at, locked_until
+	`, username, now).Scan(&t.Failures, &t.LastFailedAt, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	if now.Sub(t.LastFailedAt) > store.LoginFailureWindow {
+		t.Failures, lockedUntil = 0, nil
+	}
+	if lockedUntil != nil {
+		t.LockedUntil = *lockedUntil
+	}
+	t.Failures++
+	t.LastFailedAt = now
+	if d := lockFor(t.Failures); d > 0 {
+		t.LockedUntil = now.Add(d)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE login_throttles SET failures = $2, last_failed_at = $3, locked_until = $4 WHERE username = $1
+	`, username, t.Failures, now, nullTime(t.LockedUntil)); err != nil {
+		return nil, err
+	}
+	return t, tx.Commit(ctx)
+}
+
+func (s *UserStore) ResetLoginFailures(ctx context.Context, username string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM login_throttles WHERE username = $1`, username)
+	return err
+}
+
+func (s *UserStore) SweepLoginThrottles(ctx context.Context) (int, error) {
+	res, err := s.pool.Exec(ctx, `
+		DELETE FROM login_throttles
+		WHERE last_failed_at < $1 AND (locked_until IS NULL OR locked_until < now())
+	`, time.Now().Add(-store.LoginFailureWindow))
+	if err != nil {
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+// execUser: 사용자 한 행 UPDATE. 영향받은 행이 없으면 ErrUserNotFound.
+func (s *UserStore) execUser(ctx context.Context, sql string, args ...any) error {
+	res, err := s.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrUserNotFound
+	}
+	return nil
+}
+
 func scanUser(row pgx.Row) (*models.User, error) {
 	var u models.User
 	if err := row.Scan(
 		&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.EmailVerified, &u.CreatedAt, &u.UpdatedAt,
+		&u.TOTPSecret, &u.TOTPLastStep, &u.RecoveryCodeHashes, &u.Disabled, &u.AdminRole,
 	); err != nil {
 		return nil, err
 	}
@@ -1,6 +1,6 @@
-// Package store 는 client / group / IdP session 의 영속 인터페이스와 구현체를 모은다.
+// Package store 는 client / user / IdP session / auth code / refresh token / device code / consent / passkey / password reset 의 영속 인터페이스와 구현체를 모은다.
 //
-// Phase 1: sync.Map / Mutex 기반 인메모리 (clients.go, groups.go, idp_sessions.go)
+// Phase 1: sync.Map / Mutex 기반 인메모리 (clients.go, idp_sessions.go, tokens_memory.go)
 // Phase 2-C: 인터페이스 추출 + Postgres 구현체 (postgres/ 서브 패키지)
 //
 // 호출자(handlers, policy 등) 는 항상 인터페이스를 통해 접근한다.
@@ -9,6 +9,7 @@ package store
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/ftery0/ouath/server/models"
 )
@@ -19,6 +20,16 @@ type ClientStore interface {
 	All() []*models.Client
 	Register(c *models.Client) error
 	UpdateSilentSSO(clientID string, silentSSO bool) error
+	// Update: 어드민 / 동적 등록 관리 API 가 고칠 수 있는 필드 (이름 · 설명 · URL · 정책 토글 · scope · 등록 메타데이터) 교체.
+	// secret 은 RotateSecret 으로만, 등록 토큰은 바뀌지 않는다.
+	// 없으면 ErrClientNotFound.
+	Update(c *models.Client) error
+	// RotateSecret: newHash 를 현재 hash 로. 기존 hash 는 grace 동안 PreviousSecretHash 로 남는다 (0 이면 즉시 폐기).
+	// newSealed 는 client_secret_jwt 용 암호화 secret (그 외 방식은 "") — 유예 없이 바로 교체.
+	// 없으면 ErrClientNotFound.
+	RotateSecret(clientID, newHash, newSealed string, grace time.Duration) error
+	// Delete: 없으면 ErrClientNotFound. refresh token · 동의 정리는 호출자 몫 (RevokeByClient / DeleteByClient).
+	Delete(clientID string) error
 }
 
 // UserStore: 글로벌 user pool 영속 인터페이스 (Phase-R R-2 신규).
@@ -26,17 +37,292 @@ type ClientStore interface {
 type UserStore interface {
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
 	GetByID(ctx context.Context, id string) (*models.User, error)
+	// GetByEmail: 이메일이 같은 사용자 (대소문자 무시). 없으면 ErrUserNotFound.
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	Create(ctx context.Context, u *models.User) error
+	// UpdatePassword: bcrypt hash 교체. 없는 사용자면 ErrUserNotFound.
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	// UpdateEmail: 이메일 교체 + email_verified=false. 다른 사용자가 쓰는 이메일이면 ErrEmailAlreadyUsed.
+	UpdateEmail(ctx context.Context, userID, email string) error
+	// MarkEmailVerified: 현재 이메일이 email 과 같을 때만 email_verified=true.
+	// 그 사이 이메일이 바뀌었으면 (옛 링크) ErrUserNotFound.
+	MarkEmailVerified(ctx context.Context, userID, email string) error
+
+	// 2단계 인증 (TOTP). EnableTOTP 는 secret + 복구 코드 hash 를 통째로 교체 — 재등록하면 옛 복구 코드는 무효.
+	// 없는 사용자면 ErrUserNotFound.
+	EnableTOTP(ctx context.Context, userID, secret string, recoveryCodeHashes []string) error
+	DisableTOTP(ctx context.Context, userID string) error
+	// ConsumeTOTPStep: step 이 마지막 통과 step 보다 클 때만 기록 (atomic). 아니면 ErrTOTPReplay.
+	ConsumeTOTPStep(ctx context.Context, userID string, step int64) error
+	// ConsumeRecoveryCode: codeHash 를 목록에서 제거 (atomic, 1회용). 없으면 ErrRecoveryCodeInvalid.
+	ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) error
+
+	// 어드민 사용자 관리.
+	// List: username / display_name / email 부분 일치 (대소문자 무시) + 가입순 페이지. total 은 검색 결과 전체 수.
+	List(ctx context.Context, opts UserListOptions) (users []*models.User, total int, err error)
+	// Update: display_name / email / email_verified 교체. 없으면 ErrUserNotFound, 이메일 충돌은 ErrEmailAlreadyUsed.
+	Update(ctx context.Context, u *models.User) error
+	// Disable: disabled=false 면 다시 활성화. 없으면 ErrUserNotFound.
+	Disable(ctx context.Context, userID string, disabled bool) error
+	// SetAdminRole: 빈 role 이면 어드민 권한 회수. 없으면 ErrUserNotFound. 역할 값 검증은 호출자 몫.
+	SetAdminRole(ctx context.Context, userID, role string) error
+	// ListAdmins: 어드민 역할이 있는 사용자 (비활성 포함), username 순.
+	ListAdmins(ctx context.Context) ([]*models.User, error)
+	// Delete: 사용자 행 삭제 (패스키 / 재설정 링크 포함). 없으면 ErrUserNotFound.
+	// 세션 · refresh token · 동의 정리는 호출자 몫 (각 store 의 DeleteByUser / RevokeByUser).
+	Delete(ctx context.Context, userID string) error
+
+	// 로그인 실패 누적 (username 단위, users 행과 무관 — 없는 이름도 기록).
+	// GetLoginThrottle: 기록이 없으면 Failures 0 인 값.
+	GetLoginThrottle(ctx context.Context, username string) (*models.LoginThrottle, error)
+	// RecordLoginFailure: 실패 1회 누적 후 lockFor(누적 수) 만큼 잠금 (atomic).
+	// 마지막 실패가 LoginFailureWindow 보다 오래됐으면 1부터 다시 센다.
+	RecordLoginFailure(ctx context.Context, username string, lockFor func(failures int) time.Duration) (*models.LoginThrottle, error)
+	ResetLoginFailures(ctx context.Context, username string) error
+	// SweepLoginThrottles: 마지막 실패가 LoginFailureWindow 보다 오래된 기록 삭제.
+	SweepLoginThrottles(ctx context.Context) (int, error)
+}
+
+// LoginFailureWindow: 실패 누적 유지 시간. 마지막 실패 후 이만큼 조용하면 카운터가 초기화된다.
+const LoginFailureWindow = 24 * time.Hour
+
+// IdPSessionStore: IdP 글로벌 세션 영속 인터페이스.
+//
+// 좀비 세션 방지 보장: Get 은 만료 세션을 절대 돌려주지 않고, Touch 는 만료 세션을
+// 되살리지 않는다 (만료 판정과 갱신이 한 번에 — 인메모리는 Mutex, Postgres 는 단일 UPDATE).
+type IdPSessionStore interface {
+	// Create: amr 은 이 로그인에 쓴 인증 수단 (models.AMRPassword / AMROTP). ip / userAgent 는 /account 표시용.
+	Create(userID string, amr []string, ip, userAgent string) (string, error)
+	Get(sid string) (*models.IdPSession, bool)
+	Touch(sid string)
+	// AddClient: 이 세션으로 code 를 받아 간 client 기록 (Back-Channel Logout 대상, 이미 있으면 무시) + LastSeenAt 갱신.
+	AddClient(sid, clientID string)
+	// ListByUser: 사용자의 만료 전 세션, 최근 사용 순 (/account).
+	ListByUser(userID string) ([]*models.IdPSession, error)
+	Delete(sid string)
+	// DeleteByUser: 사용자의 세션 전부 폐기 (비밀번호 재설정 / 관리자 폐기 등).
+	// 지운 세션을 돌려준다 — 호출자가 그 세션의 client 들에 logout 을 통지한다.
+	DeleteByUser(userID string) ([]*models.IdPSession, error)
+	SweepExpired() (int, error)
+}
+
+// AuthCodeStore: 1회용 authorization code 영속 인터페이스.
+// code 평문은 저장하지 않고 HashToken(code) 를 키로 쓴다.
+type AuthCodeStore interface {
+	Save(ctx context.Context, ac *models.AuthCode) error
+	// LoadAndDelete: 꺼내는 동시에 삭제 (atomic) → 같은 code 로 두 번 토큰 교환 불가.
+	// 없으면 ErrAuthCodeNotFound. 만료 검사는 호출자 몫.
+	LoadAndDelete(ctx context.Context, code string) (*models.AuthCode, error)
+	SweepExpired(ctx context.Context) (int, error)
+}
+
+// RefreshTokenStore: refresh token 영속 인터페이스.
+// 평문 token 은 발급 응답에만 실리고, store 에는 HashToken(token) 만 남는다.
+type RefreshTokenStore interface {
+	Save(ctx context.Context, rt *models.RefreshToken) error
+	// Load: 아직 소비되지 않은 토큰만. 소비된 tombstone 은 ErrRefreshTokenNotFound.
+	Load(ctx context.Context, token string) (*models.RefreshToken, error)
+	// Consume: rotation 용 1회 소비 (atomic). 행을 지우지 않고 UsedAt 을 찍어 tombstone 으로 남긴다.
+	// 이미 소비된 토큰이면 그 토큰과 함께 ErrRefreshTokenReused — 호출자가 FamilyID 로 일괄 폐기.
+	// 없으면 ErrRefreshTokenNotFound.
+	Consume(ctx context.Context, token string) (*models.RefreshToken, error)
+	Delete(ctx context.Context, token string) error
+	// RevokeFamily: 같은 FamilyID 의 토큰 (tombstone 포함) 전부 삭제. 삭제 수 반환.
+	RevokeFamily(ctx context.Context, familyID string) (int, error)
+	// RevokeByUser: 사용자의 토큰 (모든 client, tombstone 포함) 전부 삭제. 삭제 수 반환.
+	RevokeByUser(ctx context.Context, userID string) (int, error)
+	// RevokeByClient: client 에 발급된 토큰 (모든 사용자, tombstone 포함) 전부 삭제. 삭제 수 반환.
+	RevokeByClient(ctx context.Context, clientID string) (int, error)
+	// ListByUser: 사용자의 살아 있는 토큰 (미소비 · 미만료) — /account 의 연결된 앱 목록.
+	ListByUser(ctx context.Context, userID string) ([]*models.RefreshToken, error)
+	// RevokeByUserClient: 한 사용자가 한 client 에 내준 토큰 (tombstone 포함) 전부 삭제. 삭제 수 반환.
+	RevokeByUserClient(ctx context.Context, userID, clientID string) (int, error)
+	// CountLiveByClient: client 별 살아 있는 토큰 수 — 어드민 화면의 live count.
+	CountLiveByClient(ctx context.Context) (map[string]int, error)
+	SweepExpired(ctx context.Context) (int, error)
+}
+
+// DeviceCodeStore: RFC 8628 device authorization 요청 영속 인터페이스.
+// device_code 평문은 저장하지 않고 HashToken(device_code) 를 키로 쓴다. user_code 는 정규화 형태 그대로.
+type DeviceCodeStore interface {
+	// Save: user_code 가 진행 중인 다른 요청과 겹치면 ErrUserCodeConflict — 호출자가 재생성.
+	Save(ctx context.Context, dc *models.DeviceCode) error
+	// GetByUserCode / Approve / Deny: 만료 전 pending 요청만. 아니면 ErrDeviceCodeNotFound.
+	GetByUserCode(ctx context.Context, userCode string) (*models.DeviceCode, error)
+	Approve(ctx context.Context, userCode, userID string, authTime time.Time, amr []string) error
+	Deny(ctx context.Context, userCode string) error
+	// Poll: /token polling 1회. 상태 전이는 ApplyDevicePoll (atomic).
+	// approved 면 요청을 소비하고 (nil 에러) 반환, 그 외는 ErrDevice* 에러.
+	Poll(ctx context.Context, deviceCode, clientID string) (*models.DeviceCode, error)
+	SweepExpired(ctx context.Context) (int, error)
+}
+
+// ConsentStore: 사용자 × client 동의 기록 영속 인터페이스.
+type ConsentStore interface {
+	// Get: 없으면 ErrConsentNotFound.
+	Get(ctx context.Context, userID, clientID string) (*models.ConsentGrant, error)
+	// Grant: 기존 동의에 scopes 를 합친다 (없으면 생성).
+	Grant(ctx context.Context, userID, clientID string, scopes []string) error
+	Revoke(ctx context.Context, userID, clientID string) error
+	// DeleteByUser: 사용자의 동의 기록 전부 삭제 (계정 삭제).
+	DeleteByUser(ctx context.Context, userID string) (int, error)
+	// DeleteByClient: client 에 대한 동의 기록 전부 삭제 (client 삭제).
+	DeleteByClient(ctx context.Context, clientID string) (int, error)
+}
+
+// PasswordResetStore: 비밀번호 재설정 토큰 영속 인터페이스.
+// 평문 token 은 메일 링크에만 실리고, store 에는 HashToken(token) 만 남는다. 만료 검사는 호출자 몫.
+type PasswordResetStore interface {
+	Save(ctx context.Context, pr *models.PasswordReset) error
+	// Get: 소비하지 않고 조회 (재설정 폼 표시용). 없으면 ErrPasswordResetNotFound.
+	Get(ctx context.Context, token string) (*models.PasswordReset, error)
+	// Consume: 꺼내는 동시에 삭제 (atomic) → 같은 링크로 두 번 재설정 불가. 없으면 ErrPasswordResetNotFound.
+	Consume(ctx context.Context, token string) (*models.PasswordReset, error)
+	// DeleteByUser: 사용자에게 발급된 나머지 링크 전부 무효화.
+	DeleteByUser(ctx context.Context, userID string) (int, error)
+	SweepExpired(ctx context.Context) (int, error)
+}
+
+// PasskeyStore: 사용자 × WebAuthn credential 영속 인터페이스. credential ID (base64url) 가 전역 유일 키.
+type PasskeyStore interface {
+	// Create: 같은 credential ID 가 이미 있으면 ErrPasskeyExists.
+	Create(ctx context.Context, pk *models.Passkey) error
+	// Get: 없으면 ErrPasskeyNotFound.
+	Get(ctx context.Context, credentialID string) (*models.Passkey, error)
+	// ListByUser: 등록 순서대로.
+	ListByUser(ctx context.Context, userID string) ([]*models.Passkey, error)
+	// UpdateSignCount: 인증 성공 후 새 서명 카운터 + 마지막 사용 시각.
+	UpdateSignCount(ctx context.Context, credentialID string, signCount uint32) error
+	// Delete: 본인 credential 만. 없거나 다른 사용자 것이면 ErrPasskeyNotFound.
+	Delete(ctx context.Context, userID, credentialID string) error
+}
+
+// ClientAssertionStore: RFC 7523 client assertion 의 jti 사용 기록 (재사용 차단).
+// jti 는 client 마다 유일하면 되므로 (client_id, jti) 단위로 본다.
+type ClientAssertionStore interface {
+	// Use: 처음 보는 jti 면 expiresAt 까지 기록 (atomic). 아직 만료 전인 같은 jti 가 있으면 ErrClientAssertionReplayed.
+	Use(ctx context.Context, clientID, jti string, expiresAt time.Time) error
+	SweepExpired(ctx context.Context) (int, error)
+}
+
+// AdminSessionStore: 어드민 로그인 세션. 쿠키만으로는 로그아웃이 브라우저에서 지우는 것에 그치므로
+// 서버에도 남겨 두고 매 요청 대조한다. id 는 쿠키에 실린 세션 nonce — store 에는 HashToken(id) 만.
+type AdminSessionStore interface {
+	Create(ctx context.Context, id, userID string, expiresAt time.Time) error
+	// Get: 만료 전 세션의 user_id. 없거나 만료됐으면 ErrAdminSessionNotFound.
+	Get(ctx context.Context, id string) (string, error)
+	// Delete: 로그아웃. 없어도 에러 아님.
+	Delete(ctx context.Context, id string) error
+	// DeleteByUser: 사용자의 어드민 세션 전부 (비밀번호 재설정 / 계정 비활성화). 삭제 수 반환.
+	DeleteByUser(ctx context.Context, userID string) (int, error)
+	SweepExpired(ctx context.Context) (int, error)
+}
+
+// AccessTokenCutoffStore: access token kill switch 기준 시각 — 사용자 / client / 사용자·client 쌍 단위,
+// 그리고 refresh token family 단위 (재사용 탐지 / 비활성 사용자).
+// access token 은 JWT 라 stateless 검증이므로, 여러 인스턴스가 같은 차단을 보려면 기준 시각을 공유해야 한다.
+// userID, clientID 중 빈 값은 "전체".
+type AccessTokenCutoffStore interface {
+	// Set: (userID, clientID) 의 기준 시각을 cutoff 로 (이미 있으면 덮어쓰기). expiresAt 이 지나면 정리 대상.
+	Set(ctx context.Context, userID, clientID string, cutoff, expiresAt time.Time) error
+	// Latest: (userID, ""), ("", clientID), (userID, clientID) 중 만료 전 가장 늦은 기준 시각. 없으면 zero.
+	Latest(ctx context.Context, userID, clientID string) (time.Time, error)
+	// SetFamily: familyID (access token 의 fid claim) 의 기준 시각. 이미 있으면 덮어쓰기.
+	SetFamily(ctx context.Context, familyID string, cutoff, expiresAt time.Time) error
+	// LatestFamily: familyID 의 만료 전 기준 시각. 없으면 zero.
+	LatestFamily(ctx context.Context, familyID string) (time.Time, error)
+	// SweepExpired: 두 종류 모두.
+	SweepExpired(ctx context.Context) (int, error)
+}
+
+// AuditStore: 감사 로그 영속 sink. stdout 로그와 별개로, 어드민 화면의 조회 / 내보내기용.
+type AuditStore interface {
+	Append(ctx context.Context, e *models.AuditEntry) error
+	// List: 최신순 페이지. total 은 필터에 맞는 전체 수.
+	List(ctx context.Context, q AuditQuery) (entries []*models.AuditEntry, total int, err error)
+	// Prune: before 이전 기록 삭제 (보존 기간). 삭제 수 반환.
+	Prune(ctx context.Context, before time.Time) (int, error)
 }
 
 // 컴파일 타임 인터페이스 충족 검증.
-var _ ClientStore = (*clientStore)(nil)
+var (
+	_ ClientStore        = (*clientStore)(nil)
+	_ IdPSessionStore    = (*memoryIdPSessionStore)(nil)
+	_ AuthCodeStore      = (*memoryAuthCodeStore)(nil)
+	_ RefreshTokenStore  = (*memoryRefreshTokenStore)(nil)
+	_ DeviceCodeStore    = (*memoryDeviceCodeStore)(nil)
+	_ ConsentStore       = (*memoryConsentStore)(nil)
+	_ PasskeyStore       = (*memoryPasskeyStore)(nil)
+	_ PasswordResetStore = (*memoryPasswordResetStore)(nil)
+
+	_ ClientAssertionStore   = (*memoryClientAssertionStore)(nil)
+	_ AccessTokenCutoffStore = (*memoryAccessTokenCutoffStore)(nil)
+	_ AdminSessionStore      = (*memoryAdminSessionStore)(nil)
+	_ AuditStore             = (*memoryAuditStore)(nil)
+)
+
+// UserListOptions: UserStore.List 검색 / 페이지. Limit <= 0 이면 DefaultUserPageSize.
+type UserListOptions struct {
+	Query  string
+	Offset int
+	Limit  int
+}
+
+// DefaultUserPageSize: 어드민 사용자 목록 한 페이지.
+const DefaultUserPageSize = 20
+
+// AuditQuery: AuditStore.List 필터. 빈 값은 조건 없음. Limit <= 0 이면 DefaultAuditPageSize.
+//
+//	Users : Actor 또는 Subject 가 이 중 하나 (username 과 sub 를 같이 넘긴다)
+//	Event : 접두 일치 — "login" 이면 login.success / login.failed …
+//	Since / Until : [Since, Until) 구간
+type AuditQuery struct {
+	Users    []string
+	ClientID string
+	Event    string
+	Since    time.Time
+	Until    time.Time
+	Offset   int
+	Limit    int
+}
+
+// DefaultAuditPageSize: 어드민 감사 로그 한 페이지. AuditExportLimit: 내보내기 한 번의 최대 건수.
+const (
+	DefaultAuditPageSize = 50
+	AuditExportLimit     = 10000
+)
 
 // Users: 외부 노출. main 이 Postgres 구현체로 주입.
 var Users UserStore
 
-// ErrUserNotFound / ErrUserAlreadyExists: UserStore 표준 에러.
+// ErrClientNotFound: ClientStore 변경 대상 client_id 가 없음.
+var ErrClientNotFound = errors.New("client not found")
+
+// ErrUserNotFound / ErrUserAlreadyExists / ErrEmailAlreadyUsed: UserStore 표준 에러.
 var (
 	ErrUserNotFound      = errors.New("user not found")
 	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrEmailAlreadyUsed  = errors.New("email already used")
+)
+
+// ErrTOTPReplay / ErrRecoveryCodeInvalid: 2단계 인증 코드 재사용 / 없는 (이미 쓴) 복구 코드.
+var (
+	ErrTOTPReplay          = errors.New("totp code already used")
+	ErrRecoveryCodeInvalid = errors.New("recovery code invalid")
+)
+
+// ErrAuthCodeNotFound / ErrRefreshTokenNotFound: 없음 / 이미 소비됨.
+// ErrRefreshTokenReused: rotation 으로 이미 소비된 refresh token 재제시 (탈취 신호).
+var (
+	ErrAuthCodeNotFound      = errors.New("auth code not found")
+	ErrRefreshTokenNotFound  = errors.New("refresh token not found")
+	ErrRefreshTokenReused    = errors.New("refresh token reused")
+	ErrUserCodeConflict      = errors.New("user code already in use")
+	ErrConsentNotFound       = errors.New("consent not found")
+	ErrPasskeyNotFound       = errors.New("passkey not found")
+	ErrPasskeyExists         = errors.New("passkey already registered")
+	ErrPasswordResetNotFound = errors.New("password reset token not found")
+
+	ErrClientAssertionReplayed = errors.New("client assertion jti already used")
+	ErrAdminSessionNotFound    = errors.New("admin session not found")
 )
@@ -0,0 +1,290 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+)
+
+// AuthCodes / RefreshTokens: 외부 노출. 기본은 인메모리, main 이 DATABASE_URL 보고 Postgres 로 교체.
+var (
+	AuthCodes     AuthCodeStore     = &memoryAuthCodeStore{m: make(map[string]*models.AuthCode)}
+	RefreshTokens RefreshTokenStore = &memoryRefreshTokenStore{
+		m:      make(map[string]*models.RefreshToken),
+		byUser: make(map[string]map[string]struct{}),
+	}
+)
+
+// HashToken: code / refresh token 의 저장 키. SHA-256 hex.
+// 토큰 자체가 256bit 랜덤이라 salt / bcrypt 없이도 역산 불가 — 조회는 O(1) 유지.
+// store 가 유출돼도 평문 토큰을 재사용할 수 없게 하는 것이 목적.
+func HashToken(tok string) string {
+	sum := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(sum[:])
+}
+
+// StartTokenCleanup: 5분 주기로 AuthCodes / RefreshTokens / DeviceCodes / PasswordResets / AccessTokenCutoffs / AdminSessions 의 만료 항목 + 오래된 로그인 실패 기록 정리.
+// main 에서 store 교체가 끝난 뒤 한 번 호출 (교체 전 인스턴스를 붙잡지 않도록 매 tick 전역을 읽는다).
+func StartTokenCleanup() {
+	go func() {
+		t := time.NewTicker(5 * time.Minute)
+		defer t.Stop()
+		for range t.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if n, err := AuthCodes.SweepExpired(ctx); err != nil {
+				log.Printf("[auth_codes] sweep failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[auth_codes] swept %d expired codes", n)
+			}
+			if n, err := RefreshTokens.SweepExpired(ctx); err != nil {
+				log.Printf("[refresh_tokens] sweep failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[refresh_tokens] swept %d expired tokens", n)
+			}
+			if n, err := DeviceCodes.SweepExpired(ctx); err != nil {
+				log.Printf("[device_codes] sweep failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[device_codes] swept %d expired codes", n)
+			}
+			if n, err := PasswordResets.SweepExpired(ctx); err != nil {
+				log.Printf("[password_resets] sweep failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[password_resets] swept %d expired links", n)
+			}
+			if n, err := ClientAssertions.SweepExpired(ctx); err != nil {
+				log.Printf("[client_assertions] sweep failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[client_assertions] swept %d expired jti", n)
+			}
+			if n, err := AccessTokenCutoffs.SweepExpired(ctx); err != nil {
+				log.Printf("[access_token_cutoffs] sweep failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[access_token_cutoffs] swept %d expired cutoffs", n)
+			}
+			if n, err := AdminSessions.SweepExpired(ctx); err != nil {
+				log.Printf("[admin_sessions] sweep failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[admin_sessions] swept %d expired sessions", n)
+			}
+			if n, err := Users.SweepLoginThrottles(ctx); err != nil {
+				log.Printf("[login_throttles] sweep failed: %v", err)
+			} else if n > 0 {
+				log.Printf("[login_throttles] swept %d stale records", n)
+			}
+			cancel()
+		}
+	}()
+}
+
+// memoryAuthCodeStore: map + Mutex. key = HashToken(code).
+// LoadAndDelete 의 "조회 + 삭제" 를 한 임계구역에서 처리해 재사용을 막는다.
+type memoryAuthCodeStore struct {
+	mu sync.Mutex
+	m  map[string]*models.AuthCode
+}
+
+func (s *memoryAuthCodeStore) Save(ctx context.Context, ac *models.AuthCode) error {
+	cp := *ac
+	cp.Code = "" // 평문은 보관하지 않음
+	s.mu.Lock()
+	s.m[HashToken(ac.Code)] = &cp
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryAuthCodeStore) LoadAndDelete(ctx context.Context, code string) (*models.AuthCode, error) {
+	key := HashToken(code)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ac, ok := s.m[key]
+	if !ok {
+		return nil, ErrAuthCodeNotFound
+	}
+	delete(s.m, key)
+	return ac, nil
+}
+
+func (s *memoryAuthCodeStore) SweepExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, ac := range s.m {
+		if now.After(ac.ExpiresAt) {
+			delete(s.m, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// memoryRefreshTokenStore: map + Mutex. key = HashToken(token).
+// byUser: userID → key 집합 (사용자별 조회 / 폐기용 인덱스). m 과 항상 같이 갱신한다.
+type memoryRefreshTokenStore struct {
+	mu     sync.Mutex
+	m      map[string]*models.RefreshToken
+	byUser map[string]map[string]struct{}
+}
+
+// remove: m + byUser 에서 함께 삭제. mu 를 잡은 상태에서 호출.
+func (s *memoryRefreshTokenStore) remove(key string) {
+	rt, ok := s.m[key]
+	if !ok {
+		return
+	}
+	delete(s.m, key)
+	if set := s.byUser[rt.UserID]; set != nil {
+		delete(set, key)
+		if len(set) == 0 {
+			delete(s.byUser, rt.UserID)
+		}
+	}
+}
+
+func (s *memoryRefreshTokenStore) Save(ctx context.Context, rt *models.RefreshToken) error {
+	cp := *rt
+	cp.TokenHash = HashToken(rt.Token)
+	cp.Token = ""
+	if cp.CreatedAt.IsZero() {
+		cp.CreatedAt = time.Now()
+	}
+	s.mu.Lock()
+	s.m[cp.TokenHash] = &cp
+	if s.byUser[cp.UserID] == nil {
+		s.byUser[cp.UserID] = make(map[string]struct{})
+	}
+	s.byUser[cp.UserID][cp.TokenHash] = struct{}{}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) Load(ctx context.Context, tok string) (*models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rt, ok := s.m[HashToken(tok)]
+	if !ok || !rt.UsedAt.IsZero() {
+		return nil, ErrRefreshTokenNotFound
+	}
+	cp := *rt
+	return &cp, nil
+}
+
+// Consume: 조회 + UsedAt 기록을 한 임계구역에서 → 동시 rotation 중 하나만 성공.
+func (s *memoryRefreshTokenStore) Consume(ctx context.Context, tok string) (*models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rt, ok := s.m[HashToken(tok)]
+	if !ok {
+		return nil, ErrRefreshTokenNotFound
+	}
+	cp := *rt
+	if !rt.UsedAt.IsZero() {
+		return &cp, ErrRefreshTokenReused
+	}
+	rt.UsedAt = time.Now()
+	return &cp, nil
+}
+
+func (s *memoryRefreshTokenStore) Delete(ctx context.Context, tok string) error {
+	s.mu.Lock()
+	s.remove(HashToken(tok))
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, rt := range s.m {
+		if rt.FamilyID == familyID {
+			s.remove(k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeByUser(ctx context.Context, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k := range s.byUser[userID] {
+		s.remove(k)
+		removed++
+	}
+	return removed, nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeByClient(ctx context.Context, clientID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, rt := range s.m {
+		if rt.ClientID == clientID {
+			s.remove(k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *memoryRefreshTokenStore) ListByUser(ctx context.Context, userID string) ([]*models.RefreshToken, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*models.RefreshToken
+	for k := range s.byUser[userID] {
+		rt := s.m[k]
+		if rt.UsedAt.IsZero() && now.Before(rt.ExpiresAt) {
+			cp := *rt
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeByUserClient(ctx context.Context, userID, clientID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k := range s.byUser[userID] {
+		if s.m[k].ClientID == clientID {
+			s.remove(k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *memoryRefreshTokenStore) CountLiveByClient(ctx context.Context) (map[string]int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]int)
+	for _, rt := range s.m {
+		if rt.UsedAt.IsZero() && now.Before(rt.ExpiresAt) {
+			out[rt.ClientID]++
+		}
+	}
+	return out, nil
+}
+
+func (s *memoryRefreshTokenStore) SweepExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, rt := range s.m {
+		if now.After(rt.ExpiresAt) {
+			s.remove(k)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ftery0/ouath/server/models"
+)
+
+func newTestRefreshTokenStore() *memoryRefreshTokenStore {
+	return &memoryRefreshTokenStore{
+		m:      make(map[string]*models.RefreshToken),
+		byUser: make(map[string]map[string]struct{}),
+	}
+}
+
+// TestMemoryAuthCodeStore_SingleUse: 평문은 보관하지 않고, 한 번 꺼내면 사라진다.
+func TestMemoryAuthCodeStore_SingleUse(t *testing.T) {
+	ctx := context.Background()
+	s := &memoryAuthCodeStore{m: make(map[string]*models.AuthCode)}
+	if err := s.Save(ctx, &models.AuthCode{Code: "code-1", ClientID: "app1", ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	ac, err := s.LoadAndDelete(ctx, "code-1")
+	if err != nil || ac.ClientID != "app1" || ac.Code != "" {
+		t.Fatalf("첫 교환: ac=%+v err=%v", ac, err)
+	}
+	if _, err := s.LoadAndDelete(ctx, "code-1"); !errors.Is(err, ErrAuthCodeNotFound) {
+		t.Errorf("두 번째 교환: err=%v, want ErrAuthCodeNotFound", err)
+	}
+}
+
+// TestMemoryRefreshTokenStore_Consume: 1회 소비 → tombstone, 재제시는 토큰과 함께 ErrRefreshTokenReused.
+func TestMemoryRefreshTokenStore_Consume(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name      string
+		setup     func(s *memoryRefreshTokenStore)
+		wantErr   error
+		wantToken bool // 에러와 함께 토큰 (FamilyID) 이 돌아오는가
+	}{
+		{
+			name:      "미소비 토큰",
+			wantToken: true,
+		},
+		{
+			name: "이미 소비된 토큰",
+			setup: func(s *memoryRefreshTokenStore) {
+				if _, err := s.Consume(ctx, "rt-1"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr:   ErrRefreshTokenReused,
+			wantToken: true,
+		},
+		{
+			name: "삭제된 토큰",
+			setup: func(s *memoryRefreshTokenStore) {
+				_ = s.Delete(ctx, "rt-1")
+			},
+			wantErr: ErrRefreshTokenNotFound,
+		},
+		{
+			name: "family 폐기 후",
+			setup: func(s *memoryRefreshTokenStore) {
+				_, _ = s.RevokeFamily(ctx, "fam-1")
+			},
+			wantErr: ErrRefreshTokenNotFound,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestRefreshTokenStore()
+			if err := s.Save(ctx, &models.RefreshToken{Token: "rt-1", FamilyID: "fam-1", UserID: "u1", ClientID: "app1", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+			if tc.setup != nil {
+				tc.setup(s)
+			}
+			rt, err := s.Consume(ctx, "rt-1")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err=%v, want %v", err, tc.wantErr)
+			}
+			if got := rt != nil && rt.FamilyID == "fam-1"; got != tc.wantToken {
+				t.Errorf("토큰 반환=%v, want %v (rt=%+v)", got, tc.wantToken, rt)
+			}
+			// 어떤 경우든 소비 뒤에는 Load 로 보이지 않는다
+			if _, err := s.Load(ctx, "rt-1"); !errors.Is(err, ErrRefreshTokenNotFound) {
+				t.Errorf("Consume 뒤 Load: err=%v, want ErrRefreshTokenNotFound", err)
+			}
+		})
+	}
+}
+
+// TestMemoryRefreshTokenStore_Revoke: family / 사용자 / client / 사용자×client 단위 폐기는 tombstone 까지 지운다.
+func TestMemoryRefreshTokenStore_Revoke(t *testing.T) {
+	ctx := context.Background()
+	seed := []models.RefreshToken{
+		{Token: "a1", FamilyID: "fa", UserID: "u1", ClientID: "app1"},
+		{Token: "a2", FamilyID: "fa", UserID: "u1", ClientID: "app1"}, // a1 의 rotation 결과 — a1 은 tombstone
+		{Token: "b1", FamilyID: "fb", UserID: "u1", ClientID: "app2"},
+		{Token: "c1", FamilyID: "fc", UserID: "u2", ClientID: "app1"},
+	}
+	cases := []struct {
+		name    string
+		revoke  func(s *memoryRefreshTokenStore) (int, error)
+		want    int
+		gone    []string
+		survive []string
+	}{
+		{"RevokeFamily", func(s *memoryRefreshTokenStore) (int, error) { return s.RevokeFamily(ctx, "fa") }, 2, []string{"a2"}, []string{"b1", "c1"}},
+		{"RevokeByUser", func(s *memoryRefreshTokenStore) (int, error) { return s.RevokeByUser(ctx, "u1") }, 3, []string{"a2", "b1"}, []string{"c1"}},
+		{"RevokeByClient", func(s *memoryRefreshTokenStore) (int, error) { return s.RevokeByClient(ctx, "app1") }, 3, []string{"a2", "c1"}, []string{"b1"}},
+		{"RevokeByUserClient", func(s *memoryRefreshTokenStore) (int, error) { return s.RevokeByUserClient(ctx, "u1", "app1") }, 2, []string{"a2"}, []string{"b1", "c1"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestRefreshTokenStore()
+			for _, rt := range seed {
+				rt.ExpiresAt = time.Now().Add(time.Hour)
+				if err := s.Save(ctx, &rt); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := s.Consume(ctx, "a1"); err != nil {
+				t.Fatal(err)
+			}
+			n, err := tc.revoke(s)
+			if err != nil || n != tc.want {
+				t.Errorf("삭제 수=%d err=%v, want %d", n, err, tc.want)
+			}
+			for _, tok := range tc.gone {
+				if _, err := s.Load(ctx, tok); !errors.Is(err, ErrRefreshTokenNotFound) {
+					t.Errorf("%s: 폐기 후에도 Load 됨 (err=%v)", tok, err)
+				}
+			}
+			for _, tok := range tc.survive {
+				if _, err := s.Load(ctx, tok); err != nil {
+					t.Errorf("%s: 폐기 대상이 아닌데 사라짐 (err=%v)", tok, err)
+				}
+			}
+		})
+	}
+}
+
+// TestMemoryRefreshTokenStore_Expiry: 만료 / tombstone 은 목록 · 집계에서 빠지고, Sweep 은 만료분만 지운다.
+func TestMemoryRefreshTokenStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	s := newTestRefreshTokenStore()
+	now := time.Now()
+	for _, rt := range []models.RefreshToken{
+		{Token: "live", UserID: "u1", ClientID: "app1", ExpiresAt: now.Add(time.Hour)},
+		{Token: "used", UserID: "u1", ClientID: "app1", ExpiresAt: now.Add(time.Hour)},
+		{Token: "expired", UserID: "u1", ClientID: "app2", ExpiresAt: now.Add(-time.Second)},
+	} {
+		if err := s.Save(ctx, &rt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Consume(ctx, "used"); err != nil {
+		t.Fatal(err)
+	}
+
+	list, _ := s.ListByUser(ctx, "u1")
+	if len(list) != 1 || list[0].TokenHash != HashToken("live") {
+		t.Errorf("ListByUser = %+v, want live 하나", list)
+	}
+	counts, _ := s.CountLiveByClient(ctx)
+	if counts["app1"] != 1 || counts["app2"] != 0 {
+		t.Errorf("CountLiveByClient = %v, want app1:1", counts)
+	}
+
+	if n, err := s.SweepExpired(ctx); err != nil || n != 1 {
+		t.Errorf("SweepExpired = %d (err=%v), want 1", n, err)
+	}
+	if _, ok := s.m[HashToken("expired")]; ok {
+		t.Error("만료 토큰이 남아 있음")
+	}
+	if len(s.byUser["u1"]) != 2 {
+		t.Errorf("byUser 인덱스 = %d, want 2 (live + used tombstone)", len(s.byUser["u1"]))
+	}
+}
@@ -2,7 +2,10 @@ package store
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ftery0/ouath/server/models"
 )
@@ -13,11 +16,13 @@ type memoryUserStore struct {
 	mu         sync.RWMutex
 	byUsername map[string]*models.User
 	byID       map[string]*models.User
+	throttles  map[string]*models.LoginThrottle // key = username (없는 사용자 포함)
 }
 
 var defaultMemoryUsers = &memoryUserStore{
 	byUsername: make(map[string]*models.User),
 	byID:       make(map[string]*models.User),
+	throttles:  make(map[string]*models.LoginThrottle),
 }
 
 // init: store.Users 의 기본값을 인메모리로 설정 + 시드.
@@ -42,6 +47,7 @@ func (s *memoryUserStore) GetByUsername(ctx context.Context, username string) (*
 		return nil, ErrUserNotFound
 	}
 	cp := *u
+	cp.RecoveryCodeHashes = append([]string(nil), u.RecoveryCodeHashes...)
 	return &cp, nil
 }
 
@@ -53,9 +59,23 @@ func (s *memoryUserStore) GetByID(ctx context.Context, id string) (*models.User,
 		return nil, ErrUserNotFound
 	}
 	cp := *u
+	cp.RecoveryCodeHashes = append([]string(nil), u.RecoveryCodeHashes...)
 	return &cp, nil
 }
 
+func (s *memoryUserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.byID {
+		if u.Email != "" && strings.EqualFold(u.Email, email) {
+			cp := *u
+			cp.RecoveryCodeHashes = append([]string(nil), u.RecoveryCodeHashes...)
+			return &cp, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
 func (s *memoryUserStore) Create(ctx context.Context, u *models.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -70,3 +90,267 @@ func (s *memoryUserStore) Create(ctx context.Context, u *models.User) error {
 	s.byID[u.ID] = &cp
 	return nil
 }
+
+func (s *memoryUserStore) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.PasswordHash = passwordHash
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryUserStore) UpdateEmail(ctx context.Context, userID, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	for _, other := range s.byID {
+		if other.ID != userID && other.Email != "" && strings.EqualFold(other.Email, email) {
+			return ErrEmailAlreadyUsed
+		}
+	}
+	u.Email = email
+	u.EmailVerified = false
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryUserStore) MarkEmailVerified(ctx context.Context, userID, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok || u.Email == "" || u.Email != email {
+		return ErrUserNotFound
+	}
+	u.EmailVerified = true
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryUserStore) List(ctx context.Context, opts UserListOptions) ([]*models.User, int, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultUserPageSize
+	}
+	q := strings.ToLower(strings.TrimSpace(opts.Query))
+	s.mu.RLock()
+	matched := make([]*models.User, 0, len(s.byID))
+	for _, u := range s.byID {
+		if q == "" ||
+			strings.Contains(strings.ToLower(u.Username), q) ||
+			strings.Contains(strings.ToLower(u.DisplayName), q) ||
+			strings.Contains(strings.ToLower(u.Email), q) {
+			cp := *u
+			cp.RecoveryCodeHashes = append([]string(nil), u.RecoveryCodeHashes...)
+			matched = append(matched, &cp)
+		}
+	}
+	s.mu.RUnlock()
+
+	// 가입순 — 시드 사용자는 CreatedAt 이 비어 있으므로 username 으로 고정
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+		}
+		return matched[i].Username < matched[j].Username
+	})
+	total := len(matched)
+	if opts.Offset >= total {
+		return []*models.User{}, total, nil
+	}
+	end := min(opts.Offset+opts.Limit, total)
+	return matched[opts.Offset:end], total, nil
+}
+
+func (s *memoryUserStore) Update(ctx context.Context, u *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.byID[u.ID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if u.Email != "" {
+		for _, other := range s.byID {
+			if other.ID != u.ID && strings.EqualFold(other.Email, u.Email) {
+				return ErrEmailAlreadyUsed
+			}
+		}
+	}
+	cur.DisplayName = u.DisplayName
+	cur.Email = u.Email
+	cur.EmailVerified = u.EmailVerified
+	cur.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryUserStore) Disable(ctx context.Context, userID string, disabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.Disabled = disabled
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryUserStore) SetAdminRole(ctx context.Context, userID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.AdminRole = role
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryUserStore) ListAdmins(ctx context.Context) ([]*models.User, error) {
+	s.mu.RLock()
+	var out []*models.User
+	for _, u := range s.byID {
+		if u.AdminRole != "" {
+			cp := *u
+			cp.RecoveryCodeHashes = append([]string(nil), u.RecoveryCodeHashes...)
+			out = append(out, &cp)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Username < out[j].Username })
+	return out, nil
+}
+
+// Delete: 인메모리 패스키 / 재설정 링크는 각 store 가 따로 들고 있어 여기서 같이 지운다 (Postgres 는 FK CASCADE).
+func (s *memoryUserStore) Delete(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	u, ok := s.byID[userID]
+	if ok {
+		delete(s.byID, userID)
+		delete(s.byUsername, u.Username)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return ErrUserNotFound
+	}
+	if pks, err := Passkeys.ListByUser(ctx, userID); err == nil {
+		for _, pk := range pks {
+			_ = Passkeys.Delete(ctx, userID, pk.ID)
+		}
+	}
+	_, _ = PasswordResets.DeleteByUser(ctx, userID)
+	return nil
+}
+
+func (s *memoryUserStore) EnableTOTP(ctx context.Context, userID, secret string, recoveryCodeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.TOTPSecret = secret
+	u.TOTPLastStep = 0
+	u.RecoveryCodeHashes = append([]string(nil), recoveryCodeHashes...)
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryUserStore) DisableTOTP(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.TOTPSecret = ""
+	u.TOTPLastStep = 0
+	u.RecoveryCodeHashes = nil
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *memoryUserStore) ConsumeTOTPStep(ctx context.Context, userID string, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if step <= u.TOTPLastStep {
+		return ErrTOTPReplay
+	}
+	u.TOTPLastStep = step
+	return nil
+}
+
+func (s *memoryUserStore) ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byID[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	for i, h := range u.RecoveryCodeHashes {
+		if h == codeHash {
+			u.RecoveryCodeHashes = append(u.RecoveryCodeHashes[:i:i], u.RecoveryCodeHashes[i+1:]...)
+			return nil
+		}
+	}
+	return ErrRecoveryCodeInvalid
+}
+
+func (s *memoryUserStore) GetLoginThrottle(ctx context.Context, username string) (*models.LoginThrottle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t, ok := s.throttles[username]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return &models.LoginThrottle{Username: username}, nil
+}
+
+func (s *memoryUserStore) RecordLoginFailure(ctx context.Context, username string, lockFor func(failures int) time.Duration) (*models.LoginThrottle, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.throttles[username]
+	if !ok || now.Sub(t.LastFailedAt) > LoginFailureWindow {
+		t = &models.LoginThrottle{Username: username}
+		s.throttles[username] = t
+	}
+	t.Failures++
+	t.LastFailedAt = now
+	if d := lockFor(t.Failures); d > 0 {
+		t.LockedUntil = now.Add(d)
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (s *memoryUserStore) ResetLoginFailures(ctx context.Context, username string) error {
+	s.mu.Lock()
+	delete(s.throttles, username)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryUserStore) SweepLoginThrottles(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-LoginFailureWindow)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for name, t := range s.throttles {
+		if t.LastFailedAt.Before(cutoff) && !t.Locked(time.Now()) {
+			delete(s.throttles, name)
+			n++
+		}
+	}
+	return n, nil
+}
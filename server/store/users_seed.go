@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"errors"
+	"log"
 
 	"github.com/ftery0/ouath/server/models"
 )
@@ -23,3 +24,41 @@ func SeedUsers(ctx context.Context, s UserStore) error {
 	}
 	return nil
 }
+
+// ErrAdminsExist: 이미 어드민이 있어 bootstrap 을 하지 않았다.
+var ErrAdminsExist = errors.New("admins already exist")
+
+// BootstrapAdmins: 어드민이 한 명도 없을 때만 usernames 를 superadmin 으로. 올린 username 반환.
+// 어드민이 있으면 아무것도 하지 않고 ErrAdminsExist — 역할 회수를 재부팅이 되돌리지 않게.
+// 없는 username 은 건너뛴다 (가입 전일 수 있음). 누가 그 이름으로 가입하면 다음 부팅에 올라가므로 크게 남긴다.
+func BootstrapAdmins(ctx context.Context, s UserStore, usernames []string) ([]string, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+	admins, err := s.ListAdmins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(admins) > 0 {
+		return nil, ErrAdminsExist
+	}
+	var promoted []string
+	for _, name := range usernames {
+		u, err := s.GetByUsername(ctx, name)
+		if errors.Is(err, ErrUserNotFound) {
+			log.Printf("[admin_bootstrap] WARNING: %q 사용자가 없어 건너뜀 — 이 이름으로 가입하면 다음 부팅에 superadmin 이 된다", name)
+			continue
+		}
+		if err != nil {
+			return promoted, err
+		}
+		if u.AdminRole != "" {
+			continue
+		}
+		if err := s.SetAdminRole(ctx, u.ID, models.AdminRoleSuperadmin); err != nil {
+			return promoted, err
+		}
+		promoted = append(promoted, name)
+	}
+	return promoted, nil
+}
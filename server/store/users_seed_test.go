@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ftery0/ouath/server/models"
+)
+
+// TestBootstrapAdmins: 어드민이 없을 때만 올리고, 한 명이라도 있으면 회수된 역할을 되살리지 않는다.
+func TestBootstrapAdmins(t *testing.T) {
+	ctx := context.Background()
+	s := &memoryUserStore{
+		byUsername: make(map[string]*models.User),
+		byID:       make(map[string]*models.User),
+		throttles:  make(map[string]*models.LoginThrottle),
+	}
+	for _, name := range []string{"alice", "bob"} {
+		if err := s.Create(ctx, &models.User{ID: "u-" + name, Username: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	promoted, err := BootstrapAdmins(ctx, s, []string{"alice", "ghost"})
+	if err != nil || len(promoted) != 1 || promoted[0] != "alice" {
+		t.Fatalf("첫 bootstrap = %v, %v; want [alice]", promoted, err)
+	}
+
+	// 다른 어드민이 alice 의 역할을 회수한 뒤 재부팅
+	_ = s.SetAdminRole(ctx, "u-bob", models.AdminRoleSuperadmin)
+	_ = s.SetAdminRole(ctx, "u-alice", "")
+	if promoted, err := BootstrapAdmins(ctx, s, []string{"alice"}); !errors.Is(err, ErrAdminsExist) || len(promoted) != 0 {
+		t.Errorf("어드민이 있을 때 bootstrap = %v, %v; want ErrAdminsExist", promoted, err)
+	}
+	if u, _ := s.GetByUsername(ctx, "alice"); u.AdminRole != "" {
+		t.Errorf("회수된 역할이 되살아남: %q", u.AdminRole)
+	}
+}
@@ -1,18 +1,22 @@
 package token
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims: access token.
+//   - fid: refresh token family ID. family 가 재사용 탐지로 폐기되면 같은 fid 의 access token 도 차단
+//   - gty: client_credentials 로 발급된 토큰만 "client_credentials" (sub = client_id, 사용자 없음)
 type Claims struct {
-	UserID   string `json:"sub"`
-	ClientID string `json:"client_id"`
-	Scope    string `json:"scope"`
+	UserID    string `json:"sub"`
+	ClientID  string `json:"client_id"`
+	Scope     string `json:"scope"`
+	FamilyID  string `json:"fid,omitempty"`
+	GrantType string `json:"gty,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -21,60 +25,115 @@ type Claims struct {
 //   - sub  : 사용자 식별자 (UUID)
 //   - nonce: /authorize 시점에 받은 nonce 그대로 echo (replay 방어)
 //   - auth_time: 사용자가 IdP 에서 실제 인증한 unix 초
+//   - amr  : 인증 수단 (RFC 8176) — ["pwd"] 또는 2단계 인증을 거친 ["pwd", "otp"]
+//   - sid  : IdP 세션 식별자 (Back-Channel Logout 1.0 §2.1). logout_token 의 sid 와 같은 값
 type IDTokenClaims struct {
-	Nonce    string `json:"nonce,omitempty"`
-	AuthTime int64  `json:"auth_time,omitempty"`
+	Nonce    string   `json:"nonce,omitempty"`
+	AuthTime int64    `json:"auth_time,omitempty"`
+	AMR      []string `json:"amr,omitempty"`
+	SID      string   `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-var (
-	// 비대칭키 쌍: privateKey로 서명, PublicKey로 검증
-	// 비대칭키를 쓰는 이유: 리소스 서버(Resource Server)가 비밀키 없이도 토큰을 검증할 수 있음
-	// → 인가 서버만 서명 가능, 다른 서버들은 공개키로 검증만 가능 (보안상 안전)
-	privateKey *rsa.PrivateKey
-	PublicKey  *rsa.PublicKey // JWKS 엔드포인트를 통해 외부에 공개
-	issuerVal  string
-)
+// BackchannelLogoutEvent: logout_token events claim 의 키 (Back-Channel Logout 1.0 §2.4).
+const BackchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
 
-func init() {
-	var err error
-	// 2048비트 RSA 키 생성 (프로덕션에서는 파일에서 로드해야 함)
-	privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		panic("RSA 키 생성 실패: " + err.Error())
-	}
-	PublicKey = &privateKey.PublicKey
+// LogoutTokenClaims: OIDC Back-Channel Logout 의 logout_token.
+// nonce 는 절대 넣지 않는다 — RP 가 ID Token 으로 착각해 받아들이지 못하게 (§2.4).
+type LogoutTokenClaims struct {
+	SID    string         `json:"sid,omitempty"`
+	Events map[string]any `json:"events"`
+	jwt.RegisteredClaims
 }
 
+// 비대칭키 쌍: active 개인키로 서명, JWKS 에 게시된 공개키로 검증
+// 비대칭키를 쓰는 이유: 리소스 서버(Resource Server)가 비밀키 없이도 토큰을 검증할 수 있음
+// → 인가 서버만 서명 가능, 다른 서버들은 공개키로 검증만 가능 (보안상 안전)
+//
+// 키링(next / active / retired) 은 keys.go. main 이 InitKeys 로 KeyStore 에서 읽어 설치한다.
+var issuerVal string
+
+// tokenTTL: access token / ID token 공통 수명. retired 키 게시 기간도 이 값 기준.
+const tokenTTL = 15 * time.Minute
+
+// errNoSigningKey: InitKeys 호출 전에 서명/검증을 시도한 경우.
+var errNoSigningKey = errors.New("서명키 미설정 (InitKeys 필요)")
+
 // Init: main에서 설정 로드 후 호출 (iss 클레임 설정)
 func Init(_, issuer string) {
 	issuerVal = issuer
 }
 
-func Create(userID, clientID, scope string) (string, error) {
+// Create: access token 발급. familyID 는 같이 발급되는 refresh token 의 family (없으면 "").
+func Create(userID, clientID, scope, familyID string) (string, error) {
 	now := time.Now()
 	claims := Claims{
 		UserID:   userID,
 		ClientID: clientID,
 		Scope:    scope,
+		FamilyID: familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(15 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    issuerVal,
 		},
 	}
 	// HS512(대칭키) → RS256(비대칭키)로 변경
-	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+	return sign(claims)
+}
+
+// GrantClientCredentials: client_credentials 토큰의 gty claim 값.
+const GrantClientCredentials = "client_credentials"
+
+// CreateForClient: client_credentials access token. sub 는 client 자신 — 사용자가 없다.
+func CreateForClient(clientID, scope string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:    clientID,
+		ClientID:  clientID,
+		Scope:     scope,
+		GrantType: GrantClientCredentials,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    issuerVal,
+		},
+	}
+	return sign(claims)
+}
+
+// sign: active 키로 RS256 서명 + kid 헤더. 리소스 서버는 kid 로 JWKS 에서 검증키를 고른다.
+func sign(claims jwt.Claims) (string, error) {
+	return signTyped(claims, "")
+}
+
+// signTyped: sign + typ 헤더 (비면 기본 "JWT"). logout_token 은 "logout+jwt" 로 다른 JWT 와 구분된다.
+func signTyped(claims jwt.Claims, typ string) (string, error) {
+	k := signingKey()
+	if k == nil {
+		return "", errNoSigningKey
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = k.Kid
+	if typ != "" {
+		t.Header["typ"] = typ
+	}
+	return t.SignedString(k.Private)
+}
+
+// verificationKey: jwt.Keyfunc. 서명 알고리즘이 RSA인지 반드시 확인 (알고리즘 혼동 공격 방지)
+// 후 헤더 kid 로 키링에서 공개키를 고른다 — 회전 직후에도 retired 키로 서명된 토큰이 검증된다.
+func verificationKey(t *jwt.Token) (any, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, fmt.Errorf("예상치 못한 서명 알고리즘: %v", t.Header["alg"])
+	}
+	kid, _ := t.Header["kid"].(string)
+	return lookupPublicKey(kid)
 }
 
 // ParseIDToken: ID Token JWT 검증 후 claims 반환. /logout 의 id_token_hint 파싱용.
 func ParseIDToken(tokenStr string) (*IDTokenClaims, error) {
-	t, err := jwt.ParseWithClaims(tokenStr, &IDTokenClaims{}, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("예상치 못한 서명 알고리즘: %v", t.Header["alg"])
-		}
-		return PublicKey, nil
-	})
+	t, err := jwt.ParseWithClaims(tokenStr, &IDTokenClaims{}, verificationKey)
 	if err != nil {
 		return nil, err
 	}
@@ -82,33 +141,58 @@ func ParseIDToken(tokenStr string) (*IDTokenClaims, error) {
 }
 
 // CreateIDToken: OIDC ID Token 발급. openid scope 가 있을 때만 호출됨.
-// authTime 이 zero 면 (refresh grant 등) auth_time claim 생략.
-func CreateIDToken(userID, clientID, nonce string, authTime time.Time) (string, error) {
+// authTime 이 zero / amr · sid 가 비면 (refresh grant 등) 해당 claim 생략.
+func CreateIDToken(userID, clientID, nonce, sid string, authTime time.Time, amr []string) (string, error) {
 	now := time.Now()
 	c := IDTokenClaims{
 		Nonce: nonce,
+		AMR:   amr,
+		SID:   sid,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    issuerVal,
 			Subject:   userID,
 			Audience:  jwt.ClaimStrings{clientID},
-			ExpiresAt: jwt.NewNumericDate(now.Add(15 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 	if !authTime.IsZero() {
 		c.AuthTime = authTime.Unix()
 	}
-	return jwt.NewWithClaims(jwt.SigningMethodRS256, c).SignedString(privateKey)
+	return sign(c)
+}
+
+// logoutTokenTTL: logout_token 수명. 재시도가 끝날 때까지만 유효하면 된다.
+const logoutTokenTTL = 2 * time.Minute
+
+// CreateLogoutToken: client 한 곳에 보낼 logout_token. jti 는 RP 의 재사용 차단용으로 매번 새로.
+func CreateLogoutToken(userID, clientID, sid, jti string) (string, error) {
+	now := time.Now()
+	return signTyped(LogoutTokenClaims{
+		SID:    sid,
+		Events: map[string]any{BackchannelLogoutEvent: map[string]any{}},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuerVal,
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{clientID},
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(logoutTokenTTL)),
+		},
+	}, "logout+jwt")
+}
+
+// ParseLogoutToken: logout_token 서명 검증 후 claims 반환. ParseIDToken 과 같은 키링으로 검증한다.
+func ParseLogoutToken(tokenStr string) (*LogoutTokenClaims, error) {
+	t, err := jwt.ParseWithClaims(tokenStr, &LogoutTokenClaims{}, verificationKey)
+	if err != nil {
+		return nil, err
+	}
+	return t.Claims.(*LogoutTokenClaims), nil
 }
 
 func Parse(tokenStr string) (*Claims, error) {
-	t, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (any, error) {
-		// 서명 알고리즘이 RSA인지 반드시 확인 (알고리즘 혼동 공격 방지)
-		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("예상치 못한 서명 알고리즘: %v", t.Header["alg"])
-		}
-		return PublicKey, nil
-	})
+	t, err := jwt.ParseWithClaims(tokenStr, &Claims{}, verificationKey)
 	if err != nil {
 		return nil, err
 	}
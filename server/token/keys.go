@@ -0,0 +1,675 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// signingKeyBits: 신규 생성 RSA 키 크기.
+const signingKeyBits = 2048
+
+// RetiredKeyTTL: retired 키를 JWKS 에 계속 게시하는 기간.
+// 가장 긴 JWT (access / ID token 15분) 만료 + 시계 오차 여유. 이 기간이 지나면 prune.
+const RetiredKeyTTL = tokenTTL + 5*time.Minute
+
+// keyRefreshInterval: StartKeyRotation goroutine 이 store 를 다시 읽는 주기.
+// 다른 인스턴스가 회전시킨 결과를 이 주기 안에 따라잡는다.
+const keyRefreshInterval = time.Minute
+
+// KeyStatus: 서명키 수명주기.
+//
+//	next    → JWKS 에 미리 게시만 (리소스 서버 캐시가 회전 전에 받아가도록)
+//	active  → Create / CreateIDToken 서명에 사용 (항상 1 개)
+//	retired → 서명 중단, 이미 발급된 토큰 검증용으로 RetiredKeyTTL 동안 게시
+type KeyStatus string
+
+const (
+	KeyStatusNext    KeyStatus = "next"
+	KeyStatusActive  KeyStatus = "active"
+	KeyStatusRetired KeyStatus = "retired"
+)
+
+// SigningKey: 키링의 1 항목. Kid = RFC 7638 thumbprint.
+type SigningKey struct {
+	Kid         string
+	Private     *rsa.PrivateKey
+	Status      KeyStatus
+	CreatedAt   time.Time
+	ActivatedAt time.Time // next 는 zero
+	RetiredAt   time.Time // retired 만 채워짐
+}
+
+// KeyInfo: 외부 노출용 (JWKS / 어드민). 개인키는 빠진다.
+type KeyInfo struct {
+	Kid         string
+	Public      *rsa.PublicKey
+	Status      KeyStatus
+	CreatedAt   time.Time
+	ActivatedAt time.Time
+	RetiredAt   time.Time
+}
+
+// RotationResult: Rotate 결과. 감사 로그에 그대로 남긴다.
+type RotationResult struct {
+	RetiredKid string // 방금 retired 된 (이전 active) kid
+	ActiveKid  string // 새 active (이전 next)
+	NextKid    string // 새로 생성되어 게시된 next
+}
+
+var (
+	// ErrRotationConflict: 회전 시점에 active 가 기대값과 다름 (다른 인스턴스가 먼저 회전).
+	// 호출자는 Reload 후 결과를 그대로 받아들이면 된다.
+	ErrRotationConflict = errors.New("signing key rotation conflict")
+	// ErrNoNextKey: 승격할 next 키가 없음. InitKeys 가 항상 하나 만들어 두므로 정상 흐름에선 안 나옴.
+	ErrNoNextKey = errors.New("no next signing key")
+	// ErrKeyStatusTaken: AddSigningKey 시점에 같은 상태 (active / next) 의 키가 이미 있음.
+	// 다른 인스턴스가 먼저 채운 것 — 호출자는 다시 읽어 그 키를 쓴다.
+	ErrKeyStatusTaken = errors.New("signing key status already taken")
+)
+
+// KeyStore: 서명키 영속 인터페이스.
+//
+// 구현체:
+//   - FileKeyStore            : PEM 파일 (OAUTH_SIGNING_KEY_FILE). 블록 헤더에 상태 기록
+//   - postgres.SigningKeyStore: signing_keys 테이블 (DATABASE_URL 사용 시)
+//   - MemoryKeyStore          : 테스트 / 일회성 실행용 (재시작 시 사라짐)
+type KeyStore interface {
+	ListSigningKeys(ctx context.Context) ([]*SigningKey, error)
+	// AddSigningKey: active / next 가 이미 있으면 ErrKeyStatusTaken 을 돌려줄 수 있다 (공유 store).
+	AddSigningKey(ctx context.Context, k *SigningKey) error
+	// RotateSigningKeys: 한 번에 (atomic) 수행해야 하는 회전 단계.
+	//   1. 현재 active 가 expectedActiveKid 가 아니면 ErrRotationConflict
+	//   2. active → retired (RetiredAt=now), 가장 오래된 next → active (ActivatedAt=now)
+	//   3. newNext 를 next 로 추가
+	//   4. RetiredAt < pruneBefore 인 retired 삭제
+	RotateSigningKeys(ctx context.Context, expectedActiveKid string, newNext *SigningKey, now, pruneBefore time.Time) error
+}
+
+// 키링 상태. Parse 계열은 kid 로 ring 에서 검증키를 고르고, 서명은 active 로.
+var (
+	ringMu    sync.RWMutex
+	ring      []*SigningKey
+	active    *SigningKey
+	ringStore KeyStore
+)
+
+// InitKeys: KeyStore 에서 키링을 읽어 설치한다. main 부팅 시 1 회.
+// active / next 가 없으면 (최초 부팅, 또는 단일 키만 있던 이전 버전) 생성해 저장 후 다시 읽는다 —
+// 여러 인스턴스가 동시에 첫 부팅해도 store 가 정한 순서로 같은 active 를 고르게 하기 위함.
+func InitKeys(ctx context.Context, ks KeyStore) error {
+	ringMu.Lock()
+	ringStore = ks
+	ringMu.Unlock()
+	return Reload(ctx)
+}
+
+// Reload: store 에서 키링을 다시 읽는다. 부족한 active / next 는 채운다.
+func Reload(ctx context.Context) error {
+	ks := currentStore()
+	if ks == nil {
+		return errNoSigningKey
+	}
+	keys, err := ks.ListSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("list signing keys: %w", err)
+	}
+
+	added := false
+	now := time.Now()
+	if pickActive(keys) == nil {
+		if err := addSigningKey(ctx, ks, KeyStatusActive, now); err != nil {
+			return err
+		}
+		added = true
+	}
+	if !hasStatus(keys, KeyStatusNext) {
+		if err := addSigningKey(ctx, ks, KeyStatusNext, now); err != nil {
+			return err
+		}
+		added = true
+	}
+	if added {
+		if keys, err = ks.ListSigningKeys(ctx); err != nil {
+			return fmt.Errorf("list signing keys: %w", err)
+		}
+	}
+
+	act := pickActive(keys)
+	if act == nil {
+		return errNoSigningKey
+	}
+	ringMu.Lock()
+	ring = keys
+	active = act
+	ringMu.Unlock()
+	return nil
+}
+
+// statusTaken: active / next 는 키링에 하나뿐 — 이미 있으면 true. retired 는 여럿 허용.
+func statusTaken(keys []*SigningKey, status KeyStatus) bool {
+	return status != KeyStatusRetired && hasStatus(keys, status)
+}
+
+// addSigningKey: 새 키 생성 + 저장. 다른 인스턴스가 같은 상태를 먼저 채웠으면 (ErrKeyStatusTaken) 성공으로 본다 —
+// Reload 가 곧바로 다시 읽어 그 키를 쓴다.
+func addSigningKey(ctx context.Context, ks KeyStore, status KeyStatus, now time.Time) error {
+	k, err := newSigningKey(status, now)
+	if err != nil {
+		return err
+	}
+	if err := ks.AddSigningKey(ctx, k); err != nil && !errors.Is(err, ErrKeyStatusTaken) {
+		return fmt.Errorf("save signing key: %w", err)
+	}
+	return nil
+}
+
+// Rotate: next → active, active → retired, 새 next 생성. 어드민 버튼 / 스케줄러가 호출.
+// 다른 인스턴스가 먼저 회전했다면 ErrRotationConflict (키링은 최신으로 Reload 된 상태).
+func Rotate(ctx context.Context) (RotationResult, error) {
+	ks := currentStore()
+	cur := ActiveKeyInfo()
+	if ks == nil || cur == nil {
+		return RotationResult{}, errNoSigningKey
+	}
+	now := time.Now()
+	next, err := newSigningKey(KeyStatusNext, now)
+	if err != nil {
+		return RotationResult{}, err
+	}
+	rotErr := ks.RotateSigningKeys(ctx, cur.Kid, next, now, now.Add(-RetiredKeyTTL))
+	if err := Reload(ctx); err != nil {
+		return RotationResult{}, err
+	}
+	if rotErr != nil {
+		return RotationResult{}, rotErr
+	}
+	return RotationResult{RetiredKid: cur.Kid, ActiveKid: KeyID(), NextKid: next.Kid}, nil
+}
+
+// StartKeyRotation: keyRefreshInterval 주기로 Reload 하고, interval 이 지나면 Rotate.
+// interval <= 0 이면 스케줄 회전 없이 Reload 만 (어드민 수동 회전 결과를 다른 인스턴스가 따라잡도록).
+// onRotate 는 이 인스턴스가 실제로 회전시켰을 때만 호출 (감사 로그용).
+// main 에서 한 번 호출. 학습용으로 context 종료는 따로 처리하지 않는다.
+func StartKeyRotation(interval time.Duration, onRotate func(RotationResult)) {
+	go func() {
+		t := time.NewTicker(keyRefreshInterval)
+		defer t.Stop()
+		for range t.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := Reload(ctx); err != nil {
+				log.Printf("[signing_keys] reload failed: %v", err)
+			} else if interval > 0 && rotationDue(interval, time.Now()) {
+				res, err := Rotate(ctx)
+				switch {
+				case errors.Is(err, ErrRotationConflict):
+					// 다른 인스턴스가 먼저 회전 — Reload 로 이미 반영됨
+				case err != nil:
+					log.Printf("[signing_keys] rotate failed: %v", err)
+				case onRotate != nil:
+					onRotate(res)
+				}
+			}
+			cancel()
+		}
+	}()
+}
+
+// rotationDue: active 가 interval 이상 서명에 쓰였는가.
+func rotationDue(interval time.Duration, now time.Time) bool {
+	ringMu.RLock()
+	defer ringMu.RUnlock()
+	if active == nil {
+		return false
+	}
+	since := active.ActivatedAt
+	if since.IsZero() {
+		since = active.CreatedAt
+	}
+	return !now.Before(since.Add(interval))
+}
+
+// KeyID: 현재 active 서명키의 kid. JWT 헤더 kid 로 들어간다.
+func KeyID() string {
+	ringMu.RLock()
+	defer ringMu.RUnlock()
+	if active == nil {
+		return ""
+	}
+	return active.Kid
+}
+
+// ActiveKeyInfo: 현재 active 키 정보 (없으면 nil).
+func ActiveKeyInfo() *KeyInfo {
+	ringMu.RLock()
+	defer ringMu.RUnlock()
+	if active == nil {
+		return nil
+	}
+	info := toInfo(active)
+	return &info
+}
+
+// PublishedKeys: JWKS 에 게시할 키 (next, active, 아직 만료 안 된 retired).
+// 정렬: active → next → retired (최근 retired 먼저).
+func PublishedKeys() []KeyInfo {
+	now := time.Now()
+	ringMu.RLock()
+	defer ringMu.RUnlock()
+	out := make([]KeyInfo, 0, len(ring))
+	for _, k := range ring {
+		if isPublished(k, now) {
+			out = append(out, toInfo(k))
+		}
+	}
+	rank := map[KeyStatus]int{KeyStatusActive: 0, KeyStatusNext: 1, KeyStatusRetired: 2}
+	sort.SliceStable(out, func(i, j int) bool {
+		if rank[out[i].Status] != rank[out[j].Status] {
+			return rank[out[i].Status] < rank[out[j].Status]
+		}
+		return out[i].RetiredAt.After(out[j].RetiredAt)
+	})
+	return out
+}
+
+// signingKey: 서명용 active 키.
+func signingKey() *SigningKey {
+	ringMu.RLock()
+	defer ringMu.RUnlock()
+	return active
+}
+
+// lookupPublicKey: JWT 헤더 kid 로 검증키 선택. kid 없음(이전 버전 토큰) 이면 active.
+// next 도 허용 — 다른 인스턴스가 먼저 회전해 새 active 로 서명했는데
+// 이 인스턴스가 아직 Reload 전이면 그 키는 여기선 next 로 보인다.
+func lookupPublicKey(kid string) (*rsa.PublicKey, error) {
+	now := time.Now()
+	ringMu.RLock()
+	defer ringMu.RUnlock()
+	if active == nil {
+		return nil, errNoSigningKey
+	}
+	if kid == "" {
+		return &active.Private.PublicKey, nil
+	}
+	for _, k := range ring {
+		if k.Kid == kid && isPublished(k, now) {
+			return &k.Private.PublicKey, nil
+		}
+	}
+	return nil, fmt.Errorf("알 수 없는 kid: %s", kid)
+}
+
+func isPublished(k *SigningKey, now time.Time) bool {
+	if k.Status != KeyStatusRetired {
+		return true
+	}
+	return now.Before(k.RetiredAt.Add(RetiredKeyTTL))
+}
+
+func toInfo(k *SigningKey) KeyInfo {
+	return KeyInfo{
+		Kid:         k.Kid,
+		Public:      &k.Private.PublicKey,
+		Status:      k.Status,
+		CreatedAt:   k.CreatedAt,
+		ActivatedAt: k.ActivatedAt,
+		RetiredAt:   k.RetiredAt,
+	}
+}
+
+func currentStore() KeyStore {
+	ringMu.RLock()
+	defer ringMu.RUnlock()
+	return ringStore
+}
+
+// pickActive: active 가 여러 개면 (동시 첫 부팅) 가장 먼저 활성화된 것. 모든 인스턴스가 같은 결과.
+func pickActive(keys []*SigningKey) *SigningKey {
+	var best *SigningKey
+	for _, k := range keys {
+		if k.Status != KeyStatusActive {
+			continue
+		}
+		if best == nil || olderThan(k, best) {
+			best = k
+		}
+	}
+	return best
+}
+
+func olderThan(a, b *SigningKey) bool {
+	at, bt := a.ActivatedAt, b.ActivatedAt
+	if at.IsZero() {
+		at = a.CreatedAt
+	}
+	if bt.IsZero() {
+		bt = b.CreatedAt
+	}
+	if !at.Equal(bt) {
+		return at.Before(bt)
+	}
+	return a.Kid < b.Kid
+}
+
+func hasStatus(keys []*SigningKey, st KeyStatus) bool {
+	for _, k := range keys {
+		if k.Status == st {
+			return true
+		}
+	}
+	return false
+}
+
+func newSigningKey(status KeyStatus, now time.Time) (*SigningKey, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generate signing key: %w", err)
+	}
+	k := &SigningKey{
+		Kid:       Thumbprint(&priv.PublicKey),
+		Private:   priv,
+		Status:    status,
+		CreatedAt: now,
+	}
+	if status == KeyStatusActive {
+		k.ActivatedAt = now
+	}
+	return k, nil
+}
+
+// ApplyRotation: RotateSigningKeys 의 순수 함수 버전. File / Memory store 가 공유.
+// 입력 슬라이스는 건드리지 않고 새 슬라이스를 돌려준다.
+func ApplyRotation(keys []*SigningKey, expectedActiveKid string, newNext *SigningKey, now, pruneBefore time.Time) ([]*SigningKey, error) {
+	cur := pickActive(keys)
+	if cur == nil || cur.Kid != expectedActiveKid {
+		return nil, ErrRotationConflict
+	}
+	var promote *SigningKey
+	for _, k := range keys {
+		if k.Status == KeyStatusNext && (promote == nil || k.CreatedAt.Before(promote.CreatedAt)) {
+			promote = k
+		}
+	}
+	if promote == nil {
+		return nil, ErrNoNextKey
+	}
+
+	out := make([]*SigningKey, 0, len(keys)+1)
+	for _, k := range keys {
+		cp := *k
+		switch {
+		case cp.Status == KeyStatusActive:
+			cp.Status = KeyStatusRetired
+			cp.RetiredAt = now
+		case cp.Kid == promote.Kid:
+			cp.Status = KeyStatusActive
+			cp.ActivatedAt = now
+		case cp.Status == KeyStatusRetired && cp.RetiredAt.Before(pruneBefore):
+			continue
+		}
+		out = append(out, &cp)
+	}
+	nn := *newNext
+	nn.Status = KeyStatusNext
+	return append(out, &nn), nil
+}
+
+// Thumbprint: RFC 7638 JWK thumbprint (SHA-256, base64url).
+// 필수 멤버(e, kty, n) 만 사전순으로 공백 없이 직렬화해 해시 → 같은 키면 항상 같은 kid.
+// 재시작해도 kid 가 바뀌지 않으므로 JWKS 를 캐시한 리소스 서버가 계속 올바르게 검증한다.
+func Thumbprint(pub *rsa.PublicKey) string {
+	n := base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	canonical := `{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// EncodePrivateKeyPEM: PKCS#8 "PRIVATE KEY" PEM 으로 직렬화.
+func EncodePrivateKeyPEM(key *rsa.PrivateKey) ([]byte, error) {
+	block, err := privateKeyBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+func privateKeyBlock(key *rsa.PrivateKey) (*pem.Block, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+}
+
+// DecodePrivateKeyPEM: PKCS#8 ("PRIVATE KEY") / PKCS#1 ("RSA PRIVATE KEY") 둘 다 허용.
+// openssl genrsa 로 만든 기존 키도 그대로 쓸 수 있게.
+func DecodePrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("PEM 블록 없음")
+	}
+	return parsePrivateKeyBlock(block)
+}
+
+func parsePrivateKeyBlock(block *pem.Block) (*rsa.PrivateKey, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("RSA 키가 아님")
+		}
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("지원하지 않는 PEM 타입: %s", block.Type)
+	}
+}
+
+// FileKeyStore: PEM 파일 기반 KeyStore. 키마다 PEM 블록 하나, 상태는 블록 헤더
+// (Status / Created-At / Activated-At / Retired-At) 에 기록한다.
+// 헤더 없는 단일 블록 (운영자가 openssl 로 만든 키, 이전 버전 파일) 은 active 로 읽는다.
+// 단일 호스트 가정 — 여러 프로세스가 같은 파일을 동시에 회전시키는 경우는 다루지 않는다.
+type FileKeyStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFileKeyStore(path string) *FileKeyStore {
+	return &FileKeyStore{Path: path}
+}
+
+func (s *FileKeyStore) ListSigningKeys(ctx context.Context) ([]*SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+func (s *FileKeyStore) AddSigningKey(ctx context.Context, k *SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, err := s.read()
+	if err != nil {
+		return err
+	}
+	for _, existing := range keys {
+		if existing.Kid == k.Kid {
+			return nil
+		}
+	}
+	if statusTaken(keys, k.Status) {
+		return ErrKeyStatusTaken
+	}
+	return s.write(append(keys, k))
+}
+
+func (s *FileKeyStore) RotateSigningKeys(ctx context.Context, expectedActiveKid string, newNext *SigningKey, now, pruneBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, err := s.read()
+	if err != nil {
+		return err
+	}
+	rotated, err := ApplyRotation(keys, expectedActiveKid, newNext, now, pruneBefore)
+	if err != nil {
+		return err
+	}
+	return s.write(rotated)
+}
+
+func (s *FileKeyStore) read() ([]*SigningKey, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []*SigningKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		priv, err := parsePrivateKeyBlock(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Path, err)
+		}
+		k := &SigningKey{
+			Kid:         Thumbprint(&priv.PublicKey),
+			Private:     priv,
+			Status:      KeyStatus(block.Headers["Status"]),
+			CreatedAt:   parseHeaderTime(block.Headers["Created-At"]),
+			ActivatedAt: parseHeaderTime(block.Headers["Activated-At"]),
+			RetiredAt:   parseHeaderTime(block.Headers["Retired-At"]),
+		}
+		if k.Status == "" {
+			k.Status = KeyStatusActive
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: PEM 블록 없음", s.Path)
+	}
+	return keys, nil
+}
+
+// write: 임시 파일에 쓰고 rename — 쓰는 도중 죽어도 기존 키 파일이 깨지지 않게. 권한 0600.
+func (s *FileKeyStore) write(keys []*SigningKey) error {
+	var buf []byte
+	for _, k := range keys {
+		block, err := privateKeyBlock(k.Private)
+		if err != nil {
+			return err
+		}
+		block.Headers = map[string]string{
+			"Kid":    k.Kid,
+			"Status": string(k.Status),
+		}
+		setHeaderTime(block.Headers, "Created-At", k.CreatedAt)
+		setHeaderTime(block.Headers, "Activated-At", k.ActivatedAt)
+		setHeaderTime(block.Headers, "Retired-At", k.RetiredAt)
+		buf = append(buf, pem.EncodeToMemory(block)...)
+	}
+
+	dir := filepath.Dir(s.Path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".signing-key-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+func parseHeaderTime(v string) time.Time {
+	t, _ := time.Parse(time.RFC3339, v)
+	return t
+}
+
+func setHeaderTime(h map[string]string, key string, t time.Time) {
+	if !t.IsZero() {
+		h[key] = t.UTC().Format(time.RFC3339)
+	}
+}
+
+// MemoryKeyStore: 프로세스 메모리에만 보관. 테스트에서 InitKeys 와 함께 사용.
+type MemoryKeyStore struct {
+	mu   sync.Mutex
+	keys []*SigningKey
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{}
+}
+
+func (s *MemoryKeyStore) ListSigningKeys(ctx context.Context) ([]*SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*SigningKey, len(s.keys))
+	for i, k := range s.keys {
+		cp := *k
+		out[i] = &cp
+	}
+	return out, nil
+}
+
+func (s *MemoryKeyStore) AddSigningKey(ctx context.Context, k *SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.keys {
+		if existing.Kid == k.Kid {
+			return nil
+		}
+	}
+	if statusTaken(s.keys, k.Status) {
+		return ErrKeyStatusTaken
+	}
+	cp := *k
+	s.keys = append(s.keys, &cp)
+	return nil
+}
+
+func (s *MemoryKeyStore) RotateSigningKeys(ctx context.Context, expectedActiveKid string, newNext *SigningKey, now, pruneBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rotated, err := ApplyRotation(s.keys, expectedActiveKid, newNext, now, pruneBefore)
+	if err != nil {
+		return err
+	}
+	s.keys = rotated
+	return nil
+}
@@ -0,0 +1,137 @@
+package token
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestThumbprint_RFC7638: RFC 7638 §3.1 예제 키의 thumbprint.
+func TestThumbprint_RFC7638(t *testing.T) {
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	if got, want := Thumbprint(pub), "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// TestFileKeyStore_PersistsAcrossLoads: 최초 생성 후 다시 로드해도 같은 kid.
+func TestFileKeyStore_PersistsAcrossLoads(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys", "signing.pem")
+
+	if err := InitKeys(ctx, NewFileKeyStore(path)); err != nil {
+		t.Fatal(err)
+	}
+	first := KeyID()
+
+	tok, err := Create("u-alice", "app1", "openid", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "재시작": 같은 파일에서 다시 로드
+	if err := InitKeys(ctx, NewFileKeyStore(path)); err != nil {
+		t.Fatal(err)
+	}
+	if KeyID() != first {
+		t.Errorf("kid 변경됨: %s → %s", first, KeyID())
+	}
+	if _, err := Parse(tok); err != nil {
+		t.Errorf("재시작 전 토큰 검증 실패: %v", err)
+	}
+}
+
+// TestRotate_OverlappingPublication: 회전 후에도 이전 토큰 검증 + JWKS 에 next/active/retired 게시.
+func TestRotate_OverlappingPublication(t *testing.T) {
+	ctx := context.Background()
+	if err := InitKeys(ctx, NewFileKeyStore(filepath.Join(t.TempDir(), "signing.pem"))); err != nil {
+		t.Fatal(err)
+	}
+	before := PublishedKeys()
+	if len(before) != 2 || before[0].Status != KeyStatusActive || before[1].Status != KeyStatusNext {
+		t.Fatalf("초기 키링 = %+v, want [active next]", before)
+	}
+	nextKid := before[1].Kid
+
+	oldTok, err := CreateIDToken("u-alice", "app1", "n", "", time.Time{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Rotate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ActiveKid != nextKid || KeyID() != nextKid {
+		t.Errorf("next 가 active 로 승격되지 않음: res=%+v kid=%s", res, KeyID())
+	}
+
+	statuses := map[KeyStatus]int{}
+	for _, k := range PublishedKeys() {
+		statuses[k.Status]++
+	}
+	if statuses[KeyStatusActive] != 1 || statuses[KeyStatusNext] != 1 || statuses[KeyStatusRetired] != 1 {
+		t.Errorf("회전 후 게시 키 = %v", statuses)
+	}
+
+	// retired 키로 서명된 토큰도 kid 로 찾아 검증
+	if _, err := ParseIDToken(oldTok); err != nil {
+		t.Errorf("회전 전 ID 토큰 검증 실패: %v", err)
+	}
+	newTok, err := Create("u-alice", "app1", "openid", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(newTok); err != nil {
+		t.Errorf("회전 후 토큰 검증 실패: %v", err)
+	}
+
+	// 다른 인스턴스가 먼저 회전한 상황 → conflict
+	if err := currentStore().RotateSigningKeys(ctx, res.RetiredKid, &SigningKey{}, time.Now(), time.Now()); !errors.Is(err, ErrRotationConflict) {
+		t.Errorf("stale active 로 회전 시도: got %v, want ErrRotationConflict", err)
+	}
+}
+
+// staleListStore: 첫 ListSigningKeys 만 빈 키링을 돌려준다 — 다른 인스턴스가 막 키를 채운 직후의 경쟁 재현.
+type staleListStore struct {
+	*MemoryKeyStore
+	stale bool
+}
+
+func (s *staleListStore) ListSigningKeys(ctx context.Context) ([]*SigningKey, error) {
+	if s.stale {
+		s.stale = false
+		return nil, nil
+	}
+	return s.MemoryKeyStore.ListSigningKeys(ctx)
+}
+
+// TestReload_ConcurrentInit: 다른 인스턴스가 먼저 active / next 를 채웠으면 새 키를 더하지 않고 그 키를 쓴다.
+func TestReload_ConcurrentInit(t *testing.T) {
+	ctx := context.Background()
+	shared := NewMemoryKeyStore()
+	if err := InitKeys(ctx, shared); err != nil {
+		t.Fatal(err)
+	}
+	winner := KeyID()
+
+	if err := InitKeys(ctx, &staleListStore{MemoryKeyStore: shared, stale: true}); err != nil {
+		t.Fatalf("경쟁 Reload: %v", err)
+	}
+	if KeyID() != winner {
+		t.Errorf("active kid = %s, want 먼저 채운 %s", KeyID(), winner)
+	}
+	keys, _ := shared.ListSigningKeys(ctx)
+	if len(keys) != 2 {
+		t.Errorf("키링 %d 개, want 2 (active + next)", len(keys))
+	}
+}
@@ -0,0 +1,102 @@
+// Package totp 는 RFC 6238 TOTP (HMAC-SHA1, 30초, 6자리) — Google Authenticator 등 일반 인증 앱 기본값.
+//
+// 검증은 앞뒤 1 step (±30초) 시계 오차를 허용하고, 통과한 step 을 돌려준다.
+// 같은 코드 재사용 차단은 호출자가 step 을 저장해서 한다 (store.UserStore.ConsumeTOTPStep).
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Period = 30 * time.Second
+	Digits = 6
+	// Skew: 허용하는 앞뒤 step 수. 1 이면 직전 / 현재 / 다음 코드가 모두 통과.
+	Skew = 1
+)
+
+// secretBytes: RFC 4226 권장 160bit.
+const secretBytes = 20
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret: 새 base32 secret (패딩 없음, 32자).
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// URI: 인증 앱 등록용 otpauth URI (QR 코드 내용). Key Uri Format — issuer 를 label 과 파라미터 양쪽에.
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(int(Period/time.Second)))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// Step: t 가 속한 time step 번호.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code: secret 의 step 번째 코드 (0 패딩 6자리).
+func Code(secret string, step int64) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(step), Digits), nil
+}
+
+// Validate: code 가 now 기준 ±Skew step 안의 코드인가. 통과하면 일치한 step.
+// 공백 / 하이픈은 무시 (인증 앱이 "123 456" 으로 보여주는 경우).
+func Validate(secret, code string, now time.Time) (int64, bool) {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	cur := Step(now)
+	for d := int64(-Skew); d <= Skew; d++ {
+		want := hotp(key, uint64(cur+d), Digits)
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return cur + d, true
+		}
+	}
+	return 0, false
+}
+
+// hotp: RFC 4226 §5.3 dynamic truncation.
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	m := hmac.New(sha1.New, key)
+	m.Write(msg[:])
+	sum := m.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, bin%mod)
+}
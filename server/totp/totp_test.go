@@ -0,0 +1,65 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B (SHA1) 의 8자리 값 중 하위 6자리.
+func TestCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range cases {
+		got, err := Code(secret, Step(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("t=%d: got %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	step := Step(now)
+
+	for d := int64(-2); d <= 2; d++ {
+		code, _ := Code(secret, step+d)
+		got, ok := Validate(secret, code[:3]+" "+code[3:], now)
+		wantOK := d >= -Skew && d <= Skew
+		if ok != wantOK {
+			t.Errorf("step%+d: ok=%v, want %v", d, ok, wantOK)
+		}
+		if ok && got != step+d {
+			t.Errorf("step%+d: 반환 step=%d, want %d", d, got, step+d)
+		}
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Error("5자리 코드가 통과")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("oauth.example", "alice", "JBSWY3DPEHPK3PXP")
+	for _, want := range []string{"otpauth://totp/oauth.example:alice?", "secret=JBSWY3DPEHPK3PXP", "issuer=oauth.example", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("%q 에 %q 없음", uri, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// 최소 CBOR (RFC 8949) 디코더 — attestationObject / COSE 키 해석에 필요한 만큼만.
+//
+//	uint / negint → int64, bytes → []byte, text → string, array → []any,
+//	map → map[any]any (키는 int64 또는 string), false / true / null.
+//
+// 부동소수점 · 무한 길이 (indefinite) · tag 는 WebAuthn 구조에 나오지 않으므로 거부한다.
+
+const cborMaxDepth = 16
+
+var errCBOR = errors.New("webauthn: malformed cbor")
+
+// decodeCBOR: b 의 첫 데이터 항목과 나머지 바이트. authData 처럼 뒤에 다른 데이터가 붙는 경우 rest 로 길이를 안다.
+func decodeCBOR(b []byte) (v any, rest []byte, err error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (any, []byte, error) {
+	if depth > cborMaxDepth || len(b) == 0 {
+		return nil, nil, errCBOR
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22:
+			return nil, b, nil
+		}
+		return nil, nil, errCBOR
+	}
+
+	n, b, err := cborArg(info, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if n > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return int64(n), b, nil
+	case 1:
+		if n > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return -1 - int64(n), b, nil
+	case 2, 3:
+		if n > uint64(len(b)) {
+			return nil, nil, errCBOR
+		}
+		if major == 2 {
+			return append([]byte(nil), b[:n]...), b[n:], nil
+		}
+		return string(b[:n]), b[n:], nil
+	case 4:
+		if n > uint64(len(b)) { // 항목마다 최소 1바이트
+			return nil, nil, errCBOR
+		}
+		arr := make([]any, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var item any
+			item, b, err = decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			arr = append(arr, item)
+		}
+		return arr, b, nil
+	case 5:
+		if n > uint64(len(b))/2 {
+			return nil, nil, errCBOR
+		}
+		m := make(map[any]any, n)
+		for i := uint64(0); i < n; i++ {
+			var k, item any
+			k, b, err = decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errCBOR
+			}
+			if _, dup := m[k]; dup {
+				return nil, nil, errCBOR
+			}
+			item, b, err = decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[k] = item
+		}
+		return m, b, nil
+	}
+	return nil, nil, errCBOR // 6: tag
+}
+
+// cborArg: 헤더 additional info 에 따른 길이 / 값 인자.
+func cborArg(info byte, b []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), b, nil
+	case info == 24 && len(b) >= 1:
+		return uint64(b[0]), b[1:], nil
+	case info == 25 && len(b) >= 2:
+		return uint64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case info == 26 && len(b) >= 4:
+		return uint64(binary.BigEndian.Uint32(b)), b[4:], nil
+	case info == 27 && len(b) >= 8:
+		return binary.BigEndian.Uint64(b), b[8:], nil
+	}
+	return 0, nil, errCBOR
+}
@@ -0,0 +1,118 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSE 알고리즘 (RFC 9053). pubKeyCredParams 에 선호 순서대로 싣는다.
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// SupportedAlgorithms: 등록 옵션에 싣는 알고리즘 — 대부분의 플랫폼 인증기는 ES256.
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE 키 파라미터 라벨.
+const (
+	coseKty = 1
+	coseAlg = 3
+	coseCrv = -1 // EC2 / OKP
+	coseX   = -2
+	coseY   = -3
+	coseN   = -1 // RSA
+	coseE   = -2
+
+	coseKtyOKP     = 1
+	coseKtyEC2     = 2
+	coseKtyRSA     = 3
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+var errUnsupportedKey = errors.New("webauthn: unsupported public key")
+
+// publicKey: COSE 키를 해석한 결과. alg 는 서명 검증 방식을 고정한다.
+type publicKey struct {
+	alg int
+	key crypto.PublicKey
+}
+
+// parseCOSEKey: authData 에 실린 COSE_Key (CBOR map) → 공개키. 지원 조합 외에는 errUnsupportedKey.
+func parseCOSEKey(raw []byte) (*publicKey, error) {
+	v, rest, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[any]any)
+	if !ok || len(rest) != 0 {
+		return nil, errCBOR
+	}
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errUnsupportedKey
+		}
+		// 곡선 위의 점인지는 ecdh 파서로 확인 (invalid curve 공격 방지)
+		point := append(append([]byte{0x04}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, errUnsupportedKey
+		}
+		return &publicKey{alg: AlgES256, key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+
+	case kty == coseKtyOKP && alg == AlgEdDSA:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errUnsupportedKey
+		}
+		return &publicKey{alg: AlgEdDSA, key: ed25519.PublicKey(x)}, nil
+
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := m[int64(coseN)].([]byte)
+		e, _ := m[int64(coseE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errUnsupportedKey // 2048bit 미만 거부
+		}
+		eInt := 0
+		for _, b := range e {
+			eInt = eInt<<8 | int(b)
+		}
+		return &publicKey{alg: AlgRS256, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: eInt}}, nil
+	}
+	return nil, errUnsupportedKey
+}
+
+// verify: data 에 대한 서명 확인. ES256 서명은 ASN.1 DER (WebAuthn 규격).
+func (k *publicKey) verify(data, sig []byte) bool {
+	switch k.alg {
+	case AlgES256:
+		h := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(k.key.(*ecdsa.PublicKey), h[:], sig)
+	case AlgEdDSA:
+		return ed25519.Verify(k.key.(ed25519.PublicKey), data, sig)
+	case AlgRS256:
+		h := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(k.key.(*rsa.PublicKey), crypto.SHA256, h[:], sig) == nil
+	}
+	return false
+}
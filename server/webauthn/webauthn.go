@@ -0,0 +1,330 @@
+// Package webauthn 은 WebAuthn Level 2 의 relying party 쪽 검증 — 등록 (create) 과 인증 (get) ceremony.
+//
+// 범위:
+//   - attestation 은 "none" 만 요청 / 수용 — 인증기 제조사 증명은 신뢰 근거로 쓰지 않는다
+//   - 공개키: ES256 / EdDSA / RS256 (COSE)
+//   - 사용자 확인 (UV) 필수 — 비밀번호 없이 로그인하므로 기기 잠금 해제가 두 번째 요소
+//
+// challenge 보관 (쿠키 / 세션) 과 credential 저장은 호출자 몫. 이 패키지는 바이트만 검증한다.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Timeout: 브라우저 ceremony 제한 시간 (옵션의 timeout). challenge 보관 TTL 도 이 값에 맞춘다.
+const Timeout = 5 * time.Minute
+
+const challengeBytes = 32
+
+// authenticator data flags (WebAuthn §6.1).
+const (
+	flagUP = 0x01 // user present
+	flagUV = 0x04 // user verified
+	flagBE = 0x08 // backup eligible (동기화 패스키)
+	flagAT = 0x40 // attested credential data 포함
+	flagED = 0x80 // extension data 포함
+)
+
+var (
+	// ErrVerification: ceremony 응답이 규격 / challenge / origin / 서명 검증을 통과하지 못함. 상세 사유는 wrap.
+	ErrVerification = errors.New("webauthn: verification failed")
+	// ErrSignCount: 서명 카운터가 줄거나 그대로 — 복제된 인증기 신호.
+	ErrSignCount = errors.New("webauthn: sign count did not increase")
+)
+
+// B64 는 WebAuthn JSON 에서 쓰는 base64url (패딩 없음). credential ID · challenge · 폼 필드 모두 이 인코딩.
+var B64 = base64.RawURLEncoding
+
+// RelyingParty: 이 IdP 의 RP 설정.
+//
+//	ID     : RP ID — origin 의 등록 가능한 도메인 (예: "id.example.com"). 포트 / scheme 없음
+//	Name   : 인증기 UI 에 보일 이름
+//	Origins: 허용 origin (예: "https://id.example.com"). clientDataJSON.origin 과 정확히 비교
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// Credential: 등록 ceremony 를 통과한 새 credential. 호출자가 사용자와 묶어 저장한다.
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte // COSE_Key 원본 — 인증 때 그대로 VerifyAssertion 에 넘긴다
+	SignCount      uint32
+	BackupEligible bool
+}
+
+// NewChallenge: ceremony 1회용 랜덤 challenge (32바이트).
+func NewChallenge() ([]byte, error) {
+	b := make([]byte, challengeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ───── 브라우저로 보내는 옵션 (navigator.credentials.create / get) ─────
+// 바이너리 필드는 base64url 문자열 — 페이지 스크립트가 ArrayBuffer 로 바꿔 넘긴다.
+
+type RPEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions: PublicKeyCredentialCreationOptions.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RPEntity               `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions: PublicKeyCredentialRequestOptions.
+// AllowCredentials 가 비어 있으면 discoverable credential (패스키) — 사용자 이름 입력 없이 고른다.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	UserVerification string                 `json:"userVerification"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+}
+
+// CreationOptions: 등록 옵션. userHandle 은 인증 응답의 userHandle 로 돌아온다 (사용자 ID 바이트).
+// exclude 의 credential 은 같은 인증기에 중복 등록되지 않는다.
+func (rp RelyingParty) CreationOptions(challenge, userHandle []byte, name, displayName string, exclude [][]byte) CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	return CreationOptions{
+		Challenge:          B64.EncodeToString(challenge),
+		RP:                 RPEntity{ID: rp.ID, Name: rp.Name},
+		User:               UserEntity{ID: B64.EncodeToString(userHandle), Name: name, DisplayName: displayName},
+		PubKeyCredParams:   params,
+		Timeout:            Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions: 인증 옵션. allow 가 nil 이면 discoverable credential 로 고르게 한다.
+func (rp RelyingParty) RequestOptions(challenge []byte, allow [][]byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        B64.EncodeToString(challenge),
+		RPID:             rp.ID,
+		Timeout:          Timeout.Milliseconds(),
+		UserVerification: "required",
+		AllowCredentials: descriptors(allow),
+	}
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	out := make([]CredentialDescriptor, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, CredentialDescriptor{Type: "public-key", ID: B64.EncodeToString(id)})
+	}
+	return out
+}
+
+// ───── 검증 ─────
+
+// VerifyRegistration: navigator.credentials.create() 응답 검증 (WebAuthn §7.1).
+func (rp RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	v, rest, err := decodeCBOR(attestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: attestationObject", ErrVerification)
+	}
+	att, ok := v.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: attestationObject", ErrVerification)
+	}
+	format, _ := att["fmt"].(string)
+	stmt, _ := att["attStmt"].(map[any]any)
+	rawAuthData, _ := att["authData"].([]byte)
+	if format != "none" || len(stmt) != 0 {
+		return nil, fmt.Errorf("%w: attestation format %q not accepted", ErrVerification, format)
+	}
+
+	ad, err := rp.parseAuthData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if ad.flags&flagAT == 0 || len(ad.credentialID) == 0 {
+		return nil, fmt.Errorf("%w: no attested credential", ErrVerification)
+	}
+	pk, err := parseCOSEKey(ad.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+	if !slices.Contains(SupportedAlgorithms, pk.alg) {
+		return nil, fmt.Errorf("%w: algorithm %d", ErrVerification, pk.alg)
+	}
+	return &Credential{
+		ID:             ad.credentialID,
+		PublicKey:      ad.publicKey,
+		SignCount:      ad.signCount,
+		BackupEligible: ad.flags&flagBE != 0,
+	}, nil
+}
+
+// VerifyAssertion: navigator.credentials.get() 응답 검증 (WebAuthn §7.2).
+// publicKey / storedSignCount 는 credential ID 로 찾은 저장값. 통과하면 저장할 새 sign count.
+//
+// 카운터를 쓰지 않는 인증기 (동기화 패스키 대부분) 는 항상 0 — 둘 다 0 이면 비교하지 않는다.
+func (rp RelyingParty) VerifyAssertion(challenge, publicKey []byte, storedSignCount uint32, clientDataJSON, authenticatorData, signature []byte) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	ad, err := rp.parseAuthData(authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	pk, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: stored key: %v", ErrVerification, err)
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+	if !pk.verify(signed, signature) {
+		return 0, fmt.Errorf("%w: signature", ErrVerification)
+	}
+
+	if (ad.signCount != 0 || storedSignCount != 0) && ad.signCount <= storedSignCount {
+		return 0, ErrSignCount
+	}
+	return ad.signCount, nil
+}
+
+// collectedClientData: clientDataJSON 중 검증에 쓰는 필드.
+type collectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (rp RelyingParty) verifyClientData(raw []byte, wantType string, challenge []byte) error {
+	var cd collectedClientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("%w: clientDataJSON", ErrVerification)
+	}
+	if cd.Type != wantType {
+		return fmt.Errorf("%w: type %q", ErrVerification, cd.Type)
+	}
+	got, err := B64.DecodeString(cd.Challenge)
+	if err != nil || len(challenge) == 0 || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return fmt.Errorf("%w: challenge mismatch", ErrVerification)
+	}
+	if !slices.Contains(rp.Origins, cd.Origin) {
+		return fmt.Errorf("%w: origin %q", ErrVerification, cd.Origin)
+	}
+	if cd.CrossOrigin {
+		return fmt.Errorf("%w: cross-origin iframe", ErrVerification)
+	}
+	return nil
+}
+
+// authData: authenticator data 해석 결과 (WebAuthn §6.1).
+type authData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthData: rpIdHash / UP / UV 확인 + (AT 플래그 시) credential ID · COSE 키 분리.
+func (rp RelyingParty) parseAuthData(b []byte) (*authData, error) {
+	if len(b) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrVerification)
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(b[:32], rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: rpIdHash", ErrVerification)
+	}
+	ad := &authData{flags: b[32], signCount: binary.BigEndian.Uint32(b[33:37])}
+	if ad.flags&flagUP == 0 {
+		return nil, fmt.Errorf("%w: user not present", ErrVerification)
+	}
+	if ad.flags&flagUV == 0 {
+		return nil, fmt.Errorf("%w: user not verified", ErrVerification)
+	}
+	rest := b[37:]
+
+	if ad.flags&flagAT != 0 {
+		// aaguid(16) + credentialIdLength(2) + credentialId + credentialPublicKey(CBOR)
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data", ErrVerification)
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+			return nil, fmt.Errorf("%w: credential id", ErrVerification)
+		}
+		ad.credentialID = append([]byte(nil), rest[:idLen]...)
+		rest = rest[idLen:]
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key", ErrVerification)
+		}
+		ad.publicKey = append([]byte(nil), rest[:len(rest)-len(after)]...)
+		rest = after
+	}
+	if ad.flags&flagED != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensions", ErrVerification)
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing authenticator data", ErrVerification)
+	}
+	return ad, nil
+}
@@ -0,0 +1,107 @@
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ftery0/ouath/server/webauthn"
+	"github.com/ftery0/ouath/server/webauthn/webauthntest"
+)
+
+var rp = webauthn.RelyingParty{ID: "localhost", Name: "oauth", Origins: []string{"http://localhost:8080"}}
+
+// register: 소프트웨어 인증기로 등록 ceremony 를 마치고 credential 반환.
+func register(t *testing.T, auth *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+	challenge, _ := webauthn.NewChallenge()
+	clientData, attObj, err := auth.Create(rp.CreationOptions(challenge, []byte("u-alice"), "alice", "Alice", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := rp.VerifyRegistration(challenge, clientData, attObj)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	return cred
+}
+
+func TestRegisterAndAssert(t *testing.T) {
+	auth := webauthntest.New("http://localhost:8080")
+	cred := register(t, auth)
+	if string(cred.ID) != string(auth.CredentialID()) {
+		t.Fatalf("credential id = %x, want %x", cred.ID, auth.CredentialID())
+	}
+
+	count := cred.SignCount
+	for i := 0; i < 2; i++ {
+		challenge, _ := webauthn.NewChallenge()
+		a, err := auth.Get(rp.RequestOptions(challenge, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err = rp.VerifyAssertion(challenge, cred.PublicKey, count, a.ClientDataJSON, a.AuthenticatorData, a.Signature)
+		if err != nil {
+			t.Fatalf("VerifyAssertion #%d: %v", i, err)
+		}
+	}
+	if count != 2 {
+		t.Errorf("sign count = %d, want 2", count)
+	}
+}
+
+func TestRegistration_Rejects(t *testing.T) {
+	challenge, _ := webauthn.NewChallenge()
+	other, _ := webauthn.NewChallenge()
+
+	cases := []struct {
+		name   string
+		auth   *webauthntest.Authenticator
+		opts   webauthn.CreationOptions
+		verify []byte
+	}{
+		{"다른 challenge", webauthntest.New("http://localhost:8080"), rp.CreationOptions(challenge, []byte("u"), "a", "A", nil), other},
+		{"다른 origin", webauthntest.New("https://evil.example"), rp.CreationOptions(challenge, []byte("u"), "a", "A", nil), challenge},
+		{"다른 RP ID", webauthntest.New("http://localhost:8080"), webauthn.RelyingParty{ID: "evil.example"}.CreationOptions(challenge, []byte("u"), "a", "A", nil), challenge},
+		{"UV 없음", &webauthntest.Authenticator{Origin: "http://localhost:8080"}, rp.CreationOptions(challenge, []byte("u"), "a", "A", nil), challenge},
+	}
+	for _, tc := range cases {
+		clientData, attObj, err := tc.auth.Create(tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rp.VerifyRegistration(tc.verify, clientData, attObj); !errors.Is(err, webauthn.ErrVerification) {
+			t.Errorf("%s: err = %v, want ErrVerification", tc.name, err)
+		}
+	}
+}
+
+func TestAssertion_Rejects(t *testing.T) {
+	auth := webauthntest.New("http://localhost:8080")
+	cred := register(t, auth)
+
+	challenge, _ := webauthn.NewChallenge()
+	a, _ := auth.Get(rp.RequestOptions(challenge, nil))
+
+	// 서명 변조
+	bad := append([]byte(nil), a.Signature...)
+	bad[len(bad)-1] ^= 0xff
+	if _, err := rp.VerifyAssertion(challenge, cred.PublicKey, 0, a.ClientDataJSON, a.AuthenticatorData, bad); !errors.Is(err, webauthn.ErrVerification) {
+		t.Errorf("변조 서명: err = %v", err)
+	}
+	// 다른 ceremony 의 challenge 로 검증
+	other, _ := webauthn.NewChallenge()
+	if _, err := rp.VerifyAssertion(other, cred.PublicKey, 0, a.ClientDataJSON, a.AuthenticatorData, a.Signature); !errors.Is(err, webauthn.ErrVerification) {
+		t.Errorf("다른 challenge: err = %v", err)
+	}
+	// 카운터가 저장값보다 크지 않음 → 복제 의심
+	if _, err := rp.VerifyAssertion(challenge, cred.PublicKey, 5, a.ClientDataJSON, a.AuthenticatorData, a.Signature); !errors.Is(err, webauthn.ErrSignCount) {
+		t.Errorf("카운터 역행: err = %v, want ErrSignCount", err)
+	}
+
+	// 카운터 미사용 인증기 (항상 0) 는 통과
+	auth.NoCounter, auth.SignCount = true, 0
+	a, _ = auth.Get(rp.RequestOptions(challenge, nil))
+	if _, err := rp.VerifyAssertion(challenge, cred.PublicKey, 0, a.ClientDataJSON, a.AuthenticatorData, a.Signature); err != nil {
+		t.Errorf("카운터 0 인증기: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+// Package webauthntest 는 테스트용 소프트웨어 인증기 — 브라우저 + 인증기가 만드는 ceremony 응답을 Go 에서 흉내낸다.
+//
+// ES256 키 하나 (credential 1개) 를 들고, 옵션을 받아 clientDataJSON / attestationObject / 서명을 만든다.
+// 운영 코드에서 쓰지 않는다.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/ftery0/ouath/server/webauthn"
+)
+
+// Authenticator: 소프트웨어 플랫폼 인증기.
+//
+//	Origin     : clientDataJSON.origin 에 실을 값 (브라우저가 채우는 값)
+//	SignCount  : 인증할 때마다 1씩 증가. 0 으로 고정하려면 NoCounter
+//	UserVerify : false 면 UV 플래그 없이 응답 (거부 경로 테스트용)
+type Authenticator struct {
+	Origin     string
+	NoCounter  bool
+	UserVerify bool
+	SignCount  uint32
+
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	rpID         string
+}
+
+// Assertion: navigator.credentials.get() 응답 필드.
+type Assertion struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+// New: origin 의 브라우저에 붙은 새 인증기 (UV 지원).
+func New(origin string) *Authenticator {
+	return &Authenticator{Origin: origin, UserVerify: true}
+}
+
+// CredentialID: Create 로 만든 credential 의 ID. Create 전에는 nil.
+func (a *Authenticator) CredentialID() []byte { return a.credentialID }
+
+// Create: 등록 ceremony — 키 생성 후 clientDataJSON 과 "none" attestationObject.
+func (a *Authenticator) Create(opts webauthn.CreationOptions) (clientDataJSON, attestationObject []byte, err error) {
+	challenge, err := webauthn.B64.DecodeString(opts.Challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+	userHandle, err := webauthn.B64.DecodeString(opts.User.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, nil, err
+	}
+	a.key, a.credentialID, a.userHandle, a.rpID = key, id, userHandle, opts.RP.ID
+
+	clientDataJSON = a.clientData("webauthn.create", challenge)
+
+	ecdhKey, err := key.PublicKey.ECDH()
+	if err != nil {
+		return nil, nil, err
+	}
+	point := ecdhKey.Bytes() // 0x04 || X || Y
+	coseKey := encodeMap(
+		[]any{int64(1), int64(2)},      // kty: EC2
+		[]any{int64(3), int64(-7)},     // alg: ES256
+		[]any{int64(-1), int64(1)},     // crv: P-256
+		[]any{int64(-2), point[1:33]},  // x
+		[]any{int64(-3), point[33:65]}, // y
+	)
+
+	authData := a.authData(0x40)                     // AT
+	authData = append(authData, make([]byte, 16)...) // aaguid: 0
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(id)))
+	authData = append(authData, id...)
+	authData = append(authData, coseKey...)
+
+	attestationObject = encodeMap(
+		[]any{"fmt", "none"},
+		[]any{"attStmt", map[string]any{}},
+		[]any{"authData", authData},
+	)
+	return clientDataJSON, attestationObject, nil
+}
+
+// Get: 인증 ceremony — authenticatorData || sha256(clientDataJSON) 에 서명.
+func (a *Authenticator) Get(opts webauthn.RequestOptions) (*Assertion, error) {
+	if a.key == nil {
+		return nil, errors.New("webauthntest: no credential")
+	}
+	challenge, err := webauthn.B64.DecodeString(opts.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if !a.NoCounter {
+		a.SignCount++
+	}
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+	authData := a.authData(0)
+	h := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), h[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Assertion{
+		CredentialID:      a.credentialID,
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authData,
+		Signature:         sig,
+		UserHandle:        a.userHandle,
+	}, nil
+}
+
+func (a *Authenticator) clientData(typ string, challenge []byte) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":        typ,
+		"challenge":   webauthn.B64.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return b
+}
+
+// authData: rpIdHash + flags (UP, UV, extra) + signCount.
+func (a *Authenticator) authData(extra byte) []byte {
+	h := sha256.Sum256([]byte(a.rpID))
+	flags := byte(0x01) | extra
+	if a.UserVerify {
+		flags |= 0x04
+	}
+	b := append(h[:], flags)
+	return binary.BigEndian.AppendUint32(b, a.SignCount)
+}
+
+// ───── 최소 CBOR 인코더 (canonical 순서는 호출자가 맞춘다) ─────
+
+func encodeMap(pairs ...[]any) []byte {
+	b := cborHead(5, uint64(len(pairs)))
+	for _, kv := range pairs {
+		b = append(b, encode(kv[0])...)
+		b = append(b, encode(kv[1])...)
+	}
+	return b
+}
+
+func encode(v any) []byte {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case map[string]any:
+		pairs := make([][]any, 0, len(v))
+		for k, item := range v {
+			pairs = append(pairs, []any{k, item})
+		}
+		return encodeMap(pairs...)
+	}
+	panic("webauthntest: unsupported cbor value")
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+}
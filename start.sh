@@ -26,7 +26,7 @@ cat <<EOF
           docker compose down -v                (데이터까지 초기화)
 
 ▶ 접속
-   어드민:  http://localhost:8080/admin/login   (비밀번호: admin)
+   어드민:  http://localhost:8080/admin/login   (alice / password123)
    app1 :  http://localhost:5181     (Notebook,  Spring + React)
    app2 :  http://localhost:5182     (TaskBoard, Node + Vue)
    app3 :  http://localhost:5183     (HelpDesk,  FastAPI + Next)